@@ -0,0 +1,399 @@
+package cat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/extensions"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/hangxie/parquet-go/v3/parquet"
+
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// arrowNode tells how a JSON friendly value of a parquet field is converted to
+// what Arrow JSON builders expect.
+type arrowNode struct {
+	name    string // field name in row
+	kind    string // struct, list, map, json, time, timestamp, or empty for values taken as is
+	unit    arrow.TimeUnit
+	fields  []*arrowNode
+	key     *arrowNode // map keys
+	element *arrowNode // list items and map values
+}
+
+// arrowOutput writes rows as Arrow IPC stream.
+type arrowOutput struct {
+	root   *arrowNode
+	schema *arrow.Schema
+}
+
+// newArrowOutput maps parquet schema to Arrow schema.
+func newArrowOutput(schemaRoot *pschema.SchemaNode) (*arrowOutput, error) {
+	root := &arrowNode{kind: "struct"}
+	fields := make([]arrow.Field, len(schemaRoot.Children))
+	for index, child := range schemaRoot.Children {
+		node, field, err := newArrowField(child)
+		if err != nil {
+			return nil, err
+		}
+		root.fields = append(root.fields, node)
+		fields[index] = field
+	}
+	return &arrowOutput{root: root, schema: arrow.NewSchema(fields, nil)}, nil
+}
+
+func arrowFieldName(node *pschema.SchemaNode) string {
+	if len(node.ExNamePath) != 0 {
+		return node.ExNamePath[len(node.ExNamePath)-1]
+	}
+	return node.Name
+}
+
+func newArrowField(node *pschema.SchemaNode) (*arrowNode, arrow.Field, error) {
+	name := arrowFieldName(node)
+	result, dataType, err := newArrowType(node)
+	if err != nil {
+		return nil, arrow.Field{}, err
+	}
+	result.name = name
+
+	// UNKNOWN logical type is always null unless --raw-unknown is set
+	nullable := node.RepetitionType != nil && *node.RepetitionType == parquet.FieldRepetitionType_OPTIONAL
+	nullable = nullable || node.LogicalType != nil && node.LogicalType.IsSetUNKNOWN()
+	if node.RepetitionType != nil && *node.RepetitionType == parquet.FieldRepetitionType_REPEATED {
+		result = &arrowNode{name: name, kind: "list", element: result}
+		dataType = arrow.ListOfNonNullable(dataType)
+	}
+	return result, arrow.Field{Name: name, Type: dataType, Nullable: nullable}, nil
+}
+
+// newArrowItem maps list items and map keys/values, which are named by Arrow.
+func newArrowItem(node *pschema.SchemaNode) (*arrowNode, arrow.Field, error) {
+	result, dataType, err := newArrowType(node)
+	if err != nil {
+		return nil, arrow.Field{}, err
+	}
+	nullable := node.RepetitionType != nil && *node.RepetitionType == parquet.FieldRepetitionType_OPTIONAL
+	return result, arrow.Field{Name: "element", Type: dataType, Nullable: nullable}, nil
+}
+
+func newArrowType(node *pschema.SchemaNode) (*arrowNode, arrow.DataType, error) {
+	name := arrowFieldName(node)
+	if node.ConvertedType != nil && *node.ConvertedType == parquet.ConvertedType_LIST {
+		item := listElement(node)
+		if item == nil {
+			return nil, nil, fmt.Errorf("field [%s] is not a valid LIST", name)
+		}
+		element, field, err := newArrowItem(item)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &arrowNode{kind: "list", element: element}, arrow.ListOfField(field), nil
+	}
+	if node.ConvertedType != nil && (*node.ConvertedType == parquet.ConvertedType_MAP || *node.ConvertedType == parquet.ConvertedType_MAP_KEY_VALUE) {
+		if len(node.Children) != 1 || len(node.Children[0].Children) != 2 {
+			return nil, nil, fmt.Errorf("field [%s] is not a valid MAP", name)
+		}
+		key, keyField, err := newArrowItem(node.Children[0].Children[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		value, valueField, err := newArrowItem(node.Children[0].Children[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		mapType := arrow.MapOf(keyField.Type, valueField.Type)
+		mapType.SetItemNullable(valueField.Nullable)
+		return &arrowNode{kind: "map", key: key, element: value}, mapType, nil
+	}
+	if node.LogicalType != nil && node.LogicalType.IsSetVARIANT() {
+		return &arrowNode{kind: "json"}, arrow.BinaryTypes.String, nil
+	}
+	if node.Type == nil {
+		result := &arrowNode{kind: "struct"}
+		fields := make([]arrow.Field, len(node.Children))
+		for index, child := range node.Children {
+			childNode, field, err := newArrowField(child)
+			if err != nil {
+				return nil, nil, err
+			}
+			result.fields = append(result.fields, childNode)
+			fields[index] = field
+		}
+		return result, arrow.StructOf(fields...), nil
+	}
+	return newArrowScalarType(node)
+}
+
+func arrowTimeUnit(unit *parquet.TimeUnit) arrow.TimeUnit {
+	switch {
+	case unit == nil:
+		return arrow.Nanosecond
+	case unit.IsSetMILLIS():
+		return arrow.Millisecond
+	case unit.IsSetMICROS():
+		return arrow.Microsecond
+	}
+	return arrow.Nanosecond
+}
+
+func newArrowScalarType(node *pschema.SchemaNode) (*arrowNode, arrow.DataType, error) {
+	logicalType := node.LogicalType
+	convertedType := parquet.ConvertedType(-1)
+	if node.ConvertedType != nil {
+		convertedType = *node.ConvertedType
+	}
+
+	switch {
+	case logicalType != nil && logicalType.IsSetDECIMAL(), convertedType == parquet.ConvertedType_DECIMAL:
+		precision, scale := node.GetPrecision(), node.GetScale()
+		if logicalType != nil && logicalType.IsSetDECIMAL() {
+			precision, scale = logicalType.DECIMAL.Precision, logicalType.DECIMAL.Scale
+		}
+		if precision > 38 {
+			return &arrowNode{}, &arrow.Decimal256Type{Precision: precision, Scale: scale}, nil
+		}
+		return &arrowNode{}, &arrow.Decimal128Type{Precision: precision, Scale: scale}, nil
+	case logicalType != nil && logicalType.IsSetDATE(), convertedType == parquet.ConvertedType_DATE:
+		return &arrowNode{}, arrow.FixedWidthTypes.Date32, nil
+	case convertedType == parquet.ConvertedType_TIME_MILLIS:
+		return &arrowNode{kind: "time", unit: arrow.Millisecond}, arrow.FixedWidthTypes.Time32ms, nil
+	case convertedType == parquet.ConvertedType_TIME_MICROS:
+		return &arrowNode{kind: "time", unit: arrow.Microsecond}, arrow.FixedWidthTypes.Time64us, nil
+	case logicalType != nil && logicalType.IsSetTIME():
+		unit := arrowTimeUnit(logicalType.TIME.Unit)
+		if unit == arrow.Millisecond {
+			return &arrowNode{kind: "time", unit: unit}, arrow.FixedWidthTypes.Time32ms, nil
+		}
+		return &arrowNode{kind: "time", unit: unit}, &arrow.Time64Type{Unit: unit}, nil
+	case logicalType != nil && logicalType.IsSetTIMESTAMP():
+		unit := arrowTimeUnit(logicalType.TIMESTAMP.Unit)
+		dataType := &arrow.TimestampType{Unit: unit}
+		if logicalType.TIMESTAMP.IsAdjustedToUTC {
+			dataType.TimeZone = "UTC"
+		}
+		return &arrowNode{kind: "timestamp", unit: unit}, dataType, nil
+	case convertedType == parquet.ConvertedType_TIMESTAMP_MILLIS:
+		return &arrowNode{kind: "timestamp", unit: arrow.Millisecond}, &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}, nil
+	case convertedType == parquet.ConvertedType_TIMESTAMP_MICROS:
+		return &arrowNode{kind: "timestamp", unit: arrow.Microsecond}, &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}, nil
+	case *node.Type == parquet.Type_INT96:
+		return &arrowNode{kind: "timestamp", unit: arrow.Nanosecond}, &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}, nil
+	case logicalType != nil && logicalType.IsSetUUID():
+		return &arrowNode{}, extensions.NewUUIDType(), nil
+	case logicalType != nil && logicalType.IsSetFLOAT16():
+		return &arrowNode{}, arrow.FixedWidthTypes.Float16, nil
+	case logicalType != nil && logicalType.IsSetINTEGER():
+		return &arrowNode{}, arrowIntegerType(logicalType.INTEGER.BitWidth, logicalType.INTEGER.IsSigned), nil
+	case convertedType == parquet.ConvertedType_INT_8:
+		return &arrowNode{}, arrow.PrimitiveTypes.Int8, nil
+	case convertedType == parquet.ConvertedType_INT_16:
+		return &arrowNode{}, arrow.PrimitiveTypes.Int16, nil
+	case convertedType == parquet.ConvertedType_INT_32:
+		return &arrowNode{}, arrow.PrimitiveTypes.Int32, nil
+	case convertedType == parquet.ConvertedType_INT_64:
+		return &arrowNode{}, arrow.PrimitiveTypes.Int64, nil
+	case convertedType == parquet.ConvertedType_UINT_8:
+		return &arrowNode{}, arrow.PrimitiveTypes.Uint8, nil
+	case convertedType == parquet.ConvertedType_UINT_16:
+		return &arrowNode{}, arrow.PrimitiveTypes.Uint16, nil
+	case convertedType == parquet.ConvertedType_UINT_32:
+		return &arrowNode{}, arrow.PrimitiveTypes.Uint32, nil
+	case convertedType == parquet.ConvertedType_UINT_64:
+		return &arrowNode{}, arrow.PrimitiveTypes.Uint64, nil
+	case logicalType != nil && (logicalType.IsSetSTRING() || logicalType.IsSetENUM()),
+		convertedType == parquet.ConvertedType_UTF8, convertedType == parquet.ConvertedType_ENUM:
+		return &arrowNode{}, arrow.BinaryTypes.String, nil
+	case logicalType != nil && !logicalType.IsSetUNKNOWN(), convertedType != -1:
+		// JSON, BSON, INTERVAL and geospatial types do not have Arrow
+		// counterparts, they are in the same format as JSON output
+		return &arrowNode{kind: "json"}, arrow.BinaryTypes.String, nil
+	}
+
+	switch *node.Type {
+	case parquet.Type_BOOLEAN:
+		return &arrowNode{}, arrow.FixedWidthTypes.Boolean, nil
+	case parquet.Type_INT32:
+		return &arrowNode{}, arrow.PrimitiveTypes.Int32, nil
+	case parquet.Type_INT64:
+		return &arrowNode{}, arrow.PrimitiveTypes.Int64, nil
+	case parquet.Type_FLOAT:
+		return &arrowNode{}, arrow.PrimitiveTypes.Float32, nil
+	case parquet.Type_DOUBLE:
+		return &arrowNode{}, arrow.PrimitiveTypes.Float64, nil
+	case parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		return &arrowNode{}, &arrow.FixedSizeBinaryType{ByteWidth: int(node.GetTypeLength())}, nil
+	}
+	return &arrowNode{}, arrow.BinaryTypes.Binary, nil
+}
+
+func arrowIntegerType(bitWidth int8, signed bool) arrow.DataType {
+	switch {
+	case bitWidth == 8 && signed:
+		return arrow.PrimitiveTypes.Int8
+	case bitWidth == 8:
+		return arrow.PrimitiveTypes.Uint8
+	case bitWidth == 16 && signed:
+		return arrow.PrimitiveTypes.Int16
+	case bitWidth == 16:
+		return arrow.PrimitiveTypes.Uint16
+	case bitWidth == 32 && signed:
+		return arrow.PrimitiveTypes.Int32
+	case bitWidth == 32:
+		return arrow.PrimitiveTypes.Uint32
+	case signed:
+		return arrow.PrimitiveTypes.Int64
+	}
+	return arrow.PrimitiveTypes.Uint64
+}
+
+// value converts a JSON friendly value to what Arrow JSON builders expect,
+// most values are taken as is.
+func (n *arrowNode) value(value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch n.kind {
+	case "struct":
+		record, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("value [%v] of field [%s] is not a struct", value, n.name)
+		}
+		result := make(map[string]any, len(n.fields))
+		for _, field := range n.fields {
+			fieldValue, err := field.value(record[field.name])
+			if err != nil {
+				return nil, err
+			}
+			result[field.name] = fieldValue
+		}
+		return result, nil
+	case "list":
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("value [%v] of field [%s] is not a list", value, n.name)
+		}
+		result := make([]any, len(items))
+		for index, item := range items {
+			itemValue, err := n.element.value(item)
+			if err != nil {
+				return nil, err
+			}
+			result[index] = itemValue
+		}
+		return result, nil
+	case "map":
+		// Arrow map is a list of key/value pairs
+		items, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("value [%v] of field [%s] is not a map", value, n.name)
+		}
+		keys := make([]string, 0, len(items))
+		for key := range items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		result := make([]any, len(keys))
+		for index, key := range keys {
+			keyValue, err := n.key.value(key)
+			if err != nil {
+				return nil, err
+			}
+			itemValue, err := n.element.value(items[key])
+			if err != nil {
+				return nil, err
+			}
+			result[index] = map[string]any{"key": keyValue, "value": itemValue}
+		}
+		return result, nil
+	case "json":
+		if text, ok := value.(string); ok {
+			return text, nil
+		}
+		buf, err := json.Marshal(value)
+		return string(buf), err
+	case "time":
+		timeOfDay, err := time.Parse("15:04:05.999999999", fmt.Sprint(value))
+		if err != nil {
+			return nil, fmt.Errorf("value [%v] of field [%s] is not a time: %w", value, n.name, err)
+		}
+		return int64(timeOfDay.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)) / n.unit.Multiplier()), nil
+	case "timestamp":
+		timestamp, err := time.Parse(time.RFC3339Nano, fmt.Sprint(value))
+		if err != nil {
+			return nil, fmt.Errorf("value [%v] of field [%s] is not a timestamp: %w", value, n.name, err)
+		}
+		switch n.unit {
+		case arrow.Millisecond:
+			return timestamp.UnixMilli(), nil
+		case arrow.Microsecond:
+			return timestamp.UnixMicro(), nil
+		}
+		return timestamp.UnixNano(), nil
+	}
+	return value, nil
+}
+
+// encode returns a JSON friendly row in the format of Arrow JSON builders.
+func (o *arrowOutput) encode(row any) (string, error) {
+	value, err := o.root.value(row)
+	if err != nil {
+		return "", err
+	}
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// printer writes encoded rows in record batches of up to batchSize rows.
+func (o *arrowOutput) printer(ctx context.Context, outputChan chan string, batchSize int) error {
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, o.schema)
+	defer builder.Release()
+	writer := ipc.NewWriter(os.Stdout, ipc.WithSchema(o.schema))
+
+	count := 0
+	flush := func() error {
+		if count == 0 {
+			return nil
+		}
+		record := builder.NewRecordBatch()
+		defer record.Release()
+		count = 0
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write Arrow record batch: %w", err)
+		}
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case encodedRow, more := <-outputChan:
+			if !more {
+				if err := flush(); err != nil {
+					return err
+				}
+				return writer.Close()
+			}
+			if err := builder.UnmarshalJSON([]byte(encodedRow)); err != nil {
+				return fmt.Errorf("failed to convert row to Arrow: %w", err)
+			}
+			if count++; count >= batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
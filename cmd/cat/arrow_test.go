@@ -0,0 +1,192 @@
+package cat
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func arrowTestSchema() *pschema.SchemaNode {
+	node := func(name string, repetition parquet.FieldRepetitionType, children ...*pschema.SchemaNode) *pschema.SchemaNode {
+		return &pschema.SchemaNode{
+			SchemaElement: parquet.SchemaElement{Name: name, RepetitionType: &repetition},
+			ExNamePath:    []string{"parquet_go_root", name},
+			Children:      children,
+		}
+	}
+	leaf := func(name string, physicalType parquet.Type, repetition parquet.FieldRepetitionType) *pschema.SchemaNode {
+		result := node(name, repetition)
+		result.Type = &physicalType
+		return result
+	}
+	required, optional, repeated := parquet.FieldRepetitionType_REQUIRED, parquet.FieldRepetitionType_OPTIONAL, parquet.FieldRepetitionType_REPEATED
+
+	name := leaf("name", parquet.Type_BYTE_ARRAY, optional)
+	name.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_UTF8)
+	age := leaf("age", parquet.Type_INT32, required)
+	age.LogicalType = &parquet.LogicalType{INTEGER: &parquet.IntType{BitWidth: 8, IsSigned: true}}
+	price := leaf("price", parquet.Type_INT64, optional)
+	price.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_DECIMAL)
+	price.Scale, price.Precision = new(int32), new(int32)
+	*price.Scale, *price.Precision = 2, 10
+	day := leaf("day", parquet.Type_INT32, optional)
+	day.LogicalType = &parquet.LogicalType{DATE: &parquet.DateType{}}
+	clock := leaf("clock", parquet.Type_INT64, required)
+	clock.LogicalType = &parquet.LogicalType{TIME: &parquet.TimeType{Unit: &parquet.TimeUnit{NANOS: &parquet.NanoSeconds{}}}}
+	created := leaf("created", parquet.Type_INT64, optional)
+	created.LogicalType = &parquet.LogicalType{TIMESTAMP: &parquet.TimestampType{IsAdjustedToUTC: true, Unit: &parquet.TimeUnit{MICROS: &parquet.MicroSeconds{}}}}
+	local := leaf("local", parquet.Type_INT64, required)
+	local.LogicalType = &parquet.LogicalType{TIMESTAMP: &parquet.TimestampType{IsAdjustedToUTC: false, Unit: &parquet.TimeUnit{MILLIS: &parquet.MilliSeconds{}}}}
+	uuid := leaf("uuid", parquet.Type_FIXED_LEN_BYTE_ARRAY, optional)
+	uuid.LogicalType = &parquet.LogicalType{UUID: &parquet.UUIDType{}}
+	document := leaf("document", parquet.Type_BYTE_ARRAY, optional)
+	document.LogicalType = &parquet.LogicalType{JSON: &parquet.JsonType{}}
+	code := leaf("code", parquet.Type_FIXED_LEN_BYTE_ARRAY, required)
+	code.TypeLength = new(int32)
+	*code.TypeLength = 2
+
+	element := leaf("element", parquet.Type_INT32, optional)
+	tags := node("tags", optional, node("list", repeated, element))
+	tags.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_LIST)
+	key := leaf("key", parquet.Type_INT32, required)
+	value := leaf("value", parquet.Type_DOUBLE, required)
+	scores := node("scores", optional, node("key_value", repeated, key, value))
+	scores.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_MAP)
+	friend := node("friend", optional, leaf("id", parquet.Type_INT64, required))
+
+	return node("parquet_go_root", required,
+		leaf("id", parquet.Type_INT64, required), name, age, price, day, clock, created, local, uuid, document, code,
+		tags, scores, leaf("repeated", parquet.Type_BOOLEAN, repeated), friend, leaf("raw", parquet.Type_INT96, optional))
+}
+
+func TestNewArrowOutput(t *testing.T) {
+	output, err := newArrowOutput(arrowTestSchema())
+	require.NoError(t, err)
+	expected := []string{
+		"id: type=int64",
+		"name: type=utf8, nullable",
+		"age: type=int8",
+		"price: type=decimal(10, 2), nullable",
+		"day: type=date32, nullable",
+		"clock: type=time64[ns]",
+		"created: type=timestamp[us, tz=UTC], nullable",
+		"local: type=timestamp[ms]",
+		"uuid: type=extension<arrow.uuid>, nullable",
+		"document: type=utf8, nullable",
+		"code: type=fixed_size_binary[2]",
+		"tags: type=list<element: int32, nullable>, nullable",
+		"scores: type=map<int32, float64, items_non_nullable>, nullable",
+		"repeated: type=list<item: bool>",
+		"friend: type=struct<id: int64>, nullable",
+		"raw: type=timestamp[ns, tz=UTC], nullable",
+	}
+	for index, field := range output.schema.Fields() {
+		require.Equal(t, expected[index], field.String())
+	}
+
+	badList := arrowTestSchema()
+	badList.Children[11].Children = nil
+	_, err = newArrowOutput(badList)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "field [tags] is not a valid LIST")
+
+	badMap := arrowTestSchema()
+	badMap.Children[12].Children[0].Children = nil
+	_, err = newArrowOutput(badMap)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "field [scores] is not a valid MAP")
+}
+
+func TestArrowOutput(t *testing.T) {
+	output, err := newArrowOutput(arrowTestSchema())
+	require.NoError(t, err)
+
+	rows := []map[string]any{
+		{
+			"id": int64(1), "name": "alice", "age": int32(30), "price": 12.34, "day": "2024-01-15", "clock": "10:30:45.123456789",
+			"created": "2024-01-15T10:30:00.123456Z", "local": "2024-01-15T10:30:00.123Z", "uuid": "550e8400-e29b-41d4-a716-446655440000",
+			"document": map[string]any{"a": 1}, "code": "QUI=", "tags": []any{int32(1), nil}, "scores": map[string]any{"2": 2.5, "1": 1.5},
+			"repeated": []any{true, false}, "friend": map[string]any{"id": int64(9)}, "raw": "2022-01-01T01:01:01.001001001Z",
+		},
+		{
+			"id": int64(2), "age": int32(-1), "clock": "00:00:00.000000000", "local": "1970-01-01T00:00:00Z", "code": []byte("CD"),
+			"document": `{"b":2}`, "tags": []any{}, "repeated": []any{},
+		},
+	}
+
+	outputChan := make(chan string, len(rows))
+	for _, row := range rows {
+		encoded, err := output.encode(row)
+		require.NoError(t, err)
+		outputChan <- encoded
+	}
+	close(outputChan)
+	stdout, _ := testutils.CaptureStdoutStderr(func() {
+		require.NoError(t, output.printer(context.Background(), outputChan, 1))
+	})
+
+	reader, err := ipc.NewReader(strings.NewReader(stdout))
+	require.NoError(t, err)
+	defer reader.Release()
+	require.True(t, output.schema.Equal(reader.Schema()))
+	var lines []string
+	for reader.Next() {
+		var buf strings.Builder
+		require.NoError(t, array.RecordToJSON(reader.RecordBatch(), &buf))
+		lines = append(lines, strings.TrimSpace(buf.String()))
+	}
+	require.NoError(t, reader.Err())
+	require.Equal(t, []string{
+		`{"age":30,"clock":"10:30:45.123456789","code":"QUI=","created":"2024-01-15T10:30:00.123456Z","day":"2024-01-15","document":"{\"a\":1}","friend":{"id":9},"id":1,"local":"2024-01-15T10:30:00.123Z","name":"alice","price":"12.34","raw":"2022-01-01T01:01:01.001001001Z","repeated":[true,false],"scores":[{"key":1,"value":1.5},{"key":2,"value":2.5}],"tags":[1,null],"uuid":"550e8400-e29b-41d4-a716-446655440000"}`,
+		`{"age":-1,"clock":"00:00:00","code":"Q0Q=","created":null,"day":null,"document":"{\"b\":2}","friend":null,"id":2,"local":"1970-01-01T00:00:00Z","name":null,"price":null,"raw":null,"repeated":[],"scores":null,"tags":[],"uuid":null}`,
+	}, lines)
+
+	// bad values
+	for field, value := range map[string]any{"clock": "noon", "local": "today", "tags": "x", "scores": 1, "friend": 1} {
+		row := map[string]any{}
+		for k, v := range rows[1] {
+			row[k] = v
+		}
+		row[field] = value
+		_, err := output.encode(row)
+		require.Error(t, err, field)
+		require.Contains(t, err.Error(), "of field ["+field+"]")
+	}
+
+	// value that does not fit Arrow type
+	outputChan = make(chan string, 1)
+	outputChan <- `{"id":"abc"}`
+	close(outputChan)
+	_, _ = testutils.CaptureStdoutStderr(func() {
+		err = output.printer(context.Background(), outputChan, 1)
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to convert row to Arrow")
+}
+
+func TestCmdArrow(t *testing.T) {
+	var err error
+	stdout, _ := testutils.CaptureStdoutStderr(func() {
+		err = Cmd{ReadPageSize: 2, SampleRatio: 1.0, Format: "arrow", URI: "../../testdata/good.parquet", ReadOption: pio.ReadOption{}}.Run(context.Background())
+	})
+	require.NoError(t, err)
+
+	reader, err := ipc.NewReader(strings.NewReader(stdout))
+	require.NoError(t, err)
+	defer reader.Release()
+	var buf strings.Builder
+	for reader.Next() {
+		require.NoError(t, array.RecordToJSON(reader.RecordBatch(), &buf))
+	}
+	require.NoError(t, reader.Err())
+	require.Equal(t, strings.TrimSpace(testutils.LoadExpected(t, "../../testdata/golden/cat-good-jsonl.jsonl")), strings.TrimSpace(buf.String()))
+}
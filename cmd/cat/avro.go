@@ -0,0 +1,476 @@
+package cat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+var avroNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// avroNode tells how a JSON friendly value of a parquet field is converted to
+// goavro's native value.
+type avroNode struct {
+	name     string // field name in row
+	kind     string // Avro type name, or logical type name
+	union    string // union branch name of non-null value, empty if field is not nullable
+	fields   []*avroNode
+	element  *avroNode // array items and map values
+	size     int       // fixed
+	scale    int       // decimal
+	fullName string    // record and fixed
+}
+
+// avroOutput writes rows in Avro Object Container File format.
+type avroOutput struct {
+	root   *avroNode
+	schema string
+	codec  *goavro.Codec
+}
+
+// newAvroOutput maps parquet schema in JSON schema format to Avro schema.
+func newAvroOutput(schema string) (*avroOutput, error) {
+	var jsonSchema pschema.JSONSchema
+	if err := json.Unmarshal([]byte(schema), &jsonSchema); err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+	rootName := regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(avroTags(jsonSchema.Tag)["name"], "_")
+	if !avroNameRegexp.MatchString(rootName) {
+		rootName = "parquet_go_root"
+	}
+	root, avroSchema, err := newAvroRecord(rootName, "", jsonSchema.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, _ := json.Marshal(avroSchema)
+	codec, err := goavro.NewCodec(string(buf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Avro schema: %w", err)
+	}
+	return &avroOutput{root: root, schema: string(buf), codec: codec}, nil
+}
+
+// avroTags parses tags in JSON schema, keys are in lower case.
+func avroTags(tag string) map[string]string {
+	tags := map[string]string{}
+	for item := range strings.SplitSeq(tag, ",") {
+		key, value, found := strings.Cut(item, "=")
+		if found {
+			tags[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+	return tags
+}
+
+func newAvroRecord(name, namespace string, fields []pschema.JSONSchema) (*avroNode, map[string]any, error) {
+	node := &avroNode{kind: "record", fullName: name}
+	if namespace != "" {
+		node.fullName = namespace + "." + name
+	}
+	avroFields := make([]map[string]any, len(fields))
+	for index, field := range fields {
+		child, fieldSchema, err := newAvroField(field, node.fullName)
+		if err != nil {
+			return nil, nil, err
+		}
+		node.fields = append(node.fields, child)
+		avroFields[index] = map[string]any{"name": child.name, "type": fieldSchema}
+		if child.union != "" {
+			avroFields[index]["default"] = nil
+		}
+	}
+	schema := map[string]any{"type": "record", "name": name, "fields": avroFields}
+	if namespace != "" {
+		schema["namespace"] = namespace
+	}
+	return node, schema, nil
+}
+
+// newAvroField maps a parquet field to Avro, namespace is full name of the
+// record that the field belongs to.
+func newAvroField(field pschema.JSONSchema, namespace string) (*avroNode, any, error) {
+	tags := avroTags(field.Tag)
+	name := tags["name"]
+	if !avroNameRegexp.MatchString(name) {
+		return nil, nil, fmt.Errorf("field [%s] is not a valid Avro name", name)
+	}
+
+	var node *avroNode
+	var schema any
+	var err error
+	switch tags["repetitiontype"] {
+	case "REPEATED":
+		delete(tags, "repetitiontype")
+		var element *avroNode
+		if element, schema, err = newAvroType(tags, field.Fields, name, namespace); err != nil {
+			return nil, nil, err
+		}
+		node = &avroNode{kind: "array", element: element}
+		schema = map[string]any{"type": "array", "items": schema}
+	default:
+		if node, schema, err = newAvroType(tags, field.Fields, name, namespace); err != nil {
+			return nil, nil, err
+		}
+	}
+	node.name = name
+
+	// UNKNOWN logical type is always null unless --raw-unknown is set
+	if tags["repetitiontype"] == "OPTIONAL" || tags["logicaltype"] == "UNKNOWN" {
+		node.union = node.kind
+		switch {
+		case node.fullName != "":
+			node.union = node.fullName
+		case node.kind == "bytes-decimal":
+			node.union = "bytes.decimal"
+		case node.kind == "date":
+			node.union = "int.date"
+		case node.kind == "time-millis":
+			node.union = "int.time-millis"
+		case node.kind == "time-micros" || node.kind == "timestamp-millis" || node.kind == "timestamp-micros":
+			node.union = "long." + node.kind
+		case strings.HasSuffix(node.kind, "-nanos") || strings.HasPrefix(node.kind, "local-"):
+			// goavro does not know these logical types
+			node.union = "long"
+		case node.kind == "json" || node.kind == "uuid":
+			node.union = "string"
+		}
+		schema = []any{"null", schema}
+	}
+	return node, schema, nil
+}
+
+func newAvroType(tags map[string]string, fields []pschema.JSONSchema, name, namespace string) (*avroNode, any, error) {
+	fullName := namespace + "." + name
+	switch tags["type"] {
+	case "LIST":
+		if len(fields) != 1 {
+			return nil, nil, fmt.Errorf("field [%s] is not a valid LIST", name)
+		}
+		element, schema, err := newAvroField(fields[0], fullName)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &avroNode{kind: "array", element: element}, map[string]any{"type": "array", "items": schema}, nil
+	case "MAP":
+		if len(fields) != 2 {
+			return nil, nil, fmt.Errorf("field [%s] is not a valid MAP", name)
+		}
+		value, schema, err := newAvroField(fields[1], fullName)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &avroNode{kind: "map", element: value}, map[string]any{"type": "map", "values": schema}, nil
+	case "":
+		if len(fields) != 0 {
+			return newAvroRecord(name, namespace, fields)
+		}
+	}
+
+	logicalType, convertedType := tags["logicaltype"], tags["convertedtype"]
+	unit := tags["logicaltype.unit"]
+	switch {
+	case convertedType == "DECIMAL" || logicalType == "DECIMAL":
+		precision, _ := strconv.Atoi(tags["precision"])
+		scale, _ := strconv.Atoi(tags["scale"])
+		schema := map[string]any{"type": "bytes", "logicalType": "decimal", "precision": precision, "scale": scale}
+		if tags["type"] == "FIXED_LEN_BYTE_ARRAY" {
+			size, _ := strconv.Atoi(tags["length"])
+			schema["type"], schema["name"], schema["namespace"], schema["size"] = "fixed", name, namespace, size
+			return &avroNode{kind: "fixed-decimal", scale: scale, size: size, fullName: fullName}, schema, nil
+		}
+		return &avroNode{kind: "bytes-decimal", scale: scale}, schema, nil
+	case convertedType == "DATE" || logicalType == "DATE":
+		return &avroNode{kind: "date"}, map[string]any{"type": "int", "logicalType": "date"}, nil
+	case convertedType == "TIME_MILLIS" || logicalType == "TIME" && unit == "MILLIS":
+		return &avroNode{kind: "time-millis"}, map[string]any{"type": "int", "logicalType": "time-millis"}, nil
+	case convertedType == "TIME_MICROS" || logicalType == "TIME" && unit == "MICROS":
+		return &avroNode{kind: "time-micros"}, map[string]any{"type": "long", "logicalType": "time-micros"}, nil
+	case convertedType == "TIMESTAMP_MILLIS" || convertedType == "TIMESTAMP_MICROS" || logicalType == "TIMESTAMP" || tags["type"] == "INT96":
+		kind := "timestamp-nanos"
+		switch {
+		case convertedType == "TIMESTAMP_MILLIS" || unit == "MILLIS":
+			kind = "timestamp-millis"
+		case convertedType == "TIMESTAMP_MICROS" || unit == "MICROS":
+			kind = "timestamp-micros"
+		}
+		if tags["logicaltype.isadjustedtoutc"] == "false" {
+			kind = "local-" + kind
+		}
+		return &avroNode{kind: kind}, map[string]any{"type": "long", "logicalType": kind}, nil
+	case logicalType == "UUID":
+		return &avroNode{kind: "uuid"}, map[string]any{"type": "string", "logicalType": "uuid"}, nil
+	case logicalType == "FLOAT16":
+		return &avroNode{kind: "float"}, "float", nil
+	case convertedType == "UINT_32" || convertedType == "UINT_64" || tags["type"] == "INT64":
+		return &avroNode{kind: "long"}, "long", nil
+	case tags["type"] == "INT32":
+		return &avroNode{kind: "int"}, "int", nil
+	case tags["type"] == "BOOLEAN":
+		return &avroNode{kind: "boolean"}, "boolean", nil
+	case tags["type"] == "FLOAT":
+		return &avroNode{kind: "float"}, "float", nil
+	case tags["type"] == "DOUBLE":
+		return &avroNode{kind: "double"}, "double", nil
+	case convertedType == "UTF8" || logicalType == "STRING" || convertedType == "ENUM" || logicalType == "ENUM":
+		return &avroNode{kind: "string"}, "string", nil
+	case tags["type"] == "BYTE_ARRAY" && convertedType == "" && logicalType == "":
+		return &avroNode{kind: "bytes"}, "bytes", nil
+	case tags["type"] == "FIXED_LEN_BYTE_ARRAY" && convertedType == "" && logicalType == "":
+		size, _ := strconv.Atoi(tags["length"])
+		schema := map[string]any{"type": "fixed", "name": name, "namespace": namespace, "size": size}
+		return &avroNode{kind: "fixed", size: size, fullName: fullName}, schema, nil
+	}
+	// JSON, BSON, VARIANT, INTERVAL, TIME in nanoseconds and geospatial types
+	// do not have Avro counterparts, they are in the same format as JSON output
+	return &avroNode{kind: "json"}, "string", nil
+}
+
+// native converts a JSON friendly value to goavro's native value.
+func (n *avroNode) native(value any) (any, error) {
+	if value == nil {
+		if n.union == "" {
+			return nil, fmt.Errorf("field [%s] is required but value is null", n.name)
+		}
+		return nil, nil
+	}
+	result, err := n.nativeValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert value [%v] of field [%s] to Avro %s: %w", value, n.name, n.kind, err)
+	}
+	if n.union != "" {
+		return goavro.Union(n.union, result), nil
+	}
+	return result, nil
+}
+
+func (n *avroNode) nativeValue(value any) (any, error) {
+	switch n.kind {
+	case "record":
+		record, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("not a record")
+		}
+		result := make(map[string]any, len(n.fields))
+		for _, field := range n.fields {
+			fieldValue, err := field.native(record[field.name])
+			if err != nil {
+				return nil, err
+			}
+			result[field.name] = fieldValue
+		}
+		return result, nil
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("not a list")
+		}
+		result := make([]any, len(items))
+		for index, item := range items {
+			itemValue, err := n.element.native(item)
+			if err != nil {
+				return nil, err
+			}
+			result[index] = itemValue
+		}
+		return result, nil
+	case "map":
+		items, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("not a map")
+		}
+		result := make(map[string]any, len(items))
+		for key, item := range items {
+			itemValue, err := n.element.native(item)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = itemValue
+		}
+		return result, nil
+	case "boolean":
+		return value, nil
+	case "int":
+		number, err := avroInt64(value)
+		if err != nil || number < math.MinInt32 || number > math.MaxInt32 {
+			return nil, fmt.Errorf("not a 32-bit integer")
+		}
+		return int32(number), nil
+	case "long":
+		return avroInt64(value)
+	case "float":
+		number, err := avroFloat64(value)
+		return float32(number), err
+	case "double":
+		return avroFloat64(value)
+	case "string", "uuid":
+		return fmt.Sprint(value), nil
+	case "json":
+		if text, ok := value.(string); ok {
+			return text, nil
+		}
+		buf, err := json.Marshal(value)
+		return string(buf), err
+	case "bytes", "fixed":
+		return avroBytes(value), nil
+	case "bytes-decimal", "fixed-decimal":
+		rat, ok := new(big.Rat).SetString(fmt.Sprint(value))
+		if !ok {
+			return nil, fmt.Errorf("not a decimal")
+		}
+		return rat, nil
+	case "date":
+		return time.Parse(time.DateOnly, fmt.Sprint(value))
+	case "time-millis", "time-micros":
+		timeOfDay, err := time.Parse("15:04:05.999999999", fmt.Sprint(value))
+		if err != nil {
+			return nil, err
+		}
+		return timeOfDay.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)), nil
+	}
+
+	// timestamp-* and local-timestamp-*
+	timestamp, err := time.Parse(time.RFC3339Nano, fmt.Sprint(value))
+	if err != nil {
+		return nil, err
+	}
+	switch n.kind {
+	case "timestamp-millis", "timestamp-micros":
+		return timestamp, nil
+	case "local-timestamp-millis":
+		return timestamp.UnixMilli(), nil
+	case "local-timestamp-micros":
+		return timestamp.UnixMicro(), nil
+	}
+	return timestamp.UnixNano(), nil
+}
+
+func avroInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v), nil
+		}
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v), nil
+		}
+	}
+	return strconv.ParseInt(fmt.Sprint(value), 10, 64)
+}
+
+func avroFloat64(value any) (float64, error) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	}
+	return strconv.ParseFloat(fmt.Sprint(value), 64)
+}
+
+// avroBytes returns bytes of BYTE_ARRAY and FIXED_LEN_BYTE_ARRAY values, which
+// are base64 encoded in JSON output.
+func avroBytes(value any) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case string:
+		if buf, err := base64.StdEncoding.DecodeString(v); err == nil {
+			return buf
+		}
+		return []byte(v)
+	}
+	return []byte(fmt.Sprint(value))
+}
+
+// encode returns Avro binary encoding of a JSON friendly row.
+func (o *avroOutput) encode(row any) (string, error) {
+	value, err := o.root.nativeValue(row)
+	if err != nil {
+		return "", err
+	}
+	buf, err := o.codec.BinaryFromNative(nil, value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode row in Avro: %w", err)
+	}
+	return string(buf), nil
+}
+
+// header returns OCF header with schema, uncompressed blocks and sync marker.
+func (o *avroOutput) header(sync []byte) string {
+	buf := []byte("Obj\x01")
+	buf = binary.AppendVarint(buf, 2)
+	for _, item := range [][2]string{{"avro.codec", "null"}, {"avro.schema", o.schema}} {
+		for _, text := range item {
+			buf = binary.AppendVarint(buf, int64(len(text)))
+			buf = append(buf, text...)
+		}
+	}
+	buf = binary.AppendVarint(buf, 0)
+	return string(append(buf, sync...))
+}
+
+// printer writes encoded rows in OCF blocks of up to blockSize rows.
+func (o *avroOutput) printer(ctx context.Context, outputChan chan string, blockSize int) error {
+	sync := make([]byte, 16)
+	_, _ = rand.Read(sync)
+	fmt.Print(o.header(sync))
+
+	var block []byte
+	count := 0
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		buf := binary.AppendVarint(nil, int64(count))
+		buf = binary.AppendVarint(buf, int64(len(block)))
+		fmt.Print(string(buf) + string(block) + string(sync))
+		block, count = block[:0], 0
+	}
+	defer flush()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case encodedRow, more := <-outputChan:
+			if !more {
+				return nil
+			}
+			block = append(block, encodedRow...)
+			if count++; count >= blockSize {
+				flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package cat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pio "github.com/hangxie/parquet-tools/io"
+)
+
+const avroTestSchema = `{"Tag":"name=parquet-go-root","Fields":[
+	{"Tag":"name=Id, type=INT64"},
+	{"Tag":"name=Name, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+	{"Tag":"name=Age, type=INT32, convertedtype=INT_8"},
+	{"Tag":"name=Weight, type=FLOAT, repetitiontype=OPTIONAL"},
+	{"Tag":"name=Raw, type=BYTE_ARRAY"},
+	{"Tag":"name=Code, type=FIXED_LEN_BYTE_ARRAY, length=2, repetitiontype=OPTIONAL"},
+	{"Tag":"name=Price, type=INT64, convertedtype=DECIMAL, scale=2, precision=10, repetitiontype=OPTIONAL"},
+	{"Tag":"name=Total, type=FIXED_LEN_BYTE_ARRAY, convertedtype=DECIMAL, scale=2, precision=10, length=12"},
+	{"Tag":"name=Day, type=INT32, convertedtype=DATE, repetitiontype=OPTIONAL"},
+	{"Tag":"name=Clock, type=INT32, convertedtype=TIME_MILLIS"},
+	{"Tag":"name=Created, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS, repetitiontype=OPTIONAL"},
+	{"Tag":"name=Local, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=false, logicaltype.unit=MILLIS"},
+	{"Tag":"name=Legacy, type=INT96, repetitiontype=OPTIONAL"},
+	{"Tag":"name=Uuid, type=FIXED_LEN_BYTE_ARRAY, length=16, logicaltype=UUID, repetitiontype=OPTIONAL"},
+	{"Tag":"name=Json, type=BYTE_ARRAY, convertedtype=JSON"},
+	{"Tag":"name=Tags, type=LIST","Fields":[{"Tag":"name=Element, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"}]},
+	{"Tag":"name=Scores, type=MAP, repetitiontype=OPTIONAL","Fields":[{"Tag":"name=Key, type=BYTE_ARRAY, convertedtype=UTF8"},{"Tag":"name=Value, type=DOUBLE"}]},
+	{"Tag":"name=Repeated, type=INT32, repetitiontype=REPEATED"},
+	{"Tag":"name=Friend, repetitiontype=OPTIONAL","Fields":[{"Tag":"name=Name, type=BYTE_ARRAY, convertedtype=UTF8"}]}
+]}`
+
+func TestNewAvroOutput(t *testing.T) {
+	testCases := map[string]struct {
+		schema   string
+		expected string
+		errMsg   string
+	}{
+		"bad-json":     {schema: "{", errMsg: "failed to load schema"},
+		"bad-name":     {schema: `{"Tag":"name=root","Fields":[{"Tag":"name=a-b, type=INT32"}]}`, errMsg: "field [a-b] is not a valid Avro name"},
+		"bad-list":     {schema: `{"Tag":"name=root","Fields":[{"Tag":"name=a, type=LIST"}]}`, errMsg: "field [a] is not a valid LIST"},
+		"bad-map":      {schema: `{"Tag":"name=root","Fields":[{"Tag":"name=a, type=MAP"}]}`, errMsg: "field [a] is not a valid MAP"},
+		"no-avro-type": {schema: `{"Tag":"name=root","Fields":[{"Tag":"name=a, type=FIXED_LEN_BYTE_ARRAY, length=12, convertedtype=INTERVAL"}]}`, expected: `{"fields":[{"name":"a","type":"string"}],"name":"root","type":"record"}`},
+		"nested": {
+			schema:   `{"Tag":"name=parquet-go-root","Fields":[{"Tag":"name=a, repetitiontype=OPTIONAL","Fields":[{"Tag":"name=b, type=FIXED_LEN_BYTE_ARRAY, length=2"}]}]}`,
+			expected: `{"fields":[{"default":null,"name":"a","type":["null",{"fields":[{"name":"b","type":{"name":"b","namespace":"parquet_go_root.a","size":2,"type":"fixed"}}],"name":"a","namespace":"parquet_go_root","type":"record"}]}],"name":"parquet_go_root","type":"record"}`,
+		},
+		"all": {schema: avroTestSchema},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			output, err := newAvroOutput(tc.schema)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			if tc.expected != "" {
+				require.Equal(t, tc.expected, output.schema)
+			}
+		})
+	}
+}
+
+func TestAvroOutput(t *testing.T) {
+	output, err := newAvroOutput(avroTestSchema)
+	require.NoError(t, err)
+
+	rows := []map[string]any{
+		{
+			"Id": int64(1), "Name": "alice", "Age": int32(30), "Weight": float32(55.5), "Raw": []byte("raw"), "Code": "QUI=",
+			"Price": 12.34, "Total": 0.5, "Day": "2024-01-15", "Clock": "10:30:45.123",
+			"Created": "2024-01-15T10:30:00.123456Z", "Local": "2024-01-15T10:30:00.123Z", "Legacy": "2022-01-01T01:01:01.001001000Z",
+			"Uuid": "550e8400-e29b-41d4-a716-446655440000", "Json": map[string]any{"a": 1},
+			"Tags": []any{"x", nil}, "Scores": map[string]any{"math": 1.5}, "Repeated": []any{int32(1), int32(2)},
+			"Friend": map[string]any{"Name": "bob"},
+		},
+		{
+			"Id": int64(2), "Age": int32(40), "Raw": []byte{}, "Total": 1, "Clock": "00:00:00.000",
+			"Local": "1970-01-01T00:00:00Z", "Json": `{"b":2}`, "Tags": []any{}, "Repeated": []any{},
+		},
+	}
+
+	outputChan := make(chan string, len(rows))
+	for _, row := range rows {
+		encoded, err := output.encode(row)
+		require.NoError(t, err)
+		outputChan <- encoded
+	}
+	close(outputChan)
+	stdout, _ := testutils.CaptureStdoutStderr(func() {
+		require.NoError(t, output.printer(context.Background(), outputChan, 1))
+	})
+
+	ocfReader, err := goavro.NewOCFReader(bytes.NewReader([]byte(stdout)))
+	require.NoError(t, err)
+	var records []map[string]any
+	for ocfReader.Scan() {
+		datum, err := ocfReader.Read()
+		require.NoError(t, err)
+		records = append(records, datum.(map[string]any))
+	}
+	require.NoError(t, ocfReader.Err())
+	require.Len(t, records, 2)
+
+	first := records[0]
+	require.Equal(t, int64(1), first["Id"])
+	require.Equal(t, map[string]any{"string": "alice"}, first["Name"])
+	require.Equal(t, int32(30), first["Age"])
+	require.Equal(t, []byte("raw"), first["Raw"])
+	require.Equal(t, []byte("AB"), first["Code"].(map[string]any)["parquet_go_root.Code"])
+	require.Equal(t, big.NewRat(1234, 100), first["Price"].(map[string]any)["bytes.decimal"])
+	require.Equal(t, big.NewRat(1, 2), first["Total"])
+	require.Equal(t, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), first["Day"].(map[string]any)["int.date"])
+	require.Equal(t, 10*time.Hour+30*time.Minute+45*time.Second+123*time.Millisecond, first["Clock"])
+	require.Equal(t, time.Date(2024, 1, 15, 10, 30, 0, 123456000, time.UTC), first["Created"].(map[string]any)["long.timestamp-micros"])
+	require.Equal(t, time.Date(2024, 1, 15, 10, 30, 0, 123000000, time.UTC).UnixMilli(), first["Local"])
+	require.Equal(t, time.Date(2022, 1, 1, 1, 1, 1, 1001000, time.UTC).UnixNano(), first["Legacy"].(map[string]any)["long"])
+	require.Equal(t, map[string]any{"string": "550e8400-e29b-41d4-a716-446655440000"}, first["Uuid"])
+	require.Equal(t, `{"a":1}`, first["Json"])
+	require.Equal(t, []any{map[string]any{"string": "x"}, nil}, first["Tags"])
+	require.Equal(t, map[string]any{"map": map[string]any{"math": 1.5}}, first["Scores"])
+	require.Equal(t, []any{int32(1), int32(2)}, first["Repeated"])
+	require.Equal(t, map[string]any{"parquet_go_root.Friend": map[string]any{"Name": "bob"}}, first["Friend"])
+
+	second := records[1]
+	for _, field := range []string{"Name", "Weight", "Code", "Price", "Day", "Created", "Legacy", "Uuid", "Scores", "Friend"} {
+		require.Nil(t, second[field], field)
+	}
+	require.Equal(t, `{"b":2}`, second["Json"])
+	require.Equal(t, []any{}, second["Tags"])
+
+	// bad values
+	for field, value := range map[string]any{"Id": "abc", "Age": int64(1) << 40, "Day": "15/01/2024", "Total": "abc", "Clock": nil} {
+		row := map[string]any{}
+		for k, v := range rows[1] {
+			row[k] = v
+		}
+		row[field] = value
+		_, err := output.encode(row)
+		require.Error(t, err, field)
+		require.Contains(t, err.Error(), "field ["+field+"]")
+	}
+}
+
+func TestCmdAvro(t *testing.T) {
+	var err error
+	stdout, _ := testutils.CaptureStdoutStderr(func() {
+		err = Cmd{ReadPageSize: 2, SampleRatio: 1.0, Format: "avro", URI: "../../testdata/good.parquet", ReadOption: pio.ReadOption{}}.Run(context.Background())
+	})
+	require.NoError(t, err)
+
+	ocfReader, err := goavro.NewOCFReader(strings.NewReader(stdout))
+	require.NoError(t, err)
+	var records []any
+	for ocfReader.Scan() {
+		datum, err := ocfReader.Read()
+		require.NoError(t, err)
+		records = append(records, datum)
+	}
+	require.NoError(t, ocfReader.Err())
+
+	buf, err := json.Marshal(records)
+	require.NoError(t, err)
+	var expected, actual []any
+	require.NoError(t, json.Unmarshal([]byte(testutils.LoadExpected(t, "../../testdata/golden/cat-good-json.json")), &expected))
+	require.NoError(t, json.Unmarshal(buf, &actual))
+	require.Equal(t, expected, actual)
+}
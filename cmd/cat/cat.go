@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
 	"runtime"
 	"strings"
@@ -22,18 +23,27 @@ import (
 
 // Cmd is a kong command for cat
 type Cmd struct {
-	Concurrent   bool    `help:"enable concurrent output" default:"false"`
-	FailOnInt96  bool    `help:"fail command if INT96 data type is present." name:"fail-on-int96" default:"false"`
-	Format       string  `short:"f" help:"output format (json/jsonl/csv/tsv)" enum:"json,jsonl,csv,tsv" default:"json"`
-	GeoFormat    string  `help:"experimental, output format (geojson/hex/base64) for geospatial fields" enum:"geojson,hex,base64" default:"geojson"`
-	Limit        uint64  `short:"l" help:"Max number of rows to output, 0 means no limit." default:"0"`
-	NoHeader     bool    `help:"(CSV/TSV only) do not output field name as header" default:"false"`
-	RawUnknown   bool    `help:"output actual physical value for UNKNOWN logical type columns instead of null" name:"raw-unknown" default:"false"`
-	ReadPageSize int     `help:"Page size to read from Parquet." default:"1000"`
-	SampleRatio  float32 `short:"s" help:"Sample ratio (0.0-1.0)." default:"1.0"`
-	Skip         int64   `short:"k" help:"Skip rows before apply other logics." default:"0"`
-	URI          string  `arg:"" predictor:"file" help:"URI of Parquet file."`
+	Columns        []string `help:"Only output these columns in this order, nested fields are separated by --field-delimiter." placeholder:"field.path,..."`
+	Concurrent     bool     `help:"enable concurrent output" default:"false"`
+	Explode        string   `help:"(CSV/TSV with --flatten only) output one line per element of this list field, nested fields are separated by --field-delimiter." placeholder:"field.path" default:""`
+	FailOnInt96    bool     `help:"fail command if INT96 data type is present." name:"fail-on-int96" default:"false"`
+	FieldDelimiter string   `name:"field-delimiter" help:"Delimiter separating nested field path components in field and column parameters" default:"."`
+	Flatten        bool     `help:"(CSV/TSV only) expand nested fields to columns joined by --field-delimiter, output lists, maps and geospatial fields as JSON or --geo-format" default:"false"`
+	Format         string   `short:"f" help:"output format (json/jsonl/csv/tsv/avro/arrow)" enum:"json,jsonl,csv,tsv,avro,arrow" default:"json"`
+	GeoFormat      string   `help:"experimental, output format (geojson/hex/base64) for geospatial fields" enum:"geojson,hex,base64" default:"geojson"`
+	Limit          uint64   `short:"l" help:"Max number of rows to output, 0 means no limit." default:"0"`
+	NoHeader       bool     `help:"(CSV/TSV only) do not output field name as header" default:"false"`
+	RawUnknown     bool     `help:"output actual physical value for UNKNOWN logical type columns instead of null" name:"raw-unknown" default:"false"`
+	ReadPageSize   int      `help:"Page size to read from Parquet." default:"1000"`
+	SampleRatio    float32  `short:"s" help:"Sample ratio (0.0-1.0)." default:"1.0"`
+	Skip           int64    `short:"k" help:"Skip rows before apply other logics." default:"0"`
+	URI            string   `arg:"" predictor:"file" help:"URI of Parquet file."`
+	Where          string   `short:"w" help:"Only output rows matching the filter expression, e.g. \"id >= 10 AND name IS NOT NULL\"." default:""`
 	pio.ReadOption
+
+	arrow *arrowOutput
+	avro  *avroOutput
+	flat  *flattener
 }
 
 var delimiter = map[string]struct {
@@ -46,10 +56,17 @@ var delimiter = map[string]struct {
 	"jsonl": {"", "\n", ' ', ""},
 	"csv":   {"", "\n", ',', ""},
 	"tsv":   {"", "\n", '\t', ""},
+	// avro and arrow are binary, rows are written in blocks by avroOutput and arrowOutput
+	"avro":  {"", "", ' ', ""},
+	"arrow": {"", "", ' ', ""},
 }
 
 // Run does actual cat job
 func (c Cmd) Run(ctx context.Context) error {
+	if err := pio.ValidateFieldDelimiter(c.FieldDelimiter); err != nil {
+		return err
+	}
+	c.ReadOption.FieldDelimiter = c.FieldDelimiter
 	if c.ReadPageSize < 1 {
 		return fmt.Errorf("invalid read page size %d, needs to be at least 1", c.ReadPageSize)
 	}
@@ -66,6 +83,9 @@ func (c Cmd) Run(ctx context.Context) error {
 	if _, ok := delimiter[c.Format]; !ok {
 		return fmt.Errorf("unknown format: [%s]", c.Format)
 	}
+	if c.Explode != "" && !c.Flatten {
+		return fmt.Errorf("--explode requires --flatten")
+	}
 
 	fileReader, err := pio.NewParquetFileReader(ctx, c.URI, c.ReadOption)
 	if err != nil {
@@ -84,15 +104,24 @@ func (c *Cmd) outputHeader(schemaRoot *pschema.SchemaNode) ([]string, error) {
 		return nil, nil
 	}
 
-	fieldList := make([]string, len(schemaRoot.Children))
-	for index, child := range schemaRoot.Children {
-		if len(child.Children) != 0 {
-			return nil, fmt.Errorf("field [%s] is not scalar type, cannot output in %s format", child.Name, c.Format)
+	var fieldList []string
+	if c.Flatten {
+		var err error
+		if c.flat, err = c.newFlattener(schemaRoot); err != nil {
+			return nil, err
 		}
-		if child.LogicalType != nil && (child.LogicalType.IsSetGEOGRAPHY() || child.LogicalType.IsSetGEOMETRY()) {
-			return nil, fmt.Errorf("field [%s] is not scalar type, cannot output in %s format", child.Name, c.Format)
+		fieldList = c.flat.header()
+	} else {
+		fieldList = make([]string, len(schemaRoot.Children))
+		for index, child := range schemaRoot.Children {
+			if len(child.Children) != 0 {
+				return nil, fmt.Errorf("field [%s] is not scalar type, cannot output in %s format", child.Name, c.Format)
+			}
+			if isGeospatial(child) {
+				return nil, fmt.Errorf("field [%s] is not scalar type, cannot output in %s format", child.Name, c.Format)
+			}
+			fieldList[index] = child.ExNamePath[len(child.ExNamePath)-1]
 		}
-		fieldList[index] = child.ExNamePath[len(child.ExNamePath)-1]
 	}
 
 	strBuilder := new(strings.Builder)
@@ -109,21 +138,6 @@ func (c *Cmd) outputHeader(schemaRoot *pschema.SchemaNode) ([]string, error) {
 	return fieldList, nil
 }
 
-func (c *Cmd) retrieveFieldDef(ctx context.Context, fileReader *reader.ParquetReader) ([]string, error) {
-	schemaRoot, err := pschema.NewSchemaTree(ctx, fileReader, pschema.SchemaOption{FailOnInt96: c.FailOnInt96})
-	if err != nil {
-		return nil, err
-	}
-
-	// CSV and TSV do not support nested schema
-	fieldList, err := c.outputHeader(schemaRoot)
-	if err != nil {
-		return nil, err
-	}
-
-	return fieldList, nil
-}
-
 func mapToStrList(flatValues map[string]any, fieldList []string) []string {
 	values := make([]string, len(fieldList))
 	for index, field := range fieldList {
@@ -162,7 +176,7 @@ func nullifyUnknownCols(rowStruct any, unknownCols map[string]struct{}) {
 	}
 }
 
-func (c Cmd) encoder(ctx context.Context, rowChan chan any, outputChan chan string, schemaHandler *schema.SchemaHandler, fieldList []string, unknownCols map[string]struct{}) error {
+func (c Cmd) jsonFriendlyRow(row any, schemaHandler *schema.SchemaHandler, unknownCols map[string]struct{}) (any, error) {
 	var geoMode types.GeospatialJSONMode
 	switch c.GeoFormat {
 	case "hex":
@@ -177,6 +191,17 @@ func (c Cmd) encoder(ctx context.Context, rowChan chan any, outputChan chan stri
 		types.WithGeographyJSONMode(geoMode),
 	))
 
+	rowStruct, err := marshal.ConvertToJSONFriendly(row, schemaHandler, geoOpt)
+	if err != nil {
+		return nil, err
+	}
+	if !c.RawUnknown {
+		nullifyUnknownCols(rowStruct, unknownCols)
+	}
+	return rowStruct, nil
+}
+
+func (c Cmd) encoder(ctx context.Context, rowChan chan any, outputChan chan string, schemaHandler *schema.SchemaHandler, fieldList []string, unknownCols map[string]struct{}, selector *rowSelector) error {
 	strBuilder := new(strings.Builder)
 	csvWriter := csv.NewWriter(strBuilder)
 	csvWriter.Comma = delimiter[c.Format].fieldDelimiter
@@ -188,12 +213,12 @@ func (c Cmd) encoder(ctx context.Context, rowChan chan any, outputChan chan stri
 			if !more {
 				return nil
 			}
-			rowStruct, err := marshal.ConvertToJSONFriendly(row, schemaHandler, geoOpt)
+			rowStruct, err := c.jsonFriendlyRow(row, schemaHandler, unknownCols)
 			if err != nil {
 				return err
 			}
-			if !c.RawUnknown {
-				nullifyUnknownCols(rowStruct, unknownCols)
+			if selector != nil && !selector.selected(rowStruct) {
+				continue
 			}
 
 			// Format the row as a string based on the format
@@ -206,12 +231,33 @@ func (c Cmd) encoder(ctx context.Context, rowChan chan any, outputChan chan stri
 				}
 				formattedRow = string(buf)
 			case "csv", "tsv":
-				values := mapToStrList(rowStruct.(map[string]any), fieldList)
-				line, err := c.valuesToCSV(values, strBuilder, csvWriter)
-				if err != nil {
+				var lines [][]string
+				if c.flat != nil {
+					var err error
+					if lines, err = c.flat.lines(rowStruct.(map[string]any)); err != nil {
+						return err
+					}
+				} else {
+					lines = [][]string{mapToStrList(rowStruct.(map[string]any), fieldList)}
+				}
+				for _, values := range lines {
+					line, err := c.valuesToCSV(values, strBuilder, csvWriter)
+					if err != nil {
+						return err
+					}
+					formattedRow += line
+				}
+				formattedRow = strings.TrimRight(formattedRow, "\n")
+			case "avro":
+				var err error
+				if formattedRow, err = c.avro.encode(rowStruct); err != nil {
+					return err
+				}
+			case "arrow":
+				var err error
+				if formattedRow, err = c.arrow.encode(rowStruct); err != nil {
 					return err
 				}
-				formattedRow = strings.TrimRight(line, "\n")
 			default:
 				return fmt.Errorf("unsupported format: [%s]", c.Format)
 			}
@@ -226,6 +272,12 @@ func (c Cmd) encoder(ctx context.Context, rowChan chan any, outputChan chan stri
 }
 
 func (c Cmd) printer(ctx context.Context, outputChan chan string) error {
+	switch c.Format {
+	case "avro":
+		return c.avro.printer(ctx, outputChan, c.ReadPageSize)
+	case "arrow":
+		return c.arrow.printer(ctx, outputChan, c.ReadPageSize)
+	}
 	fmt.Print(delimiter[c.Format].begin)
 	defer func() {
 		fmt.Print(delimiter[c.Format].end + "\n")
@@ -252,19 +304,62 @@ func (c Cmd) printer(ctx context.Context, outputChan chan string) error {
 }
 
 func (c Cmd) outputRows(ctx context.Context, fileReader *reader.ParquetReader) error {
-	fieldList, err := c.retrieveFieldDef(ctx, fileReader)
+	// page encoding is not needed, skip it to avoid reading page headers of every column
+	schemaRoot, err := pschema.NewSchemaTree(ctx, fileReader, pschema.SchemaOption{FailOnInt96: c.FailOnInt96, SkipPageEncoding: true})
 	if err != nil {
 		return err
 	}
 
-	schemaRoot, err := pschema.NewSchemaTree(ctx, fileReader, pschema.SchemaOption{})
+	// rows to read, everything after skipped rows unless --where rules out some
+	candidates := []rowRange{{start: c.Skip, end: math.MaxInt64}}
+	var filter *rowFilter
+	var selector *rowSelector
+	if c.Where != "" {
+		filter, err = newRowFilter(c.Where, schemaRoot, fileReader, c.FieldDelimiter)
+		if err != nil {
+			return err
+		}
+		candidates = clipRanges(filter.candidateRows(ctx, fileReader), c.Skip)
+		selector = &rowSelector{filter: filter, sampleRatio: c.SampleRatio, limit: c.Limit}
+	}
+
+	// with --columns rows are read from a reader that only knows projected columns,
+	// footer based pruning above still uses the original reader
+	outputRoot, readRoot, rowReader := schemaRoot, schemaRoot, fileReader
+	var proj *projection
+	if len(c.Columns) != 0 {
+		if proj, err = c.newProjection(schemaRoot, filter); err != nil {
+			return err
+		}
+		outputRoot, readRoot = proj.outputRoot, proj.readRoot
+		if selector != nil {
+			selector.proj = proj
+		}
+		if rowReader, err = pio.NewProjectedParquetReader(ctx, fileReader, readRoot.JSONSchema(), c.ReadOption); err != nil {
+			return err
+		}
+	}
+	unknownCols := unknownColumnNames(readRoot)
+
+	// CSV and TSV do not support nested schema unless --flatten is set
+	fieldList, err := c.outputHeader(outputRoot)
 	if err != nil {
 		return err
 	}
-	unknownCols := unknownColumnNames(schemaRoot)
+
+	switch c.Format {
+	case "avro":
+		if c.avro, err = newAvroOutput(outputRoot.JSONSchema()); err != nil {
+			return err
+		}
+	case "arrow":
+		if c.arrow, err = newArrowOutput(outputRoot); err != nil {
+			return err
+		}
+	}
 
 	// skip rows
-	if err := fileReader.SkipRowsWithContext(ctx, c.Skip); err != nil {
+	if err := rowReader.SkipRowsWithContext(ctx, c.Skip); err != nil {
 		return err
 	}
 
@@ -295,38 +390,61 @@ func (c Cmd) outputRows(ctx context.Context, fileReader *reader.ParquetReader) e
 	for range concurrency {
 		g.Go(func() error {
 			defer encodersWg.Done()
-			return c.encoder(gctx, rowChan, outputChan, fileReader.SchemaHandler, fieldList, unknownCols)
+			return c.encoder(gctx, rowChan, outputChan, rowReader.SchemaHandler, fieldList, unknownCols, selector)
 		})
 	}
 
-	// Start a producer goroutine to avoid blocking main thread
+	// Start a producer goroutine to avoid blocking main thread, with --where
+	// encoders filter rows and count towards limit
 	g.Go(func() error {
 		defer close(rowChan)
 
-		for counter := uint64(0); counter < c.Limit; {
-			select {
-			case <-gctx.Done():
-				return gctx.Err()
-			default:
-			}
-
-			rows, err := fileReader.ReadByNumberWithContext(gctx, c.ReadPageSize)
-			if err != nil {
-				return fmt.Errorf("failed to cat: %w", err)
+		position := c.Skip
+		counter := uint64(0)
+		limitReached := func() bool {
+			if selector != nil {
+				return selector.done()
 			}
-			if len(rows) == 0 {
+			return counter >= c.Limit
+		}
+		for _, candidate := range candidates {
+			if limitReached() {
 				break
 			}
-
-			for i := 0; i < len(rows) && counter < c.Limit; i++ {
-				if rand.Float32() >= c.SampleRatio {
-					continue
+			if candidate.start > position {
+				if err := rowReader.SkipRowsWithContext(gctx, candidate.start-position); err != nil {
+					return fmt.Errorf("failed to cat: %w", err)
 				}
+				position = candidate.start
+			}
+
+			for position < candidate.end && !limitReached() {
 				select {
-				case rowChan <- rows[i]:
-					counter++
 				case <-gctx.Done():
 					return gctx.Err()
+				default:
+				}
+
+				rows, err := rowReader.ReadByNumberWithContext(gctx, int(min(int64(c.ReadPageSize), candidate.end-position)))
+				if err != nil {
+					return fmt.Errorf("failed to cat: %w", err)
+				}
+				if len(rows) == 0 {
+					return nil
+				}
+				position += int64(len(rows))
+
+				for i := 0; i < len(rows) && !limitReached(); i++ {
+					// with --where encoders sample rows that match
+					if selector == nil && rand.Float32() >= c.SampleRatio {
+						continue
+					}
+					select {
+					case rowChan <- rows[i]:
+						counter++
+					case <-gctx.Done():
+						return gctx.Err()
+					}
 				}
 			}
 		}
@@ -131,6 +131,14 @@ func TestCmd(t *testing.T) {
 			cmd:    Cmd{ReadOption: rOpt, Skip: 10, Limit: 10, ReadPageSize: 10, SampleRatio: 0.5, Format: "tsv", NoHeader: true, URI: "../../testdata/all-types.parquet"},
 			errMsg: "field [Variant] is not scalar type",
 		},
+		"explode-without-flatten": {
+			cmd:    Cmd{ReadOption: rOpt, Skip: 0, Limit: 10, ReadPageSize: 10, SampleRatio: 1.0, Format: "csv", Explode: "List", URI: "../../testdata/all-types.parquet"},
+			errMsg: "--explode requires --flatten",
+		},
+		"explode-not-list": {
+			cmd:    Cmd{ReadOption: rOpt, Skip: 0, Limit: 10, ReadPageSize: 10, SampleRatio: 1.0, Format: "csv", Flatten: true, Explode: "Map", URI: "../../testdata/all-types.parquet"},
+			errMsg: "field [Map] is not a list, cannot explode",
+		},
 		"geospatial-csv": {
 			cmd:    Cmd{ReadOption: rOpt, Skip: 10, Limit: 10, ReadPageSize: 10, SampleRatio: 0.5, Format: "csv", NoHeader: true, URI: "../../testdata/geospatial.parquet"},
 			errMsg: "field [Geometry] is not scalar type",
@@ -455,7 +463,7 @@ func TestCmdEncoder(t *testing.T) {
 				Format: "json",
 			}
 
-			err := cmd.encoder(ctx, rowChan, outputChan, fileReader.SchemaHandler, fieldList, nil)
+			err := cmd.encoder(ctx, rowChan, outputChan, fileReader.SchemaHandler, fieldList, nil, nil)
 
 			if tc.wantErr {
 				require.Error(t, err)
@@ -482,7 +490,7 @@ func TestCmdEncoderRejectsUnsupportedFormat(t *testing.T) {
 	rowChan <- rows[0]
 
 	err = (Cmd{Format: "unsupported"}).encoder(
-		context.Background(), rowChan, make(chan string, 1), fileReader.SchemaHandler, nil, nil,
+		context.Background(), rowChan, make(chan string, 1), fileReader.SchemaHandler, nil, nil, nil,
 	)
 	require.ErrorContains(t, err, "unsupported format: [unsupported]")
 }
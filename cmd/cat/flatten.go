@@ -0,0 +1,193 @@
+package cat
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// flatColumn is a CSV/TSV column in --flatten mode, path is relative to the
+// row, or to the list element if the column comes from the exploded list.
+type flatColumn struct {
+	name     string
+	path     []string
+	geo      bool
+	exploded bool
+}
+
+// flattener turns nested rows into CSV/TSV lines: plain groups are expanded
+// to columns, other composite values are JSON encoded, and the list chosen
+// by --explode has one line per element.
+type flattener struct {
+	columns     []flatColumn
+	delimiter   string
+	explodePath []string
+	exploded    bool
+	geoFormat   string
+}
+
+func (c Cmd) newFlattener(schemaRoot *pschema.SchemaNode) (*flattener, error) {
+	result := &flattener{delimiter: c.FieldDelimiter, geoFormat: c.GeoFormat}
+	if c.Explode != "" {
+		result.explodePath = strings.Split(c.Explode, c.FieldDelimiter)
+	}
+	if err := result.addColumns(schemaRoot, nil, nil, false); err != nil {
+		return nil, err
+	}
+	if result.explodePath != nil && !result.exploded {
+		return nil, fmt.Errorf("field [%s] does not exist or is inside a list or map, cannot explode", c.Explode)
+	}
+	return result, nil
+}
+
+func (f *flattener) addColumns(node *pschema.SchemaNode, namePath, valuePath []string, exploded bool) error {
+	for _, child := range node.Children {
+		name := child.ExNamePath[len(child.ExNamePath)-1]
+		childNamePath := append(slices.Clone(namePath), name)
+		childValuePath := append(slices.Clone(valuePath), name)
+		if !exploded && slices.Equal(childNamePath, f.explodePath) {
+			element := listElement(child)
+			if child.RepetitionType != nil && *child.RepetitionType == parquet.FieldRepetitionType_REPEATED {
+				element = child
+			}
+			if element == nil {
+				return fmt.Errorf("field [%s] is not a list, cannot explode", strings.Join(childNamePath, f.delimiter))
+			}
+			f.exploded = true
+			if isPlainGroup(element) {
+				if err := f.addColumns(element, childNamePath, nil, true); err != nil {
+					return err
+				}
+				continue
+			}
+			f.columns = append(f.columns, flatColumn{name: strings.Join(childNamePath, f.delimiter), geo: isGeospatial(element), exploded: true})
+			continue
+		}
+		if isPlainGroup(child) {
+			if err := f.addColumns(child, childNamePath, childValuePath, exploded); err != nil {
+				return err
+			}
+			continue
+		}
+		f.columns = append(f.columns, flatColumn{
+			name:     strings.Join(childNamePath, f.delimiter),
+			path:     childValuePath,
+			geo:      isGeospatial(child),
+			exploded: exploded,
+		})
+	}
+	return nil
+}
+
+// isPlainGroup tells if node is a struct, which is the only type to be
+// expanded to columns.
+func isPlainGroup(node *pschema.SchemaNode) bool {
+	if node.Type != nil || node.ConvertedType != nil || node.LogicalType != nil {
+		return false
+	}
+	return node.RepetitionType == nil || *node.RepetitionType != parquet.FieldRepetitionType_REPEATED
+}
+
+func isGeospatial(node *pschema.SchemaNode) bool {
+	return node.LogicalType != nil && (node.LogicalType.IsSetGEOMETRY() || node.LogicalType.IsSetGEOGRAPHY())
+}
+
+// listElement returns schema of element of LIST, or nil if node is not a
+// valid LIST.
+func listElement(node *pschema.SchemaNode) *pschema.SchemaNode {
+	isList := node.ConvertedType != nil && *node.ConvertedType == parquet.ConvertedType_LIST
+	isList = isList || node.LogicalType != nil && node.LogicalType.IsSetLIST()
+	if !isList || len(node.Children) != 1 {
+		return nil
+	}
+	// 3-level list has a plain repeated group between LIST and element, while
+	// element of legacy 2-level list can be a group with single field, or a list
+	element := node.Children[0]
+	if element.ConvertedType == nil && element.LogicalType == nil && len(element.Children) == 1 {
+		element = element.Children[0]
+	}
+	return element
+}
+
+func (f *flattener) header() []string {
+	result := make([]string, len(f.columns))
+	for index, column := range f.columns {
+		result[index] = column.name
+	}
+	return result
+}
+
+// lines returns values of all lines of a row, there is always one line
+// unless the exploded list has more than one element.
+func (f *flattener) lines(row map[string]any) ([][]string, error) {
+	elements := []any{nil}
+	if f.explodePath != nil {
+		if list, ok := lookupValue(row, f.explodePath).([]any); ok && len(list) != 0 {
+			elements = list
+		}
+	}
+
+	result := make([][]string, len(elements))
+	for index, element := range elements {
+		values := make([]string, len(f.columns))
+		for columnIndex, column := range f.columns {
+			var value any
+			if column.exploded {
+				value = lookupValue(element, column.path)
+			} else {
+				value = lookupValue(row, column.path)
+			}
+			var err error
+			if values[columnIndex], err = f.format(value, column.geo); err != nil {
+				return nil, fmt.Errorf("failed to format field [%s]: %w", column.name, err)
+			}
+		}
+		result[index] = values
+	}
+	return result, nil
+}
+
+func lookupValue(value any, path []string) any {
+	for _, name := range path {
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = fields[name]
+	}
+	return value
+}
+
+var geoFormatKey = map[string]string{
+	"geojson": "geometry",
+	"hex":     "wkb_hex",
+	"base64":  "wkb_b64",
+}
+
+func (f *flattener) format(value any, geo bool) (string, error) {
+	if geo {
+		// keep the geometry in --geo-format only, CRS and algorithm come from schema
+		if fields, ok := value.(map[string]any); ok {
+			if geometry, found := fields[geoFormatKey[f.geoFormat]]; found {
+				value = geometry
+			}
+		}
+	}
+	switch value.(type) {
+	case nil:
+		// nil is just empty in CSV/TSV
+		return "", nil
+	case map[string]any, []any:
+		buf, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	}
+	return fmt.Sprint(value), nil
+}
@@ -0,0 +1,142 @@
+package cat
+
+import (
+	"testing"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/stretchr/testify/require"
+
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func flattenTestSchema() *pschema.SchemaNode {
+	node := func(path []string, repetition parquet.FieldRepetitionType, children ...*pschema.SchemaNode) *pschema.SchemaNode {
+		return &pschema.SchemaNode{
+			SchemaElement: parquet.SchemaElement{Name: path[len(path)-1], RepetitionType: &repetition},
+			ExNamePath:    append([]string{"parquet_go_root"}, path...),
+			Children:      children,
+		}
+	}
+	leaf := func(path []string, repetition parquet.FieldRepetitionType) *pschema.SchemaNode {
+		result := node(path, repetition)
+		result.Type = parquet.TypePtr(parquet.Type_INT64)
+		return result
+	}
+	required, optional, repeated := parquet.FieldRepetitionType_REQUIRED, parquet.FieldRepetitionType_OPTIONAL, parquet.FieldRepetitionType_REPEATED
+
+	shape := node([]string{"shape"}, optional)
+	shape.Type = parquet.TypePtr(parquet.Type_BYTE_ARRAY)
+	shape.LogicalType = &parquet.LogicalType{GEOMETRY: &parquet.GeometryType{}}
+	tags := node([]string{"tags"}, optional, node([]string{"tags", "list"}, repeated, leaf([]string{"tags", "list", "element"}, optional)))
+	tags.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_LIST)
+	friends := node([]string{"friends"}, optional,
+		node([]string{"friends", "list"}, repeated,
+			node([]string{"friends", "list", "element"}, required,
+				leaf([]string{"friends", "list", "element", "id"}, required),
+				node([]string{"friends", "list", "element", "address"}, optional,
+					leaf([]string{"friends", "list", "element", "address", "zip"}, optional)))))
+	friends.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_LIST)
+	scores := node([]string{"scores"}, optional,
+		node([]string{"scores", "key_value"}, repeated, leaf([]string{"scores", "key_value", "key"}, required), leaf([]string{"scores", "key_value", "value"}, required)))
+	scores.ConvertedType = parquet.ConvertedTypePtr(parquet.ConvertedType_MAP)
+
+	root := node([]string{"parquet_go_root"}, required,
+		leaf([]string{"id"}, required),
+		node([]string{"owner"}, optional, leaf([]string{"owner", "id"}, required), node([]string{"owner", "contact"}, optional, leaf([]string{"owner", "contact", "phone"}, optional))),
+		shape, tags, friends, scores, leaf([]string{"repeated"}, repeated))
+	root.ExNamePath = root.ExNamePath[1:]
+	return root
+}
+
+func TestFlattener(t *testing.T) {
+	row := map[string]any{
+		"id":    1,
+		"owner": map[string]any{"id": 2, "contact": map[string]any{"phone": nil}},
+		"shape": map[string]any{"crs": "OGC:CRS84", "wkb_hex": "0101", "geometry": map[string]any{"type": "Point", "coordinates": []any{0, 0}}},
+		"tags":  []any{3, 4},
+		"friends": []any{
+			map[string]any{"id": 5, "address": map[string]any{"zip": "10001"}},
+			map[string]any{"id": 6, "address": nil},
+		},
+		"scores":   map[string]any{"a": 1.5},
+		"repeated": []any{},
+	}
+	testCases := map[string]struct {
+		cmd    Cmd
+		header []string
+		lines  [][]string
+		errMsg string
+	}{
+		"flatten": {
+			cmd:    Cmd{FieldDelimiter: ".", GeoFormat: "geojson"},
+			header: []string{"id", "owner.id", "owner.contact.phone", "shape", "tags", "friends", "scores", "repeated"},
+			lines: [][]string{
+				{"1", "2", "", `{"coordinates":[0,0],"type":"Point"}`, "[3,4]", `[{"address":{"zip":"10001"},"id":5},{"address":null,"id":6}]`, `{"a":1.5}`, "[]"},
+			},
+		},
+		"field-delimiter": {
+			cmd:    Cmd{FieldDelimiter: "/", GeoFormat: "hex"},
+			header: []string{"id", "owner/id", "owner/contact/phone", "shape", "tags", "friends", "scores", "repeated"},
+			lines: [][]string{
+				{"1", "2", "", "0101", "[3,4]", `[{"address":{"zip":"10001"},"id":5},{"address":null,"id":6}]`, `{"a":1.5}`, "[]"},
+			},
+		},
+		"explode-scalar": {
+			cmd:    Cmd{FieldDelimiter: ".", GeoFormat: "base64", Explode: "tags"},
+			header: []string{"id", "owner.id", "owner.contact.phone", "shape", "tags", "friends", "scores", "repeated"},
+			lines: [][]string{
+				{"1", "2", "", `{"crs":"OGC:CRS84","geometry":{"coordinates":[0,0],"type":"Point"},"wkb_hex":"0101"}`, "3", `[{"address":{"zip":"10001"},"id":5},{"address":null,"id":6}]`, `{"a":1.5}`, "[]"},
+				{"1", "2", "", `{"crs":"OGC:CRS84","geometry":{"coordinates":[0,0],"type":"Point"},"wkb_hex":"0101"}`, "4", `[{"address":{"zip":"10001"},"id":5},{"address":null,"id":6}]`, `{"a":1.5}`, "[]"},
+			},
+		},
+		"explode-struct": {
+			cmd:    Cmd{FieldDelimiter: ".", GeoFormat: "hex", Explode: "friends"},
+			header: []string{"id", "owner.id", "owner.contact.phone", "shape", "tags", "friends.id", "friends.address.zip", "scores", "repeated"},
+			lines: [][]string{
+				{"1", "2", "", "0101", "[3,4]", "5", "10001", `{"a":1.5}`, "[]"},
+				{"1", "2", "", "0101", "[3,4]", "6", "", `{"a":1.5}`, "[]"},
+			},
+		},
+		"explode-empty": {
+			cmd:    Cmd{FieldDelimiter: ".", GeoFormat: "hex", Explode: "repeated"},
+			header: []string{"id", "owner.id", "owner.contact.phone", "shape", "tags", "friends", "scores", "repeated"},
+			lines: [][]string{
+				{"1", "2", "", "0101", "[3,4]", `[{"address":{"zip":"10001"},"id":5},{"address":null,"id":6}]`, `{"a":1.5}`, ""},
+			},
+		},
+		"explode-not-list": {
+			cmd:    Cmd{FieldDelimiter: ".", Explode: "owner"},
+			errMsg: "field [owner] is not a list, cannot explode",
+		},
+		"explode-nested-not-list": {
+			cmd:    Cmd{FieldDelimiter: ".", Explode: "owner.id"},
+			errMsg: "field [owner.id] is not a list, cannot explode",
+		},
+		"explode-not-exist": {
+			cmd:    Cmd{FieldDelimiter: ".", Explode: "friends.id"},
+			errMsg: "field [friends.id] does not exist or is inside a list or map, cannot explode",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			flat, err := tc.cmd.newFlattener(flattenTestSchema())
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.header, flat.header())
+			lines, err := flat.lines(row)
+			require.NoError(t, err)
+			require.Equal(t, tc.lines, lines)
+		})
+	}
+
+	flat, err := Cmd{FieldDelimiter: "."}.newFlattener(flattenTestSchema())
+	require.NoError(t, err)
+	_, err = flat.lines(map[string]any{"tags": []any{make(chan int)}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to format field [tags]")
+}
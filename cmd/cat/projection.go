@@ -0,0 +1,109 @@
+package cat
+
+import (
+	"fmt"
+	"strings"
+
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// projection is the result of --columns, readRoot has columns that need to be
+// read from the file, which are selected columns plus those referenced by
+// --where, outputRoot has selected columns only.
+type projection struct {
+	readRoot   *pschema.SchemaNode
+	outputRoot *pschema.SchemaNode
+}
+
+// newProjection builds projected schemas from --columns.
+func (c Cmd) newProjection(schemaRoot *pschema.SchemaNode, filter *rowFilter) (*projection, error) {
+	paths := make([][]string, 0, len(c.Columns))
+	for _, column := range c.Columns {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			return nil, fmt.Errorf("empty column name in --columns")
+		}
+		paths = append(paths, strings.Split(column, c.FieldDelimiter))
+	}
+	outputRoot, err := selectColumns(schemaRoot, paths, c.FieldDelimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter != nil {
+		for _, col := range filter.columns {
+			paths = append(paths, col.node.ExNamePath[1:])
+		}
+	}
+	readRoot, err := selectColumns(schemaRoot, paths, c.FieldDelimiter)
+	if err != nil {
+		return nil, err
+	}
+	return &projection{readRoot: readRoot, outputRoot: outputRoot}, nil
+}
+
+// selectColumns returns a copy of schema tree that only has nodes on paths, a
+// path to a group node selects everything underneath it. Top level fields are
+// in the order they first appear in paths so CSV/TSV header follows it.
+func selectColumns(root *pschema.SchemaNode, paths [][]string, delimiter string) (*pschema.SchemaNode, error) {
+	projected := *root
+	projected.Children = nil
+	for _, path := range paths {
+		if !addColumn(&projected, root, path) {
+			return nil, fmt.Errorf("column [%s] does not exist", strings.Join(path, delimiter))
+		}
+	}
+	projected.NumChildren = new(int32(len(projected.Children)))
+	return &projected, nil
+}
+
+func addColumn(dst, src *pschema.SchemaNode, path []string) bool {
+	if len(path) == 0 {
+		// select the whole sub-tree
+		dst.Children = src.Children
+		dst.NumChildren = src.NumChildren
+		return true
+	}
+
+	srcChild := childByName(src, path[0])
+	if srcChild == nil {
+		return false
+	}
+	if dstChild := childByName(dst, path[0]); dstChild != nil {
+		return addColumn(dstChild, srcChild, path[1:])
+	}
+
+	dstChild := *srcChild
+	dstChild.Children = nil
+	if !addColumn(&dstChild, srcChild, path[1:]) {
+		return false
+	}
+	dst.Children = append(dst.Children, &dstChild)
+	dst.NumChildren = new(int32(len(dst.Children)))
+	return true
+}
+
+func childByName(node *pschema.SchemaNode, name string) *pschema.SchemaNode {
+	for _, child := range node.Children {
+		if child.ExNamePath[len(child.ExNamePath)-1] == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// trimRow removes fields that are not in node, they were only read because
+// --where needs them.
+func trimRow(row map[string]any, node *pschema.SchemaNode) {
+	for key, value := range row {
+		child := childByName(node, key)
+		if child == nil {
+			delete(row, key)
+			continue
+		}
+		// only plain groups map to nested objects with field names as keys
+		if nested, ok := value.(map[string]any); ok && len(child.Children) != 0 && child.ConvertedType == nil && child.LogicalType == nil {
+			trimRow(nested, child)
+		}
+	}
+}
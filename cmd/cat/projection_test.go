@@ -0,0 +1,151 @@
+package cat
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func TestSelectColumns(t *testing.T) {
+	names := func(node *pschema.SchemaNode) []string {
+		var result []string
+		for _, child := range node.Children {
+			result = append(result, strings.Join(child.ExNamePath[1:], "."))
+			for _, grandChild := range child.Children {
+				result = append(result, strings.Join(grandChild.ExNamePath[1:], "."))
+			}
+		}
+		return result
+	}
+
+	testCases := map[string]struct {
+		paths     []string
+		delimiter string
+		expected  []string
+		errMsg    string
+	}{
+		"single":          {paths: []string{"name"}, expected: []string{"name"}},
+		"keep-order":      {paths: []string{"score", "id"}, expected: []string{"score", "id"}},
+		"duplicated":      {paths: []string{"id", "name", "id"}, expected: []string{"id", "name"}},
+		"nested-leaf":     {paths: []string{"info.tags", "id"}, expected: []string{"info", "info.tags", "id"}},
+		"nested-merged":   {paths: []string{"info.tags", "id", "info.age"}, expected: []string{"info", "info.tags", "info.age", "id"}},
+		"group":           {paths: []string{"info"}, expected: []string{"info", "info.age", "info.tags"}},
+		"group-and-leaf":  {paths: []string{"info", "info.tags"}, expected: []string{"info", "info.age", "info.tags"}},
+		"leaf-and-group":  {paths: []string{"info.tags", "info"}, expected: []string{"info", "info.age", "info.tags"}},
+		"delimiter":       {paths: []string{"info/age"}, delimiter: "/", expected: []string{"info", "info.age"}},
+		"not-exist":       {paths: []string{"id", "foo"}, errMsg: "column [foo] does not exist"},
+		"nested-no-exist": {paths: []string{"info/foo"}, delimiter: "/", errMsg: "column [info/foo] does not exist"},
+		"beyond-leaf":     {paths: []string{"id.foo"}, errMsg: "column [id.foo] does not exist"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			root := whereTestSchema()
+			delimiter := tc.delimiter
+			if delimiter == "" {
+				delimiter = "."
+			}
+			paths := make([][]string, len(tc.paths))
+			for i, path := range tc.paths {
+				paths[i] = strings.Split(path, delimiter)
+			}
+
+			projected, err := selectColumns(root, paths, delimiter)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, names(projected))
+			require.Equal(t, int32(len(projected.Children)), projected.GetNumChildren())
+			// original schema is untouched
+			require.Equal(t, []string{"id", "name", "score", "active", "info", "info.age", "info.tags", "geo"}, names(root))
+		})
+	}
+}
+
+func TestTrimRow(t *testing.T) {
+	root := whereTestSchema()
+	projected, err := selectColumns(root, [][]string{{"name"}, {"info", "tags"}}, ".")
+	require.NoError(t, err)
+
+	row := map[string]any{
+		"id":   int64(1),
+		"name": "foo",
+		"info": map[string]any{"age": int32(10), "tags": []any{"a"}},
+	}
+	trimRow(row, projected)
+	require.Equal(t, map[string]any{
+		"name": "foo",
+		"info": map[string]any{"tags": []any{"a"}},
+	}, row)
+
+	row = map[string]any{"id": int64(1), "info": nil}
+	trimRow(row, projected)
+	require.Equal(t, map[string]any{"info": nil}, row)
+}
+
+func TestCmdColumns(t *testing.T) {
+	testCases := map[string]struct {
+		cmd    Cmd
+		output string
+		errMsg string
+	}{
+		"not-exist": {
+			cmd:    Cmd{URI: "good.parquet", Columns: []string{"shoe_brand", "brand"}},
+			errMsg: "column [brand] does not exist",
+		},
+		"empty-column": {
+			cmd:    Cmd{URI: "good.parquet", Columns: []string{"shoe_brand", " "}},
+			errMsg: "empty column name in --columns",
+		},
+		"jsonl": {
+			cmd: Cmd{URI: "good.parquet", Columns: []string{"shoe_name"}},
+			output: `{"shoe_name":"air_griffey"}` + "\n" +
+				`{"shoe_name":"grant_hill_2"}` + "\n" +
+				`{"shoe_name":"curry7"}` + "\n",
+		},
+		"csv-header-order": {
+			cmd:    Cmd{URI: "good.parquet", Columns: []string{"shoe_name", "shoe_brand"}, Format: "csv", Limit: 2},
+			output: "shoe_name,shoe_brand\nair_griffey,nike\ngrant_hill_2,fila\n",
+		},
+		"where-on-other-column": {
+			cmd:    Cmd{URI: "good.parquet", Columns: []string{"shoe_name"}, Where: "shoe_brand = 'fila'", Format: "tsv"},
+			output: "shoe_name\ngrant_hill_2\n",
+		},
+		"nested-csv": {
+			cmd:    Cmd{URI: "all-types.parquet", Columns: []string{"Map"}, Format: "csv"},
+			errMsg: "field [Map] is not scalar type, cannot output in csv format",
+		},
+		"skip-nested-column": {
+			cmd:    Cmd{URI: "all-types.parquet", Columns: []string{"Bool", "Int32"}, Format: "csv", Skip: 1, Limit: 1},
+			output: "Bool,Int32\nfalse,1\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cmd := tc.cmd
+			cmd.URI = "../../testdata/" + cmd.URI
+			cmd.ReadPageSize = 10
+			cmd.SampleRatio = 1.0
+			cmd.FieldDelimiter = "."
+			if cmd.Format == "" {
+				cmd.Format = "jsonl"
+			}
+			if tc.errMsg != "" {
+				err := cmd.Run(context.Background())
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.Equal(t, tc.output, testutils.CommandStdout(t, cmd))
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package cat
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/hangxie/parquet-go/v3/common"
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/reader"
+
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// salt of split block bloom filter, see https://github.com/apache/parquet-format/blob/master/BloomFilter.md
+var bloomFilterSalt = [8]uint32{
+	0x47b6137b, 0x44974d91, 0x8824ad5b, 0xa2b7289d,
+	0x705495c7, 0x2df1424b, 0x9efc4947, 0x5c6bfb31,
+}
+
+// rowRange is a half-open range [start, end) of row numbers.
+type rowRange struct {
+	start, end int64
+}
+
+// rowFilter is a compiled --where expression.
+type rowFilter struct {
+	expr    whereExpr
+	columns []*whereColumn
+}
+
+func newRowFilter(where string, schemaRoot *pschema.SchemaNode, fileReader *reader.ParquetReader, delimiter string) (*rowFilter, error) {
+	expr, columns, err := parseWhere(where, schemaRoot, delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fileReader.Footer.RowGroups) != 0 {
+		for index, chunk := range fileReader.Footer.RowGroups[0].Columns {
+			if chunk == nil || chunk.MetaData == nil {
+				continue
+			}
+			pathKey := strings.Join(chunk.MetaData.PathInSchema, common.ParGoPathDelimiter)
+			for _, col := range columns {
+				if strings.Join(col.node.InNamePath[1:], common.ParGoPathDelimiter) == pathKey {
+					col.index = index
+				}
+			}
+		}
+	}
+	return &rowFilter{expr: expr, columns: columns}, nil
+}
+
+func (f *rowFilter) match(row map[string]any) bool {
+	return f.expr.eval(row)
+}
+
+// rowSelector applies --where, sampling and limit in encoders so that
+// --concurrent also parallelizes conversion and filtering of rows, matched is
+// shared by all encoders.
+type rowSelector struct {
+	filter      *rowFilter
+	proj        *projection
+	sampleRatio float32
+	limit       uint64
+	matched     atomic.Uint64
+}
+
+// selected tells if a converted row is to be output, fields only read for
+// --where are removed from selected rows.
+func (s *rowSelector) selected(rowStruct any) bool {
+	values, ok := rowStruct.(map[string]any)
+	if !ok || !s.filter.match(values) || rand.Float32() >= s.sampleRatio {
+		return false
+	}
+	// rows beyond limit are dropped as other encoders got there first
+	if s.matched.Add(1) > s.limit {
+		return false
+	}
+	if s.proj != nil {
+		trimRow(values, s.proj.outputRoot)
+	}
+	return true
+}
+
+// done tells if limit is reached so that no more rows need to be read.
+func (s *rowSelector) done() bool {
+	return s.matched.Load() >= s.limit
+}
+
+// candidateRows returns rows of the file that may match the filter, rows in
+// other ranges are ruled out by statistics, page indexes or bloom filters.
+func (f *rowFilter) candidateRows(ctx context.Context, fileReader *reader.ParquetReader) []rowRange {
+	var result []rowRange
+	var base int64
+	for index, rowGroup := range fileReader.Footer.RowGroups {
+		scope := &rowGroupScope{
+			ctx:      ctx,
+			reader:   fileReader,
+			index:    index,
+			rowGroup: rowGroup,
+		}
+		for _, candidate := range f.expr.candidates(scope) {
+			result = appendRange(result, rowRange{start: base + candidate.start, end: base + candidate.end})
+		}
+		base += rowGroup.NumRows
+	}
+	return result
+}
+
+// rowGroupScope carries what pruning needs to know about one row group.
+type rowGroupScope struct {
+	ctx      context.Context
+	reader   *reader.ParquetReader
+	index    int
+	rowGroup *parquet.RowGroup
+}
+
+func (s *rowGroupScope) allRows() []rowRange {
+	if s.rowGroup.NumRows <= 0 {
+		return nil
+	}
+	return []rowRange{{start: 0, end: s.rowGroup.NumRows}}
+}
+
+// leafCandidates checks column chunk statistics and bloom filter first, and
+// narrows down to pages with the column index if the chunk cannot be skipped.
+// Anything that cannot be read keeps the rows, pruning is best effort.
+func (s *rowGroupScope) leafCandidates(p leafPredicate) []rowRange {
+	col := p.column()
+	if col.index < 0 || col.index >= len(s.rowGroup.Columns) {
+		return s.allRows()
+	}
+	chunk := s.rowGroup.Columns[col.index]
+	if chunk == nil || chunk.MetaData == nil {
+		return s.allRows()
+	}
+
+	chunkValues := valueRange{numRows: s.rowGroup.NumRows}
+	if stats := chunk.MetaData.Statistics; stats != nil {
+		chunkValues.min, chunkValues.max = col.node.DecodeStatistics(stats)
+		chunkValues.nullCount = stats.NullCount
+	}
+	if !p.mayMatch(chunkValues) || !s.bloomFilterMayContain(col, chunk, p.bloomValues()) {
+		return nil
+	}
+
+	if ranges, ok := s.pageCandidates(p); ok {
+		return ranges
+	}
+	return s.allRows()
+}
+
+func (s *rowGroupScope) pageCandidates(p leafPredicate) ([]rowRange, bool) {
+	col := p.column()
+	columnIndex, err := s.reader.ReadColumnIndexWithContext(s.ctx, s.index, col.index)
+	if err != nil || columnIndex == nil {
+		return nil, false
+	}
+	offsetIndex, err := s.reader.ReadOffsetIndexWithContext(s.ctx, s.index, col.index)
+	if err != nil || offsetIndex == nil {
+		return nil, false
+	}
+	locations := offsetIndex.PageLocations
+	if len(locations) != len(columnIndex.NullPages) ||
+		len(locations) != len(columnIndex.MinValues) ||
+		len(locations) != len(columnIndex.MaxValues) {
+		return nil, false
+	}
+
+	var ranges []rowRange
+	for i, location := range locations {
+		if location == nil {
+			return nil, false
+		}
+		end := s.rowGroup.NumRows
+		if i+1 < len(locations) && locations[i+1] != nil {
+			end = locations[i+1].FirstRowIndex
+		}
+		pageValues := valueRange{
+			numRows: end - location.FirstRowIndex,
+			allNull: columnIndex.NullPages[i],
+		}
+		if !pageValues.allNull {
+			pageValues.min, pageValues.max = col.node.DecodeStatistics(&parquet.Statistics{
+				MinValue: columnIndex.MinValues[i],
+				MaxValue: columnIndex.MaxValues[i],
+			})
+		}
+		if i < len(columnIndex.NullCounts) {
+			pageValues.nullCount = &columnIndex.NullCounts[i]
+		}
+		if p.mayMatch(pageValues) {
+			ranges = appendRange(ranges, rowRange{start: location.FirstRowIndex, end: end})
+		}
+	}
+	return ranges, true
+}
+
+func (s *rowGroupScope) bloomFilterMayContain(col *whereColumn, chunk *parquet.ColumnChunk, values []any) bool {
+	if len(values) == 0 || chunk.MetaData.BloomFilterOffset == nil || chunk.CryptoMetadata != nil {
+		return true
+	}
+	keys := make([][]byte, len(values))
+	for i, value := range values {
+		key, ok := bloomFilterKey(col.node, value)
+		if !ok {
+			return true
+		}
+		keys[i] = key
+	}
+
+	bitset, err := pio.ReadBloomFilter(s.ctx, s.reader.PFile, chunk.MetaData)
+	if err != nil {
+		return true
+	}
+	for _, key := range keys {
+		if bloomFilterContains(bitset, xxhash.Sum64(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// bloomFilterKey returns PLAIN encoded value that bloom filter hashes, it only
+// supports literals that map to physical values unambiguously.
+func bloomFilterKey(node *pschema.SchemaNode, value any) ([]byte, bool) {
+	if node.Type == nil {
+		return nil, false
+	}
+	value = normalizeValue(value)
+	switch *node.Type {
+	case parquet.Type_INT32, parquet.Type_INT64:
+		number, ok := value.(int64)
+		if !ok || !isSignedInteger(node) {
+			return nil, false
+		}
+		if *node.Type == parquet.Type_INT64 {
+			return binary.LittleEndian.AppendUint64(nil, uint64(number)), true
+		}
+		if number < math.MinInt32 || number > math.MaxInt32 {
+			return nil, false
+		}
+		return binary.LittleEndian.AppendUint32(nil, uint32(int32(number))), true
+	case parquet.Type_BYTE_ARRAY:
+		str, ok := value.(string)
+		if !ok || !isString(node) {
+			return nil, false
+		}
+		return []byte(str), true
+	}
+	return nil, false
+}
+
+func isSignedInteger(node *pschema.SchemaNode) bool {
+	if node.LogicalType != nil {
+		return node.LogicalType.IsSetINTEGER() && node.LogicalType.INTEGER.IsSigned
+	}
+	if node.ConvertedType != nil {
+		return slices.Contains([]parquet.ConvertedType{
+			parquet.ConvertedType_INT_8,
+			parquet.ConvertedType_INT_16,
+			parquet.ConvertedType_INT_32,
+			parquet.ConvertedType_INT_64,
+		}, *node.ConvertedType)
+	}
+	return true
+}
+
+func isString(node *pschema.SchemaNode) bool {
+	if node.LogicalType != nil {
+		return node.LogicalType.IsSetSTRING() || node.LogicalType.IsSetENUM()
+	}
+	return node.ConvertedType != nil &&
+		(*node.ConvertedType == parquet.ConvertedType_UTF8 || *node.ConvertedType == parquet.ConvertedType_ENUM)
+}
+
+func bloomFilterContains(bitset []byte, hash uint64) bool {
+	numBlocks := uint64(len(bitset) / 32)
+	if numBlocks == 0 {
+		return true
+	}
+	blockIndex := ((hash >> 32) * numBlocks) >> 32
+	block := bitset[blockIndex*32 : blockIndex*32+32]
+	key := uint32(hash)
+	for i, salt := range bloomFilterSalt {
+		mask := uint32(1) << ((key * salt) >> 27)
+		if binary.LittleEndian.Uint32(block[i*4:])&mask == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// appendRange appends r to sorted ranges, merging it with the last one if
+// they overlap or touch.
+func appendRange(ranges []rowRange, r rowRange) []rowRange {
+	if r.start >= r.end {
+		return ranges
+	}
+	if last := len(ranges) - 1; last >= 0 && r.start <= ranges[last].end {
+		ranges[last].end = max(ranges[last].end, r.end)
+		return ranges
+	}
+	return append(ranges, r)
+}
+
+func intersectRanges(a, b []rowRange) []rowRange {
+	var result []rowRange
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		result = appendRange(result, rowRange{start: max(a[i].start, b[j].start), end: min(a[i].end, b[j].end)})
+		if a[i].end < b[j].end {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+func unionRanges(a, b []rowRange) []rowRange {
+	var result []rowRange
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		if j == len(b) || (i < len(a) && a[i].start <= b[j].start) {
+			result = appendRange(result, a[i])
+			i++
+		} else {
+			result = appendRange(result, b[j])
+			j++
+		}
+	}
+	return result
+}
+
+// clipRanges drops rows before start.
+func clipRanges(ranges []rowRange, start int64) []rowRange {
+	var result []rowRange
+	for _, r := range ranges {
+		result = appendRange(result, rowRange{start: max(r.start, start), end: r.end})
+	}
+	return result
+}
@@ -0,0 +1,283 @@
+package cat
+
+import (
+	"context"
+	"encoding/binary"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func TestRangeOperations(t *testing.T) {
+	t.Run("append", func(t *testing.T) {
+		var ranges []rowRange
+		ranges = appendRange(ranges, rowRange{start: 0, end: 3})
+		ranges = appendRange(ranges, rowRange{start: 3, end: 5})
+		ranges = appendRange(ranges, rowRange{start: 4, end: 4})
+		ranges = appendRange(ranges, rowRange{start: 7, end: 9})
+		require.Equal(t, []rowRange{{0, 5}, {7, 9}}, ranges)
+	})
+
+	t.Run("intersect", func(t *testing.T) {
+		a := []rowRange{{0, 5}, {10, 20}}
+		b := []rowRange{{3, 12}, {15, 16}, {19, 30}}
+		require.Equal(t, []rowRange{{3, 5}, {10, 12}, {15, 16}, {19, 20}}, intersectRanges(a, b))
+		require.Nil(t, intersectRanges(a, nil))
+	})
+
+	t.Run("union", func(t *testing.T) {
+		a := []rowRange{{0, 5}, {10, 20}}
+		b := []rowRange{{3, 7}, {20, 25}, {30, 31}}
+		require.Equal(t, []rowRange{{0, 7}, {10, 25}, {30, 31}}, unionRanges(a, b))
+		require.Equal(t, a, unionRanges(nil, a))
+	})
+
+	t.Run("clip", func(t *testing.T) {
+		ranges := []rowRange{{0, 5}, {10, 20}}
+		require.Equal(t, []rowRange{{3, 5}, {10, 20}}, clipRanges(ranges, 3))
+		require.Equal(t, []rowRange{{12, 20}}, clipRanges(ranges, 12))
+		require.Nil(t, clipRanges(ranges, 20))
+	})
+}
+
+func TestBloomFilterKey(t *testing.T) {
+	node := func(physicalType parquet.Type, convertedType *parquet.ConvertedType, logicalType *parquet.LogicalType) *pschema.SchemaNode {
+		return &pschema.SchemaNode{SchemaElement: parquet.SchemaElement{
+			Type:          &physicalType,
+			ConvertedType: convertedType,
+			LogicalType:   logicalType,
+		}}
+	}
+	testCases := map[string]struct {
+		node  *pschema.SchemaNode
+		value any
+		key   []byte
+	}{
+		"int32":           {node: node(parquet.Type_INT32, nil, nil), value: int64(-1), key: []byte{0xff, 0xff, 0xff, 0xff}},
+		"int32-overflow":  {node: node(parquet.Type_INT32, nil, nil), value: int64(1 << 40)},
+		"int64":           {node: node(parquet.Type_INT64, nil, nil), value: int64(1), key: []byte{1, 0, 0, 0, 0, 0, 0, 0}},
+		"int64-float":     {node: node(parquet.Type_INT64, nil, nil), value: 1.5},
+		"int-8":           {node: node(parquet.Type_INT32, parquet.ConvertedTypePtr(parquet.ConvertedType_INT_8), nil), value: int64(2), key: []byte{2, 0, 0, 0}},
+		"uint-8":          {node: node(parquet.Type_INT32, parquet.ConvertedTypePtr(parquet.ConvertedType_UINT_8), nil), value: int64(2)},
+		"signed-integer":  {node: node(parquet.Type_INT64, nil, &parquet.LogicalType{INTEGER: &parquet.IntType{BitWidth: 64, IsSigned: true}}), value: int64(2), key: []byte{2, 0, 0, 0, 0, 0, 0, 0}},
+		"unsigned":        {node: node(parquet.Type_INT64, nil, &parquet.LogicalType{INTEGER: &parquet.IntType{BitWidth: 64, IsSigned: false}}), value: int64(2)},
+		"date":            {node: node(parquet.Type_INT32, parquet.ConvertedTypePtr(parquet.ConvertedType_DATE), &parquet.LogicalType{DATE: &parquet.DateType{}}), value: int64(2)},
+		"utf8":            {node: node(parquet.Type_BYTE_ARRAY, parquet.ConvertedTypePtr(parquet.ConvertedType_UTF8), nil), value: "abc", key: []byte("abc")},
+		"string":          {node: node(parquet.Type_BYTE_ARRAY, nil, &parquet.LogicalType{STRING: &parquet.StringType{}}), value: "abc", key: []byte("abc")},
+		"enum":            {node: node(parquet.Type_BYTE_ARRAY, nil, &parquet.LogicalType{ENUM: &parquet.EnumType{}}), value: "abc", key: []byte("abc")},
+		"string-number":   {node: node(parquet.Type_BYTE_ARRAY, parquet.ConvertedTypePtr(parquet.ConvertedType_UTF8), nil), value: int64(1)},
+		"raw-byte-array":  {node: node(parquet.Type_BYTE_ARRAY, nil, nil), value: "abc"},
+		"double":          {node: node(parquet.Type_DOUBLE, nil, nil), value: 1.5},
+		"no-type":         {node: &pschema.SchemaNode{}, value: int64(1)},
+		"json-annotation": {node: node(parquet.Type_BYTE_ARRAY, parquet.ConvertedTypePtr(parquet.ConvertedType_JSON), nil), value: "{}"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			key, ok := bloomFilterKey(tc.node, tc.value)
+			require.Equal(t, tc.key != nil, ok)
+			require.Equal(t, tc.key, key)
+		})
+	}
+}
+
+func TestBloomFilterContains(t *testing.T) {
+	fileReader, err := pio.NewParquetFileReader(context.Background(), "../../testdata/bloom-filter.parquet", pio.ReadOption{})
+	require.NoError(t, err)
+	defer func() {
+		_ = fileReader.PFile.Close()
+	}()
+
+	// column 0 is ID (INT64), column 1 is Name (STRING)
+	bitset, err := pio.ReadBloomFilter(context.Background(), fileReader.PFile, fileReader.Footer.RowGroups[0].Columns[0].MetaData)
+	require.NoError(t, err)
+	for value := range int64(10) {
+		require.True(t, bloomFilterContains(bitset, xxhash.Sum64(binary.LittleEndian.AppendUint64(nil, uint64(value)))))
+	}
+	require.False(t, bloomFilterContains(bitset, xxhash.Sum64(binary.LittleEndian.AppendUint64(nil, 42))))
+
+	bitset, err = pio.ReadBloomFilter(context.Background(), fileReader.PFile, fileReader.Footer.RowGroups[0].Columns[1].MetaData)
+	require.NoError(t, err)
+	require.True(t, bloomFilterContains(bitset, xxhash.Sum64String("name-5")))
+	require.False(t, bloomFilterContains(bitset, xxhash.Sum64String("name-10")))
+
+	require.True(t, bloomFilterContains(nil, 0))
+}
+
+func TestCandidateRows(t *testing.T) {
+	testCases := map[string]struct {
+		uri      string
+		where    string
+		expected []rowRange
+	}{
+		// row-group.parquet has 17 and 3 rows in its row groups, with page indexes,
+		// strings compare lexically so "the name is: 18" falls in the first page
+		"page-index":            {uri: "row-group.parquet", where: "brand = 'the brand is: 5'", expected: []rowRange{{5, 8}}},
+		"page-index-or":         {uri: "row-group.parquet", where: "brand = 'the brand is: 5' OR name = 'the name is: 18'", expected: []rowRange{{0, 3}, {5, 8}, {18, 19}}},
+		"page-index-and":        {uri: "row-group.parquet", where: "brand >= 'the brand is: 5' AND name <= 'the name is: 3'", expected: nil},
+		"row-group-statistics":  {uri: "row-group.parquet", where: "brand > 'the brand is: 9'", expected: nil},
+		"not-keeps-everything":  {uri: "row-group.parquet", where: "NOT brand = 'the brand is: 5'", expected: []rowRange{{0, 20}}},
+		"is-null":               {uri: "row-group.parquet", where: "brand IS NULL", expected: nil},
+		"bloom-filter-string":   {uri: "bloom-filter.parquet", where: "Name = 'name-10'", expected: nil},
+		"bloom-filter-in":       {uri: "bloom-filter.parquet", where: "Name IN ('name-10', 'name-11')", expected: nil},
+		"bloom-filter-hit":      {uri: "bloom-filter.parquet", where: "Name = 'name-3'", expected: []rowRange{{3, 6}}},
+		"bloom-filter-int":      {uri: "bloom-filter.parquet", where: "ID = 42", expected: nil},
+		"no-bloom-filter":       {uri: "bloom-filter.parquet", where: "Category = 'cat-10'", expected: []rowRange{{0, 9}}},
+		"statistics-int":        {uri: "bloom-filter.parquet", where: "Age < 20", expected: nil},
+		"statistics-kind-mixed": {uri: "bloom-filter.parquet", where: "Age < '20'", expected: []rowRange{{0, 10}}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			fileReader, err := pio.NewParquetFileReader(context.Background(), "../../testdata/"+tc.uri, pio.ReadOption{})
+			require.NoError(t, err)
+			defer func() {
+				_ = fileReader.PFile.Close()
+			}()
+			schemaRoot, err := pschema.NewSchemaTree(context.Background(), fileReader, pschema.SchemaOption{})
+			require.NoError(t, err)
+
+			filter, err := newRowFilter(tc.where, schemaRoot, fileReader, ".")
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, filter.candidateRows(context.Background(), fileReader))
+		})
+	}
+}
+
+func TestRowSelector(t *testing.T) {
+	expr, _, err := parseWhere("id > 1", whereTestSchema(), ".")
+	require.NoError(t, err)
+	outputRoot := &pschema.SchemaNode{Children: []*pschema.SchemaNode{whereTestSchema().Children[0]}}
+	newRows := func() []any {
+		rows := make([]any, 100)
+		for index := range rows {
+			rows[index] = map[string]any{"id": int64(index), "name": "name"}
+		}
+		return rows
+	}
+
+	t.Run("concurrent-limit", func(t *testing.T) {
+		selector := &rowSelector{filter: &rowFilter{expr: expr}, sampleRatio: 1.0, limit: 10}
+		rows := newRows()
+		selected := make([]bool, len(rows))
+		var wg sync.WaitGroup
+		for worker := range 4 {
+			wg.Go(func() {
+				for index := worker; index < len(rows); index += 4 {
+					selected[index] = selector.selected(rows[index])
+				}
+			})
+		}
+		wg.Wait()
+		require.Equal(t, 10, len(slices.DeleteFunc(selected, func(s bool) bool { return !s })))
+		require.True(t, selector.done())
+	})
+
+	t.Run("no-match", func(t *testing.T) {
+		selector := &rowSelector{filter: &rowFilter{expr: expr}, sampleRatio: 1.0, limit: 10}
+		require.False(t, selector.selected(map[string]any{"id": int64(1)}))
+		require.False(t, selector.selected("not a map"))
+		require.False(t, selector.done())
+	})
+
+	t.Run("sample", func(t *testing.T) {
+		selector := &rowSelector{filter: &rowFilter{expr: expr}, sampleRatio: 0.0, limit: 10}
+		for _, row := range newRows() {
+			require.False(t, selector.selected(row))
+		}
+	})
+
+	t.Run("trim", func(t *testing.T) {
+		selector := &rowSelector{filter: &rowFilter{expr: expr}, proj: &projection{outputRoot: outputRoot}, sampleRatio: 1.0, limit: 10}
+		row := map[string]any{"id": int64(2), "name": "name"}
+		require.True(t, selector.selected(row))
+		require.Equal(t, map[string]any{"id": int64(2)}, row)
+	})
+}
+
+func TestCmdWhere(t *testing.T) {
+	testCases := map[string]struct {
+		cmd    Cmd
+		output string
+		errMsg string
+	}{
+		"invalid-expression": {
+			cmd:    Cmd{URI: "good.parquet", Where: "shoe_brand = nike"},
+			errMsg: "invalid literal [nike]",
+		},
+		"unknown-column": {
+			cmd:    Cmd{URI: "good.parquet", Where: "brand = 'nike'"},
+			errMsg: "column [brand] does not exist",
+		},
+		"invalid-delimiter": {
+			cmd:    Cmd{URI: "good.parquet", Where: "shoe_brand = 'nike'", FieldDelimiter: "::"},
+			errMsg: "field delimiter must be a single character",
+		},
+		"equal": {
+			cmd:    Cmd{URI: "good.parquet", Where: "shoe_brand = 'fila'"},
+			output: `{"shoe_brand":"fila","shoe_name":"grant_hill_2"}` + "\n",
+		},
+		"compound": {
+			cmd:    Cmd{URI: "good.parquet", Where: "shoe_brand IN ('nike', 'steph_curry') AND NOT shoe_name = 'curry7'"},
+			output: `{"shoe_brand":"nike","shoe_name":"air_griffey"}` + "\n",
+		},
+		"no-match": {
+			cmd:    Cmd{URI: "good.parquet", Where: "shoe_brand = 'adidas'"},
+			output: "\n",
+		},
+		"csv": {
+			cmd:    Cmd{URI: "good.parquet", Where: "shoe_name >= 'c'", Format: "csv"},
+			output: "shoe_brand,shoe_name\nfila,grant_hill_2\nsteph_curry,curry7\n",
+		},
+		"page-pruning-with-skip": {
+			cmd: Cmd{URI: "row-group.parquet", Where: "brand >= 'the brand is: 5'", Skip: 6, ReadPageSize: 2},
+			output: `{"brand":"the brand is: 6","name":"the name is: 6"}` + "\n" +
+				`{"brand":"the brand is: 7","name":"the name is: 7"}` + "\n" +
+				`{"brand":"the brand is: 8","name":"the name is: 8"}` + "\n" +
+				`{"brand":"the brand is: 9","name":"the name is: 9"}` + "\n",
+		},
+		"limit-counts-matched-rows": {
+			cmd: Cmd{URI: "row-group.parquet", Where: "brand = 'the brand is: 2' OR brand = 'the brand is: 19' OR brand = 'the brand is: 18'", Limit: 2},
+			output: `{"brand":"the brand is: 2","name":"the name is: 2"}` + "\n" +
+				`{"brand":"the brand is: 18","name":"the name is: 18"}` + "\n",
+		},
+		"concurrent": {
+			cmd:    Cmd{URI: "good.parquet", Where: "shoe_brand = 'fila'", Concurrent: true},
+			output: `{"shoe_brand":"fila","shoe_name":"grant_hill_2"}` + "\n",
+		},
+		"bloom-filter": {
+			cmd:    Cmd{URI: "bloom-filter.parquet", Where: "ID IN (3, 42) AND Category = 'cat-0'"},
+			output: `{"Age":23,"Category":"cat-0","ID":3,"Name":"name-3","Score":4.5}` + "\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cmd := tc.cmd
+			cmd.URI = "../../testdata/" + cmd.URI
+			cmd.ReadPageSize = max(cmd.ReadPageSize, 10)
+			cmd.SampleRatio = 1.0
+			if cmd.Format == "" {
+				cmd.Format = "jsonl"
+			}
+			if cmd.FieldDelimiter == "" {
+				cmd.FieldDelimiter = "."
+			}
+			if tc.errMsg != "" {
+				err := cmd.Run(context.Background())
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.Equal(t, tc.output, testutils.CommandStdout(t, cmd))
+		})
+	}
+}
@@ -0,0 +1,650 @@
+package cat
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// whereExpr is a node of a parsed --where expression. eval decides a decoded
+// row, candidates narrows a row group down to the row ranges that may match.
+type whereExpr interface {
+	eval(row map[string]any) bool
+	candidates(scope *rowGroupScope) []rowRange
+}
+
+// leafPredicate is a whereExpr that tests a single column, so it can be
+// checked against statistics, page indexes and bloom filters.
+type leafPredicate interface {
+	whereExpr
+	column() *whereColumn
+	mayMatch(values valueRange) bool
+	bloomValues() []any
+}
+
+// whereColumn is a leaf column referenced by a --where expression.
+type whereColumn struct {
+	name  string
+	node  *pschema.SchemaNode
+	index int // position of the column chunk in a row group, -1 if unknown
+}
+
+func (col *whereColumn) value(row map[string]any) any {
+	var current any = row
+	for _, name := range col.node.ExNamePath[1:] {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = m[name]
+	}
+	return current
+}
+
+// valueRange summarizes values of a column over a range of rows, it comes
+// from either column chunk statistics or a page of the column index.
+type valueRange struct {
+	min, max  any
+	nullCount *int64
+	numRows   int64
+	allNull   bool
+}
+
+func (r valueRange) noValue() bool {
+	return r.allNull || (r.nullCount != nil && *r.nullCount >= r.numRows)
+}
+
+type comparison struct {
+	col   *whereColumn
+	op    string
+	value any
+}
+
+func (p *comparison) column() *whereColumn { return p.col }
+
+func (p *comparison) eval(row map[string]any) bool {
+	result, ok := compareValues(p.col.value(row), p.value)
+	if !ok {
+		return false
+	}
+	switch p.op {
+	case "=":
+		return result == 0
+	case "!=":
+		return result != 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	}
+	return false
+}
+
+func (p *comparison) mayMatch(values valueRange) bool {
+	if values.noValue() {
+		return false
+	}
+	low, lowOK := compareValues(values.min, p.value)
+	high, highOK := compareValues(values.max, p.value)
+	if !lowOK || !highOK {
+		return true
+	}
+	switch p.op {
+	case "=":
+		return low <= 0 && high >= 0
+	case "!=":
+		return low != 0 || high != 0
+	case "<":
+		return low < 0
+	case "<=":
+		return low <= 0
+	case ">":
+		return high > 0
+	case ">=":
+		return high >= 0
+	}
+	return true
+}
+
+func (p *comparison) bloomValues() []any {
+	if p.op != "=" {
+		return nil
+	}
+	return []any{p.value}
+}
+
+func (p *comparison) candidates(scope *rowGroupScope) []rowRange {
+	return scope.leafCandidates(p)
+}
+
+type inList struct {
+	col    *whereColumn
+	values []any
+	negate bool
+}
+
+func (p *inList) column() *whereColumn { return p.col }
+
+func (p *inList) eval(row map[string]any) bool {
+	value := p.col.value(row)
+	if value == nil {
+		return false
+	}
+	for _, candidate := range p.values {
+		if result, ok := compareValues(value, candidate); ok && result == 0 {
+			return !p.negate
+		}
+	}
+	return p.negate
+}
+
+func (p *inList) mayMatch(values valueRange) bool {
+	if values.noValue() {
+		return false
+	}
+	if p.negate {
+		return true
+	}
+	for _, candidate := range p.values {
+		low, lowOK := compareValues(values.min, candidate)
+		high, highOK := compareValues(values.max, candidate)
+		if !lowOK || !highOK || (low <= 0 && high >= 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *inList) bloomValues() []any {
+	if p.negate {
+		return nil
+	}
+	return p.values
+}
+
+func (p *inList) candidates(scope *rowGroupScope) []rowRange {
+	return scope.leafCandidates(p)
+}
+
+type nullCheck struct {
+	col    *whereColumn
+	negate bool
+}
+
+func (p *nullCheck) column() *whereColumn { return p.col }
+
+func (p *nullCheck) eval(row map[string]any) bool {
+	return (p.col.value(row) == nil) != p.negate
+}
+
+func (p *nullCheck) mayMatch(values valueRange) bool {
+	if p.negate {
+		return !values.noValue()
+	}
+	return values.allNull || values.nullCount == nil || *values.nullCount > 0
+}
+
+func (p *nullCheck) bloomValues() []any { return nil }
+
+func (p *nullCheck) candidates(scope *rowGroupScope) []rowRange {
+	return scope.leafCandidates(p)
+}
+
+type andExpr struct{ left, right whereExpr }
+
+func (e *andExpr) eval(row map[string]any) bool {
+	return e.left.eval(row) && e.right.eval(row)
+}
+
+func (e *andExpr) candidates(scope *rowGroupScope) []rowRange {
+	left := e.left.candidates(scope)
+	if len(left) == 0 {
+		return nil
+	}
+	return intersectRanges(left, e.right.candidates(scope))
+}
+
+type orExpr struct{ left, right whereExpr }
+
+func (e *orExpr) eval(row map[string]any) bool {
+	return e.left.eval(row) || e.right.eval(row)
+}
+
+func (e *orExpr) candidates(scope *rowGroupScope) []rowRange {
+	return unionRanges(e.left.candidates(scope), e.right.candidates(scope))
+}
+
+type notExpr struct{ expr whereExpr }
+
+func (e *notExpr) eval(row map[string]any) bool {
+	return !e.expr.eval(row)
+}
+
+// candidates cannot be derived from the operand: rows that failed it because
+// of a null are matches here, so the whole row group stays.
+func (e *notExpr) candidates(scope *rowGroupScope) []rowRange {
+	return scope.allRows()
+}
+
+// normalizeValue brings decoded values and literals to a few comparable
+// kinds: int64, float64, string and bool.
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint:
+		if uint64(v) <= math.MaxInt64 {
+			return int64(v)
+		}
+		return float64(v)
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v)
+		}
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []byte:
+		return string(v)
+	}
+	return value
+}
+
+// compareValues returns -1, 0 or 1 when a is less than, equal to or greater
+// than b, ok is false for nulls, NaN and values of different kinds.
+func compareValues(a, b any) (int, bool) {
+	a, b = normalizeValue(a), normalizeValue(b)
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return cmp.Compare(x, y), true
+		case float64:
+			return compareFloat(float64(x), y)
+		}
+	case float64:
+		switch y := b.(type) {
+		case int64:
+			return compareFloat(x, float64(y))
+		case float64:
+			return compareFloat(x, y)
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case y:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func compareFloat(a, b float64) (int, bool) {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return 0, false
+	}
+	return cmp.Compare(a, b), true
+}
+
+// whereParser is a recursive descent parser of
+//
+//	expr      := and (OR and)*
+//	and       := not (AND not)*
+//	not       := NOT not | '(' expr ')' | predicate
+//	predicate := column op literal | column [NOT] IN '(' literal (',' literal)* ')' | column IS [NOT] NULL
+type whereParser struct {
+	tokens  []whereToken
+	pos     int
+	root    *pschema.SchemaNode
+	delim   string
+	columns []*whereColumn
+}
+
+type whereTokenKind int
+
+const (
+	tokenEOF whereTokenKind = iota
+	tokenWord
+	tokenQuotedName
+	tokenString
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+type whereToken struct {
+	kind whereTokenKind
+	text string
+	pos  int
+}
+
+func (t whereToken) isKeyword(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune("()',\"`=!<>", r)
+}
+
+func tokenizeWhere(input string) ([]whereToken, error) {
+	var tokens []whereToken
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, whereToken{kind: tokenLeftParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, whereToken{kind: tokenRightParen, text: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, whereToken{kind: tokenComma, text: ",", pos: i})
+			i++
+		case r == '\'' || r == '"' || r == '`':
+			// quotes are escaped by doubling them, as in SQL
+			var builder strings.Builder
+			start := i
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated quote at position %d", start)
+				}
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						i++
+					} else {
+						break
+					}
+				}
+				builder.WriteRune(runes[i])
+			}
+			i++
+			kind := tokenQuotedName
+			if r == '\'' {
+				kind = tokenString
+			}
+			tokens = append(tokens, whereToken{kind: kind, text: builder.String(), pos: start})
+		case strings.ContainsRune("=!<>", r):
+			start := i
+			for i++; i < len(runes) && strings.ContainsRune("=<>", runes[i]) && i-start < 2; i++ {
+			}
+			op := string(runes[start:i])
+			switch op {
+			case "=", "==":
+				op = "="
+			case "!=", "<>":
+				op = "!="
+			case "<", "<=", ">", ">=":
+			default:
+				return nil, fmt.Errorf("unknown operator [%s] at position %d", op, start)
+			}
+			tokens = append(tokens, whereToken{kind: tokenOperator, text: op, pos: start})
+		default:
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, whereToken{kind: tokenWord, text: string(runes[start:i]), pos: start})
+		}
+	}
+	return append(tokens, whereToken{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// parseWhere parses a --where expression and binds its columns to leaf nodes
+// of schemaRoot, nested fields are addressed with delimiter separated names.
+func parseWhere(input string, schemaRoot *pschema.SchemaNode, delimiter string) (whereExpr, []*whereColumn, error) {
+	tokens, err := tokenizeWhere(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid where expression: %w", err)
+	}
+	if delimiter == "" {
+		delimiter = "."
+	}
+	parser := &whereParser{tokens: tokens, root: schemaRoot, delim: delimiter}
+	expr, err := parser.parseOr()
+	if err == nil && parser.peek().kind != tokenEOF {
+		err = fmt.Errorf("unexpected [%s] at position %d", parser.peek().text, parser.peek().pos)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid where expression: %w", err)
+	}
+	return expr, parser.columns, nil
+}
+
+func (p *whereParser) peek() whereToken {
+	return p.tokens[p.pos]
+}
+
+func (p *whereParser) next() whereToken {
+	token := p.tokens[p.pos]
+	if token.kind != tokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *whereParser) expect(kind whereTokenKind, text string) error {
+	token := p.next()
+	if token.kind != kind {
+		if token.kind == tokenEOF {
+			return fmt.Errorf("expect [%s] but reached end of expression", text)
+		}
+		return fmt.Errorf("expect [%s] but got [%s] at position %d", text, token.text, token.pos)
+	}
+	return nil
+}
+
+func (p *whereParser) parseOr() (whereExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *whereParser) parseAnd() (whereExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("AND") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *whereParser) parseNot() (whereExpr, error) {
+	token := p.peek()
+	switch {
+	case token.isKeyword("NOT"):
+		p.next()
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{expr: expr}, nil
+	case token.kind == tokenLeftParen:
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRightParen, ")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *whereParser) parsePredicate() (whereExpr, error) {
+	token := p.next()
+	if token.kind != tokenWord && token.kind != tokenQuotedName {
+		if token.kind == tokenEOF {
+			return nil, fmt.Errorf("expect column name but reached end of expression")
+		}
+		return nil, fmt.Errorf("expect column name but got [%s] at position %d", token.text, token.pos)
+	}
+	col, err := p.bindColumn(token.text)
+	if err != nil {
+		return nil, err
+	}
+
+	token = p.next()
+	switch {
+	case token.kind == tokenOperator:
+		value, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return &comparison{col: col, op: token.text, value: value}, nil
+	case token.isKeyword("IS"):
+		negate := false
+		if p.peek().isKeyword("NOT") {
+			p.next()
+			negate = true
+		}
+		if !p.next().isKeyword("NULL") {
+			return nil, fmt.Errorf("expect NULL after IS at position %d", token.pos)
+		}
+		return &nullCheck{col: col, negate: negate}, nil
+	case token.isKeyword("IN"), token.isKeyword("NOT") && p.peek().isKeyword("IN"):
+		negate := token.isKeyword("NOT")
+		if negate {
+			p.next()
+		}
+		if err := p.expect(tokenLeftParen, "("); err != nil {
+			return nil, err
+		}
+		var values []any
+		for {
+			value, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(tokenRightParen, ")"); err != nil {
+			return nil, err
+		}
+		return &inList{col: col, values: values, negate: negate}, nil
+	case token.kind == tokenEOF:
+		return nil, fmt.Errorf("expect operator after [%s] but reached end of expression", col.name)
+	}
+	return nil, fmt.Errorf("expect operator after [%s] but got [%s] at position %d", col.name, token.text, token.pos)
+}
+
+func (p *whereParser) parseLiteral() (any, error) {
+	token := p.next()
+	switch token.kind {
+	case tokenString:
+		return token.text, nil
+	case tokenWord:
+		switch {
+		case token.isKeyword("TRUE"):
+			return true, nil
+		case token.isKeyword("FALSE"):
+			return false, nil
+		case token.isKeyword("NULL"):
+			return nil, fmt.Errorf("use IS NULL or IS NOT NULL to compare with NULL at position %d", token.pos)
+		}
+		if value, err := strconv.ParseInt(token.text, 10, 64); err == nil {
+			return value, nil
+		}
+		if value, err := strconv.ParseFloat(token.text, 64); err == nil && !math.IsNaN(value) {
+			return value, nil
+		}
+		return nil, fmt.Errorf("invalid literal [%s] at position %d, strings need to be single quoted", token.text, token.pos)
+	case tokenEOF:
+		return nil, fmt.Errorf("expect literal but reached end of expression")
+	}
+	return nil, fmt.Errorf("expect literal but got [%s] at position %d", token.text, token.pos)
+}
+
+func (p *whereParser) bindColumn(name string) (*whereColumn, error) {
+	for _, col := range p.columns {
+		if col.name == name {
+			return col, nil
+		}
+	}
+
+	node := p.root
+	for _, part := range strings.Split(name, p.delim) {
+		var child *pschema.SchemaNode
+		for _, candidate := range node.Children {
+			if candidate.ExNamePath[len(candidate.ExNamePath)-1] == part {
+				child = candidate
+				break
+			}
+		}
+		if child == nil {
+			return nil, fmt.Errorf("column [%s] does not exist", name)
+		}
+		if child.RepetitionType != nil && *child.RepetitionType == parquet.FieldRepetitionType_REPEATED {
+			return nil, fmt.Errorf("column [%s] is a repeated field which is not supported", name)
+		}
+		node = child
+	}
+	if len(node.Children) != 0 {
+		return nil, fmt.Errorf("column [%s] is not a leaf column", name)
+	}
+	if node.LogicalType != nil && (node.LogicalType.IsSetGEOGRAPHY() || node.LogicalType.IsSetGEOMETRY()) {
+		return nil, fmt.Errorf("column [%s] is a geospatial column which is not supported", name)
+	}
+
+	col := &whereColumn{name: name, node: node, index: -1}
+	p.columns = append(p.columns, col)
+	return col, nil
+}
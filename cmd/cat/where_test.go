@@ -0,0 +1,228 @@
+package cat
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/stretchr/testify/require"
+
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func whereTestSchema() *pschema.SchemaNode {
+	leaf := func(name string, physicalType parquet.Type, repetition parquet.FieldRepetitionType, path ...string) *pschema.SchemaNode {
+		return &pschema.SchemaNode{
+			SchemaElement: parquet.SchemaElement{Name: name, Type: &physicalType, RepetitionType: &repetition},
+			InNamePath:    append([]string{"Parquet_go_root"}, path...),
+			ExNamePath:    append([]string{"parquet_go_root"}, path...),
+		}
+	}
+	info := &pschema.SchemaNode{
+		SchemaElement: parquet.SchemaElement{Name: "info", RepetitionType: parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_OPTIONAL)},
+		InNamePath:    []string{"Parquet_go_root", "info"},
+		ExNamePath:    []string{"parquet_go_root", "info"},
+		Children: []*pschema.SchemaNode{
+			leaf("age", parquet.Type_INT32, parquet.FieldRepetitionType_OPTIONAL, "info", "age"),
+			leaf("tags", parquet.Type_BYTE_ARRAY, parquet.FieldRepetitionType_REPEATED, "info", "tags"),
+		},
+	}
+	geo := leaf("geo", parquet.Type_BYTE_ARRAY, parquet.FieldRepetitionType_OPTIONAL, "geo")
+	geo.LogicalType = &parquet.LogicalType{GEOMETRY: &parquet.GeometryType{}}
+	return &pschema.SchemaNode{
+		SchemaElement: parquet.SchemaElement{Name: "Parquet_go_root"},
+		InNamePath:    []string{"Parquet_go_root"},
+		ExNamePath:    []string{"parquet_go_root"},
+		Children: []*pschema.SchemaNode{
+			leaf("id", parquet.Type_INT64, parquet.FieldRepetitionType_REQUIRED, "id"),
+			leaf("name", parquet.Type_BYTE_ARRAY, parquet.FieldRepetitionType_OPTIONAL, "name"),
+			leaf("score", parquet.Type_DOUBLE, parquet.FieldRepetitionType_OPTIONAL, "score"),
+			leaf("active", parquet.Type_BOOLEAN, parquet.FieldRepetitionType_OPTIONAL, "active"),
+			info,
+			geo,
+		},
+	}
+}
+
+func TestParseWhere(t *testing.T) {
+	testCases := map[string]struct {
+		where     string
+		delimiter string
+		columns   []string
+		errMsg    string
+	}{
+		"empty":              {where: "", errMsg: "expect column name but reached end of expression"},
+		"unknown-column":     {where: "foo = 1", errMsg: "column [foo] does not exist"},
+		"not-leaf":           {where: "info = 1", errMsg: "column [info] is not a leaf column"},
+		"repeated":           {where: "info.tags = 'a'", errMsg: "column [info.tags] is a repeated field"},
+		"geospatial":         {where: "geo IS NULL", errMsg: "column [geo] is a geospatial column"},
+		"missing-operator":   {where: "id", errMsg: "expect operator after [id] but reached end"},
+		"bad-operator":       {where: "id => 1", errMsg: "unknown operator [=>]"},
+		"bad-operator-bang":  {where: "id ! 1", errMsg: "unknown operator [!]"},
+		"missing-literal":    {where: "id =", errMsg: "expect literal but reached end"},
+		"unquoted-string":    {where: "name = bob", errMsg: "invalid literal [bob] at position 7, strings need to be single quoted"},
+		"null-literal":       {where: "name = NULL", errMsg: "use IS NULL or IS NOT NULL"},
+		"unterminated":       {where: "name = 'bob", errMsg: "unterminated quote at position 7"},
+		"is-without-null":    {where: "name IS 1", errMsg: "expect NULL after IS"},
+		"unbalanced":         {where: "(id = 1", errMsg: "expect [)] but reached end of expression"},
+		"trailing":           {where: "id = 1 id = 2", errMsg: "unexpected [id] at position 7"},
+		"in-missing-paren":   {where: "id IN 1, 2", errMsg: "expect [(] but got [1]"},
+		"in-trailing-comma":  {where: "id IN (1, )", errMsg: "expect literal but got [)]"},
+		"literal-as-column":  {where: "'id' = 1", errMsg: "expect column name but got [id]"},
+		"comparison":         {where: "id >= 10", columns: []string{"id"}},
+		"nested":             {where: "info.age < 30 AND name IS NOT NULL", columns: []string{"info.age", "name"}},
+		"custom-delimiter":   {where: "info/age <> 30", delimiter: "/", columns: []string{"info/age"}},
+		"keyword-case":       {where: "not id in (1, 2) or name is null", columns: []string{"id", "name"}},
+		"not-in":             {where: "id NOT IN (1,2,3)", columns: []string{"id"}},
+		"quoted-column":      {where: "`name` = 'O''Brien' AND \"id\" == -1", columns: []string{"name", "id"}},
+		"same-column-reused": {where: "(id > 1 AND id < 10) OR score = 1.5", columns: []string{"id", "score"}},
+		"bool-literal":       {where: "active = TRUE", columns: []string{"active"}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, columns, err := parseWhere(tc.where, whereTestSchema(), tc.delimiter)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			names := make([]string, len(columns))
+			for i, col := range columns {
+				names[i] = col.name
+				require.Equal(t, -1, col.index)
+			}
+			require.Equal(t, tc.columns, names)
+		})
+	}
+}
+
+func TestWhereEval(t *testing.T) {
+	rows := []map[string]any{
+		{"id": int64(1), "name": "alice", "score": 1.5, "active": true, "info": map[string]any{"age": int32(30)}},
+		{"id": int64(2), "name": nil, "score": 2.0, "active": false, "info": nil},
+		{"id": int64(3), "name": "O'Brien", "score": nil, "active": nil, "info": map[string]any{"age": nil}},
+	}
+	testCases := map[string]struct {
+		where    string
+		expected []int64
+	}{
+		"equal":          {where: "id = 2", expected: []int64{2}},
+		"not-equal":      {where: "id != 2", expected: []int64{1, 3}},
+		"less":           {where: "id < 2", expected: []int64{1}},
+		"less-equal":     {where: "id <= 2", expected: []int64{1, 2}},
+		"greater":        {where: "id > 2", expected: []int64{3}},
+		"greater-equal":  {where: "id >= 2", expected: []int64{2, 3}},
+		"int-vs-float":   {where: "score > 1", expected: []int64{1, 2}},
+		"float-vs-int":   {where: "id = 2.0", expected: []int64{2}},
+		"string":         {where: "name = 'O''Brien'", expected: []int64{3}},
+		"string-order":   {where: "name < 'b'", expected: []int64{1, 3}},
+		"kind-mismatch":  {where: "name = 1", expected: nil},
+		"null-compare":   {where: "name != 'alice'", expected: []int64{3}},
+		"nested":         {where: "info.age = 30", expected: []int64{1}},
+		"nested-null":    {where: "info.age IS NULL", expected: []int64{2, 3}},
+		"is-not-null":    {where: "name IS NOT NULL", expected: []int64{1, 3}},
+		"in":             {where: "id IN (1, 3, 5)", expected: []int64{1, 3}},
+		"not-in":         {where: "id NOT IN (1, 3)", expected: []int64{2}},
+		"not-in-null":    {where: "name NOT IN ('alice')", expected: []int64{3}},
+		"bool":           {where: "active = false", expected: []int64{2}},
+		"and":            {where: "id > 1 AND name IS NULL", expected: []int64{2}},
+		"or":             {where: "id = 1 OR name IS NULL", expected: []int64{1, 2}},
+		"precedence":     {where: "id = 1 OR id = 2 AND name IS NOT NULL", expected: []int64{1}},
+		"parentheses":    {where: "(id = 1 OR id = 2) AND name IS NULL", expected: []int64{2}},
+		"not":            {where: "NOT id = 1", expected: []int64{2, 3}},
+		"not-null-value": {where: "NOT name = 'alice'", expected: []int64{2, 3}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			expr, _, err := parseWhere(tc.where, whereTestSchema(), ".")
+			require.NoError(t, err)
+			var matched []int64
+			for _, row := range rows {
+				if expr.eval(row) {
+					matched = append(matched, row["id"].(int64))
+				}
+			}
+			require.Equal(t, tc.expected, matched)
+		})
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	testCases := map[string]struct {
+		a, b     any
+		expected int
+		ok       bool
+	}{
+		"int32-int64":     {a: int32(1), b: int64(2), expected: -1, ok: true},
+		"uint64-int64":    {a: uint64(3), b: int64(2), expected: 1, ok: true},
+		"uint64-overflow": {a: uint64(math.MaxUint64), b: int64(math.MaxInt64), expected: 1, ok: true},
+		"float32-float64": {a: float32(1.5), b: 1.5, expected: 0, ok: true},
+		"int-float":       {a: int64(1), b: 1.5, expected: -1, ok: true},
+		"large-int64":     {a: int64(math.MaxInt64), b: int64(math.MaxInt64 - 1), expected: 1, ok: true},
+		"bytes-string":    {a: []byte("b"), b: "a", expected: 1, ok: true},
+		"bool":            {a: false, b: true, expected: -1, ok: true},
+		"bool-equal":      {a: true, b: true, expected: 0, ok: true},
+		"bool-greater":    {a: true, b: false, expected: 1, ok: true},
+		"nan":             {a: math.NaN(), b: 1.0, ok: false},
+		"nil":             {a: nil, b: int64(1), ok: false},
+		"string-int":      {a: "1", b: int64(1), ok: false},
+		"bool-int":        {a: true, b: int64(1), ok: false},
+		"int-string":      {a: int64(1), b: "1", ok: false},
+		"float-string":    {a: 1.0, b: "1", ok: false},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			result, ok := compareValues(tc.a, tc.b)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestWhereMayMatch(t *testing.T) {
+	nullCount := func(n int64) *int64 { return &n }
+	testCases := map[string]struct {
+		where    string
+		values   valueRange
+		expected bool
+	}{
+		"equal-in-range":          {where: "id = 5", values: valueRange{min: int64(1), max: int64(9), numRows: 10}, expected: true},
+		"equal-below-range":       {where: "id = 0", values: valueRange{min: int64(1), max: int64(9), numRows: 10}, expected: false},
+		"equal-above-range":       {where: "id = 10", values: valueRange{min: int64(1), max: int64(9), numRows: 10}, expected: false},
+		"not-equal-single-value":  {where: "id != 5", values: valueRange{min: int64(5), max: int64(5), numRows: 10}, expected: false},
+		"not-equal-range":         {where: "id != 5", values: valueRange{min: int64(5), max: int64(6), numRows: 10}, expected: true},
+		"less":                    {where: "id < 1", values: valueRange{min: int64(1), max: int64(9), numRows: 10}, expected: false},
+		"less-equal":              {where: "id <= 1", values: valueRange{min: int64(1), max: int64(9), numRows: 10}, expected: true},
+		"greater":                 {where: "id > 9", values: valueRange{min: int64(1), max: int64(9), numRows: 10}, expected: false},
+		"greater-equal":           {where: "id >= 9", values: valueRange{min: int64(1), max: int64(9), numRows: 10}, expected: true},
+		"no-statistics":           {where: "id = 100", values: valueRange{numRows: 10}, expected: true},
+		"kind-mismatch":           {where: "name = 1", values: valueRange{min: "a", max: "z", numRows: 10}, expected: true},
+		"all-null-page":           {where: "id = 5", values: valueRange{allNull: true, numRows: 10}, expected: false},
+		"all-null-count":          {where: "id = 5", values: valueRange{nullCount: nullCount(10), numRows: 10}, expected: false},
+		"in-hit":                  {where: "id IN (0, 5)", values: valueRange{min: int64(1), max: int64(9), numRows: 10}, expected: true},
+		"in-miss":                 {where: "id IN (0, 10)", values: valueRange{min: int64(1), max: int64(9), numRows: 10}, expected: false},
+		"in-no-statistics":        {where: "id IN (0, 10)", values: valueRange{numRows: 10}, expected: true},
+		"not-in":                  {where: "id NOT IN (5)", values: valueRange{min: int64(5), max: int64(5), numRows: 10}, expected: true},
+		"not-in-all-null":         {where: "id NOT IN (5)", values: valueRange{allNull: true, numRows: 10}, expected: false},
+		"is-null-no-null":         {where: "name IS NULL", values: valueRange{nullCount: nullCount(0), numRows: 10}, expected: false},
+		"is-null-some-null":       {where: "name IS NULL", values: valueRange{nullCount: nullCount(1), numRows: 10}, expected: true},
+		"is-null-unknown":         {where: "name IS NULL", values: valueRange{numRows: 10}, expected: true},
+		"is-null-null-page":       {where: "name IS NULL", values: valueRange{allNull: true, numRows: 10}, expected: true},
+		"is-not-null-all-null":    {where: "name IS NOT NULL", values: valueRange{nullCount: nullCount(10), numRows: 10}, expected: false},
+		"is-not-null-some-values": {where: "name IS NOT NULL", values: valueRange{nullCount: nullCount(9), numRows: 10}, expected: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			expr, _, err := parseWhere(tc.where, whereTestSchema(), ".")
+			require.NoError(t, err)
+			predicate, ok := expr.(leafPredicate)
+			require.True(t, ok)
+			require.Equal(t, tc.expected, predicate.mayMatch(tc.values))
+		})
+	}
+}
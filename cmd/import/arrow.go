@@ -0,0 +1,314 @@
+package importcmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/extensions"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// arrowMagic leads Arrow IPC file (Feather V2), what follows is the same as
+// IPC stream until end-of-stream marker.
+const arrowMagic = "ARROW1"
+
+// newArrowReader reads both Arrow IPC file and stream, file footer is not
+// needed so source does not have to be seekable.
+func newArrowReader(source io.Reader) (*ipc.Reader, error) {
+	reader := bufio.NewReader(source)
+	if magic, err := reader.Peek(len(arrowMagic)); err == nil && string(magic) == arrowMagic {
+		// magic is padded to 8 bytes
+		if _, err := reader.Discard(8); err != nil {
+			return nil, err
+		}
+	}
+	return ipc.NewReader(reader)
+}
+
+// arrowJSONSchema maps Arrow schema to parquet schema in the same format as
+// JSON schema file.
+func arrowJSONSchema(schema *arrow.Schema) (string, error) {
+	root := pschema.JSONSchema{Tag: "name=parquet_go_root"}
+	for _, field := range schema.Fields() {
+		fieldSchema, err := arrowFieldSchema(field.Name, field.Type, field.Nullable)
+		if err != nil {
+			return "", err
+		}
+		root.Fields = append(root.Fields, fieldSchema)
+	}
+	buf, _ := json.MarshalIndent(root, "", "  ")
+	return string(buf), nil
+}
+
+func arrowFieldSchema(name string, dataType arrow.DataType, nullable bool) (pschema.JSONSchema, error) {
+	tags := []string{"name=" + name}
+	var fields []pschema.JSONSchema
+	switch dataType := dataType.(type) {
+	case *arrow.StructType:
+		for _, field := range dataType.Fields() {
+			fieldSchema, err := arrowFieldSchema(field.Name, field.Type, field.Nullable)
+			if err != nil {
+				return pschema.JSONSchema{}, err
+			}
+			fields = append(fields, fieldSchema)
+		}
+	case *arrow.MapType:
+		key, err := arrowFieldSchema("Key", dataType.KeyType(), false)
+		if err != nil {
+			return pschema.JSONSchema{}, err
+		}
+		value, err := arrowFieldSchema("Value", dataType.ItemType(), dataType.ItemField().Nullable)
+		if err != nil {
+			return pschema.JSONSchema{}, err
+		}
+		tags = append(tags, "type=MAP")
+		fields = []pschema.JSONSchema{key, value}
+	case arrow.ListLikeType:
+		element, err := arrowFieldSchema("Element", dataType.Elem(), dataType.ElemField().Nullable)
+		if err != nil {
+			return pschema.JSONSchema{}, err
+		}
+		tags = append(tags, "type=LIST")
+		fields = []pschema.JSONSchema{element}
+	case *arrow.DictionaryType:
+		return arrowFieldSchema(name, dataType.ValueType, nullable)
+	case *extensions.UUIDType:
+		tags = append(tags, "type=FIXED_LEN_BYTE_ARRAY", "length=16", "logicaltype=UUID")
+	case arrow.ExtensionType:
+		return arrowFieldSchema(name, dataType.StorageType(), nullable)
+	case arrow.DecimalType:
+		tags = append(tags, "type=BYTE_ARRAY", "convertedtype=DECIMAL", fmt.Sprintf("scale=%d", dataType.GetScale()), fmt.Sprintf("precision=%d", dataType.GetPrecision()))
+	case *arrow.FixedSizeBinaryType:
+		tags = append(tags, "type=FIXED_LEN_BYTE_ARRAY", fmt.Sprintf("length=%d", dataType.ByteWidth))
+	case *arrow.Time32Type:
+		tags = append(tags, "type=INT32", "convertedtype=TIME_MILLIS")
+	case *arrow.Time64Type:
+		if dataType.Unit == arrow.Microsecond {
+			tags = append(tags, "type=INT64", "convertedtype=TIME_MICROS")
+		} else {
+			tags = append(tags, "type=INT64", "logicaltype=TIME", "logicaltype.isadjustedtoutc=true", "logicaltype.unit=NANOS")
+		}
+	case *arrow.TimestampType:
+		unit := "NANOS"
+		switch dataType.Unit {
+		case arrow.Second, arrow.Millisecond:
+			unit = "MILLIS"
+		case arrow.Microsecond:
+			unit = "MICROS"
+		}
+		tags = append(tags, "type=INT64", "logicaltype=TIMESTAMP", fmt.Sprintf("logicaltype.isadjustedtoutc=%t", dataType.TimeZone != ""), "logicaltype.unit="+unit)
+	default:
+		scalarTags, ok := arrowScalarTags[dataType.ID()]
+		if !ok {
+			return pschema.JSONSchema{}, fmt.Errorf("Arrow type [%s] of field [%s] is not supported", dataType, name)
+		}
+		tags = append(tags, scalarTags...)
+	}
+	if nullable {
+		tags = append(tags, "repetitiontype=OPTIONAL")
+	}
+	return pschema.JSONSchema{Tag: strings.Join(tags, ", "), Fields: fields}, nil
+}
+
+var arrowScalarTags = map[arrow.Type][]string{
+	arrow.BOOL:         {"type=BOOLEAN"},
+	arrow.INT8:         {"type=INT32", "convertedtype=INT_8"},
+	arrow.INT16:        {"type=INT32", "convertedtype=INT_16"},
+	arrow.INT32:        {"type=INT32"},
+	arrow.INT64:        {"type=INT64"},
+	arrow.UINT8:        {"type=INT32", "convertedtype=UINT_8"},
+	arrow.UINT16:       {"type=INT32", "convertedtype=UINT_16"},
+	arrow.UINT32:       {"type=INT32", "convertedtype=UINT_32"},
+	arrow.UINT64:       {"type=INT64", "convertedtype=UINT_64"},
+	arrow.FLOAT16:      {"type=FIXED_LEN_BYTE_ARRAY", "length=2", "logicaltype=FLOAT16"},
+	arrow.FLOAT32:      {"type=FLOAT"},
+	arrow.FLOAT64:      {"type=DOUBLE"},
+	arrow.STRING:       {"type=BYTE_ARRAY", "convertedtype=UTF8"},
+	arrow.LARGE_STRING: {"type=BYTE_ARRAY", "convertedtype=UTF8"},
+	arrow.STRING_VIEW:  {"type=BYTE_ARRAY", "convertedtype=UTF8"},
+	arrow.BINARY:       {"type=BYTE_ARRAY"},
+	arrow.LARGE_BINARY: {"type=BYTE_ARRAY"},
+	arrow.BINARY_VIEW:  {"type=BYTE_ARRAY"},
+	arrow.DATE32:       {"type=INT32", "convertedtype=DATE"},
+	arrow.DATE64:       {"type=INT32", "convertedtype=DATE"},
+}
+
+// arrowValue converts value at index of an Arrow array to what JSON writer
+// expects.
+func arrowValue(values arrow.Array, index int) (any, error) {
+	if values.IsNull(index) {
+		return nil, nil
+	}
+	switch values := values.(type) {
+	case *array.Struct:
+		dataType := values.DataType().(*arrow.StructType)
+		result := make(map[string]any, values.NumField())
+		for fieldIndex, field := range dataType.Fields() {
+			fieldValue, err := arrowValue(values.Field(fieldIndex), index)
+			if err != nil {
+				return nil, fmt.Errorf("field [%s]: %w", field.Name, err)
+			}
+			result[field.Name] = fieldValue
+		}
+		return result, nil
+	case *array.Map:
+		start, end := values.ValueOffsets(index)
+		result := make(map[string]any, end-start)
+		for item := int(start); item < int(end); item++ {
+			key, err := arrowValue(values.Keys(), item)
+			if err != nil {
+				return nil, err
+			}
+			value, err := arrowValue(values.Items(), item)
+			if err != nil {
+				return nil, err
+			}
+			keyText, err := arrowMapKey(key)
+			if err != nil {
+				return nil, err
+			}
+			result[keyText] = value
+		}
+		return result, nil
+	case array.ListLike:
+		start, end := values.ValueOffsets(index)
+		result := make([]any, 0, end-start)
+		for item := int(start); item < int(end); item++ {
+			value, err := arrowValue(values.ListValues(), item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+		return result, nil
+	case *array.Dictionary:
+		return arrowValue(values.Dictionary(), values.GetValueIndex(index))
+	case *extensions.UUIDArray:
+		return values.Value(index).String(), nil
+	case array.ExtensionArray:
+		return arrowValue(values.Storage(), index)
+	case *array.Decimal32, *array.Decimal64, *array.Decimal128, *array.Decimal256:
+		return json.Number(values.ValueStr(index)), nil
+	case *array.Float16:
+		return values.Value(index).Float32(), nil
+	case *array.Date32:
+		return values.Value(index).ToTime().Format(time.DateOnly), nil
+	case *array.Date64:
+		return values.Value(index).ToTime().Format(time.DateOnly), nil
+	case *array.Time32:
+		unit := values.DataType().(*arrow.Time32Type).Unit
+		return values.Value(index).ToTime(unit).Format("15:04:05.000"), nil
+	case *array.Time64:
+		unit := values.DataType().(*arrow.Time64Type).Unit
+		if unit == arrow.Microsecond {
+			return values.Value(index).ToTime(unit).Format("15:04:05.000000"), nil
+		}
+		return values.Value(index).ToTime(unit).Format("15:04:05.000000000"), nil
+	case *array.Timestamp:
+		unit := values.DataType().(*arrow.TimestampType).Unit
+		return values.Value(index).ToTime(unit).UTC().Format(time.RFC3339Nano), nil
+	}
+	// booleans, numbers and strings are taken as is, binaries are base64
+	// encoded by JSON marshaller
+	return values.GetOneForMarshal(index), nil
+}
+
+// arrowMapKey returns text of a converted map key. Keys of JSON objects can
+// only be strings, JSON writer parses them the same way as JSON values, so the
+// key goes through JSON marshaller like values do, eg binary keys are base64
+// encoded.
+func arrowMapKey(key any) (string, error) {
+	if text, ok := key.(string); ok {
+		return text, nil
+	}
+	buf, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+	return fmt.Sprint(value), nil
+}
+
+func (c Cmd) importArrow(ctx context.Context, schema string, arrowReader *ipc.Reader, rejects *rejecter) error {
+	rowGroups := pio.NewRowGroupLimiter(c.RowGroupRows)
+	parquetWriter, err := pio.NewJSONWriter(ctx, c.URI, c.WriteOption, schema)
+	if err != nil {
+		return fmt.Errorf("failed to create JSON writer: %w", err)
+	}
+	// Best-effort close (with HDFS retry) on every error path once the writer
+	// exists; disabled once the explicit close below takes over.
+	closed := false
+	defer func() {
+		if !closed {
+			_ = c.closeWriter(parquetWriter.PFile)
+		}
+	}()
+	recordWriter := newJSONRecordWriter(parquetWriter)
+
+	index := 0
+	for arrowReader.Next() {
+		batch := arrowReader.RecordBatch()
+		for row := range int(batch.NumRows()) {
+			index++
+			record := make(map[string]any, batch.NumCols())
+			var err error
+			for column, field := range batch.Schema().Fields() {
+				if record[field.Name], err = arrowValue(batch.Column(column), row); err != nil {
+					err = fmt.Errorf("field [%s]: %w", field.Name, err)
+					break
+				}
+			}
+			var buf []byte
+			if err == nil {
+				buf, err = json.Marshal(record)
+			}
+			if err != nil {
+				err = fmt.Errorf("failed to convert record %d of [%s]: %w", index, c.Source, err)
+				if err := rejects.reject(index, 0, "", err); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := recordWriter.write(ctx, string(buf)); err != nil {
+				err = fmt.Errorf("failed to write to parquet file: %w", err)
+				if err := rejects.reject(index, 0, string(buf), err); err != nil {
+					return err
+				}
+				continue
+			}
+			rejects.imported++
+			if err := rowGroups.Written(ctx, &parquetWriter.ParquetWriter); err != nil {
+				return fmt.Errorf("failed to write to [%s]: %w", c.URI, err)
+			}
+		}
+	}
+	if err := arrowReader.Err(); err != nil {
+		return fmt.Errorf("failed to read Arrow source [%s]: %w", c.Source, err)
+	}
+
+	if err := parquetWriter.WriteStopWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to close Parquet writer [%s]: %w", c.URI, err)
+	}
+	closed = true
+	if err := c.closeWriter(parquetWriter.PFile); err != nil {
+		return fmt.Errorf("failed to close Parquet file [%s]: %w", c.URI, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package importcmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/extensions"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func arrowTestSchema() *arrow.Schema {
+	return arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "age", Type: arrow.PrimitiveTypes.Uint8},
+		{Name: "weight", Type: arrow.FixedWidthTypes.Float16, Nullable: true},
+		{Name: "raw", Type: arrow.BinaryTypes.Binary},
+		{Name: "code", Type: &arrow.FixedSizeBinaryType{ByteWidth: 2}},
+		{Name: "price", Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}, Nullable: true},
+		{Name: "day", Type: arrow.FixedWidthTypes.Date32},
+		{Name: "clock", Type: arrow.FixedWidthTypes.Time64us},
+		{Name: "created", Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}},
+		{Name: "local", Type: &arrow.TimestampType{Unit: arrow.Nanosecond}, Nullable: true},
+		{Name: "uuid", Type: extensions.NewUUIDType(), Nullable: true},
+		{Name: "color", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.BinaryTypes.String}},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		{Name: "scores", Type: arrow.MapOf(arrow.PrimitiveTypes.Int32, arrow.PrimitiveTypes.Float64)},
+		{Name: "friend", Type: arrow.StructOf(arrow.Field{Name: "name", Type: arrow.BinaryTypes.String}), Nullable: true},
+	}, nil)
+}
+
+const arrowTestRecords = `[
+	{"id": 1, "name": "alice", "age": 30, "weight": 55.5, "raw": "cmF3", "code": "QUI=", "price": "12.34", "day": "2024-01-15",
+	 "clock": "10:30:00.000001", "created": "2024-01-15T10:30:00.123Z", "local": "2024-01-15T10:30:00.123456789",
+	 "uuid": "550e8400-e29b-41d4-a716-446655440000", "color": "RED", "tags": ["a", "b"], "scores": [{"key": 1, "value": 1.5}],
+	 "friend": {"name": "bob"}},
+	{"id": 2, "age": 40, "raw": "", "code": "Q0Q=", "day": "1970-01-01", "clock": "00:00:00", "created": "1970-01-01T00:00:00Z",
+	 "color": "GREEN", "scores": []}
+]`
+
+// writeArrowFile writes records in Arrow IPC file format, or stream format if
+// stream is true.
+func writeArrowFile(t *testing.T, fileName string, stream bool) {
+	schema := arrowTestSchema()
+	records, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, bytes.NewReader([]byte(arrowTestRecords)))
+	require.NoError(t, err)
+	defer records.Release()
+
+	file, err := os.Create(fileName)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, file.Close())
+	}()
+	if stream {
+		writer := ipc.NewWriter(file, ipc.WithSchema(schema))
+		require.NoError(t, writer.Write(records))
+		require.NoError(t, writer.Close())
+		return
+	}
+	writer, err := ipc.NewFileWriter(file, ipc.WithSchema(schema))
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(records))
+	require.NoError(t, writer.Close())
+}
+
+func TestArrowJSONSchema(t *testing.T) {
+	schema, err := arrowJSONSchema(arrowTestSchema())
+	require.NoError(t, err)
+	var jsonSchema pschema.JSONSchema
+	require.NoError(t, json.Unmarshal([]byte(schema), &jsonSchema))
+	require.Equal(t, "name=parquet_go_root", jsonSchema.Tag)
+	expected := []string{
+		"name=id, type=INT64",
+		"name=name, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL",
+		"name=age, type=INT32, convertedtype=UINT_8",
+		"name=weight, type=FIXED_LEN_BYTE_ARRAY, length=2, logicaltype=FLOAT16, repetitiontype=OPTIONAL",
+		"name=raw, type=BYTE_ARRAY",
+		"name=code, type=FIXED_LEN_BYTE_ARRAY, length=2",
+		"name=price, type=BYTE_ARRAY, convertedtype=DECIMAL, scale=2, precision=10, repetitiontype=OPTIONAL",
+		"name=day, type=INT32, convertedtype=DATE",
+		"name=clock, type=INT64, convertedtype=TIME_MICROS",
+		"name=created, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MILLIS",
+		"name=local, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=false, logicaltype.unit=NANOS, repetitiontype=OPTIONAL",
+		"name=uuid, type=FIXED_LEN_BYTE_ARRAY, length=16, logicaltype=UUID, repetitiontype=OPTIONAL",
+		"name=color, type=BYTE_ARRAY, convertedtype=UTF8",
+		"name=tags, type=LIST, repetitiontype=OPTIONAL",
+		"name=scores, type=MAP",
+		"name=friend, repetitiontype=OPTIONAL",
+	}
+	require.Len(t, jsonSchema.Fields, len(expected))
+	for index, tag := range expected {
+		require.Equal(t, tag, jsonSchema.Fields[index].Tag)
+	}
+	require.Equal(t, "name=Element, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL", jsonSchema.Fields[13].Fields[0].Tag)
+	require.Equal(t, "name=Key, type=INT32", jsonSchema.Fields[14].Fields[0].Tag)
+	require.Equal(t, "name=Value, type=DOUBLE, repetitiontype=OPTIONAL", jsonSchema.Fields[14].Fields[1].Tag)
+	require.Equal(t, "name=name, type=BYTE_ARRAY, convertedtype=UTF8", jsonSchema.Fields[15].Fields[0].Tag)
+
+	_, err = arrowJSONSchema(arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.FixedWidthTypes.MonthInterval}}, nil))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Arrow type [month_interval] of field [a] is not supported")
+}
+
+func TestArrowValue(t *testing.T) {
+	for name, stream := range map[string]bool{"file": false, "stream": true} {
+		t.Run(name, func(t *testing.T) {
+			source := filepath.Join(t.TempDir(), "user.arrow")
+			writeArrowFile(t, source, stream)
+			file, err := os.Open(source)
+			require.NoError(t, err)
+			defer func() {
+				_ = file.Close()
+			}()
+
+			reader, err := newArrowReader(file)
+			require.NoError(t, err)
+			defer reader.Release()
+			require.True(t, reader.Next())
+			batch := reader.RecordBatch()
+			require.Equal(t, int64(2), batch.NumRows())
+
+			var rows []map[string]any
+			for row := range int(batch.NumRows()) {
+				record := map[string]any{}
+				for column, field := range batch.Schema().Fields() {
+					record[field.Name], err = arrowValue(batch.Column(column), row)
+					require.NoError(t, err)
+				}
+				rows = append(rows, record)
+			}
+			buf, err := json.Marshal(rows)
+			require.NoError(t, err)
+			require.JSONEq(t, `[
+				{"id": 1, "name": "alice", "age": 30, "weight": 55.5, "raw": "cmF3", "code": "QUI=", "price": 12.34, "day": "2024-01-15",
+				 "clock": "10:30:00.000001", "created": "2024-01-15T10:30:00.123Z", "local": "2024-01-15T10:30:00.123456789Z",
+				 "uuid": "550e8400-e29b-41d4-a716-446655440000", "color": "RED", "tags": ["a", "b"], "scores": {"1": 1.5},
+				 "friend": {"name": "bob"}},
+				{"id": 2, "name": null, "age": 40, "weight": null, "raw": "", "code": "Q0Q=", "price": null, "day": "1970-01-01",
+				 "clock": "00:00:00.000000", "created": "1970-01-01T00:00:00Z", "local": null, "uuid": null, "color": "GREEN",
+				 "tags": null, "scores": {}, "friend": null}
+			]`, string(buf))
+			require.False(t, reader.Next())
+			require.NoError(t, reader.Err())
+		})
+	}
+
+	_, err := newArrowReader(bytes.NewReader([]byte("not arrow")))
+	require.Error(t, err)
+}
+
+func TestArrowMapKey(t *testing.T) {
+	testCases := map[string]struct {
+		keyType arrow.DataType
+		records string
+		expect  string
+	}{
+		"int32":     {arrow.PrimitiveTypes.Int32, `[{"m": [{"key": -1, "value": "a"}, {"key": 2, "value": "b"}]}]`, `{"-1": "a", "2": "b"}`},
+		"int64":     {arrow.PrimitiveTypes.Int64, `[{"m": [{"key": 9007199254740993, "value": "a"}]}]`, `{"9007199254740993": "a"}`},
+		"float64":   {arrow.PrimitiveTypes.Float64, `[{"m": [{"key": 1.5, "value": "a"}]}]`, `{"1.5": "a"}`},
+		"binary":    {arrow.BinaryTypes.Binary, `[{"m": [{"key": "cmF3", "value": "a"}]}]`, `{"cmF3": "a"}`},
+		"string":    {arrow.BinaryTypes.String, `[{"m": [{"key": "k", "value": "a"}]}]`, `{"k": "a"}`},
+		"timestamp": {&arrow.TimestampType{Unit: arrow.Second}, `[{"m": [{"key": "2024-01-15T10:30:00", "value": "a"}]}]`, `{"2024-01-15T10:30:00Z": "a"}`},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			schema := arrow.NewSchema([]arrow.Field{{Name: "m", Type: arrow.MapOf(tc.keyType, arrow.BinaryTypes.String)}}, nil)
+			records, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, bytes.NewReader([]byte(tc.records)))
+			require.NoError(t, err)
+			defer records.Release()
+
+			value, err := arrowValue(records.Column(0), 0)
+			require.NoError(t, err)
+			buf, err := json.Marshal(value)
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expect, string(buf))
+		})
+	}
+}
+
+func TestCmdArrow(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "user.arrow")
+	writeArrowFile(t, source, false)
+
+	t.Run("schema-output", func(t *testing.T) {
+		cmd := Cmd{Format: "arrow", Source: source, SchemaOutput: filepath.Join(tempDir, "user.schema")}
+		require.NoError(t, cmd.Run(context.Background()))
+		schema, err := os.ReadFile(cmd.SchemaOutput)
+		require.NoError(t, err)
+		var jsonSchema pschema.JSONSchema
+		require.NoError(t, json.Unmarshal(schema, &jsonSchema))
+		require.Len(t, jsonSchema.Fields, 16)
+	})
+
+	t.Run("not-arrow", func(t *testing.T) {
+		err := Cmd{Format: "arrow", Source: "../../testdata/csv.source", URI: filepath.Join(tempDir, "dummy.parquet")}.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read Arrow source")
+	})
+
+	t.Run("import", func(t *testing.T) {
+		cmd := Cmd{
+			Format:      "arrow",
+			Source:      source,
+			URI:         filepath.Join(tempDir, "user.parquet"),
+			WriteOption: pio.WriteOption{CompressionCodec: "SNAPPY", PageSize: 1024 * 1024, RowGroupSize: 128 * 1024 * 1024},
+		}
+		_, _ = testutils.CaptureStdoutStderr(func() {
+			require.NoError(t, cmd.Run(context.Background()))
+		})
+
+		reader, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+		require.NoError(t, err)
+		defer func() {
+			_ = reader.PFile.Close()
+		}()
+		require.Equal(t, int64(2), reader.GetNumRows())
+	})
+
+	t.Run("int-keyed-map", func(t *testing.T) {
+		schema := arrow.NewSchema([]arrow.Field{{Name: "m", Type: arrow.MapOf(arrow.PrimitiveTypes.Int64, arrow.BinaryTypes.String)}}, nil)
+		records, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema,
+			bytes.NewReader([]byte(`[{"m": [{"key": -1, "value": "a"}, {"key": 2, "value": "b"}]}, {"m": []}]`)))
+		require.NoError(t, err)
+		defer records.Release()
+
+		mapSource := filepath.Join(tempDir, "map.arrow")
+		file, err := os.Create(mapSource)
+		require.NoError(t, err)
+		writer := ipc.NewWriter(file, ipc.WithSchema(schema))
+		require.NoError(t, writer.Write(records))
+		require.NoError(t, writer.Close())
+		require.NoError(t, file.Close())
+
+		cmd := Cmd{
+			Format:      "arrow",
+			Source:      mapSource,
+			URI:         filepath.Join(tempDir, "map.parquet"),
+			WriteOption: pio.WriteOption{CompressionCodec: "SNAPPY", PageSize: 1024 * 1024, RowGroupSize: 128 * 1024 * 1024},
+		}
+		stdout, _ := testutils.CaptureStdoutStderr(func() {
+			require.NoError(t, cmd.Run(context.Background()))
+		})
+		require.Empty(t, stdout)
+		require.Equal(t, `[{"m":{"-1":"a","2":"b"}},{"m":{}}]`+"\n", testutils.CommandStdout(t, importTestCatCmd(cmd.URI, pio.ReadOption{})))
+	})
+}
@@ -0,0 +1,416 @@
+package importcmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// avroType is an Avro type resolved to what parquet schema and JSON writer need,
+// named types are resolved to their definitions.
+type avroType struct {
+	kind      string // Avro primitive or complex type name, or logical type name
+	union     bool   // value is wrapped by union
+	optional  bool   // union has null
+	size      int    // fixed, and decimal in fixed
+	precision int    // decimal
+	scale     int    // decimal
+	fields    []avroField
+	element   *avroType // array items and map values
+}
+
+type avroField struct {
+	name string
+	typ  *avroType
+}
+
+// avroSchemaParser resolves named types while walking an Avro schema.
+type avroSchemaParser struct {
+	named   map[string]*avroType
+	parsing map[string]struct{}
+}
+
+func avroFullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func (p *avroSchemaParser) parse(schema any, namespace string) (*avroType, error) {
+	switch schema := schema.(type) {
+	case string:
+		switch schema {
+		case "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &avroType{kind: schema}, nil
+		case "null":
+			return nil, fmt.Errorf("Avro type [null] is only supported in union with another type")
+		}
+		fullName := avroFullName(schema, namespace)
+		if _, found := p.parsing[fullName]; found {
+			return nil, fmt.Errorf("recursive Avro type [%s] is not supported", fullName)
+		}
+		if named, found := p.named[fullName]; found {
+			return named, nil
+		}
+		if named, found := p.named[schema]; found {
+			return named, nil
+		}
+		return nil, fmt.Errorf("unknown Avro type [%s]", schema)
+	case []any:
+		var types []any
+		for _, member := range schema {
+			if member != "null" {
+				types = append(types, member)
+			}
+		}
+		if len(types) != 1 {
+			return nil, fmt.Errorf("Avro union %v is not supported, only union of null and another type is", schema)
+		}
+		typ, err := p.parse(types[0], namespace)
+		if err != nil {
+			return nil, err
+		}
+		union := *typ
+		union.union, union.optional = true, len(schema) != 1
+		return &union, nil
+	case map[string]any:
+		return p.parseComplex(schema, namespace)
+	}
+	return nil, fmt.Errorf("invalid Avro type [%v]", schema)
+}
+
+func (p *avroSchemaParser) parseComplex(schema map[string]any, namespace string) (*avroType, error) {
+	typeName, ok := schema["type"].(string)
+	if !ok {
+		// like {"type": {"type": "string"}}
+		return p.parse(schema["type"], namespace)
+	}
+
+	name, _ := schema["name"].(string)
+	if ns, ok := schema["namespace"].(string); ok {
+		namespace = ns
+	}
+	fullName := avroFullName(name, namespace)
+	if index := strings.LastIndex(fullName, "."); index >= 0 {
+		namespace = fullName[:index]
+	}
+
+	typ := &avroType{kind: typeName}
+	switch typeName {
+	case "record", "error":
+		typ.kind = "record"
+		p.parsing[fullName] = struct{}{}
+		fields, _ := schema["fields"].([]any)
+		for _, field := range fields {
+			fieldMap, _ := field.(map[string]any)
+			fieldName, _ := fieldMap["name"].(string)
+			fieldType, err := p.parse(fieldMap["type"], namespace)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse Avro field [%s.%s]: %w", fullName, fieldName, err)
+			}
+			typ.fields = append(typ.fields, avroField{name: fieldName, typ: fieldType})
+		}
+		delete(p.parsing, fullName)
+		if len(typ.fields) == 0 {
+			return nil, fmt.Errorf("Avro record [%s] does not have any field", fullName)
+		}
+	case "enum":
+	case "fixed":
+		size, _ := schema["size"].(float64)
+		typ.size = int(size)
+	case "array", "map":
+		key := "items"
+		if typeName == "map" {
+			key = "values"
+		}
+		element, err := p.parse(schema[key], namespace)
+		if err != nil {
+			return nil, err
+		}
+		typ.element = element
+	default:
+		primitive, err := p.parse(typeName, namespace)
+		if err != nil {
+			return nil, err
+		}
+		typ = primitive
+	}
+
+	// logical types that are not known fall back to the underlying type per Avro spec
+	logicalType, _ := schema["logicalType"].(string)
+	switch {
+	case logicalType == "decimal" && (typ.kind == "bytes" || typ.kind == "fixed"):
+		precision, _ := schema["precision"].(float64)
+		scale, _ := schema["scale"].(float64)
+		typ = &avroType{kind: logicalType, size: typ.size, precision: int(precision), scale: int(scale)}
+	case logicalType == "date" && typ.kind == "int",
+		logicalType == "time-millis" && typ.kind == "int",
+		logicalType == "time-micros" && typ.kind == "long",
+		strings.HasSuffix(logicalType, "timestamp-millis") && typ.kind == "long",
+		strings.HasSuffix(logicalType, "timestamp-micros") && typ.kind == "long",
+		strings.HasSuffix(logicalType, "timestamp-nanos") && typ.kind == "long",
+		logicalType == "uuid" && typ.kind == "string":
+		typ = &avroType{kind: logicalType}
+	}
+	if typeName == "record" || typeName == "error" || typeName == "enum" || typeName == "fixed" {
+		p.named[fullName] = typ
+	}
+	return typ, nil
+}
+
+func (t avroType) jsonSchema(name string) pschema.JSONSchema {
+	tags := []string{"name=" + name}
+	var fields []pschema.JSONSchema
+	switch t.kind {
+	case "record":
+		for _, field := range t.fields {
+			fields = append(fields, field.typ.jsonSchema(field.name))
+		}
+	case "array":
+		tags = append(tags, "type=LIST")
+		fields = []pschema.JSONSchema{t.element.jsonSchema("Element")}
+	case "map":
+		tags = append(tags, "type=MAP")
+		fields = []pschema.JSONSchema{{Tag: "name=Key, type=BYTE_ARRAY, convertedtype=UTF8"}, t.element.jsonSchema("Value")}
+	case "boolean":
+		tags = append(tags, "type=BOOLEAN")
+	case "int":
+		tags = append(tags, "type=INT32")
+	case "long":
+		tags = append(tags, "type=INT64")
+	case "float":
+		tags = append(tags, "type=FLOAT")
+	case "double":
+		tags = append(tags, "type=DOUBLE")
+	case "bytes":
+		tags = append(tags, "type=BYTE_ARRAY")
+	case "string":
+		tags = append(tags, "type=BYTE_ARRAY", "convertedtype=UTF8")
+	case "enum":
+		tags = append(tags, "type=BYTE_ARRAY", "convertedtype=ENUM")
+	case "fixed":
+		tags = append(tags, "type=FIXED_LEN_BYTE_ARRAY", "length="+strconv.Itoa(t.size))
+	case "decimal":
+		if t.size == 0 {
+			tags = append(tags, "type=BYTE_ARRAY")
+		} else {
+			tags = append(tags, "type=FIXED_LEN_BYTE_ARRAY", "length="+strconv.Itoa(t.size))
+		}
+		tags = append(tags, "convertedtype=DECIMAL", "scale="+strconv.Itoa(t.scale), "precision="+strconv.Itoa(t.precision))
+	case "uuid":
+		tags = append(tags, "type=FIXED_LEN_BYTE_ARRAY", "length=16", "logicaltype=UUID")
+	case "date":
+		tags = append(tags, "type=INT32", "convertedtype=DATE")
+	case "time-millis":
+		tags = append(tags, "type=INT32", "convertedtype=TIME_MILLIS")
+	case "time-micros":
+		tags = append(tags, "type=INT64", "convertedtype=TIME_MICROS")
+	default:
+		// timestamp-* and local-timestamp-*
+		unit := strings.ToUpper(t.kind[strings.LastIndex(t.kind, "-")+1:])
+		adjusted := strconv.FormatBool(!strings.HasPrefix(t.kind, "local-"))
+		tags = append(tags, "type=INT64", "logicaltype=TIMESTAMP", "logicaltype.isadjustedtoutc="+adjusted, "logicaltype.unit="+unit)
+	}
+	if t.optional {
+		tags = append(tags, "repetitiontype=OPTIONAL")
+	}
+	return pschema.JSONSchema{Tag: strings.Join(tags, ", "), Fields: fields}
+}
+
+// jsonValue converts value decoded by goavro to what JSON writer expects.
+func (t avroType) jsonValue(value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if t.union {
+		// non-null value of union is wrapped in a map keyed by type name
+		union, ok := value.(map[string]any)
+		if !ok || len(union) != 1 {
+			return nil, fmt.Errorf("expect union value but got [%v]", value)
+		}
+		for _, v := range union {
+			value = v
+		}
+		t.union = false
+		return t.jsonValue(value)
+	}
+
+	switch t.kind {
+	case "record":
+		record, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expect record value but got [%v]", value)
+		}
+		result := make(map[string]any, len(t.fields))
+		for _, field := range t.fields {
+			fieldValue, err := field.typ.jsonValue(record[field.name])
+			if err != nil {
+				return nil, fmt.Errorf("field [%s]: %w", field.name, err)
+			}
+			result[field.name] = fieldValue
+		}
+		return result, nil
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("expect array value but got [%v]", value)
+		}
+		result := make([]any, len(items))
+		for index, item := range items {
+			itemValue, err := t.element.jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[index] = itemValue
+		}
+		return result, nil
+	case "map":
+		items, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expect map value but got [%v]", value)
+		}
+		result := make(map[string]any, len(items))
+		for key, item := range items {
+			itemValue, err := t.element.jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = itemValue
+		}
+		return result, nil
+	case "decimal":
+		if rat, ok := value.(*big.Rat); ok {
+			return json.Number(rat.FloatString(t.scale)), nil
+		}
+	case "date":
+		if date, ok := value.(time.Time); ok {
+			return date.UTC().Format(time.DateOnly), nil
+		}
+	case "time-millis", "time-micros":
+		if duration, ok := value.(time.Duration); ok {
+			layout := "15:04:05.000"
+			if t.kind == "time-micros" {
+				layout = "15:04:05.000000"
+			}
+			return time.Time{}.Add(duration).Format(layout), nil
+		}
+	case "timestamp-millis", "timestamp-micros", "timestamp-nanos",
+		"local-timestamp-millis", "local-timestamp-micros", "local-timestamp-nanos":
+		switch v := value.(type) {
+		case time.Time:
+			return v.UTC().Format(time.RFC3339Nano), nil
+		case int64:
+			var timestamp time.Time
+			switch {
+			case strings.HasSuffix(t.kind, "millis"):
+				timestamp = time.UnixMilli(v)
+			case strings.HasSuffix(t.kind, "micros"):
+				timestamp = time.UnixMicro(v)
+			default:
+				timestamp = time.Unix(0, v)
+			}
+			return timestamp.UTC().Format(time.RFC3339Nano), nil
+		}
+	default:
+		// primitives, enum and uuid are what JSON writer expects, bytes and
+		// fixed are base64 encoded by JSON marshaller
+		return value, nil
+	}
+	return nil, fmt.Errorf("unexpected %s value [%v]", t.kind, value)
+}
+
+// avroJSONSchema maps writer schema of Avro OCF to parquet schema in the same
+// format as JSON schema file.
+func avroJSONSchema(ocfReader *goavro.OCFReader) (*avroType, string, error) {
+	return parseAvroSchema(ocfReader.MetaData()["avro.schema"])
+}
+
+func parseAvroSchema(avroSchema []byte) (*avroType, string, error) {
+	var schema any
+	if err := json.Unmarshal(avroSchema, &schema); err != nil {
+		return nil, "", fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	parser := avroSchemaParser{named: map[string]*avroType{}, parsing: map[string]struct{}{}}
+	root, err := parser.parse(schema, "")
+	if err != nil {
+		return nil, "", err
+	}
+	if root.kind != "record" || root.optional {
+		return nil, "", fmt.Errorf("Avro schema needs to be a record, not [%s]", root.kind)
+	}
+
+	jsonSchema := root.jsonSchema("parquet_go_root")
+	buf, _ := json.MarshalIndent(jsonSchema, "", "  ")
+	return root, string(buf), nil
+}
+
+func (c Cmd) importAvro(ctx context.Context, schema string, root *avroType, ocfReader *goavro.OCFReader, rejects *rejecter) error {
+	rowGroups := pio.NewRowGroupLimiter(c.RowGroupRows)
+	parquetWriter, err := pio.NewJSONWriter(ctx, c.URI, c.WriteOption, schema)
+	if err != nil {
+		return fmt.Errorf("failed to create JSON writer: %w", err)
+	}
+	// Best-effort close (with HDFS retry) on every error path once the writer
+	// exists; disabled once the explicit close below takes over.
+	closed := false
+	defer func() {
+		if !closed {
+			_ = c.closeWriter(parquetWriter.PFile)
+		}
+	}()
+	recordWriter := newJSONRecordWriter(parquetWriter)
+
+	for index := 1; ocfReader.Scan(); index++ {
+		// decoder cannot move on after a bad record, so it is always fatal
+		datum, err := ocfReader.Read()
+		if err != nil {
+			return fmt.Errorf("failed to read record %d from [%s]: %w", index, c.Source, err)
+		}
+		value, err := root.jsonValue(datum)
+		var record []byte
+		if err == nil {
+			record, err = json.Marshal(value)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to convert record %d of [%s]: %w", index, c.Source, err)
+			if err := rejects.reject(index, 0, fmt.Sprint(datum), err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := recordWriter.write(ctx, string(record)); err != nil {
+			err = fmt.Errorf("failed to write to parquet file: %w", err)
+			if err := rejects.reject(index, 0, string(record), err); err != nil {
+				return err
+			}
+			continue
+		}
+		rejects.imported++
+		if err := rowGroups.Written(ctx, &parquetWriter.ParquetWriter); err != nil {
+			return fmt.Errorf("failed to write to [%s]: %w", c.URI, err)
+		}
+	}
+	if err := ocfReader.Err(); err != nil {
+		return fmt.Errorf("failed to read Avro source [%s]: %w", c.Source, err)
+	}
+
+	if err := parquetWriter.WriteStopWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to close Parquet writer [%s]: %w", c.URI, err)
+	}
+	closed = true
+	if err := c.closeWriter(parquetWriter.PFile); err != nil {
+		return fmt.Errorf("failed to close Parquet file [%s]: %w", c.URI, err)
+	}
+
+	return nil
+}
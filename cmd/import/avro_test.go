@@ -0,0 +1,207 @@
+package importcmd
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+const avroTestSchema = `{"type":"record","name":"User","namespace":"test","fields":[
+	{"name":"id","type":"long"},
+	{"name":"name","type":["null","string"],"default":null},
+	{"name":"active","type":"boolean"},
+	{"name":"score","type":["double","null"]},
+	{"name":"raw","type":"bytes"},
+	{"name":"color","type":{"type":"enum","name":"Color","symbols":["RED","GREEN"]}},
+	{"name":"code","type":{"type":"fixed","name":"Code","size":2}},
+	{"name":"price","type":{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}},
+	{"name":"total","type":["null",{"type":"fixed","name":"Total","size":8,"logicalType":"decimal","precision":18,"scale":3}]},
+	{"name":"day","type":{"type":"int","logicalType":"date"}},
+	{"name":"clock","type":{"type":"long","logicalType":"time-micros"}},
+	{"name":"created","type":{"type":"long","logicalType":"timestamp-millis"}},
+	{"name":"local","type":{"type":"long","logicalType":"local-timestamp-micros"}},
+	{"name":"uuid","type":{"type":"string","logicalType":"uuid"}},
+	{"name":"tags","type":{"type":"array","items":"string"}},
+	{"name":"scores","type":{"type":"map","values":"int"}},
+	{"name":"friend","type":["null",{"type":"record","name":"Friend","fields":[{"name":"name","type":"string"},{"name":"code","type":"test.Code"}]}]}
+]}`
+
+func writeAvroFile(t *testing.T, fileName, schema string, records ...any) {
+	file, err := os.Create(fileName)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, file.Close())
+	}()
+	ocfWriter, err := goavro.NewOCFWriter(goavro.OCFConfig{W: file, Schema: schema})
+	require.NoError(t, err)
+	require.NoError(t, ocfWriter.Append(records))
+}
+
+func avroTestRecord(id int64) map[string]any {
+	return map[string]any{
+		"id":      id,
+		"name":    goavro.Union("string", "alice"),
+		"active":  true,
+		"score":   nil,
+		"raw":     []byte("raw"),
+		"color":   "GREEN",
+		"code":    []byte("AB"),
+		"price":   big.NewRat(1234, 100),
+		"total":   goavro.Union("test.Total", big.NewRat(1, 2)),
+		"day":     time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		"clock":   10*time.Hour + 30*time.Minute + time.Microsecond,
+		"created": time.Date(2024, 1, 15, 10, 30, 0, 123000000, time.UTC),
+		"local":   time.Date(2024, 1, 15, 10, 30, 0, 123456000, time.UTC).UnixMicro(),
+		"uuid":    "550e8400-e29b-41d4-a716-446655440000",
+		"tags":    []any{"a", "b"},
+		"scores":  map[string]any{"math": 90},
+		"friend":  goavro.Union("test.Friend", map[string]any{"name": "bob", "code": []byte("CD")}),
+	}
+}
+
+func TestAvroJSONSchema(t *testing.T) {
+	testCases := map[string]struct {
+		schema   string
+		expected []string
+		errMsg   string
+	}{
+		"bad-json":      {schema: `{`, errMsg: "invalid Avro schema"},
+		"not-record":    {schema: `"string"`, errMsg: "Avro schema needs to be a record, not [string]"},
+		"bad-union":     {schema: `{"type":"record","name":"r","fields":[{"name":"a","type":["int","string"]}]}`, errMsg: "Avro union [int string] is not supported"},
+		"null":          {schema: `{"type":"record","name":"r","fields":[{"name":"a","type":"null"}]}`, errMsg: "Avro type [null] is only supported in union"},
+		"unknown-type":  {schema: `{"type":"record","name":"r","fields":[{"name":"a","type":"foo"}]}`, errMsg: "unknown Avro type [foo]"},
+		"recursive":     {schema: `{"type":"record","name":"r","fields":[{"name":"a","type":["null","r"]}]}`, errMsg: "recursive Avro type [r]"},
+		"null-only":     {schema: `{"type":"record","name":"r","fields":[{"name":"a","type":["null"]}]}`, errMsg: "is not supported"},
+		"unknown-logic": {schema: `{"type":"record","name":"r","fields":[{"name":"a","type":{"type":"int","logicalType":"foo"}}]}`, expected: []string{"name=a, type=INT32"}},
+		"all": {
+			schema: avroTestSchema,
+			expected: []string{
+				"name=id, type=INT64",
+				"name=name, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL",
+				"name=active, type=BOOLEAN",
+				"name=score, type=DOUBLE, repetitiontype=OPTIONAL",
+				"name=raw, type=BYTE_ARRAY",
+				"name=color, type=BYTE_ARRAY, convertedtype=ENUM",
+				"name=code, type=FIXED_LEN_BYTE_ARRAY, length=2",
+				"name=price, type=BYTE_ARRAY, convertedtype=DECIMAL, scale=2, precision=10",
+				"name=total, type=FIXED_LEN_BYTE_ARRAY, length=8, convertedtype=DECIMAL, scale=3, precision=18, repetitiontype=OPTIONAL",
+				"name=day, type=INT32, convertedtype=DATE",
+				"name=clock, type=INT64, convertedtype=TIME_MICROS",
+				"name=created, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MILLIS",
+				"name=local, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=false, logicaltype.unit=MICROS",
+				"name=uuid, type=FIXED_LEN_BYTE_ARRAY, length=16, logicaltype=UUID",
+				"name=tags, type=LIST",
+				"name=scores, type=MAP",
+				"name=friend, repetitiontype=OPTIONAL",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, schema, err := parseAvroSchema([]byte(tc.schema))
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			var jsonSchema pschema.JSONSchema
+			require.NoError(t, json.Unmarshal([]byte(schema), &jsonSchema))
+			require.Equal(t, "name=parquet_go_root", jsonSchema.Tag)
+			require.Len(t, jsonSchema.Fields, len(tc.expected))
+			for index, tag := range tc.expected {
+				require.Equal(t, tag, jsonSchema.Fields[index].Tag)
+			}
+		})
+	}
+}
+
+func TestAvroJSONValue(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "user.avro")
+	writeAvroFile(t, source, avroTestSchema, avroTestRecord(1))
+	file, err := os.Open(source)
+	require.NoError(t, err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	ocfReader, err := goavro.NewOCFReader(file)
+	require.NoError(t, err)
+	root, _, err := avroJSONSchema(ocfReader)
+	require.NoError(t, err)
+	require.True(t, ocfReader.Scan())
+	datum, err := ocfReader.Read()
+	require.NoError(t, err)
+
+	value, err := root.jsonValue(datum)
+	require.NoError(t, err)
+	buf, err := json.Marshal(value)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"id": 1, "name": "alice", "active": true, "score": null, "raw": "cmF3", "color": "GREEN", "code": "QUI=",
+		"price": 12.34, "total": 0.500, "day": "2024-01-15", "clock": "10:30:00.000001",
+		"created": "2024-01-15T10:30:00.123Z", "local": "2024-01-15T10:30:00.123456Z",
+		"uuid": "550e8400-e29b-41d4-a716-446655440000", "tags": ["a", "b"], "scores": {"math": 90},
+		"friend": {"name": "bob", "code": "Q0Q="}
+	}`, string(buf))
+
+	_, err = root.jsonValue(map[string]any{"day": "2024-01-15"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "field [day]: unexpected date value [2024-01-15]")
+	_, err = root.jsonValue(map[string]any{"name": "alice"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "field [name]: expect union value but got [alice]")
+}
+
+func TestCmdAvro(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "user.avro")
+	writeAvroFile(t, source, avroTestSchema, avroTestRecord(1), avroTestRecord(2))
+
+	t.Run("schema-output", func(t *testing.T) {
+		cmd := Cmd{Format: "avro", Source: source, SchemaOutput: filepath.Join(tempDir, "user.schema")}
+		require.NoError(t, cmd.Run(context.Background()))
+		schema, err := os.ReadFile(cmd.SchemaOutput)
+		require.NoError(t, err)
+		var jsonSchema pschema.JSONSchema
+		require.NoError(t, json.Unmarshal(schema, &jsonSchema))
+		require.Len(t, jsonSchema.Fields, 17)
+	})
+
+	t.Run("not-avro", func(t *testing.T) {
+		err := Cmd{Format: "avro", Source: "../../testdata/csv.source", URI: filepath.Join(tempDir, "dummy.parquet")}.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read Avro source")
+	})
+
+	t.Run("import", func(t *testing.T) {
+		cmd := Cmd{
+			Format:      "avro",
+			Source:      source,
+			URI:         filepath.Join(tempDir, "user.parquet"),
+			WriteOption: pio.WriteOption{CompressionCodec: "SNAPPY", PageSize: 1024 * 1024, RowGroupSize: 128 * 1024 * 1024},
+		}
+		_, _ = testutils.CaptureStdoutStderr(func() {
+			require.NoError(t, cmd.Run(context.Background()))
+		})
+
+		reader, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+		require.NoError(t, err)
+		defer func() {
+			_ = reader.PFile.Close()
+		}()
+		require.Equal(t, int64(2), reader.GetNumRows())
+	})
+}
@@ -0,0 +1,106 @@
+package importcmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	compressionAuto  = "auto"
+	compressionNone  = "none"
+	compressionGzip  = "gzip"
+	compressionZstd  = "zstd"
+	compressionBzip2 = "bzip2"
+)
+
+var compressionMagic = []struct {
+	compression string
+	match       func(header []byte) bool
+}{
+	{compressionGzip, hasMagic(0x1f, 0x8b)},
+	{compressionZstd, hasMagic(0x28, 0xb5, 0x2f, 0xfd)},
+	// "BZh" is followed by block size from '1' to '9'
+	{compressionBzip2, func(header []byte) bool {
+		return len(header) >= 4 && string(header[:3]) == "BZh" && header[3] >= '1' && header[3] <= '9'
+	}},
+}
+
+func hasMagic(magic ...byte) func([]byte) bool {
+	return func(header []byte) bool {
+		return bytes.HasPrefix(header, magic)
+	}
+}
+
+var compressionExtension = map[string]string{
+	".gz":   compressionGzip,
+	".gzip": compressionGzip,
+	".zst":  compressionZstd,
+	".zstd": compressionZstd,
+	".bz2":  compressionBzip2,
+}
+
+// plainExtension are extensions of uncompressed source, plain text can start
+// with magic bytes by chance.
+var plainExtension = map[string]struct{}{
+	".csv":   {},
+	".json":  {},
+	".jsonl": {},
+}
+
+// detectCompression trusts extension of plain text source like .csv first,
+// then checks magic bytes at beginning of source, then extension of name.
+func detectCompression(source *bufio.Reader, name string) string {
+	extension := strings.ToLower(path.Ext(name))
+	if _, found := plainExtension[extension]; found {
+		return compressionNone
+	}
+	// error is ignored as short source just does not match longer magic bytes
+	header, _ := source.Peek(4)
+	for _, item := range compressionMagic {
+		if item.match(header) {
+			return item.compression
+		}
+	}
+	if compression, found := compressionExtension[extension]; found {
+		return compression
+	}
+	return compressionNone
+}
+
+// decompress wraps source with decompressor per --source-compression, closing
+// the returned reader does not close source.
+func (c Cmd) decompress(source io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(source)
+	compression := c.SourceCompression
+	if compression == "" || compression == compressionAuto {
+		compression = detectCompression(buffered, c.Source)
+	}
+
+	switch compression {
+	case compressionNone:
+		return io.NopCloser(buffered), nil
+	case compressionGzip:
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip source [%s]: %w", c.Source, err)
+		}
+		return gzipReader, nil
+	case compressionZstd:
+		zstdReader, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zstd source [%s]: %w", c.Source, err)
+		}
+		return zstdReader.IOReadCloser(), nil
+	case compressionBzip2:
+		return io.NopCloser(bzip2.NewReader(buffered)), nil
+	}
+	return nil, fmt.Errorf("[%s] is not a recognized source compression", compression)
+}
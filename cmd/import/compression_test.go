@@ -0,0 +1,139 @@
+package importcmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectCompression(t *testing.T) {
+	testCases := map[string]struct {
+		content  string
+		name     string
+		expected string
+	}{
+		"gzip-magic":       {content: "\x1f\x8bxxxx", name: "source", expected: compressionGzip},
+		"zstd-magic":       {content: "\x28\xb5\x2f\xfdxxxx", name: "source", expected: compressionZstd},
+		"bzip2-magic":      {content: "BZh9xxxx", name: "source", expected: compressionBzip2},
+		"magic-wins":       {content: "BZh9xxxx", name: "source.gz", expected: compressionBzip2},
+		"gzip-ext":         {content: "abc", name: "s3://bucket/source.csv.GZ", expected: compressionGzip},
+		"zstd-ext":         {content: "", name: "source.jsonl.zst", expected: compressionZstd},
+		"bzip2-ext":        {content: "abc", name: "source.json.bz2", expected: compressionBzip2},
+		"plain":            {content: "a,b,c\n", name: "source.csv", expected: compressionNone},
+		"stdin":            {content: "", name: "-", expected: compressionNone},
+		"partial-magic":    {content: "\x28\xb5", name: "source", expected: compressionNone},
+		"bzip2-level":      {content: "BZh1xxxx", name: "source", expected: compressionBzip2},
+		"no-bzip2-level":   {content: "BZh,x,y\n", name: "source", expected: compressionNone},
+		"short-bzip2":      {content: "BZh", name: "source", expected: compressionNone},
+		"csv-with-magic":   {content: "BZh9,x,y\n", name: "source.csv", expected: compressionNone},
+		"json-with-magic":  {content: "\x1f\x8b", name: "https://example.com/source.JSON", expected: compressionNone},
+		"jsonl-with-magic": {content: "\x28\xb5\x2f\xfd", name: "source.jsonl", expected: compressionNone},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			source := bufio.NewReader(strings.NewReader(tc.content))
+			require.Equal(t, tc.expected, detectCompression(source, tc.name))
+			// peeking does not consume source
+			content, err := io.ReadAll(source)
+			require.NoError(t, err)
+			require.Equal(t, tc.content, string(content))
+		})
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	testCases := map[string]struct {
+		source      string
+		compression string
+		expected    string
+		errMsg      string
+	}{
+		"gzip":          {source: "csv.source.gz", expected: "csv.source"},
+		"zstd":          {source: "jsonl.source.zst", expected: "jsonl.source"},
+		"bzip2":         {source: "json.source.bz2", expected: "json.source"},
+		"plain":         {source: "csv.source", expected: "csv.source"},
+		"override-none": {source: "csv.source.gz", compression: compressionNone, expected: "csv.source.gz"},
+		"override-gzip": {source: "csv.source.gz", compression: compressionGzip, expected: "csv.source"},
+		"not-gzip":      {source: "csv.source", compression: compressionGzip, errMsg: "failed to read gzip source"},
+		"unknown":       {source: "csv.source", compression: "lzma", errMsg: "[lzma] is not a recognized source compression"},
+		"explicit-auto": {source: "jsonl.source.zst", compression: compressionAuto, expected: "jsonl.source"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			sourceFile, err := os.Open(filepath.Join("../../testdata", tc.source))
+			require.NoError(t, err)
+			defer func() {
+				_ = sourceFile.Close()
+			}()
+
+			cmd := Cmd{Source: tc.source, SourceCompression: tc.compression}
+			source, err := cmd.decompress(sourceFile)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			defer func() {
+				require.NoError(t, source.Close())
+			}()
+
+			expected, err := os.ReadFile(filepath.Join("../../testdata", tc.expected))
+			require.NoError(t, err)
+			content, err := io.ReadAll(source)
+			require.NoError(t, err)
+			require.True(t, bytes.Equal(expected, content))
+		})
+	}
+}
+
+func TestCmdCompressedSource(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testCases := map[string]struct {
+		cmd    Cmd
+		errMsg string
+	}{
+		"csv-gzip":    {cmd: Cmd{Format: "csv", Source: "csv.source.gz"}},
+		"jsonl-zstd":  {cmd: Cmd{Format: "jsonl", Source: "jsonl.source.zst"}},
+		"json-bzip2":  {cmd: Cmd{Format: "json", Source: "json.source.bz2"}},
+		"not-bzip2":   {cmd: Cmd{Format: "json", Source: "json.source", SourceCompression: compressionBzip2}, errMsg: "bzip2 data invalid"},
+		"not-decoded": {cmd: Cmd{Format: "jsonl", Source: "jsonl.source.zst", SourceCompression: compressionNone}, errMsg: "invalid JSON string"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// compressed and plain source infer the same schema
+			cmd := tc.cmd
+			cmd.Source = filepath.Join("../../testdata", cmd.Source)
+			cmd.SchemaOutput = filepath.Join(tempDir, name+".schema")
+			err := cmd.Run(context.Background())
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			plain := cmd
+			plain.Source = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(cmd.Source, ".gz"), ".zst"), ".bz2")
+			plain.SchemaOutput = filepath.Join(tempDir, name+"-plain.schema")
+			require.NoError(t, plain.Run(context.Background()))
+
+			expected, err := os.ReadFile(plain.SchemaOutput)
+			require.NoError(t, err)
+			actual, err := os.ReadFile(cmd.SchemaOutput)
+			require.NoError(t, err)
+			require.Equal(t, string(expected), string(actual))
+		})
+	}
+}
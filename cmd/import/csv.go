@@ -0,0 +1,255 @@
+package importcmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// csvValueKind tells how a CSV value needs to be normalized before it is
+// passed to CSV writer.
+type csvValueKind int
+
+const (
+	csvValueOther csvValueKind = iota
+	csvValueDate
+	csvValueTimestamp
+	csvValueNumber
+)
+
+// csvColumn is name, repetition and value kind of a column in CSV schema.
+type csvColumn struct {
+	name     string
+	optional bool
+	kind     csvValueKind
+}
+
+// parseCSVColumn gets column name, repetition type and value kind from a line
+// of CSV schema, keys are case-insensitive as they are in parquet-go tags.
+func parseCSVColumn(tag string) (csvColumn, error) {
+	var column csvColumn
+	tags := map[string]string{}
+	for item := range strings.SplitSeq(tag, ",") {
+		key, value, found := strings.Cut(item, "=")
+		if !found {
+			continue
+		}
+		tags[strings.ToLower(strings.TrimSpace(key))] = strings.ToUpper(strings.TrimSpace(value))
+		if strings.EqualFold(strings.TrimSpace(key), "name") {
+			column.name = strings.TrimSpace(value)
+		}
+	}
+	if column.name == "" {
+		return column, fmt.Errorf("there is no column name in schema [%s]", tag)
+	}
+
+	column.optional = tags["repetitiontype"] == "OPTIONAL"
+	switch {
+	case tags["convertedtype"] == "DATE" || tags["logicaltype"] == "DATE":
+		column.kind = csvValueDate
+	case strings.HasPrefix(tags["convertedtype"], "TIMESTAMP_") || tags["logicaltype"] == "TIMESTAMP" || tags["type"] == "INT96":
+		column.kind = csvValueTimestamp
+	case tags["convertedtype"] == "DECIMAL" || tags["logicaltype"] == "DECIMAL" || tags["type"] == "FLOAT" || tags["type"] == "DOUBLE":
+		column.kind = csvValueNumber
+	}
+	return column, nil
+}
+
+func parseCSVColumns(schema []string) ([]csvColumn, error) {
+	columns := make([]csvColumn, len(schema))
+	for index, tag := range schema {
+		column, err := parseCSVColumn(tag)
+		if err != nil {
+			return nil, err
+		}
+		columns[index] = column
+	}
+	return columns, nil
+}
+
+// mapColumns returns index of each schema column in CSV header, -1 means an
+// optional column is not in the header so its value is null. Columns in header
+// but not in schema are ignored.
+func (c Cmd) mapColumns(columns []csvColumn, header []string) ([]int, error) {
+	headerIndex := make(map[string]int, len(header))
+	for index, name := range header {
+		if index == 0 {
+			// Excel and some other tools write UTF-8 BOM
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.TrimSpace(name)
+		if _, found := headerIndex[name]; found {
+			return nil, fmt.Errorf("column [%s] appears more than once in CSV header of [%s]", name, c.Source)
+		}
+		headerIndex[name] = index
+	}
+
+	columnIndex := make([]int, len(columns))
+	var missing []string
+	for index, column := range columns {
+		position, found := headerIndex[column.name]
+		switch {
+		case found:
+			columnIndex[index] = position
+		case column.optional:
+			columnIndex[index] = -1
+		default:
+			missing = append(missing, column.name)
+		}
+	}
+	if len(missing) != 0 {
+		return nil, fmt.Errorf("required columns %v are not in CSV header of [%s]", missing, c.Source)
+	}
+	return columnIndex, nil
+}
+
+// csvValueParser turns CSV values in formats of --null-values, --date-layout,
+// --timestamp-layout and --decimal-separator into what CSV writer expects.
+type csvValueParser struct {
+	nullValues       map[string]struct{}
+	dateLayout       string
+	timestampLayout  string
+	decimalSeparator string
+}
+
+func (c Cmd) newCSVValueParser() (csvValueParser, error) {
+	parser := csvValueParser{
+		nullValues:       make(map[string]struct{}, len(c.NullValues)),
+		dateLayout:       c.DateLayout,
+		timestampLayout:  c.TimestampLayout,
+		decimalSeparator: c.DecimalSeparator,
+	}
+	for _, value := range c.NullValues {
+		parser.nullValues[value] = struct{}{}
+	}
+	if parser.decimalSeparator == "" {
+		parser.decimalSeparator = "."
+	}
+	if utf8.RuneCountInString(parser.decimalSeparator) != 1 || strings.ContainsAny(parser.decimalSeparator, "0123456789+-eE") {
+		return parser, fmt.Errorf("invalid decimal separator [%s]", c.DecimalSeparator)
+	}
+	return parser, nil
+}
+
+func (p csvValueParser) isNull(value string) bool {
+	_, found := p.nullValues[value]
+	return found
+}
+
+// number replaces decimal separator with ".".
+func (p csvValueParser) number(value string) string {
+	if p.decimalSeparator == "" || p.decimalSeparator == "." {
+		return value
+	}
+	return strings.Replace(value, p.decimalSeparator, ".", 1)
+}
+
+func (p csvValueParser) date(value string) (time.Time, error) {
+	if p.dateLayout == "" {
+		return time.Parse(time.DateOnly, value)
+	}
+	return time.Parse(p.dateLayout, value)
+}
+
+func (p csvValueParser) timestamp(value string) (time.Time, error) {
+	if p.timestampLayout == "" {
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return time.Parse(p.timestampLayout, value)
+}
+
+// parse returns value for CSV writer, nil means null.
+func (p csvValueParser) parse(column csvColumn, value string) (*string, error) {
+	if column.optional && p.isNull(value) {
+		return nil, nil
+	}
+
+	switch column.kind {
+	case csvValueDate:
+		if p.dateLayout != "" {
+			date, err := p.date(value)
+			if err != nil {
+				return nil, fmt.Errorf("value [%s] of column [%s] does not match date layout [%s]", value, column.name, p.dateLayout)
+			}
+			value = date.Format(time.DateOnly)
+		}
+	case csvValueTimestamp:
+		if p.timestampLayout != "" {
+			timestamp, err := p.timestamp(value)
+			if err != nil {
+				return nil, fmt.Errorf("value [%s] of column [%s] does not match timestamp layout [%s]", value, column.name, p.timestampLayout)
+			}
+			value = timestamp.UTC().Format(time.RFC3339Nano)
+		}
+	case csvValueNumber:
+		value = p.number(value)
+	}
+	return &value, nil
+}
+
+// record returns values of schema columns in fields for CSV writer, fields are
+// in the same order as schema if columnIndex is nil.
+func (p csvValueParser) record(columns []csvColumn, columnIndex []int, fields []string) ([]*string, error) {
+	if columnIndex == nil {
+		values := make([]*string, len(fields))
+		for i := range fields {
+			// CSV writer complains about extra fields
+			var column csvColumn
+			if i < len(columns) {
+				column = columns[i]
+			}
+			value, err := p.parse(column, fields[i])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+
+	values := make([]*string, len(columnIndex))
+	for i, index := range columnIndex {
+		if index < 0 {
+			continue
+		}
+		value, err := p.parse(columns[i], fields[index])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (p csvValueParser) isDate(value string) bool {
+	if p.dateLayout == "" {
+		return isDate(value)
+	}
+	_, err := p.date(value)
+	return err == nil
+}
+
+// timestampFraction returns number of digits of fractional seconds if value is
+// a timestamp, it comes from timestamp layout if there is one.
+func (p csvValueParser) timestampFraction(value string) (int, bool) {
+	if p.timestampLayout == "" {
+		return timestampFraction(value)
+	}
+	if _, err := p.timestamp(value); err != nil {
+		return 0, false
+	}
+	// fractional seconds are ".000" or ".999" in layout, or with "," as separator
+	for index := 0; index+1 < len(p.timestampLayout); index++ {
+		if p.timestampLayout[index] != '.' && p.timestampLayout[index] != ',' {
+			continue
+		}
+		digit := p.timestampLayout[index+1]
+		if digit != '0' && digit != '9' {
+			continue
+		}
+		rest := p.timestampLayout[index+1:]
+		return len(rest) - len(strings.TrimLeft(rest, string(digit))), true
+	}
+	return 0, true
+}
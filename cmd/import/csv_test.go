@@ -0,0 +1,283 @@
+package importcmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	pio "github.com/hangxie/parquet-tools/io"
+)
+
+func TestParseCSVColumn(t *testing.T) {
+	testCases := map[string]struct {
+		tag      string
+		expected csvColumn
+		errMsg   string
+	}{
+		"required":          {tag: "name=Id, type=INT64", expected: csvColumn{name: "Id"}},
+		"optional":          {tag: "name=Name, type=BYTE_ARRAY, repetitiontype=OPTIONAL", expected: csvColumn{name: "Name", optional: true}},
+		"case":              {tag: " Name = Name , RepetitionType = optional", expected: csvColumn{name: "Name", optional: true}},
+		"date":              {tag: "name=D, type=INT32, convertedtype=DATE", expected: csvColumn{name: "D", kind: csvValueDate}},
+		"date-logical":      {tag: "name=D, type=INT32, logicaltype=DATE", expected: csvColumn{name: "D", kind: csvValueDate}},
+		"timestamp":         {tag: "name=T, type=INT64, convertedtype=TIMESTAMP_MICROS", expected: csvColumn{name: "T", kind: csvValueTimestamp}},
+		"timestamp-logical": {tag: "name=T, type=INT64, logicaltype=TIMESTAMP, logicaltype.unit=NANOS", expected: csvColumn{name: "T", kind: csvValueTimestamp}},
+		"int96":             {tag: "name=T, type=INT96, repetitiontype=OPTIONAL", expected: csvColumn{name: "T", optional: true, kind: csvValueTimestamp}},
+		"float":             {tag: "name=F, type=FLOAT", expected: csvColumn{name: "F", kind: csvValueNumber}},
+		"double":            {tag: "name=F, type=DOUBLE", expected: csvColumn{name: "F", kind: csvValueNumber}},
+		"decimal":           {tag: "name=F, type=INT32, convertedtype=DECIMAL, scale=2, precision=9", expected: csvColumn{name: "F", kind: csvValueNumber}},
+		"int":               {tag: "name=I, type=INT32, convertedtype=INT_16", expected: csvColumn{name: "I"}},
+		"no-name":           {tag: "type=INT64", errMsg: "there is no column name in schema [type=INT64]"},
+		"empty-name":        {tag: "name=, type=INT64", errMsg: "there is no column name in schema"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			column, err := parseCSVColumn(tc.tag)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, column)
+		})
+	}
+}
+
+func TestMapColumns(t *testing.T) {
+	columns, err := parseCSVColumns([]string{
+		"name=Id, type=INT64",
+		"name=Name, type=BYTE_ARRAY, convertedtype=UTF8",
+		"name=Note, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL",
+	})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		header   []string
+		expected []int
+		errMsg   string
+	}{
+		"same-order":       {header: []string{"Id", "Name", "Note"}, expected: []int{0, 1, 2}},
+		"reordered":        {header: []string{"Note", "Id", "Name"}, expected: []int{1, 2, 0}},
+		"extra-columns":    {header: []string{"Extra", "Name", "Id", "Note", "More"}, expected: []int{2, 1, 3}},
+		"missing-optional": {header: []string{"Name", "Id"}, expected: []int{1, 0, -1}},
+		"bom-and-spaces":   {header: []string{"\ufeffId", " Name ", "Note"}, expected: []int{0, 1, 2}},
+		"missing-required": {header: []string{"Note"}, errMsg: "required columns [Id Name] are not in CSV header of [source.csv]"},
+		"case-sensitive":   {header: []string{"id", "Name"}, errMsg: "required columns [Id] are not in CSV header"},
+		"duplicated":       {header: []string{"Id", "Name", "Id"}, errMsg: "column [Id] appears more than once in CSV header"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			columnIndex, err := Cmd{Source: "source.csv"}.mapColumns(columns, tc.header)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, columnIndex)
+		})
+	}
+}
+
+func TestCmdMapByHeader(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFile := func(name, content string) string {
+		fileName := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(fileName, []byte(content), 0o644))
+		return fileName
+	}
+	schema := writeFile("schema", "name=Id, type=INT64\nname=Name, type=BYTE_ARRAY, convertedtype=UTF8\nname=Note, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL\n")
+	wOpt := pio.WriteOption{CompressionCodec: "SNAPPY", PageSize: 1024 * 1024, RowGroupSize: 128 * 1024 * 1024}
+
+	t.Run("missing-required", func(t *testing.T) {
+		cmd := Cmd{
+			Format:      "csv",
+			Source:      writeFile("missing.csv", "Note,Id\nfoo,1\n"),
+			Schema:      schema,
+			MapByHeader: true,
+			URI:         filepath.Join(tempDir, "missing.parquet"),
+			WriteOption: wOpt,
+		}
+		err := cmd.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "required columns [Name] are not in CSV header")
+		// nothing is written
+		_, err = os.Stat(cmd.URI)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("empty-source", func(t *testing.T) {
+		cmd := Cmd{
+			Format:      "csv",
+			Source:      writeFile("empty.csv", ""),
+			Schema:      schema,
+			MapByHeader: true,
+			URI:         filepath.Join(tempDir, "empty.parquet"),
+			WriteOption: wOpt,
+		}
+		err := cmd.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read CSV header from")
+	})
+
+	t.Run("good", func(t *testing.T) {
+		cmd := Cmd{
+			Format:      "csv",
+			Source:      writeFile("good.csv", "Extra,Name,Id\nx,foo,1\ny,bar,2\n"),
+			Schema:      schema,
+			MapByHeader: true,
+			URI:         filepath.Join(tempDir, "good.parquet"),
+			WriteOption: wOpt,
+		}
+		require.NoError(t, cmd.Run(context.Background()))
+
+		reader, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+		require.NoError(t, err)
+		defer func() {
+			_ = reader.PFile.Close()
+		}()
+		require.Equal(t, int64(2), reader.GetNumRows())
+	})
+}
+
+func TestCSVValueParser(t *testing.T) {
+	date := csvColumn{name: "D", kind: csvValueDate}
+	timestamp := csvColumn{name: "T", kind: csvValueTimestamp}
+	number := csvColumn{name: "N", kind: csvValueNumber}
+	text := csvColumn{name: "S"}
+	optional := csvColumn{name: "O", optional: true}
+
+	testCases := map[string]struct {
+		cmd      Cmd
+		column   csvColumn
+		value    string
+		expected *string
+		errMsg   string
+	}{
+		"as-is":              {column: text, value: "NULL", expected: new("NULL")},
+		"null":               {cmd: Cmd{NullValues: []string{"", "NULL"}}, column: optional, value: "NULL", expected: nil},
+		"null-empty":         {cmd: Cmd{NullValues: []string{"", "NULL"}}, column: optional, value: "", expected: nil},
+		"null-case":          {cmd: Cmd{NullValues: []string{"NULL"}}, column: optional, value: "null", expected: new("null")},
+		"null-required":      {cmd: Cmd{NullValues: []string{"NA"}}, column: text, value: "NA", expected: new("NA")},
+		"date-default":       {column: date, value: "2024-01-02", expected: new("2024-01-02")},
+		"date-layout":        {cmd: Cmd{DateLayout: "01/02/2006"}, column: date, value: "12/31/2024", expected: new("2024-12-31")},
+		"date-mismatch":      {cmd: Cmd{DateLayout: "01/02/2006"}, column: date, value: "2024-12-31", errMsg: "value [2024-12-31] of column [D] does not match date layout [01/02/2006]"},
+		"timestamp-default":  {column: timestamp, value: "2024-01-02 03:04:05", expected: new("2024-01-02 03:04:05")},
+		"timestamp-layout":   {cmd: Cmd{TimestampLayout: "2006-01-02 15:04:05.000"}, column: timestamp, value: "2024-01-02 03:04:05.123", expected: new("2024-01-02T03:04:05.123Z")},
+		"timestamp-zone":     {cmd: Cmd{TimestampLayout: "2006-01-02 15:04:05 -0700"}, column: timestamp, value: "2024-01-02 03:04:05 +0800", expected: new("2024-01-01T19:04:05Z")},
+		"timestamp-mismatch": {cmd: Cmd{TimestampLayout: time.DateTime}, column: timestamp, value: "2024-01-02", errMsg: "does not match timestamp layout"},
+		"decimal-separator":  {cmd: Cmd{DecimalSeparator: ","}, column: number, value: "-1,25", expected: new("-1.25")},
+		"separator-text":     {cmd: Cmd{DecimalSeparator: ","}, column: text, value: "1,25", expected: new("1,25")},
+		"bad-separator":      {cmd: Cmd{DecimalSeparator: ",,"}, errMsg: "invalid decimal separator [,,]"},
+		"digit-separator":    {cmd: Cmd{DecimalSeparator: "0"}, errMsg: "invalid decimal separator [0]"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			parser, err := tc.cmd.newCSVValueParser()
+			if err == nil {
+				var value *string
+				value, err = parser.parse(tc.column, tc.value)
+				if err == nil {
+					require.Equal(t, tc.expected, value)
+				}
+			}
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCSVValueParserRecord(t *testing.T) {
+	parser, err := Cmd{NullValues: []string{"NA"}, DecimalSeparator: ","}.newCSVValueParser()
+	require.NoError(t, err)
+	columns := []csvColumn{{name: "S", optional: true}, {name: "N", kind: csvValueNumber}}
+
+	values, err := parser.record(columns, nil, []string{"NA", "1,5", "extra"})
+	require.NoError(t, err)
+	require.Equal(t, []*string{nil, new("1.5"), new("extra")}, values)
+
+	values, err = parser.record(columns, []int{-1, 0}, []string{"2,5", "x"})
+	require.NoError(t, err)
+	require.Equal(t, []*string{nil, new("2.5")}, values)
+
+	parser, err = Cmd{DateLayout: "01/02/2006"}.newCSVValueParser()
+	require.NoError(t, err)
+	_, err = parser.record([]csvColumn{{name: "D", kind: csvValueDate}}, nil, []string{"bad"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not match date layout")
+}
+
+func TestCmdCSVValueOptions(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFile := func(name, content string) string {
+		fileName := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(fileName, []byte(content), 0o644))
+		return fileName
+	}
+	schema := writeFile("schema", "name=Id, type=INT64\nname=Day, type=INT32, convertedtype=DATE, repetitiontype=OPTIONAL\nname=Price, type=DOUBLE, repetitiontype=OPTIONAL\n")
+	wOpt := pio.WriteOption{CompressionCodec: "SNAPPY", PageSize: 1024 * 1024, RowGroupSize: 128 * 1024 * 1024}
+
+	t.Run("bad-value", func(t *testing.T) {
+		cmd := Cmd{
+			Format:      "csv",
+			Source:      writeFile("bad-value.csv", "1,31/12/2024,\"1,5\"\n2,2024-12-31,NA\n"),
+			Schema:      schema,
+			DateLayout:  "02/01/2006",
+			NullValues:  []string{"NA"},
+			URI:         filepath.Join(tempDir, "bad-value.parquet"),
+			WriteOption: wOpt,
+		}
+		err := cmd.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse line 2 of")
+	})
+
+	t.Run("good", func(t *testing.T) {
+		cmd := Cmd{
+			Format:           "csv",
+			Source:           writeFile("good.csv", "1,31/12/2024,\"1,5\"\n2,NA,\\N\n"),
+			Schema:           schema,
+			DateLayout:       "02/01/2006",
+			DecimalSeparator: ",",
+			NullValues:       []string{"NA", "\\N"},
+			URI:              filepath.Join(tempDir, "good.parquet"),
+			WriteOption:      wOpt,
+		}
+		require.NoError(t, cmd.Run(context.Background()))
+
+		reader, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+		require.NoError(t, err)
+		defer func() {
+			_ = reader.PFile.Close()
+		}()
+		require.Equal(t, int64(2), reader.GetNumRows())
+	})
+
+	t.Run("infer", func(t *testing.T) {
+		cmd := Cmd{
+			Format:           "csv",
+			Source:           writeFile("infer.csv", "1,31/12/2024,\"1,5\"\n2,NA,\\N\n"),
+			DateLayout:       "02/01/2006",
+			DecimalSeparator: ",",
+			NullValues:       []string{"NA", "\\N"},
+			SchemaOutput:     filepath.Join(tempDir, "infer.schema"),
+		}
+		require.NoError(t, cmd.Run(context.Background()))
+		schema, err := os.ReadFile(cmd.SchemaOutput)
+		require.NoError(t, err)
+		require.Equal(t, "name=Column1, type=INT64\n"+
+			"name=Column2, type=INT32, convertedtype=DATE, repetitiontype=OPTIONAL\n"+
+			"name=Column3, type=DOUBLE, repetitiontype=OPTIONAL\n", string(schema))
+	})
+}
@@ -2,6 +2,7 @@ package importcmd
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/csv"
 	"encoding/json"
@@ -13,7 +14,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/apache/arrow-go/v18/arrow/ipc"
 	parquetSource "github.com/hangxie/parquet-go/v3/source"
+	"github.com/linkedin/goavro/v2"
 
 	pio "github.com/hangxie/parquet-tools/io"
 )
@@ -25,13 +28,25 @@ const (
 
 // Cmd is a kong command for import
 type Cmd struct {
-	FieldDelimiter   string `name:"field-delimiter" help:"Delimiter separating nested field path components in field and column parameters" default:"."`
-	Format           string `help:"Source file formats (csv/json/jsonl)." short:"f" enum:"csv,json,jsonl" default:"csv"`
-	JSONLMaxLineSize int    `name:"jsonl-max-line-size" help:"Maximum JSONL record size in bytes, excluding the line delimiter." default:"16777216"`
-	Schema           string `required:"" short:"m" predictor:"file" help:"Schema file name."`
-	SkipHeader       bool   `help:"Skip first line of CSV files" default:"false"`
-	Source           string `required:"" short:"s" predictor:"file" help:"Source file name."`
-	URI              string `arg:"" predictor:"file" help:"URI of Parquet file."`
+	FieldDelimiter    string   `name:"field-delimiter" help:"Delimiter separating nested field path components in field and column parameters" default:"."`
+	DateLayout        string   `name:"date-layout" help:"Go time layout of DATE values in CSV, like 01/02/2006, default is 2006-01-02."`
+	DecimalSeparator  string   `name:"decimal-separator" help:"Decimal separator of FLOAT, DOUBLE and DECIMAL values in CSV." default:"."`
+	Format            string   `help:"Source file formats (csv/json/jsonl/avro/arrow)." short:"f" enum:"csv,json,jsonl,avro,arrow" default:"csv"`
+	InferDecimal      bool     `name:"infer-decimal" help:"Infer numbers with decimal point as DECIMAL instead of DOUBLE when inferring schema." default:"false"`
+	InferSampleSize   int      `name:"infer-sample-size" help:"Number of records to sample when inferring schema, 0 means all records, which reads source twice and does not work with standard input." default:"1000"`
+	JSONLMaxLineSize  int      `name:"jsonl-max-line-size" help:"Maximum JSONL record size in bytes, excluding the line delimiter." default:"16777216"`
+	MapByHeader       bool     `name:"map-by-header" help:"Map CSV columns to schema columns by names in header line instead of position, implies --skip-header." default:"false"`
+	MaxErrors         int      `name:"max-errors" help:"Maximum number of bad records to skip before import fails, -1 means no limit." default:"0"`
+	NullValues        []string `name:"null-values" help:"CSV values that are null in OPTIONAL columns, like ',NULL,NA' for empty string, NULL and NA." placeholder:"VALUE,..."`
+	RejectFile        string   `name:"reject-file" predictor:"file" help:"Write bad records to this local file as JSONL, with record number, line number and error."`
+	Schema            string   `short:"m" predictor:"file" help:"Schema file name, schema is inferred from source if not set."`
+	SchemaOutput      string   `name:"schema-output" predictor:"file" help:"Write inferred schema to this file, import is skipped if URI is not set."`
+	SkipHeader        bool     `help:"Skip first line of CSV files" default:"false"`
+	Source            string   `required:"" short:"s" predictor:"file" help:"URI of source file, use - for standard input."`
+	SourceCompression string   `name:"source-compression" help:"Compression of source file (auto/none/gzip/zstd/bzip2), auto detects it from magic bytes or file extension." enum:"auto,none,gzip,zstd,bzip2" default:"auto"`
+	TimestampLayout   string   `name:"timestamp-layout" help:"Go time layout of TIMESTAMP and INT96 values in CSV, like 2006-01-02 15:04:05, default is RFC3339."`
+	URI               string   `arg:"" optional:"" predictor:"file" help:"URI of Parquet file."`
+	pio.SourceOption
 	pio.WriteOption
 }
 
@@ -41,15 +56,253 @@ func (c Cmd) Run(ctx context.Context) error {
 		return err
 	}
 	c.WriteOption.FieldDelimiter = c.FieldDelimiter
+	c.SkipHeader = c.SkipHeader || c.MapByHeader
+	if c.Schema != "" && c.SchemaOutput != "" {
+		return fmt.Errorf("--schema-output cannot be used with --schema")
+	}
+	if c.URI == "" && c.SchemaOutput == "" {
+		return fmt.Errorf("URI of Parquet file is required unless --schema-output is set")
+	}
+	if c.InferSampleSize < 0 {
+		return fmt.Errorf("invalid infer sample size %d, needs to be at least 0", c.InferSampleSize)
+	}
+	if c.MaxErrors < -1 {
+		return fmt.Errorf("invalid max errors %d, needs to be at least -1", c.MaxErrors)
+	}
+
+	if c.Format != "csv" && c.Format != "json" && c.Format != "jsonl" && c.Format != "avro" && c.Format != "arrow" {
+		return fmt.Errorf("[%s] is not a recognized source format", c.Format)
+	}
+	inferSchema := c.Schema == "" && c.Format != "avro" && c.Format != "arrow"
+	if inferSchema && c.InferSampleSize == 0 && c.Source == pio.StdinURI {
+		return fmt.Errorf("--infer-sample-size 0 cannot be used with standard input, use a positive sample size")
+	}
+
+	// schema file is loaded before source is opened so a bad schema does not
+	// consume standard input
+	var csvSchema []string
+	var jsonSchema string
+	var err error
+	if c.Schema != "" {
+		if c.Format == "csv" {
+			csvSchema, err = c.loadCSVSchema()
+		} else {
+			jsonSchema, err = c.loadJSONSchema()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	source, err := c.openInput(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	// inference reads sampled records through a tee, import replays them before
+	// reading the rest of source, so source is read only once, which is required
+	// by standard input and saves another download of remote source. Sampling
+	// all records would hold entire source in memory, source is opened again
+	// instead.
+	var sampled bytes.Buffer
+	input := io.MultiReader(&sampled, source)
+	var avroReader *goavro.OCFReader
+	var avroRoot *avroType
+	var arrowReader *ipc.Reader
+	switch {
+	case c.Format == "avro":
+		// Avro OCF carries its own writer schema, so there is nothing to infer
+		if avroReader, err = goavro.NewOCFReader(source); err != nil {
+			return fmt.Errorf("failed to read Avro source [%s]: %w", c.Source, err)
+		}
+		var avroSchema string
+		if avroRoot, avroSchema, err = avroJSONSchema(avroReader); err != nil {
+			return fmt.Errorf("failed to map Avro schema of [%s]: %w", c.Source, err)
+		}
+		if c.Schema == "" {
+			jsonSchema = avroSchema
+			if err := c.writeSchemaOutput(jsonSchema + "\n"); err != nil || c.URI == "" {
+				return err
+			}
+		}
+	case c.Format == "arrow":
+		// Arrow IPC carries its own schema as well
+		if arrowReader, err = newArrowReader(source); err != nil {
+			return fmt.Errorf("failed to read Arrow source [%s]: %w", c.Source, err)
+		}
+		defer arrowReader.Release()
+		if c.Schema == "" {
+			if jsonSchema, err = arrowJSONSchema(arrowReader.Schema()); err != nil {
+				return fmt.Errorf("failed to map Arrow schema of [%s]: %w", c.Source, err)
+			}
+			if err := c.writeSchemaOutput(jsonSchema + "\n"); err != nil || c.URI == "" {
+				return err
+			}
+		}
+	case inferSchema:
+		sample := io.TeeReader(source, &sampled)
+		if c.InferSampleSize == 0 {
+			sample = source
+		}
+		var schemaText string
+		if c.Format == "csv" {
+			csvSchema, err = c.inferCSVSchema(sample)
+			schemaText = strings.Join(csvSchema, "\n")
+		} else {
+			jsonSchema, err = c.inferJSONSchema(sample)
+			schemaText = jsonSchema
+		}
+		if err != nil {
+			return fmt.Errorf("failed to infer schema: %w", err)
+		}
+		if err := c.writeSchemaOutput(schemaText + "\n"); err != nil || c.URI == "" {
+			return err
+		}
+		if c.InferSampleSize == 0 {
+			_ = source.Close()
+			if source, err = c.openInput(ctx); err != nil {
+				return err
+			}
+			input = source
+		}
+	}
+
+	rejects, err := c.newRejecter()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rejects.close()
+	}()
 	switch c.Format {
 	case "csv":
-		return c.importCSV(ctx)
+		err = c.importCSV(ctx, csvSchema, input, rejects)
 	case "json":
-		return c.importJSON(ctx)
-	case "jsonl":
-		return c.importJSONL(ctx)
+		err = c.importJSON(ctx, jsonSchema, input, rejects)
+	case "avro":
+		err = c.importAvro(ctx, jsonSchema, avroRoot, avroReader, rejects)
+	case "arrow":
+		err = c.importArrow(ctx, jsonSchema, arrowReader, rejects)
+	default:
+		err = c.importJSONL(ctx, jsonSchema, input, rejects)
+	}
+	if err != nil {
+		return err
+	}
+	if err := rejects.close(); err != nil {
+		return err
+	}
+	rejects.summary()
+	return nil
+}
+
+// inputReader is decompressed source, closing it closes source file as well.
+type inputReader struct {
+	io.ReadCloser
+	sourceFile io.Closer
+}
+
+func (r inputReader) Close() error {
+	_ = r.ReadCloser.Close()
+	return r.sourceFile.Close()
+}
+
+// openInput opens --source and decompresses it per --source-compression.
+func (c Cmd) openInput(ctx context.Context) (io.ReadCloser, error) {
+	sourceFile, err := c.openSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	source, err := c.decompress(sourceFile)
+	if err != nil {
+		_ = sourceFile.Close()
+		return nil, err
+	}
+	return inputReader{ReadCloser: source, sourceFile: sourceFile}, nil
+}
+
+// openSource opens --source, error message varies by format for backward
+// compatibility.
+func (c Cmd) openSource(ctx context.Context) (io.ReadCloser, error) {
+	source, err := pio.NewSourceFileReader(ctx, c.Source, c.SourceOption)
+	if err == nil {
+		return source, nil
+	}
+	switch c.Format {
+	case "csv":
+		return nil, fmt.Errorf("failed to open CSV file [%s]: %w", c.Source, err)
+	case "json":
+		return nil, fmt.Errorf("failed to load source from [%s]: %w", c.Source, err)
+	}
+	return nil, fmt.Errorf("failed to open source file [%s]: %w", c.Source, err)
+}
+
+func (c Cmd) loadCSVSchema() ([]string, error) {
+	schemaData, err := os.ReadFile(c.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema from [%s]: %w", c.Schema, err)
+	}
+
+	var schema []string
+	for line := range strings.SplitSeq(string(schemaData), "\n") {
+		line = strings.Trim(line, "\r\n\t ")
+		if line == "" {
+			continue
+		}
+		schema = append(schema, line)
+	}
+	return schema, nil
+}
+
+// loadJSONSchema loads schema file of both JSON and JSONL.
+func (c Cmd) loadJSONSchema() (string, error) {
+	schemaData, err := os.ReadFile(c.Schema)
+	if err != nil {
+		return "", fmt.Errorf("failed to load schema from [%s]: %w", c.Schema, err)
+	}
+
+	var dummy map[string]any
+	if err := json.Unmarshal(schemaData, &dummy); err != nil {
+		return "", fmt.Errorf("content of [%s] is not a valid schema JSON", c.Schema)
+	}
+	return string(schemaData), nil
+}
+
+func (c Cmd) writeSchemaOutput(schema string) error {
+	if c.SchemaOutput == "" {
+		return nil
+	}
+	if err := os.WriteFile(c.SchemaOutput, []byte(schema), 0o644); err != nil {
+		return fmt.Errorf("failed to write schema to [%s]: %w", c.SchemaOutput, err)
+	}
+	return nil
+}
+
+// jsonlScanner returns a line scanner that honors --jsonl-max-line-size, along
+// with the effective maximum line size.
+func (c Cmd) jsonlScanner(r io.Reader) (*bufio.Scanner, int, error) {
+	maxLineSize := c.JSONLMaxLineSize
+	if maxLineSize == 0 {
+		maxLineSize = defaultJSONLMaxLineSize
 	}
-	return fmt.Errorf("[%s] is not a recognized source format", c.Format)
+	if maxLineSize < 0 {
+		return nil, 0, errors.New("JSONL maximum line size must be greater than zero")
+	}
+	if maxLineSize > math.MaxInt-maxJSONLDelimiterSize {
+		return nil, 0, errors.New("JSONL maximum line size is too large")
+	}
+
+	scanner := bufio.NewScanner(r)
+	scannerBufferSize := maxLineSize + maxJSONLDelimiterSize
+	scanner.Buffer(
+		make([]byte, 0, min(bufio.MaxScanTokenSize, scannerBufferSize)),
+		scannerBufferSize,
+	)
+	scanner.Split(bufio.ScanLines)
+	return scanner, maxLineSize, nil
 }
 
 func (c Cmd) closeWriter(pf parquetSource.ParquetFileWriter) error {
@@ -66,30 +319,33 @@ func (c Cmd) closeWriter(pf parquetSource.ParquetFileWriter) error {
 	return err
 }
 
-func (c Cmd) importCSV(ctx context.Context) error {
-	schemaData, err := os.ReadFile(c.Schema)
+func (c Cmd) importCSV(ctx context.Context, schema []string, source io.Reader, rejects *rejecter) error {
+	csvReader := csv.NewReader(source)
+
+	columns, err := parseCSVColumns(schema)
 	if err != nil {
-		return fmt.Errorf("failed to load schema from [%s]: %w", c.Schema, err)
+		return err
 	}
-
-	var schema []string
-	for line := range strings.SplitSeq(string(schemaData), "\n") {
-		line = strings.Trim(line, "\r\n\t ")
-		if line == "" {
-			continue
-		}
-		schema = append(schema, line)
+	parser, err := c.newCSVValueParser()
+	if err != nil {
+		return err
 	}
 
-	csvFile, err := os.Open(c.Source)
-	if err != nil {
-		return fmt.Errorf("failed to open CSV file [%s]: %w", c.Source, err)
+	// header is checked before creating anything at target location
+	var columnIndex []int
+	if c.SkipHeader {
+		header, err := csvReader.Read()
+		if c.MapByHeader {
+			if err != nil {
+				return fmt.Errorf("failed to read CSV header from [%s]: %w", c.Source, err)
+			}
+			if columnIndex, err = c.mapColumns(columns, header); err != nil {
+				return err
+			}
+		}
 	}
-	defer func() {
-		_ = csvFile.Close()
-	}()
-	csvReader := csv.NewReader(csvFile)
 
+	rowGroups := pio.NewRowGroupLimiter(c.RowGroupRows)
 	parquetWriter, err := pio.NewCSVWriter(ctx, c.URI, c.WriteOption, schema)
 	if err != nil {
 		return fmt.Errorf("failed to create CSV writer: %w", err)
@@ -103,23 +359,42 @@ func (c Cmd) importCSV(ctx context.Context) error {
 		}
 	}()
 
-	if c.SkipHeader {
-		_, _ = csvReader.Read()
-	}
-	for {
+	for record := 1; ; record++ {
 		fields, err := csvReader.Read()
 		if errors.Is(err, io.EOF) {
 			break
 		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// reader moves on to next record after a parse error
+			err = fmt.Errorf("failed to read CSV record from [%s]: %w", c.Source, err)
+			if err := rejects.reject(record, parseErr.StartLine, csvLine(fields), err); err != nil {
+				return err
+			}
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("failed to read CSV record from [%s]: %w", c.Source, err)
 		}
-		parquetFields := make([]*string, len(fields))
-		for i := range fields {
-			parquetFields[i] = &fields[i]
+		line, _ := csvReader.FieldPos(0)
+		parquetFields, err := parser.record(columns, columnIndex, fields)
+		if err != nil {
+			err = fmt.Errorf("failed to parse line %d of [%s]: %w", line, c.Source, err)
+			if err := rejects.reject(record, line, csvLine(fields), err); err != nil {
+				return err
+			}
+			continue
 		}
 		if err = parquetWriter.WriteStringWithContext(ctx, parquetFields); err != nil {
-			return fmt.Errorf("failed to write [%v] to parquet: %w", fields, err)
+			err = fmt.Errorf("failed to write [%v] to parquet: %w", fields, err)
+			if err := rejects.reject(record, line, csvLine(fields), err); err != nil {
+				return err
+			}
+			continue
+		}
+		rejects.imported++
+		if err := rowGroups.Written(ctx, &parquetWriter.ParquetWriter); err != nil {
+			return fmt.Errorf("failed to write to [%s]: %w", c.URI, err)
 		}
 	}
 	if err := parquetWriter.WriteStopWithContext(ctx); err != nil {
@@ -134,28 +409,21 @@ func (c Cmd) importCSV(ctx context.Context) error {
 	return nil
 }
 
-func (c Cmd) importJSON(ctx context.Context) error {
-	schemaData, err := os.ReadFile(c.Schema)
-	if err != nil {
-		return fmt.Errorf("failed to load schema from [%s]: %w", c.Schema, err)
+func (c Cmd) importJSON(ctx context.Context, schema string, source io.Reader, rejects *rejecter) error {
+	// records are decoded one by one so memory usage does not grow with source size,
+	// check the opening bracket before creating anything at target location
+	lines := &lineCounter{reader: source}
+	decoder := json.NewDecoder(lines)
+	token, err := decoder.Token()
+	if err == nil && token != json.Delim('[') {
+		err = fmt.Errorf("expect [[] but got [%v]", token)
 	}
-
-	jsonData, err := os.ReadFile(c.Source)
 	if err != nil {
-		return fmt.Errorf("failed to load source from [%s]: %w", c.Source, err)
-	}
-
-	var dummy map[string]any
-	if err := json.Unmarshal(schemaData, &dummy); err != nil {
-		return fmt.Errorf("content of [%s] is not a valid schema JSON", c.Schema)
-	}
-
-	var records []json.RawMessage
-	if err := json.Unmarshal(jsonData, &records); err != nil {
 		return fmt.Errorf("content of [%s] is not a valid JSON array: %w", c.Source, err)
 	}
 
-	parquetWriter, err := pio.NewJSONWriter(ctx, c.URI, c.WriteOption, string(schemaData))
+	rowGroups := pio.NewRowGroupLimiter(c.RowGroupRows)
+	parquetWriter, err := pio.NewJSONWriter(ctx, c.URI, c.WriteOption, schema)
 	if err != nil {
 		return fmt.Errorf("failed to create JSON writer: %w", err)
 	}
@@ -167,12 +435,42 @@ func (c Cmd) importJSON(ctx context.Context) error {
 			_ = c.closeWriter(parquetWriter.PFile)
 		}
 	}()
+	recordWriter := newJSONRecordWriter(parquetWriter)
 
-	for _, record := range records {
-		if err := parquetWriter.WriteWithContext(ctx, string(record)); err != nil {
-			return fmt.Errorf("failed to write to parquet file: %w", err)
+	var dummy map[string]any
+	for index := 1; decoder.More(); index++ {
+		// decoder cannot move on after a syntax error, so it is always fatal
+		var record json.RawMessage
+		if err := decoder.Decode(&record); err != nil {
+			return fmt.Errorf("content of [%s] is not a valid JSON array: %w", c.Source, err)
+		}
+		// raw message has no leading white space, so it starts right before where decoder is
+		line := lines.lineAt(decoder.InputOffset() - int64(len(record)))
+		if err := json.Unmarshal(record, &dummy); err != nil {
+			err = fmt.Errorf("record %d of [%s] is not a JSON object: %s", index, c.Source, string(record))
+			if err := rejects.reject(index, line, string(record), err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := recordWriter.write(ctx, string(record)); err != nil {
+			err = fmt.Errorf("failed to write to parquet file: %w", err)
+			if err := rejects.reject(index, line, string(record), err); err != nil {
+				return err
+			}
+			continue
+		}
+		rejects.imported++
+		if err := rowGroups.Written(ctx, &parquetWriter.ParquetWriter); err != nil {
+			return fmt.Errorf("failed to write to [%s]: %w", c.URI, err)
 		}
 	}
+	if token, err := decoder.Token(); err != nil || token != json.Delim(']') {
+		return fmt.Errorf("content of [%s] is not a valid JSON array: unexpected end of array", c.Source)
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("content of [%s] is not a valid JSON array: unexpected data after array", c.Source)
+	}
 
 	if err := parquetWriter.WriteStopWithContext(ctx); err != nil {
 		return fmt.Errorf("failed to close Parquet writer [%s]: %w", c.URI, err)
@@ -185,44 +483,45 @@ func (c Cmd) importJSON(ctx context.Context) error {
 	return nil
 }
 
-func (c Cmd) importJSONL(ctx context.Context) error {
-	maxLineSize := c.JSONLMaxLineSize
-	if maxLineSize == 0 {
-		maxLineSize = defaultJSONLMaxLineSize
-	}
-	if maxLineSize < 0 {
-		return errors.New("JSONL maximum line size must be greater than zero")
-	}
-	if maxLineSize > math.MaxInt-maxJSONLDelimiterSize {
-		return errors.New("JSONL maximum line size is too large")
-	}
+// lineCounter keeps track of line numbers of what JSON decoder has read, line
+// number of an offset can be looked up once, offsets have to be in ascending
+// order. Only newlines that have been read but not looked up are kept, they
+// are bound by buffer size of JSON decoder.
+type lineCounter struct {
+	reader   io.Reader
+	read     int64
+	line     int
+	newlines []int64
+}
 
-	schemaData, err := os.ReadFile(c.Schema)
-	if err != nil {
-		return fmt.Errorf("failed to load schema from [%s]: %w", c.Schema, err)
+func (l *lineCounter) Read(p []byte) (int, error) {
+	n, err := l.reader.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			l.newlines = append(l.newlines, l.read+int64(i))
+		}
 	}
+	l.read += int64(n)
+	return n, err
+}
 
-	var dummy map[string]any
-	if err := json.Unmarshal(schemaData, &dummy); err != nil {
-		return fmt.Errorf("content of [%s] is not a valid schema JSON", c.Schema)
+// lineAt returns 1-based line number of byte at offset.
+func (l *lineCounter) lineAt(offset int64) int {
+	for len(l.newlines) != 0 && l.newlines[0] < offset {
+		l.line++
+		l.newlines = l.newlines[1:]
 	}
+	return l.line + 1
+}
 
-	jsonlFile, err := os.Open(c.Source)
+func (c Cmd) importJSONL(ctx context.Context, schema string, source io.Reader, rejects *rejecter) error {
+	scanner, maxLineSize, err := c.jsonlScanner(source)
 	if err != nil {
-		return fmt.Errorf("failed to open source file [%s]: %w", c.Source, err)
+		return err
 	}
-	defer func() {
-		_ = jsonlFile.Close()
-	}()
-	scanner := bufio.NewScanner(jsonlFile)
-	scannerBufferSize := maxLineSize + maxJSONLDelimiterSize
-	scanner.Buffer(
-		make([]byte, 0, min(bufio.MaxScanTokenSize, scannerBufferSize)),
-		scannerBufferSize,
-	)
-	scanner.Split(bufio.ScanLines)
 
-	parquetWriter, err := pio.NewJSONWriter(ctx, c.URI, c.WriteOption, string(schemaData))
+	rowGroups := pio.NewRowGroupLimiter(c.RowGroupRows)
+	parquetWriter, err := pio.NewJSONWriter(ctx, c.URI, c.WriteOption, schema)
 	if err != nil {
 		return fmt.Errorf("failed to create JSON writer: %w", err)
 	}
@@ -234,8 +533,10 @@ func (c Cmd) importJSONL(ctx context.Context) error {
 			_ = c.closeWriter(parquetWriter.PFile)
 		}
 	}()
+	recordWriter := newJSONRecordWriter(parquetWriter)
 
-	for scanner.Scan() {
+	var dummy map[string]any
+	for line := 1; scanner.Scan(); line++ {
 		jsonData := scanner.Bytes()
 		if len(jsonData) > maxLineSize {
 			return fmt.Errorf(
@@ -245,11 +546,23 @@ func (c Cmd) importJSONL(ctx context.Context) error {
 			)
 		}
 		if err := json.Unmarshal(jsonData, &dummy); err != nil {
-			return fmt.Errorf("invalid JSON string: %s", string(jsonData))
+			err = fmt.Errorf("invalid JSON string: %s", string(jsonData))
+			if err := rejects.reject(line, line, string(jsonData), err); err != nil {
+				return err
+			}
+			continue
 		}
 
-		if err := parquetWriter.WriteWithContext(ctx, string(jsonData)); err != nil {
-			return fmt.Errorf("failed to write to parquet file: %w", err)
+		if err := recordWriter.write(ctx, string(jsonData)); err != nil {
+			err = fmt.Errorf("failed to write to parquet file: %w", err)
+			if err := rejects.reject(line, line, string(jsonData), err); err != nil {
+				return err
+			}
+			continue
+		}
+		rejects.imported++
+		if err := rowGroups.Written(ctx, &parquetWriter.ParquetWriter); err != nil {
+			return fmt.Errorf("failed to write to [%s]: %w", c.URI, err)
 		}
 	}
 	if err := scanner.Err(); err != nil {
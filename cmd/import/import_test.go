@@ -389,6 +389,52 @@ func TestCmdJSONLMaxLineSizeBoundary(t *testing.T) {
 	}
 }
 
+func TestCmdJSONStreaming(t *testing.T) {
+	testCases := map[string]struct {
+		source   string
+		wantRows int64
+		wantErr  string
+	}{
+		"empty-array":       {source: "[]", wantRows: 0},
+		"white-spaces":      {source: " \n[ {\"Value\": \"a\"} ,\n{\"Value\": \"b\"}\n]\n", wantRows: 2},
+		"many-records":      {source: "[" + strings.TrimSuffix(strings.Repeat(`{"Value":"abc"},`, 10000), ",") + "]", wantRows: 10000},
+		"empty-source":      {source: "", wantErr: "is not a valid JSON array: EOF"},
+		"object":            {source: `{"Value": "a"}`, wantErr: "is not a valid JSON array: expect [[] but got [{]"},
+		"missing-comma":     {source: `[{"Value": "a"} {"Value": "b"}]`, wantErr: "is not a valid JSON array: invalid character '{' after array element"},
+		"trailing-comma":    {source: `[{"Value": "a"},]`, wantErr: "is not a valid JSON array: invalid character ',' looking for beginning of value"},
+		"truncated":         {source: `[{"Value": "a"}`, wantErr: "is not a valid JSON array: unexpected end of JSON input"},
+		"truncated-element": {source: `[{"Value": "a"}, {"Val`, wantErr: "is not a valid JSON array: unexpected EOF"},
+		"trailing-data":     {source: `[{"Value": "a"}] []`, wantErr: "is not a valid JSON array: unexpected data after array"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			sourcePath := filepath.Join(tempDir, "source.json")
+			schemaPath := filepath.Join(tempDir, "schema.json")
+			parquetPath := filepath.Join(tempDir, "output.parquet")
+			require.NoError(t, os.WriteFile(sourcePath, []byte(tc.source), 0o600))
+			require.NoError(t, os.WriteFile(schemaPath, []byte(jsonlLineSizeTestSchema), 0o600))
+
+			err := (Cmd{
+				Source: sourcePath,
+				Format: "json",
+				Schema: schemaPath,
+				URI:    parquetPath,
+			}).Run(context.Background())
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			reader, err := pio.NewParquetFileReader(context.Background(), parquetPath, pio.ReadOption{})
+			require.NoError(t, err)
+			require.Equal(t, tc.wantRows, reader.GetNumRows())
+		})
+	}
+}
+
 func TestCmdDefaultDataPageVersionWithDictionaryEncoding(t *testing.T) {
 	tempDir := t.TempDir()
 	testdataDir := filepath.Join("..", "..", "testdata")
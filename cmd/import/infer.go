@@ -0,0 +1,533 @@
+package importcmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// maxDecimalPrecision is the largest precision that a DECIMAL column can have,
+// numbers need more digits are inferred as DOUBLE.
+const maxDecimalPrecision = 38
+
+// fieldKind is type of a field inferred from sampled values, number kinds are
+// ordered from narrow to wide.
+type fieldKind int
+
+const (
+	kindNull fieldKind = iota // only nulls seen so far
+	kindBoolean
+	kindInt64
+	kindDecimal
+	kindDouble
+	kindDate
+	kindTimestamp
+	kindString
+	kindStruct
+	kindList
+)
+
+var kindName = map[fieldKind]string{
+	kindNull:      "null",
+	kindBoolean:   "boolean",
+	kindInt64:     "integer",
+	kindDecimal:   "decimal",
+	kindDouble:    "double",
+	kindDate:      "date",
+	kindTimestamp: "timestamp",
+	kindString:    "string",
+	kindStruct:    "object",
+	kindList:      "array",
+}
+
+func (k fieldKind) isNumber() bool {
+	return k == kindInt64 || k == kindDecimal || k == kindDouble
+}
+
+func (k fieldKind) isText() bool {
+	return k == kindDate || k == kindTimestamp || k == kindString
+}
+
+// inferredField is schema of a field inferred from sampled values.
+type inferredField struct {
+	name      string
+	kind      fieldKind
+	optional  bool
+	intDigits int              // max number of digits before decimal point
+	scale     int              // max number of digits after decimal point
+	fraction  int              // max number of digits of fractional seconds
+	objects   int              // number of objects merged into a struct
+	seen      int              // number of objects that have this field
+	fields    []*inferredField // fields of struct
+	element   *inferredField   // element of list, nil if all lists are empty
+}
+
+// schemaInferrer builds inferredField from values, textual is for CSV whose
+// values are all strings, so conflicting types fall back to string, and csv
+// tells null values and formats of CSV values.
+type schemaInferrer struct {
+	decimal bool
+	textual bool
+	csv     csvValueParser
+}
+
+func (i schemaInferrer) textField(name, value string) *inferredField {
+	field := &inferredField{name: name, kind: kindString}
+	switch {
+	case i.csv.isNull(value):
+		field.kind = kindNull
+		field.optional = true
+	case value == "true" || value == "false":
+		field.kind = kindBoolean
+	case i.numberField(field, i.csv.number(value)):
+	case i.csv.isDate(value):
+		field.kind = kindDate
+	default:
+		if fraction, ok := i.csv.timestampFraction(value); ok {
+			field.kind = kindTimestamp
+			field.fraction = fraction
+		}
+	}
+	return field
+}
+
+// numberField sets field kind if value is a decimal number literal, numbers
+// with leading zeros are more likely to be codes than numbers so they are not.
+func (i schemaInferrer) numberField(field *inferredField, value string) bool {
+	digits := strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+	if digits == "" || strings.Trim(digits, "0123456789.eE+-") != "" {
+		return false
+	}
+	if len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9' {
+		return false
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		field.kind = kindInt64
+		field.intDigits = len(digits)
+		return true
+	}
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return false
+	}
+	// numbers with exponent are double only
+	field.kind = kindDouble
+	if !i.decimal || strings.ContainsAny(digits, "eE") {
+		return true
+	}
+	integer, fraction, _ := strings.Cut(digits, ".")
+	if len(strings.TrimLeft(integer, "0"))+len(fraction) <= maxDecimalPrecision {
+		field.kind = kindDecimal
+		field.intDigits = len(strings.TrimLeft(integer, "0"))
+		field.scale = len(fraction)
+	}
+	return true
+}
+
+func (i schemaInferrer) jsonField(name string, value any) (*inferredField, error) {
+	field := &inferredField{name: name}
+	switch v := value.(type) {
+	case nil:
+		field.optional = true
+	case bool:
+		field.kind = kindBoolean
+	case json.Number:
+		if !i.numberField(field, v.String()) {
+			return nil, fmt.Errorf("field [%s] has invalid number [%s]", name, v)
+		}
+	case string:
+		field.kind = kindString
+		if isDate(v) {
+			field.kind = kindDate
+		} else if fraction, ok := timestampFraction(v); ok {
+			field.kind = kindTimestamp
+			field.fraction = fraction
+		}
+	case []any:
+		field.kind = kindList
+		for _, item := range v {
+			element, err := i.jsonField("Element", item)
+			if err != nil {
+				return nil, err
+			}
+			if field.element == nil {
+				field.element = element
+			} else if err := i.merge(field.element, element); err != nil {
+				return nil, fmt.Errorf("field [%s]: %w", name, err)
+			}
+		}
+	case map[string]any:
+		field.kind = kindStruct
+		field.objects = 1
+		// encoding/json does not keep order of keys, sort them to have a stable schema
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			child, err := i.jsonField(key, v[key])
+			if err != nil {
+				return nil, err
+			}
+			child.seen = 1
+			field.fields = append(field.fields, child)
+		}
+	default:
+		return nil, fmt.Errorf("field [%s] has unsupported value [%v]", name, value)
+	}
+	return field, nil
+}
+
+// merge widens f so values of other fit in it as well.
+func (i schemaInferrer) merge(f, other *inferredField) error {
+	f.optional = f.optional || other.optional
+	f.seen += other.seen
+	if other.kind == kindNull {
+		return nil
+	}
+	if f.kind == kindNull {
+		optional, seen := f.optional, f.seen
+		*f = *other
+		f.optional, f.seen = optional, seen
+		return nil
+	}
+
+	f.intDigits = max(f.intDigits, other.intDigits)
+	f.scale = max(f.scale, other.scale)
+	f.fraction = max(f.fraction, other.fraction)
+	switch {
+	case f.kind == other.kind && f.kind == kindStruct:
+		f.objects += other.objects
+		for _, otherChild := range other.fields {
+			child := f.field(otherChild.name)
+			if child == nil {
+				f.fields = append(f.fields, otherChild)
+				continue
+			}
+			if err := i.merge(child, otherChild); err != nil {
+				return err
+			}
+		}
+	case f.kind == other.kind && f.kind == kindList:
+		if f.element == nil {
+			f.element = other.element
+		} else if other.element != nil {
+			if err := i.merge(f.element, other.element); err != nil {
+				return fmt.Errorf("field [%s]: %w", f.name, err)
+			}
+		}
+	case f.kind == other.kind:
+	case f.kind.isNumber() && other.kind.isNumber():
+		f.kind = max(f.kind, other.kind)
+	case f.kind.isText() && other.kind.isText():
+		f.kind = kindString
+	case i.textual && f.kind < kindStruct && other.kind < kindStruct:
+		f.kind = kindString
+	default:
+		return fmt.Errorf("field [%s] has both %s and %s values", f.name, kindName[f.kind], kindName[other.kind])
+	}
+	return nil
+}
+
+func (f *inferredField) field(name string) *inferredField {
+	for _, child := range f.fields {
+		if child.name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// mapValue returns value type if struct f looks like a map: it has been seen
+// more than once with different keys, and all values are of the same type.
+func (i schemaInferrer) mapValue(f *inferredField) (*inferredField, bool) {
+	if len(f.fields) == 0 {
+		// there is no way to have a group without any field
+		return &inferredField{name: "Value", optional: true}, true
+	}
+	varyingKeys := false
+	for _, child := range f.fields {
+		varyingKeys = varyingKeys || child.seen < f.objects
+	}
+	if f.objects < 2 || !varyingKeys {
+		return nil, false
+	}
+
+	value := &inferredField{name: "Value"}
+	for _, child := range f.fields {
+		if err := (schemaInferrer{decimal: i.decimal}).merge(value, cloneField(child)); err != nil {
+			return nil, false
+		}
+	}
+	value.name = "Value"
+	return value, true
+}
+
+func cloneField(f *inferredField) *inferredField {
+	clone := *f
+	clone.fields = make([]*inferredField, len(f.fields))
+	for index, child := range f.fields {
+		clone.fields[index] = cloneField(child)
+	}
+	if f.element != nil {
+		clone.element = cloneField(f.element)
+	}
+	return &clone
+}
+
+func (i schemaInferrer) jsonSchema(f *inferredField, optional bool) (pschema.JSONSchema, error) {
+	if strings.ContainsAny(f.name, ",=") || strings.TrimSpace(f.name) != f.name || f.name == "" {
+		return pschema.JSONSchema{}, fmt.Errorf("field name [%s] cannot be used in schema", f.name)
+	}
+
+	tags := []string{"name=" + f.name}
+	var fields []pschema.JSONSchema
+	switch f.kind {
+	case kindStruct:
+		if value, isMap := i.mapValue(f); isMap {
+			tags = append(tags, "type=MAP")
+			valueSchema, err := i.jsonSchema(value, value.optional)
+			if err != nil {
+				return pschema.JSONSchema{}, err
+			}
+			fields = []pschema.JSONSchema{{Tag: "name=Key, type=BYTE_ARRAY, convertedtype=UTF8"}, valueSchema}
+			break
+		}
+		for _, child := range f.fields {
+			childSchema, err := i.jsonSchema(child, child.optional || child.seen < f.objects)
+			if err != nil {
+				return pschema.JSONSchema{}, err
+			}
+			fields = append(fields, childSchema)
+		}
+	case kindList:
+		tags = append(tags, "type=LIST")
+		element := f.element
+		if element == nil {
+			element = &inferredField{optional: true}
+		}
+		element.name = "Element"
+		elementSchema, err := i.jsonSchema(element, element.optional)
+		if err != nil {
+			return pschema.JSONSchema{}, err
+		}
+		fields = []pschema.JSONSchema{elementSchema}
+	default:
+		tags = append(tags, f.scalarTags()...)
+	}
+	if optional {
+		tags = append(tags, "repetitiontype=OPTIONAL")
+	}
+	return pschema.JSONSchema{Tag: strings.Join(tags, ", "), Fields: fields}, nil
+}
+
+func (f *inferredField) scalarTags() []string {
+	switch f.kind {
+	case kindBoolean:
+		return []string{"type=BOOLEAN"}
+	case kindInt64:
+		return []string{"type=INT64"}
+	case kindDecimal:
+		precision := max(f.intDigits+f.scale, 1)
+		if precision > maxDecimalPrecision {
+			return []string{"type=DOUBLE"}
+		}
+		physicalType := "BYTE_ARRAY"
+		if precision <= 9 {
+			physicalType = "INT32"
+		} else if precision <= 18 {
+			physicalType = "INT64"
+		}
+		return []string{
+			"type=" + physicalType,
+			"convertedtype=DECIMAL",
+			"scale=" + strconv.Itoa(f.scale),
+			"precision=" + strconv.Itoa(precision),
+		}
+	case kindDouble:
+		return []string{"type=DOUBLE"}
+	case kindDate:
+		return []string{"type=INT32", "convertedtype=DATE"}
+	case kindTimestamp:
+		unit := "NANOS"
+		if f.fraction <= 3 {
+			unit = "MILLIS"
+		} else if f.fraction <= 6 {
+			unit = "MICROS"
+		}
+		return []string{"type=INT64", "logicaltype=TIMESTAMP", "logicaltype.isadjustedtoutc=true", "logicaltype.unit=" + unit}
+	}
+	// strings, and fields with null values only
+	return []string{"type=BYTE_ARRAY", "convertedtype=UTF8"}
+}
+
+func isDate(value string) bool {
+	if len(value) != len(time.DateOnly) {
+		return false
+	}
+	_, err := time.Parse(time.DateOnly, value)
+	return err == nil
+}
+
+// timestampFraction returns number of digits of fractional seconds if value is
+// a RFC3339 timestamp.
+func timestampFraction(value string) (int, bool) {
+	if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+		return 0, false
+	}
+	_, rest, found := strings.Cut(value, ".")
+	if !found {
+		return 0, true
+	}
+	return len(rest) - len(strings.TrimLeft(rest, "0123456789")), true
+}
+
+// inferCSVSchema samples CSV records and returns schema in the same format as
+// CSV schema file, column names come from header if --skip-header is set.
+func (c Cmd) inferCSVSchema(source io.Reader) ([]string, error) {
+	parser, err := c.newCSVValueParser()
+	if err != nil {
+		return nil, err
+	}
+	inferrer := schemaInferrer{decimal: c.InferDecimal, textual: true, csv: parser}
+	csvReader := csv.NewReader(source)
+	var header []string
+	if c.SkipHeader {
+		if header, err = csvReader.Read(); err != nil {
+			return nil, fmt.Errorf("failed to read CSV header from [%s]: %w", c.Source, err)
+		}
+	}
+
+	var columns []*inferredField
+	for count := 0; c.InferSampleSize == 0 || count < c.InferSampleSize; count++ {
+		fields, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV record from [%s]: %w", c.Source, err)
+		}
+		for index, value := range fields {
+			name := fmt.Sprintf("Column%d", index+1)
+			if header != nil {
+				name = header[index]
+			}
+			field := inferrer.textField(name, value)
+			if index == len(columns) {
+				columns = append(columns, field)
+				continue
+			}
+			if err := inferrer.merge(columns[index], field); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("there is no record in [%s] to infer schema from", c.Source)
+	}
+
+	schema := make([]string, len(columns))
+	for index, column := range columns {
+		columnSchema, err := inferrer.jsonSchema(column, column.optional)
+		if err != nil {
+			return nil, err
+		}
+		schema[index] = columnSchema.Tag
+	}
+	return schema, nil
+}
+
+// inferJSONSchema samples JSON or JSONL records and returns schema in the same
+// format as JSON schema file.
+func (c Cmd) inferJSONSchema(source io.Reader) (string, error) {
+	var next func() ([]byte, error)
+	if c.Format == "jsonl" {
+		scanner, maxLineSize, err := c.jsonlScanner(source)
+		if err != nil {
+			return "", err
+		}
+		next = func() ([]byte, error) {
+			if scanner.Scan() {
+				return scanner.Bytes(), nil
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, fmt.Errorf("failed to read JSONL source file [%s] with maximum line size %d bytes: %w", c.Source, maxLineSize, err)
+			}
+			return nil, io.EOF
+		}
+	} else {
+		decoder := json.NewDecoder(source)
+		token, err := decoder.Token()
+		if err == nil && token != json.Delim('[') {
+			err = fmt.Errorf("expect [[] but got [%v]", token)
+		}
+		if err != nil {
+			return "", fmt.Errorf("content of [%s] is not a valid JSON array: %w", c.Source, err)
+		}
+		next = func() ([]byte, error) {
+			if !decoder.More() {
+				return nil, io.EOF
+			}
+			var record json.RawMessage
+			if err := decoder.Decode(&record); err != nil {
+				return nil, fmt.Errorf("content of [%s] is not a valid JSON array: %w", c.Source, err)
+			}
+			return record, nil
+		}
+	}
+
+	inferrer := schemaInferrer{decimal: c.InferDecimal}
+	root := &inferredField{name: "parquet_go_root"}
+	for count := 0; c.InferSampleSize == 0 || count < c.InferSampleSize; count++ {
+		record, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(record))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return "", fmt.Errorf("invalid JSON string: %s", string(record))
+		}
+		if _, ok := value.(map[string]any); !ok {
+			return "", fmt.Errorf("record %d of [%s] is not a JSON object", count+1, c.Source)
+		}
+		field, err := inferrer.jsonField(root.name, value)
+		if err != nil {
+			return "", err
+		}
+		if err := inferrer.merge(root, field); err != nil {
+			return "", err
+		}
+	}
+	if root.objects == 0 {
+		return "", fmt.Errorf("there is no record in [%s] to infer schema from", c.Source)
+	}
+	if len(root.fields) == 0 {
+		return "", fmt.Errorf("there is no field in records of [%s] to infer schema from", c.Source)
+	}
+
+	schema := pschema.JSONSchema{Tag: "name=" + root.name}
+	for _, child := range root.fields {
+		childSchema, err := inferrer.jsonSchema(child, child.optional || child.seen < root.objects)
+		if err != nil {
+			return "", err
+		}
+		schema.Fields = append(schema.Fields, childSchema)
+	}
+	buf, _ := json.MarshalIndent(schema, "", "  ")
+	return string(buf), nil
+}
@@ -0,0 +1,428 @@
+package importcmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func TestTextField(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		decimal  bool
+		expected string
+	}{
+		"boolean":         {value: "true", expected: "type=BOOLEAN"},
+		"boolean-case":    {value: "TRUE", expected: "type=BYTE_ARRAY, convertedtype=UTF8"},
+		"int":             {value: "-123", expected: "type=INT64"},
+		"int-plus":        {value: "+123", expected: "type=INT64"},
+		"int-overflow":    {value: "12345678901234567890", expected: "type=DOUBLE"},
+		"zero":            {value: "0", expected: "type=INT64"},
+		"leading-zero":    {value: "00123", expected: "type=BYTE_ARRAY, convertedtype=UTF8"},
+		"zero-fraction":   {value: "0.5", expected: "type=DOUBLE"},
+		"double":          {value: "1.25", expected: "type=DOUBLE"},
+		"exponent":        {value: "1e5", expected: "type=DOUBLE"},
+		"decimal":         {value: "-1.25", decimal: true, expected: "type=INT32, convertedtype=DECIMAL, scale=2, precision=3"},
+		"decimal-int64":   {value: "12345678.1234", decimal: true, expected: "type=INT64, convertedtype=DECIMAL, scale=4, precision=12"},
+		"decimal-bytes":   {value: "1234567890.1234567890", decimal: true, expected: "type=BYTE_ARRAY, convertedtype=DECIMAL, scale=10, precision=20"},
+		"decimal-exp":     {value: "1.5e3", decimal: true, expected: "type=DOUBLE"},
+		"decimal-too-big": {value: "1234567890123456789012345678901234567890.5", decimal: true, expected: "type=DOUBLE"},
+		"not-number":      {value: "1-2", expected: "type=BYTE_ARRAY, convertedtype=UTF8"},
+		"infinity":        {value: "Inf", expected: "type=BYTE_ARRAY, convertedtype=UTF8"},
+		"empty":           {value: "", expected: "type=BYTE_ARRAY, convertedtype=UTF8"},
+		"date":            {value: "2024-02-29", expected: "type=INT32, convertedtype=DATE"},
+		"bad-date":        {value: "2023-02-29", expected: "type=BYTE_ARRAY, convertedtype=UTF8"},
+		"timestamp":       {value: "2024-01-02T03:04:05Z", expected: "type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MILLIS"},
+		"timestamp-micro": {value: "2024-01-02T03:04:05.123456+08:00", expected: "type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"},
+		"timestamp-nano":  {value: "2024-01-02T03:04:05.1234567Z", expected: "type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=NANOS"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			field := schemaInferrer{decimal: tc.decimal, textual: true}.textField("Col", tc.value)
+			require.Equal(t, tc.expected, strings.Join(field.scalarTags(), ", "))
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	testCases := map[string]struct {
+		values   []string
+		textual  bool
+		decimal  bool
+		expected string
+		errMsg   string
+	}{
+		"int-double":       {values: []string{`1`, `2.5`}, expected: "name=f, type=DOUBLE"},
+		"int-decimal":      {values: []string{`12345`, `1.5`}, decimal: true, expected: "name=f, type=INT32, convertedtype=DECIMAL, scale=1, precision=6"},
+		"decimal-scale":    {values: []string{`1.5`, `2.25`}, decimal: true, expected: "name=f, type=INT32, convertedtype=DECIMAL, scale=2, precision=3"},
+		"date-timestamp":   {values: []string{`"2024-01-01"`, `"2024-01-01T00:00:00Z"`}, expected: "name=f, type=BYTE_ARRAY, convertedtype=UTF8"},
+		"timestamp-units":  {values: []string{`"2024-01-01T00:00:00.1Z"`, `"2024-01-01T00:00:00.1234Z"`}, expected: "name=f, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"},
+		"null-first":       {values: []string{`null`, `true`}, expected: "name=f, type=BOOLEAN, repetitiontype=OPTIONAL"},
+		"null-last":        {values: []string{`1`, `null`}, expected: "name=f, type=INT64, repetitiontype=OPTIONAL"},
+		"null-only":        {values: []string{`null`}, expected: "name=f, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+		"bool-string":      {values: []string{`true`, `"a"`}, errMsg: "field [f] has both boolean and string values"},
+		"bool-string-text": {values: []string{`true`, `"a"`}, textual: true, expected: "name=f, type=BYTE_ARRAY, convertedtype=UTF8"},
+		"object-array":     {values: []string{`{"a":1}`, `[1]`}, textual: true, errMsg: "field [f] has both object and array values"},
+		"list-element":     {values: []string{`[]`, `[1, 2.5]`}, expected: `name=f, type=LIST|name=Element, type=DOUBLE`},
+		"list-conflict":    {values: []string{`[1]`, `["a"]`}, errMsg: "field [f]: field [Element] has both integer and string values"},
+		"empty-list":       {values: []string{`[]`}, expected: `name=f, type=LIST|name=Element, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL`},
+		"struct-fields":    {values: []string{`{"a":1}`, `{"a":1,"b":"x"}`}, expected: `name=f|name=a, type=INT64|name=b, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL`},
+		"map":              {values: []string{`{"a":1}`, `{"b":2.5}`}, expected: `name=f, type=MAP|name=Key, type=BYTE_ARRAY, convertedtype=UTF8|name=Value, type=DOUBLE`},
+		"not-map":          {values: []string{`{"a":1}`, `{"b":"x"}`}, expected: `name=f|name=a, type=INT64, repetitiontype=OPTIONAL|name=b, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL`},
+		"empty-object":     {values: []string{`{}`}, expected: `name=f, type=MAP|name=Key, type=BYTE_ARRAY, convertedtype=UTF8|name=Value, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL`},
+	}
+
+	// flatten schema tags in depth-first order so they can be compared as a string
+	var flatten func(schema pschema.JSONSchema) []string
+	flatten = func(schema pschema.JSONSchema) []string {
+		tags := []string{schema.Tag}
+		for _, field := range schema.Fields {
+			tags = append(tags, flatten(field)...)
+		}
+		return tags
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			inferrer := schemaInferrer{decimal: tc.decimal, textual: tc.textual}
+			merged := &inferredField{name: "f"}
+			var err error
+			for _, value := range tc.values {
+				decoder := json.NewDecoder(strings.NewReader(value))
+				decoder.UseNumber()
+				var v any
+				require.NoError(t, decoder.Decode(&v))
+				field, fieldErr := inferrer.jsonField("f", v)
+				require.NoError(t, fieldErr)
+				if err = inferrer.merge(merged, field); err != nil {
+					break
+				}
+			}
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			schema, err := inferrer.jsonSchema(merged, merged.optional)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, strings.Join(flatten(schema), "|"))
+		})
+	}
+}
+
+func TestInferCSVSchema(t *testing.T) {
+	tempDir := t.TempDir()
+	writeSource := func(name, content string) string {
+		fileName := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(fileName, []byte(content), 0o644))
+		return fileName
+	}
+
+	testCases := map[string]struct {
+		cmd      Cmd
+		expected []string
+		errMsg   string
+	}{
+		"no-header": {
+			cmd: Cmd{Source: writeSource("no-header.csv", "1,a,true\n2.5,b,false\n")},
+			expected: []string{
+				"name=Column1, type=DOUBLE",
+				"name=Column2, type=BYTE_ARRAY, convertedtype=UTF8",
+				"name=Column3, type=BOOLEAN",
+			},
+		},
+		"header": {
+			cmd: Cmd{Source: writeSource("header.csv", "id,price,day\n1,1.50,2024-01-01\n2,20.25,2024-01-02\n"), SkipHeader: true, InferDecimal: true},
+			expected: []string{
+				"name=id, type=INT64",
+				"name=price, type=INT32, convertedtype=DECIMAL, scale=2, precision=4",
+				"name=day, type=INT32, convertedtype=DATE",
+			},
+		},
+		"sample-size": {
+			cmd:      Cmd{Source: writeSource("sample-size.csv", "1\n2\nabc\n"), InferSampleSize: 2},
+			expected: []string{"name=Column1, type=INT64"},
+		},
+		"all-records": {
+			cmd:      Cmd{Source: writeSource("all-records.csv", "1\n2\nabc\n")},
+			expected: []string{"name=Column1, type=BYTE_ARRAY, convertedtype=UTF8"},
+		},
+		"testdata": {
+			cmd: Cmd{Source: "../../testdata/csv-with-header.source", SkipHeader: true},
+		},
+		"empty-source": {
+			cmd:    Cmd{Source: writeSource("empty.csv", "")},
+			errMsg: "there is no record in",
+		},
+		"header-only": {
+			cmd:    Cmd{Source: writeSource("header-only.csv", "a,b\n"), SkipHeader: true},
+			errMsg: "there is no record in",
+		},
+		"bad-header": {
+			cmd:    Cmd{Source: writeSource("bad-header.csv", ""), SkipHeader: true},
+			errMsg: "failed to read CSV header from",
+		},
+		"bad-column-name": {
+			cmd:    Cmd{Source: writeSource("bad-column-name.csv", "a=b\n1\n"), SkipHeader: true},
+			errMsg: "field name [a=b] cannot be used in schema",
+		},
+		"malformed": {
+			cmd:    Cmd{Source: "../../testdata/csv-malformed.source"},
+			errMsg: "failed to read CSV record from",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			source, err := os.Open(tc.cmd.Source)
+			require.NoError(t, err)
+			defer func() {
+				_ = source.Close()
+			}()
+
+			schema, err := tc.cmd.inferCSVSchema(source)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			if tc.expected != nil {
+				require.Equal(t, tc.expected, schema)
+				return
+			}
+			require.Len(t, schema, 39)
+			require.Equal(t, "name=Bool, type=BOOLEAN", schema[0])
+			require.Equal(t, "name=Date, type=INT32, convertedtype=DATE", schema[2])
+			require.Equal(t, "name=Int96, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=NANOS", schema[19])
+		})
+	}
+}
+
+func TestInferJSONSchema(t *testing.T) {
+	tempDir := t.TempDir()
+	writeSource := func(name, content string) string {
+		fileName := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(fileName, []byte(content), 0o644))
+		return fileName
+	}
+
+	testCases := map[string]struct {
+		cmd      Cmd
+		expected string
+		errMsg   string
+	}{
+		"json": {
+			cmd: Cmd{Format: "json", Source: writeSource("good.json", `[{"b":1,"a":"x"},{"a":"y","c":[true]}]`)},
+			expected: `{
+  "Tag": "name=parquet_go_root",
+  "Fields": [
+    {
+      "Tag": "name=a, type=BYTE_ARRAY, convertedtype=UTF8"
+    },
+    {
+      "Tag": "name=b, type=INT64, repetitiontype=OPTIONAL"
+    },
+    {
+      "Tag": "name=c, type=LIST, repetitiontype=OPTIONAL",
+      "Fields": [
+        {
+          "Tag": "name=Element, type=BOOLEAN"
+        }
+      ]
+    }
+  ]
+}`,
+		},
+		"jsonl-sample-size": {
+			cmd: Cmd{Format: "jsonl", Source: writeSource("good.jsonl", `{"a":1}`+"\n"+`{"a":"x"}`+"\n"), InferSampleSize: 1},
+			expected: `{
+  "Tag": "name=parquet_go_root",
+  "Fields": [
+    {
+      "Tag": "name=a, type=INT64"
+    }
+  ]
+}`,
+		},
+		"testdata-json":    {cmd: Cmd{Format: "json", Source: "../../testdata/json.source"}},
+		"testdata-jsonl":   {cmd: Cmd{Format: "jsonl", Source: "../../testdata/jsonl.source"}},
+		"not-array":        {cmd: Cmd{Format: "json", Source: writeSource("not-array.json", `{"a":1}`)}, errMsg: "is not a valid JSON array"},
+		"bad-array":        {cmd: Cmd{Format: "json", Source: writeSource("bad-array.json", `[{"a":1},]`)}, errMsg: "is not a valid JSON array"},
+		"empty-array":      {cmd: Cmd{Format: "json", Source: writeSource("empty.json", `[]`)}, errMsg: "there is no record in"},
+		"no-field":         {cmd: Cmd{Format: "json", Source: writeSource("no-field.json", `[{}]`)}, errMsg: "there is no field in records of"},
+		"not-object":       {cmd: Cmd{Format: "jsonl", Source: writeSource("not-object.jsonl", "{\"a\":1}\n[1]\n")}, errMsg: "record 2 of"},
+		"bad-jsonl":        {cmd: Cmd{Format: "jsonl", Source: writeSource("bad.jsonl", "{\"a\":1}\n{\"a\":\n")}, errMsg: "invalid JSON string:"},
+		"conflict":         {cmd: Cmd{Format: "jsonl", Source: writeSource("conflict.jsonl", "{\"a\":1}\n{\"a\":\"x\"}\n")}, errMsg: "field [a] has both integer and string values"},
+		"bad-field-name":   {cmd: Cmd{Format: "jsonl", Source: writeSource("bad-name.jsonl", "{\"a,b\":1}\n")}, errMsg: "field name [a,b] cannot be used in schema"},
+		"line-too-long":    {cmd: Cmd{Format: "jsonl", Source: writeSource("long.jsonl", "{\"a\":12345}\n"), JSONLMaxLineSize: 4}, errMsg: "with maximum line size 4 bytes"},
+		"invalid-max-line": {cmd: Cmd{Format: "jsonl", Source: writeSource("max-line.jsonl", "{\"a\":1}\n"), JSONLMaxLineSize: -1}, errMsg: "JSONL maximum line size must be greater than zero"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			source, err := os.Open(tc.cmd.Source)
+			require.NoError(t, err)
+			defer func() {
+				_ = source.Close()
+			}()
+
+			schema, err := tc.cmd.inferJSONSchema(source)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			if tc.expected != "" {
+				require.Equal(t, tc.expected, schema)
+				return
+			}
+			var dummy map[string]any
+			require.NoError(t, json.Unmarshal([]byte(schema), &dummy))
+		})
+	}
+}
+
+func TestCmdSchemaOutput(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testCases := map[string]struct {
+		cmd      Cmd
+		expected string
+		errMsg   string
+	}{
+		"csv": {
+			cmd:      Cmd{Format: "csv", Source: "../../testdata/csv-with-header.source", SkipHeader: true, InferSampleSize: 1},
+			expected: "name=Bool, type=BOOLEAN\n",
+		},
+		"jsonl": {
+			cmd:      Cmd{Format: "jsonl", Source: "../../testdata/jsonl.source"},
+			expected: "\"Tag\": \"name=parquet_go_root\"",
+		},
+		"with-schema": {
+			cmd:    Cmd{Format: "csv", Source: "../../testdata/csv.source", Schema: "../../testdata/csv.schema"},
+			errMsg: "--schema-output cannot be used with --schema",
+		},
+		"sample-size": {
+			cmd:    Cmd{Format: "csv", Source: "../../testdata/csv.source", InferSampleSize: -1},
+			errMsg: "invalid infer sample size -1",
+		},
+		"bad-output": {
+			cmd:    Cmd{Format: "csv", Source: "../../testdata/csv.source", SchemaOutput: filepath.Join(tempDir, "does", "not", "exist")},
+			errMsg: "failed to write schema to",
+		},
+		"infer-failure": {
+			cmd:    Cmd{Format: "json", Source: "../../testdata/jsonl.source"},
+			errMsg: "failed to infer schema:",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cmd := tc.cmd
+			if cmd.SchemaOutput == "" {
+				cmd.SchemaOutput = filepath.Join(tempDir, name+".schema")
+			}
+			err := cmd.Run(context.Background())
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			schema, err := os.ReadFile(cmd.SchemaOutput)
+			require.NoError(t, err)
+			require.Contains(t, string(schema), tc.expected)
+		})
+	}
+
+	t.Run("no-uri", func(t *testing.T) {
+		cmd := Cmd{Format: "csv", Source: "../../testdata/csv.source"}
+		err := cmd.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "URI of Parquet file is required unless --schema-output is set")
+	})
+}
+
+func TestCmdStdin(t *testing.T) {
+	tempDir := t.TempDir()
+	useStdin := func(t *testing.T, fileName string) {
+		stdin, err := os.Open(fileName)
+		require.NoError(t, err)
+		savedStdin := os.Stdin
+		os.Stdin = stdin
+		t.Cleanup(func() {
+			os.Stdin = savedStdin
+			_ = stdin.Close()
+		})
+	}
+
+	t.Run("schema-output", func(t *testing.T) {
+		useStdin(t, "../../testdata/jsonl.source")
+		cmd := Cmd{Format: "jsonl", Source: "-", InferSampleSize: 1, SchemaOutput: filepath.Join(tempDir, "stdin.schema")}
+		require.NoError(t, cmd.Run(context.Background()))
+		schema, err := os.ReadFile(cmd.SchemaOutput)
+		require.NoError(t, err)
+		require.Contains(t, string(schema), "name=Bool, type=BOOLEAN")
+	})
+
+	t.Run("infer-and-import", func(t *testing.T) {
+		// sampled records are replayed so all records are imported from a stream
+		useStdin(t, "../../testdata/jsonl.source")
+		cmd := Cmd{
+			Format:          "jsonl",
+			Source:          "-",
+			InferSampleSize: 3,
+			URI:             filepath.Join(tempDir, "stdin.parquet"),
+			WriteOption:     pio.WriteOption{CompressionCodec: "SNAPPY", PageSize: 1024 * 1024, RowGroupSize: 128 * 1024 * 1024},
+		}
+		require.NoError(t, cmd.Run(context.Background()))
+
+		reader, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+		require.NoError(t, err)
+		require.Equal(t, int64(10), reader.GetNumRows())
+	})
+
+	t.Run("unlimited-sample", func(t *testing.T) {
+		useStdin(t, "../../testdata/jsonl.source")
+		cmd := Cmd{Format: "jsonl", Source: "-", InferSampleSize: 0, SchemaOutput: filepath.Join(tempDir, "unlimited.schema")}
+		err := cmd.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "--infer-sample-size 0 cannot be used with standard input")
+	})
+
+	t.Run("unlimited-sample-from-file", func(t *testing.T) {
+		// source file is read again instead of replaying all records from memory
+		cmd := Cmd{
+			Format:          "jsonl",
+			Source:          "../../testdata/jsonl.source",
+			InferSampleSize: 0,
+			URI:             filepath.Join(tempDir, "unlimited.parquet"),
+			WriteOption:     pio.WriteOption{CompressionCodec: "SNAPPY", PageSize: 1024 * 1024, RowGroupSize: 128 * 1024 * 1024},
+		}
+		require.NoError(t, cmd.Run(context.Background()))
+
+		reader, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+		require.NoError(t, err)
+		require.Equal(t, int64(10), reader.GetNumRows())
+	})
+
+	t.Run("bad-schema", func(t *testing.T) {
+		useStdin(t, "../../testdata/csv.source")
+		cmd := Cmd{Format: "csv", Source: "-", Schema: "does/not/exist", URI: filepath.Join(tempDir, "dummy")}
+		err := cmd.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to load schema from")
+	})
+}
@@ -0,0 +1,75 @@
+package importcmd
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/hangxie/parquet-go/v3/layout"
+	"github.com/hangxie/parquet-go/v3/schema"
+	"github.com/hangxie/parquet-go/v3/writer"
+)
+
+// jsonRecordWriter writes JSON records one by one. JSON writer only marshals
+// buffered records when it flushes, so a bad record would fail a later write
+// or the end of import. Each record is marshalled when it is written instead,
+// and the writer merges marshalled records when it flushes rather than
+// marshalling them again.
+type jsonRecordWriter struct {
+	*writer.JSONWriter
+	marshal func([]any, *schema.SchemaHandler) (*map[string]*layout.Table, error)
+	tables  map[string]map[string]*layout.Table
+	flushed atomic.Bool
+}
+
+func newJSONRecordWriter(parquetWriter *writer.JSONWriter) *jsonRecordWriter {
+	recordWriter := &jsonRecordWriter{
+		JSONWriter: parquetWriter,
+		marshal:    parquetWriter.MarshalFunc,
+		tables:     map[string]map[string]*layout.Table{},
+	}
+	parquetWriter.MarshalFunc = recordWriter.marshalWritten
+	return recordWriter
+}
+
+// write marshals record before it is buffered by JSON writer.
+func (w *jsonRecordWriter) write(ctx context.Context, record string) error {
+	// records written before the last flush are not needed any more
+	if w.flushed.Swap(false) {
+		clear(w.tables)
+	}
+	if _, found := w.tables[record]; !found {
+		tables, err := w.marshal([]any{record}, w.SchemaHandler)
+		if err != nil {
+			return err
+		}
+		w.tables[record] = *tables
+	}
+	return w.WriteWithContext(ctx, record)
+}
+
+// marshalWritten is MarshalFunc of JSON writer, it merges tables of records
+// marshalled by write. Flush calls it from several goroutines, which only read
+// tables as write cannot run until flush ends.
+func (w *jsonRecordWriter) marshalWritten(src []any, schemaHandler *schema.SchemaHandler) (*map[string]*layout.Table, error) {
+	w.flushed.Store(true)
+	result := map[string]*layout.Table{}
+	for _, obj := range src {
+		record, _ := obj.(string)
+		tables, found := w.tables[record]
+		if !found {
+			marshalled, err := w.marshal([]any{obj}, schemaHandler)
+			if err != nil {
+				return nil, err
+			}
+			tables = *marshalled
+		}
+		for path, table := range tables {
+			if result[path] == nil {
+				result[path] = layout.NewTableFromTable(table)
+				result[path].RepetitionType = table.RepetitionType
+			}
+			result[path].Merge(table)
+		}
+	}
+	return &result, nil
+}
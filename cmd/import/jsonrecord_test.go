@@ -0,0 +1,67 @@
+package importcmd
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/hangxie/parquet-go/v3/layout"
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/schema"
+	"github.com/stretchr/testify/require"
+
+	pio "github.com/hangxie/parquet-tools/io"
+)
+
+func TestJSONRecordWriter(t *testing.T) {
+	ctx := context.Background()
+	wOpt := pio.WriteOption{CompressionCodec: "SNAPPY", PageSize: 1024 * 1024, RowGroupSize: 128 * 1024 * 1024}
+	parquetWriter, err := pio.NewJSONWriter(ctx, filepath.Join(t.TempDir(), "records.parquet"), wOpt,
+		`{"Tag":"name=parquet_go_root","Fields":[{"Tag":"name=Id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"}]}`)
+	require.NoError(t, err)
+	defer func() {
+		_ = parquetWriter.PFile.Close()
+	}()
+
+	var marshalled []any
+	parquetWriter.MarshalFunc = func(src []any, _ *schema.SchemaHandler) (*map[string]*layout.Table, error) {
+		if src[0] == "bad" {
+			return nil, errors.New("bad record")
+		}
+		marshalled = append(marshalled, src...)
+		return &map[string]*layout.Table{
+			"Id": {RepetitionType: parquet.FieldRepetitionType_OPTIONAL, Values: src, DefinitionLevels: []int32{1}, RepetitionLevels: []int32{0}},
+		}, nil
+	}
+	recordWriter := newJSONRecordWriter(parquetWriter)
+
+	// each record is marshalled once when it is written, bad record fails its
+	// own write
+	require.NoError(t, recordWriter.write(ctx, "a"))
+	require.NoError(t, recordWriter.write(ctx, "b"))
+	require.NoError(t, recordWriter.write(ctx, "a"))
+	require.EqualError(t, recordWriter.write(ctx, "bad"), "bad record")
+	require.Equal(t, []any{"a", "b"}, marshalled)
+
+	// flush merges records marshalled by write instead of marshalling them again
+	tables, err := parquetWriter.MarshalFunc([]any{"a", "b", "a"}, parquetWriter.SchemaHandler)
+	require.NoError(t, err)
+	require.Equal(t, []any{"a", "b"}, marshalled)
+	require.Len(t, *tables, 1)
+	table := (*tables)["Id"]
+	require.Equal(t, parquet.FieldRepetitionType_OPTIONAL, table.RepetitionType)
+	require.Equal(t, []any{"a", "b", "a"}, table.Values)
+	require.Equal(t, []int32{1, 1, 1}, table.DefinitionLevels)
+	require.Equal(t, []int32{0, 0, 0}, table.RepetitionLevels)
+
+	// a record that was not written is marshalled when it is flushed
+	tables, err = parquetWriter.MarshalFunc([]any{"c"}, parquetWriter.SchemaHandler)
+	require.NoError(t, err)
+	require.Equal(t, []any{"c"}, (*tables)["Id"].Values)
+	require.Equal(t, []any{"a", "b", "c"}, marshalled)
+
+	// records are dropped once they have been flushed
+	require.NoError(t, recordWriter.write(ctx, "a"))
+	require.Equal(t, []any{"a", "b", "c", "a"}, marshalled)
+}
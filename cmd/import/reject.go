@@ -0,0 +1,96 @@
+package importcmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// rejectedRecord is a line in --reject-file.
+type rejectedRecord struct {
+	Record int    `json:"record"`         // 1-based record number in source
+	Line   int    `json:"line,omitempty"` // 1-based line number where record starts, Avro and Arrow sources do not have it
+	Error  string `json:"error"`
+	Data   string `json:"data,omitempty"`
+}
+
+// rejecter keeps track of bad records per --max-errors and --reject-file.
+type rejecter struct {
+	maxErrors int
+	imported  int
+	rejected  int
+	file      *os.File
+	writer    *bufio.Writer
+}
+
+func (c Cmd) newRejecter() (*rejecter, error) {
+	r := &rejecter{maxErrors: c.MaxErrors}
+	if c.RejectFile == "" {
+		return r, nil
+	}
+
+	file, err := os.Create(c.RejectFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reject file [%s]: %w", c.RejectFile, err)
+	}
+	r.file = file
+	r.writer = bufio.NewWriter(file)
+	return r, nil
+}
+
+// reject records a bad record, it returns error if the import needs to stop,
+// reason is returned as is if bad records are not tolerated at all.
+func (r *rejecter) reject(record, line int, data string, reason error) error {
+	r.rejected++
+	if r.writer != nil {
+		buf, _ := json.Marshal(rejectedRecord{Record: record, Line: line, Error: reason.Error(), Data: data})
+		if _, err := r.writer.Write(append(buf, '\n')); err != nil {
+			return fmt.Errorf("failed to write to reject file: %w", err)
+		}
+	}
+	switch {
+	case r.maxErrors == 0:
+		return reason
+	case r.maxErrors > 0 && r.rejected > r.maxErrors:
+		return fmt.Errorf("more than %d records are rejected: %w", r.maxErrors, reason)
+	}
+	return nil
+}
+
+// close flushes and closes reject file, it is safe to call more than once.
+func (r *rejecter) close() error {
+	if r.file == nil {
+		return nil
+	}
+	file := r.file
+	r.file = nil
+	if err := r.writer.Flush(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write to reject file: %w", err)
+	}
+	return file.Close()
+}
+
+// summary prints number of imported and rejected records if bad records can be
+// tolerated or saved.
+func (r *rejecter) summary() {
+	if r.maxErrors == 0 && r.writer == nil {
+		return
+	}
+	fmt.Printf("%d records imported, %d records rejected\n", r.imported, r.rejected)
+}
+
+// csvLine turns fields back to a CSV line for reject file.
+func csvLine(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write(fields)
+	writer.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,190 @@
+package importcmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pio "github.com/hangxie/parquet-tools/io"
+)
+
+func TestRejecter(t *testing.T) {
+	testCases := map[string]struct {
+		maxErrors int
+		stopAt    int
+		errMsg    string
+	}{
+		"not-tolerated": {maxErrors: 0, stopAt: 1, errMsg: "bad record 1"},
+		"limited":       {maxErrors: 2, stopAt: 3, errMsg: "more than 2 records are rejected: bad record 3"},
+		"unlimited":     {maxErrors: -1, stopAt: 0},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rejectFile := filepath.Join(t.TempDir(), "rejects.jsonl")
+			rejects, err := Cmd{MaxErrors: tc.maxErrors, RejectFile: rejectFile}.newRejecter()
+			require.NoError(t, err)
+
+			stopAt := 0
+			for record := 1; record <= 5; record++ {
+				err := rejects.reject(record, record+1, "a,b", errors.New("bad record "+string(rune('0'+record))))
+				if err != nil {
+					stopAt = record
+					require.Equal(t, tc.errMsg, err.Error())
+					break
+				}
+			}
+			require.Equal(t, tc.stopAt, stopAt)
+			require.NoError(t, rejects.close())
+			require.NoError(t, rejects.close())
+
+			content, err := os.ReadFile(rejectFile)
+			require.NoError(t, err)
+			lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+			require.Equal(t, rejects.rejected, len(lines))
+			require.Equal(t, `{"record":1,"line":2,"error":"bad record 1","data":"a,b"}`, lines[0])
+		})
+	}
+
+	t.Run("bad-reject-file", func(t *testing.T) {
+		_, err := Cmd{RejectFile: filepath.Join(t.TempDir(), "does", "not", "exist")}.newRejecter()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create reject file")
+	})
+
+	t.Run("summary", func(t *testing.T) {
+		rejects := &rejecter{maxErrors: 0, imported: 3}
+		stdout, _ := testutils.CaptureStdoutStderr(rejects.summary)
+		require.Empty(t, stdout)
+
+		rejects = &rejecter{maxErrors: -1, imported: 3, rejected: 1}
+		stdout, _ = testutils.CaptureStdoutStderr(rejects.summary)
+		require.Equal(t, "3 records imported, 1 records rejected\n", stdout)
+	})
+}
+
+func TestCSVLine(t *testing.T) {
+	require.Equal(t, "", csvLine(nil))
+	require.Equal(t, `a,"b,c",""""`, csvLine([]string{"a", "b,c", `"`}))
+}
+
+func TestCmdReject(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFile := func(name, content string) string {
+		fileName := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(fileName, []byte(content), 0o644))
+		return fileName
+	}
+	wOpt := pio.WriteOption{CompressionCodec: "SNAPPY", PageSize: 1024 * 1024, RowGroupSize: 128 * 1024 * 1024}
+	csvSchema := writeFile("csv.schema", "name=Id, type=INT64\nname=Name, type=BYTE_ARRAY, convertedtype=UTF8\n")
+	jsonSchema := writeFile("json.schema", `{"Tag":"name=parquet_go_root","Fields":[{"Tag":"name=Id, type=INT64"}]}`)
+
+	t.Run("bad-max-errors", func(t *testing.T) {
+		err := Cmd{Format: "csv", Source: "dummy", URI: "dummy", MaxErrors: -2}.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid max errors -2")
+	})
+
+	testCases := map[string]struct {
+		cmd      Cmd
+		imported int
+		rejects  []string
+		errMsg   string
+	}{
+		"csv": {
+			cmd:      Cmd{Format: "csv", Source: "1,a\nx,b\n2,\"c\n", Schema: csvSchema, MaxErrors: -1},
+			imported: 1,
+			rejects:  []string{`{"record":2,"line":2,"error":"failed to write [[x b]] to parquet:`, `{"record":3,"line":3,"error":"failed to read CSV record from [`},
+		},
+		"csv-too-many": {
+			cmd:     Cmd{Format: "csv", Source: "x,a\ny,b\n", Schema: csvSchema, MaxErrors: 1},
+			rejects: []string{`{"record":1,"line":1,`, `{"record":2,"line":2,`},
+			errMsg:  "more than 1 records are rejected",
+		},
+		"jsonl": {
+			cmd:      Cmd{Format: "jsonl", Source: "{\"Id\":1}\n{\"Id\":\n[1]\n{\"Id\":2}\n", Schema: jsonSchema, MaxErrors: 2},
+			imported: 2,
+			rejects:  []string{`{"record":2,"line":2,"error":"invalid JSON string: {\"Id\":","data":"{\"Id\":"}`, `{"record":3,"line":3,"error":"invalid JSON string: [1]","data":"[1]"}`},
+		},
+		"json": {
+			cmd:      Cmd{Format: "json", Source: "[{\"Id\":1},2,\n{\"Id\":3},\n\n  [\n3]]", Schema: jsonSchema, MaxErrors: -1},
+			imported: 2,
+			rejects:  []string{`{"record":2,"line":1,"error":"record 2 of [`, `{"record":4,"line":4,"error":"record 4 of [`},
+		},
+		"not-tolerated": {
+			cmd:     Cmd{Format: "jsonl", Source: "{\"Id\":1}\n[1]\n", Schema: jsonSchema},
+			rejects: []string{`{"record":2,"line":2,"error":"invalid JSON string: [1]","data":"[1]"}`},
+			errMsg:  "invalid JSON string: [1]",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cmd := tc.cmd
+			cmd.Source = writeFile(name+".source", cmd.Source)
+			cmd.URI = filepath.Join(tempDir, name+".parquet")
+			cmd.RejectFile = filepath.Join(tempDir, name+".rejects")
+			cmd.WriteOption = wOpt
+
+			var err error
+			stdout, _ := testutils.CaptureStdoutStderr(func() {
+				err = cmd.Run(context.Background())
+			})
+			content, readErr := os.ReadFile(cmd.RejectFile)
+			require.NoError(t, readErr)
+			lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+			require.Equal(t, len(tc.rejects), len(lines))
+			for i := range tc.rejects {
+				require.True(t, strings.HasPrefix(lines[i], tc.rejects[i]), lines[i])
+			}
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Contains(t, stdout, "records rejected")
+
+			reader, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+			require.NoError(t, err)
+			defer func() {
+				_ = reader.PFile.Close()
+			}()
+			require.Equal(t, int64(tc.imported), reader.GetNumRows())
+		})
+	}
+
+	t.Run("jsonl-type-mismatch", func(t *testing.T) {
+		// the bad record is in the middle and is rejected by itself, not at flush
+		cmd := Cmd{
+			Format:      "jsonl",
+			Source:      writeFile("type-mismatch.source", "{\"Id\":1}\n{\"Id\":\"abc\"}\n{\"Id\":3}\n"),
+			Schema:      jsonSchema,
+			MaxErrors:   1,
+			URI:         filepath.Join(tempDir, "type-mismatch.parquet"),
+			RejectFile:  filepath.Join(tempDir, "type-mismatch.rejects"),
+			WriteOption: wOpt,
+		}
+		var err error
+		stdout, _ := testutils.CaptureStdoutStderr(func() {
+			err = cmd.Run(context.Background())
+		})
+		require.NoError(t, err)
+		require.Equal(t, "2 records imported, 1 records rejected\n", stdout)
+
+		content, err := os.ReadFile(cmd.RejectFile)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		require.Len(t, lines, 1)
+		require.True(t, strings.HasPrefix(lines[0], `{"record":2,"line":2,"error":"failed to write to parquet file:`), lines[0])
+		require.True(t, strings.HasSuffix(lines[0], `"data":"{\"Id\":\"abc\"}"}`), lines[0])
+
+		require.Equal(t, `[{"Id":1},{"Id":3}]`+"\n", testutils.CommandStdout(t, importTestCatCmd(cmd.URI, pio.ReadOption{})))
+	})
+}
@@ -0,0 +1,28 @@
+package merge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/reader"
+
+	pio "github.com/hangxie/parquet-tools/io"
+)
+
+// copyRowGroups merges by copying row groups of all source files as is, footer
+// of target file has metadata as its key-value metadata.
+func (c Cmd) copyRowGroups(ctx context.Context, fileReaders []*reader.ParquetReader, metadata []*parquet.KeyValue) error {
+	var rowGroups []pio.RowGroupSource
+	for i, fileReader := range fileReaders {
+		if err := pio.CheckCopyable(fileReader.Footer); err != nil {
+			return fmt.Errorf("cannot copy row groups from [%s]: %w", c.Source[i], err)
+		}
+		for _, rowGroup := range fileReader.Footer.RowGroups {
+			rowGroups = append(rowGroups, pio.RowGroupSource{URI: c.Source[i], PFile: fileReader.PFile, RowGroup: rowGroup})
+		}
+	}
+	footer := *fileReaders[0].Footer
+	footer.KeyValueMetadata = metadata
+	return pio.CopyRowGroups(ctx, c.URI, &footer, rowGroups)
+}
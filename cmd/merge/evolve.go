@@ -0,0 +1,150 @@
+package merge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hangxie/parquet-go/v3/marshal"
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/reader"
+
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// cloneSchema copies the tree so that union can be changed without touching
+// schema of source files.
+func cloneSchema(node *pschema.SchemaNode) *pschema.SchemaNode {
+	clone := *node
+	clone.Children = make([]*pschema.SchemaNode, len(node.Children))
+	for index, child := range node.Children {
+		clone.Children[index] = cloneSchema(child)
+	}
+	return &clone
+}
+
+// unionSchema adds fields of node to union, fields missing from either side
+// become OPTIONAL, INT32 and FLOAT are widened to INT64 and DOUBLE, it
+// returns path of the first field that cannot be evolved.
+func unionSchema(union, node *pschema.SchemaNode, delimiter string) (string, bool) {
+	for _, child := range node.Children {
+		if findChild(union, child.Name) == nil {
+			newChild := cloneSchema(child)
+			newChild.RepetitionType = relaxed(newChild.RepetitionType)
+			union.Children = append(union.Children, newChild)
+		}
+	}
+	for _, unionChild := range union.Children {
+		child := findChild(node, unionChild.Name)
+		if child == nil {
+			unionChild.RepetitionType = relaxed(unionChild.RepetitionType)
+			continue
+		}
+		if !evolvable(unionChild, child) {
+			return strings.Join(unionChild.ExNamePath[1:], delimiter), false
+		}
+		if widened(unionChild, child) {
+			unionChild.Type, unionChild.ConvertedType, unionChild.LogicalType = child.Type, child.ConvertedType, child.LogicalType
+		}
+		if unionChild.GetRepetitionType() != child.GetRepetitionType() {
+			unionChild.RepetitionType = parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_OPTIONAL)
+		}
+		if path, ok := unionSchema(unionChild, child, delimiter); !ok {
+			return path, false
+		}
+	}
+	return "", true
+}
+
+func findChild(node *pschema.SchemaNode, name string) *pschema.SchemaNode {
+	for _, child := range node.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// relaxed returns OPTIONAL for REQUIRED, a missing REPEATED field is just
+// an empty list.
+func relaxed(repetitionType *parquet.FieldRepetitionType) *parquet.FieldRepetitionType {
+	if repetitionType != nil && *repetitionType == parquet.FieldRepetitionType_REPEATED {
+		return repetitionType
+	}
+	return parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_OPTIONAL)
+}
+
+// evolvable tells if data of both fields can be written to one field, it
+// does not look into children.
+func evolvable(a, b *pschema.SchemaNode) bool {
+	repeated := parquet.FieldRepetitionType_REPEATED
+	if (a.GetRepetitionType() == repeated) != (b.GetRepetitionType() == repeated) {
+		return false
+	}
+	left, right := *a, *b
+	left.Children, right.Children = nil, nil
+	left.RepetitionType, right.RepetitionType = nil, nil
+	if widened(&left, &right) || widened(&right, &left) {
+		right.Type, right.ConvertedType, right.LogicalType = left.Type, left.ConvertedType, left.LogicalType
+	}
+	return left.IsCompatible(&right, pschema.CompareOption{})
+}
+
+// widened tells if narrow field needs to be widened to type of wide field.
+func widened(narrow, wide *pschema.SchemaNode) bool {
+	if narrow.Type == nil || wide.Type == nil {
+		return false
+	}
+	switch {
+	case *narrow.Type == parquet.Type_INT32 && *wide.Type == parquet.Type_INT64:
+		narrowOK, narrowSigned := narrow.IntegerAnnotation()
+		wideOK, wideSigned := wide.IntegerAnnotation()
+		// negative values do not fit into unsigned integer
+		return narrowOK && wideOK && (wideSigned || !narrowSigned)
+	case *narrow.Type == parquet.Type_FLOAT && *wide.Type == parquet.Type_DOUBLE:
+		return narrow.ConvertedType == nil && narrow.LogicalType == nil && wide.ConvertedType == nil && wide.LogicalType == nil
+	}
+	return false
+}
+
+// evolveRules lists rules applied to data of a source file to write it with
+// the union schema.
+func evolveRules(node, union *pschema.SchemaNode, delimiter string) []string {
+	var rules []string
+	for _, unionChild := range union.Children {
+		path := strings.Join(unionChild.ExNamePath[1:], delimiter)
+		child := findChild(node, unionChild.Name)
+		if child == nil {
+			if unionChild.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+				rules = append(rules, fmt.Sprintf("field [%s] is missing, filled with empty list", path))
+			} else {
+				rules = append(rules, fmt.Sprintf("field [%s] is missing, filled with null", path))
+			}
+			continue
+		}
+		if child.Type != nil && *child.Type != *unionChild.Type {
+			rules = append(rules, fmt.Sprintf("field [%s] is widened from %s to %s", path, *child.Type, *unionChild.Type))
+		}
+		if child.GetRepetitionType() != unionChild.GetRepetitionType() {
+			rules = append(rules, fmt.Sprintf("field [%s] is relaxed from %s to %s", path, child.GetRepetitionType(), unionChild.GetRepetitionType()))
+		}
+		rules = append(rules, evolveRules(child, unionChild, delimiter)...)
+	}
+	return rules
+}
+
+// jsonRow converts rows of a source file to JSON, so they can be written
+// with the union schema the same way import does.
+func jsonRow(fileReader *reader.ParquetReader) func(any) (any, error) {
+	return func(row any) (any, error) {
+		value, err := marshal.ConvertToJSONFriendly(row, fileReader.SchemaHandler)
+		if err != nil {
+			return nil, err
+		}
+		buf, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(buf), nil
+	}
+}
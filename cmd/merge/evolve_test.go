@@ -0,0 +1,232 @@
+package merge
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/reader"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func evolveTestField(path string, fieldType parquet.Type, repetition parquet.FieldRepetitionType) *pschema.SchemaNode {
+	names := strings.Split(path, ".")
+	return &pschema.SchemaNode{
+		SchemaElement: parquet.SchemaElement{Name: names[len(names)-1], Type: &fieldType, RepetitionType: &repetition},
+		ExNamePath:    append([]string{"parquet_go_root"}, names...),
+	}
+}
+
+func evolveTestGroup(path string, repetition parquet.FieldRepetitionType, children ...*pschema.SchemaNode) *pschema.SchemaNode {
+	result := evolveTestField(path, parquet.Type_BOOLEAN, repetition)
+	result.Type = nil
+	result.Children = children
+	return result
+}
+
+func TestUnionSchema(t *testing.T) {
+	required, optional, repeated := parquet.FieldRepetitionType_REQUIRED, parquet.FieldRepetitionType_OPTIONAL, parquet.FieldRepetitionType_REPEATED
+	root := func(children ...*pschema.SchemaNode) *pschema.SchemaNode {
+		return &pschema.SchemaNode{SchemaElement: parquet.SchemaElement{Name: "parquet_go_root"}, ExNamePath: []string{"parquet_go_root"}, Children: children}
+	}
+	withConvertedType := func(node *pschema.SchemaNode, convertedType parquet.ConvertedType) *pschema.SchemaNode {
+		node.ConvertedType = &convertedType
+		return node
+	}
+
+	testCases := map[string]struct {
+		schemas []*pschema.SchemaNode
+		union   []string
+		rules   [][]string
+		errPath string
+	}{
+		"same": {
+			schemas: []*pschema.SchemaNode{
+				root(evolveTestField("a", parquet.Type_INT32, required)),
+				root(evolveTestField("a", parquet.Type_INT32, required)),
+			},
+			union: []string{"name=a, type=INT32"},
+			rules: [][]string{nil, nil},
+		},
+		"new-column": {
+			schemas: []*pschema.SchemaNode{
+				root(evolveTestField("a", parquet.Type_INT32, required)),
+				root(evolveTestField("a", parquet.Type_INT32, required), evolveTestField("b", parquet.Type_DOUBLE, required), evolveTestField("c", parquet.Type_INT64, repeated)),
+			},
+			union: []string{"name=a, type=INT32", "name=b, type=DOUBLE, repetitiontype=OPTIONAL", "name=c, type=INT64, repetitiontype=REPEATED"},
+			rules: [][]string{
+				{"field [b] is missing, filled with null", "field [c] is missing, filled with empty list"},
+				{"field [b] is relaxed from REQUIRED to OPTIONAL"},
+			},
+		},
+		"widen": {
+			schemas: []*pschema.SchemaNode{
+				root(evolveTestField("a", parquet.Type_INT32, optional), evolveTestField("b", parquet.Type_DOUBLE, required)),
+				root(evolveTestField("a", parquet.Type_INT64, required), evolveTestField("b", parquet.Type_FLOAT, required)),
+			},
+			union: []string{"name=a, type=INT64, repetitiontype=OPTIONAL", "name=b, type=DOUBLE"},
+			rules: [][]string{
+				{"field [a] is widened from INT32 to INT64"},
+				{"field [a] is relaxed from REQUIRED to OPTIONAL", "field [b] is widened from FLOAT to DOUBLE"},
+			},
+		},
+		"widen-unsigned": {
+			schemas: []*pschema.SchemaNode{
+				root(withConvertedType(evolveTestField("a", parquet.Type_INT32, required), parquet.ConvertedType_UINT_32)),
+				root(evolveTestField("a", parquet.Type_INT64, required)),
+			},
+			union: []string{"name=a, type=INT64"},
+			rules: [][]string{{"field [a] is widened from INT32 to INT64"}, nil},
+		},
+		"nested": {
+			schemas: []*pschema.SchemaNode{
+				root(evolveTestGroup("a", required, evolveTestField("a.b", parquet.Type_INT32, required))),
+				root(evolveTestGroup("a", required, evolveTestField("a.c", parquet.Type_INT32, required))),
+				root(evolveTestField("d", parquet.Type_BOOLEAN, required)),
+			},
+			union: []string{"name=a, repetitiontype=OPTIONAL", "name=d, type=BOOLEAN, repetitiontype=OPTIONAL"},
+			rules: [][]string{
+				{"field [a] is relaxed from REQUIRED to OPTIONAL", "field [a.b] is relaxed from REQUIRED to OPTIONAL", "field [a.c] is missing, filled with null", "field [d] is missing, filled with null"},
+				{"field [a] is relaxed from REQUIRED to OPTIONAL", "field [a.b] is missing, filled with null", "field [a.c] is relaxed from REQUIRED to OPTIONAL", "field [d] is missing, filled with null"},
+				{"field [a] is missing, filled with null", "field [d] is relaxed from REQUIRED to OPTIONAL"},
+			},
+		},
+		"narrow-signed-to-unsigned": {
+			schemas: []*pschema.SchemaNode{
+				root(evolveTestField("a", parquet.Type_INT32, required)),
+				root(withConvertedType(evolveTestField("a", parquet.Type_INT64, required), parquet.ConvertedType_UINT_64)),
+			},
+			errPath: "a",
+		},
+		"different-type": {
+			schemas: []*pschema.SchemaNode{
+				root(evolveTestField("a", parquet.Type_INT64, required)),
+				root(evolveTestField("a", parquet.Type_DOUBLE, required)),
+			},
+			errPath: "a",
+		},
+		"date-to-int64": {
+			schemas: []*pschema.SchemaNode{
+				root(withConvertedType(evolveTestField("a", parquet.Type_INT32, required), parquet.ConvertedType_DATE)),
+				root(evolveTestField("a", parquet.Type_INT64, required)),
+			},
+			errPath: "a",
+		},
+		"repeated": {
+			schemas: []*pschema.SchemaNode{
+				root(evolveTestField("a", parquet.Type_INT64, repeated)),
+				root(evolveTestField("a", parquet.Type_INT64, optional)),
+			},
+			errPath: "a",
+		},
+		"nested-conflict": {
+			schemas: []*pschema.SchemaNode{
+				root(evolveTestGroup("a", required, evolveTestField("a.b", parquet.Type_INT32, required))),
+				root(evolveTestGroup("a", required, evolveTestGroup("a.b", required))),
+			},
+			errPath: "a.b",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			union := cloneSchema(tc.schemas[0])
+			for _, schema := range tc.schemas[1:] {
+				path, ok := unionSchema(union, schema, ".")
+				if tc.errPath != "" && !ok {
+					require.Equal(t, tc.errPath, path)
+					return
+				}
+				require.True(t, ok)
+			}
+			require.Empty(t, tc.errPath)
+
+			var jsonSchema pschema.JSONSchema
+			require.NoError(t, json.Unmarshal([]byte(union.JSONSchema()), &jsonSchema))
+			require.Len(t, jsonSchema.Fields, len(tc.union))
+			for index, field := range jsonSchema.Fields {
+				require.Equal(t, tc.union[index], field.Tag)
+			}
+			for index, schema := range tc.schemas {
+				require.Equal(t, tc.rules[index], evolveRules(schema, union, "."))
+			}
+		})
+	}
+}
+
+func TestCmdEvolveSchema(t *testing.T) {
+	sources := []string{"../../testdata/csv-good.parquet", "../../testdata/csv-optional.parquet", "../../testdata/good.parquet"}
+	rules := `[../../testdata/csv-good.parquet] field [Id] is relaxed from REQUIRED to OPTIONAL
+[../../testdata/csv-good.parquet] field [Name] is relaxed from REQUIRED to OPTIONAL
+[../../testdata/csv-good.parquet] field [Age] is relaxed from REQUIRED to OPTIONAL
+[../../testdata/csv-good.parquet] field [Temperature] is relaxed from REQUIRED to OPTIONAL
+[../../testdata/csv-good.parquet] field [Vaccinated] is relaxed from REQUIRED to OPTIONAL
+[../../testdata/csv-good.parquet] field [shoe_brand] is missing, filled with null
+[../../testdata/csv-good.parquet] field [shoe_name] is missing, filled with null
+[../../testdata/csv-optional.parquet] field [Id] is relaxed from REQUIRED to OPTIONAL
+[../../testdata/csv-optional.parquet] field [Name] is relaxed from REQUIRED to OPTIONAL
+[../../testdata/csv-optional.parquet] field [Age] is relaxed from REQUIRED to OPTIONAL
+[../../testdata/csv-optional.parquet] field [Vaccinated] is relaxed from REQUIRED to OPTIONAL
+[../../testdata/csv-optional.parquet] field [shoe_brand] is missing, filled with null
+[../../testdata/csv-optional.parquet] field [shoe_name] is missing, filled with null
+[../../testdata/good.parquet] field [Id] is missing, filled with null
+[../../testdata/good.parquet] field [Name] is missing, filled with null
+[../../testdata/good.parquet] field [Age] is missing, filled with null
+[../../testdata/good.parquet] field [Temperature] is missing, filled with null
+[../../testdata/good.parquet] field [Vaccinated] is missing, filled with null
+[../../testdata/good.parquet] field [shoe_brand] is relaxed from REQUIRED to OPTIONAL
+[../../testdata/good.parquet] field [shoe_name] is relaxed from REQUIRED to OPTIONAL
+`
+
+	t.Run("not-compatible", func(t *testing.T) {
+		cmd := Cmd{EvolveSchema: true, ReadPageSize: 10, FieldDelimiter: ".", Source: []string{"../../testdata/csv-good.parquet", "../../testdata/csv-nested.parquet"}, URI: "dummy"}
+		err := cmd.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "[../../testdata/csv-nested.parquet] field [Temperature] is not compatible with previous files")
+	})
+
+	t.Run("fast", func(t *testing.T) {
+		cmd := Cmd{EvolveSchema: true, Fast: true, ReadPageSize: 10, FieldDelimiter: ".", Source: []string{"../../testdata/good.parquet", "../../testdata/good.parquet"}, URI: "dummy"}
+		err := cmd.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "--fast cannot be used with --evolve-schema")
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		cmd := Cmd{EvolveSchema: true, FieldDelimiter: ".", Source: sources}
+		var err error
+		stdout, stderr := testutils.CaptureStdoutStderr(func() {
+			var fileReaders []*reader.ParquetReader
+			fileReaders, _, _, err = cmd.openSources(context.Background())
+			for _, fileReader := range fileReaders {
+				_ = fileReader.PFile.Close()
+			}
+		})
+		require.NoError(t, err)
+		require.Empty(t, stdout)
+		require.Equal(t, rules, stderr)
+	})
+
+	t.Run("good", func(t *testing.T) {
+		cmd := Cmd{
+			EvolveSchema:   true,
+			ReadPageSize:   10,
+			FieldDelimiter: ".",
+			Source:         sources,
+			URI:            filepath.Join(t.TempDir(), "evolved.parquet"),
+		}
+		var err error
+		stdout, stderr := testutils.CaptureStdoutStderr(func() {
+			err = cmd.Run(context.Background())
+		})
+		require.NoError(t, err)
+		require.Empty(t, stdout)
+		require.Equal(t, rules, stderr)
+	})
+}
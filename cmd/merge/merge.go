@@ -3,9 +3,11 @@ package merge
 import (
 	"context"
 	"fmt"
+	"os"
 	"runtime"
 
 	"github.com/hangxie/parquet-go/v3/reader"
+	"github.com/hangxie/parquet-go/v3/writer"
 	"golang.org/x/sync/errgroup"
 
 	pio "github.com/hangxie/parquet-tools/io"
@@ -14,12 +16,17 @@ import (
 
 // Cmd is a kong command for merge
 type Cmd struct {
-	Concurrent     bool     `help:"enable concurrent processing" default:"false"`
-	FailOnInt96    bool     `help:"fail command if INT96 data type is present." name:"fail-on-int96" default:"false"`
-	FieldDelimiter string   `name:"field-delimiter" help:"Delimiter separating nested field path components in field and column parameters" default:"."`
-	ReadPageSize   int      `help:"Page size to read from Parquet." default:"1000"`
-	Source         []string `short:"s" help:"Files to be merged."`
-	URI            string   `arg:"" predictor:"file" help:"URI of Parquet file."`
+	Concurrent       bool     `help:"enable concurrent processing" default:"false"`
+	EvolveSchema     bool     `help:"merge files with different but compatible schemas into union of their schemas." default:"false"`
+	FailOnInt96      bool     `help:"fail command if INT96 data type is present." name:"fail-on-int96" default:"false"`
+	Fast             bool     `help:"copy row groups as is without decoding, write options other than URI do not apply." default:"false"`
+	FieldDelimiter   string   `name:"field-delimiter" help:"Delimiter separating nested field path components in field and column parameters" default:"."`
+	MetadataConflict string   `help:"How to resolve a key-value metadata key with different values in source files (first/last/drop/fail)." enum:"first,last,drop,fail" default:"first"`
+	ReadPageSize     int      `help:"Page size to read from Parquet." default:"1000"`
+	SortBy           []string `name:"sort-by" help:"Merge files sorted by these fields into a sorted file, order is ASC by default, leading - means DESC." placeholder:"[-]field.path[=ASC|DESC]"`
+	Source           []string `short:"s" help:"Files to be merged, wildcards are expanded to matching files."`
+	URI              string   `arg:"" predictor:"file" help:"URI of Parquet file."`
+	pio.MetadataOption
 	pio.ReadOption
 	pio.WriteOption
 }
@@ -32,11 +39,25 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 	if c.ReadPageSize < 1 {
 		return fmt.Errorf("invalid read page size %d, needs to be at least 1", c.ReadPageSize)
 	}
+	sources, err := pio.ExpandURIs(ctx, c.Source, c.ReadOption)
+	if err != nil {
+		return err
+	}
+	c.Source = sources
 	if len(c.Source) <= 1 {
 		return fmt.Errorf("needs at least 2 source files")
 	}
+	if c.Fast && (c.WriterFooterKey != nil || len(c.WriterColumnKeys) != 0 || c.WriterKeyFile != nil || c.EncryptAllColumns || c.PlaintextFooter) {
+		return fmt.Errorf("--fast cannot write encrypted file")
+	}
+	if c.Fast && c.EvolveSchema {
+		return fmt.Errorf("--fast cannot be used with --evolve-schema")
+	}
+	if len(c.SortBy) != 0 && (c.Fast || c.Concurrent) {
+		return fmt.Errorf("--sort-by cannot be used with --fast or --concurrent")
+	}
 
-	fileReaders, schemaJSON, err := c.openSources(ctx)
+	fileReaders, schemas, rootSchema, err := c.openSources(ctx)
 	if err != nil {
 		return err
 	}
@@ -46,12 +67,30 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 		}
 	}()
 
+	metadata, err := c.keyValueMetadata(fileReaders)
+	if err != nil {
+		return err
+	}
+
+	if c.Fast {
+		return c.copyRowGroups(ctx, fileReaders, metadata)
+	}
+
+	var columns []sortColumn
+	if len(c.SortBy) != 0 {
+		if columns, err = c.sortColumns(rootSchema); err != nil {
+			return err
+		}
+		c.SortingColumns = sortingColumns(columns)
+	}
+
 	c.ReadOption.FieldDelimiter = c.FieldDelimiter
 	c.WriteOption.FieldDelimiter = c.FieldDelimiter
-	fileWriter, err := pio.NewGenericWriter(ctx, c.URI, c.WriteOption, schemaJSON)
+	fileWriter, err := c.newWriter(ctx, rootSchema.JSONSchema())
 	if err != nil {
 		return fmt.Errorf("failed to write to [%s]: %w", c.URI, err)
 	}
+	fileWriter.Footer.KeyValueMetadata = metadata
 	defer func() {
 		if err := fileWriter.WriteStopWithContext(ctx); err != nil && retErr == nil {
 			retErr = fmt.Errorf("failed to end write [%s]: %w", c.URI, err)
@@ -68,11 +107,14 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 	writerChan := make(chan any)
 
 	g.Go(func() error {
-		return pio.PipelineWriter(gctx, fileWriter, writerChan, c.URI)
+		return pio.PipelineWriter(gctx, fileWriter, writerChan, c.URI, c.RowGroupRows)
 	})
 
 	g.Go(func() error {
 		defer close(writerChan)
+		if columns != nil {
+			return c.sortedReader(gctx, fileReaders, schemas, columns, writerChan)
+		}
 		readerGroup := new(errgroup.Group)
 		if c.Concurrent {
 			readerGroup.SetLimit(runtime.NumCPU())
@@ -81,7 +123,11 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 		}
 		for i := range fileReaders {
 			readerGroup.Go(func() error {
-				return pio.PipelineReader(gctx, fileReaders[i], writerChan, c.Source[i], c.ReadPageSize, nil)
+				var transform func(any) (any, error)
+				if c.EvolveSchema {
+					transform = jsonRow(fileReaders[i])
+				}
+				return pio.PipelineReader(gctx, fileReaders[i], writerChan, c.Source[i], c.ReadPageSize, transform)
 			})
 		}
 		return readerGroup.Wait()
@@ -90,9 +136,24 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 	return g.Wait()
 }
 
-func (c Cmd) openSources(ctx context.Context) (_ []*reader.ParquetReader, _ string, retErr error) {
-	var schemaJSON string
+// newWriter writes rows as is, or rows converted to JSON with the union
+// schema if --evolve-schema is set.
+func (c Cmd) newWriter(ctx context.Context, schemaJSON string) (*writer.ParquetWriter, error) {
+	if !c.EvolveSchema {
+		return pio.NewGenericWriter(ctx, c.URI, c.WriteOption, schemaJSON)
+	}
+	jsonWriter, err := pio.NewJSONWriter(ctx, c.URI, c.WriteOption, schemaJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonWriter.ParquetWriter, nil
+}
+
+// openSources opens all source files, it returns schema of each source file
+// and schema of target file.
+func (c Cmd) openSources(ctx context.Context) (_ []*reader.ParquetReader, _ []*pschema.SchemaNode, _ *pschema.SchemaNode, retErr error) {
 	var rootSchema *pschema.SchemaNode
+	schemas := make([]*pschema.SchemaNode, 0, len(c.Source))
 	fileReaders := make([]*reader.ParquetReader, 0, len(c.Source))
 	// Close any already-opened readers if we bail out partway through; the
 	// caller only registers its deferred close once openSources succeeds.
@@ -106,25 +167,43 @@ func (c Cmd) openSources(ctx context.Context) (_ []*reader.ParquetReader, _ stri
 	for _, source := range c.Source {
 		fileReader, err := pio.NewParquetFileReader(ctx, source, c.ReadOption)
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to read from [%s]: %w", source, err)
+			return nil, nil, nil, fmt.Errorf("failed to read from [%s]: %w", source, err)
 		}
 		fileReaders = append(fileReaders, fileReader)
 
 		currSchema, err := pschema.NewSchemaTree(ctx, fileReader, pschema.SchemaOption{FailOnInt96: c.FailOnInt96})
 		if err != nil {
-			return nil, "", err
+			return nil, nil, nil, err
 		}
+		schemas = append(schemas, currSchema)
 
 		if rootSchema == nil {
 			rootSchema = currSchema
-			schemaJSON = rootSchema.JSONSchema()
+			if c.EvolveSchema {
+				rootSchema = cloneSchema(currSchema)
+			}
+			continue
+		}
+
+		if c.EvolveSchema {
+			if path, ok := unionSchema(rootSchema, currSchema, c.FieldDelimiter); !ok {
+				return nil, nil, nil, fmt.Errorf("[%s] field [%s] is not compatible with previous files", source, path)
+			}
 			continue
 		}
 
 		if !rootSchema.IsCompatible(currSchema, pschema.CompareOption{}) {
-			return nil, "", fmt.Errorf("[%s] does not have same schema as previous files", source)
+			return nil, nil, nil, fmt.Errorf("[%s] does not have same schema as previous files", source)
+		}
+	}
+
+	if c.EvolveSchema {
+		for index, schema := range schemas {
+			for _, rule := range evolveRules(schema, rootSchema, c.FieldDelimiter) {
+				fmt.Fprintf(os.Stderr, "[%s] %s\n", c.Source[index], rule)
+			}
 		}
 	}
 
-	return fileReaders, schemaJSON, nil
+	return fileReaders, schemas, rootSchema, nil
 }
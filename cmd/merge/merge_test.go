@@ -7,6 +7,8 @@ import (
 	"slices"
 	"testing"
 
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/hangxie/parquet-go/v3/parquet"
 	"github.com/stretchr/testify/require"
 
 	"github.com/hangxie/parquet-tools/cmd/cat"
@@ -30,6 +32,14 @@ func TestCmd(t *testing.T) {
 				Cmd{ReadOption: rOpt, Concurrent: false, FailOnInt96: false, ReadPageSize: 10, Source: []string{"../../testdata/good.parquet"}, URI: "dummy"},
 				"needs at least 2 source files",
 			},
+			"source-no-match": {
+				Cmd{ReadOption: rOpt, Concurrent: false, FailOnInt96: false, ReadPageSize: 10, Source: []string{"../../testdata/good.parquet", "../../testdata/*.foo"}, URI: "dummy"},
+				"no file matches [../../testdata/*.foo]",
+			},
+			"source-wildcard-need-more": {
+				Cmd{ReadOption: rOpt, Concurrent: false, FailOnInt96: false, ReadPageSize: 10, Source: []string{"../../testdata/goo?.parquet"}, URI: "dummy"},
+				"needs at least 2 source files",
+			},
 			"source-non-existent": {
 				Cmd{ReadOption: rOpt, Concurrent: false, FailOnInt96: true, ReadPageSize: 10, Source: []string{"does/not/exist1", "does/not/exist2"}, URI: "dummy"},
 				"no such file or directory",
@@ -362,13 +372,118 @@ func TestCmdEncryptionErrors(t *testing.T) {
 	}
 }
 
+func TestCmdFast(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		testCases := map[string]struct {
+			cmd    Cmd
+			errMsg string
+		}{
+			"encrypted-output": {
+				Cmd{Fast: true, ReadPageSize: 10, Source: []string{"../../testdata/good.parquet", "../../testdata/good.parquet"}, URI: "dummy", WriteOption: pio.WriteOption{WriterFooterKey: mergeEncryptionFooterKey}},
+				"--fast cannot write encrypted file",
+			},
+			"diff-schema": {
+				Cmd{Fast: true, ReadPageSize: 10, Source: []string{"../../testdata/good.parquet", "../../testdata/empty.parquet"}, URI: "dummy"},
+				"does not have same schema",
+			},
+			"target-file": {
+				Cmd{Fast: true, ReadPageSize: 10, Source: []string{"../../testdata/good.parquet", "../../testdata/good.parquet"}, URI: "://uri"},
+				"unable to parse file location",
+			},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				err := tc.cmd.Run(context.Background())
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+			})
+		}
+	})
+
+	testCases := map[string][]string{
+		"good":         {"good.parquet", "good-snappy.parquet", "good.parquet"},
+		"bloom-filter": {"bloom-filter.parquet", "bloom-filter.parquet"},
+		"row-group":    {"row-group.parquet", "row-group.parquet"},
+		"all-types":    {"all-types.parquet", "all-types.parquet"},
+	}
+	for name, sources := range testCases {
+		t.Run(name, func(t *testing.T) {
+			for i := range sources {
+				sources[i] = filepath.Join("..", "..", "testdata", sources[i])
+			}
+			cmd := Cmd{Fast: true, ReadPageSize: 10, Source: sources, URI: filepath.Join(t.TempDir(), name+".parquet")}
+			require.NoError(t, cmd.Run(context.Background()))
+			require.True(t, testutils.HasSameSchema(sources[0], cmd.URI))
+
+			target, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+			require.NoError(t, err)
+			defer func() {
+				_ = target.PFile.Close()
+			}()
+			targetData, err := os.ReadFile(cmd.URI)
+			require.NoError(t, err)
+
+			// every column chunk, bloom filter and column index has the same bytes as in source
+			var numRows int64
+			rowGroups := target.Footer.RowGroups
+			for _, source := range sources {
+				sourceData, err := os.ReadFile(source)
+				require.NoError(t, err)
+				reader, err := pio.NewParquetFileReader(context.Background(), source, pio.ReadOption{})
+				require.NoError(t, err)
+				_ = reader.PFile.Close()
+				numRows += reader.Footer.NumRows
+				for _, rowGroup := range reader.Footer.RowGroups {
+					require.Equal(t, rowGroup.NumRows, rowGroups[0].NumRows)
+					for index, chunk := range rowGroup.Columns {
+						copied := rowGroups[0].Columns[index]
+						start, length := pio.ChunkRange(chunk.MetaData)
+						newStart, newLength := pio.ChunkRange(copied.MetaData)
+						require.Equal(t, length, newLength)
+						require.Equal(t, sourceData[start:start+length], targetData[newStart:newStart+newLength])
+						if chunk.MetaData.BloomFilterOffset != nil {
+							offset, length := *copied.MetaData.BloomFilterOffset, int64(*copied.MetaData.BloomFilterLength)
+							require.Equal(t, sourceData[*chunk.MetaData.BloomFilterOffset:*chunk.MetaData.BloomFilterOffset+length], targetData[offset:offset+length])
+						}
+						if chunk.ColumnIndexOffset != nil {
+							offset, length := *copied.ColumnIndexOffset, int64(*copied.ColumnIndexLength)
+							require.Equal(t, sourceData[*chunk.ColumnIndexOffset:*chunk.ColumnIndexOffset+length], targetData[offset:offset+length])
+						}
+						require.Equal(t, chunk.OffsetIndexOffset == nil, copied.OffsetIndexOffset == nil)
+					}
+					rowGroups = rowGroups[1:]
+				}
+			}
+			require.Empty(t, rowGroups)
+			require.Equal(t, numRows, target.Footer.NumRows)
+
+			// pages in offset index moved with column chunks
+			for rowGroupIndex, rowGroup := range target.Footer.RowGroups {
+				require.Equal(t, int16(rowGroupIndex), *rowGroup.Ordinal)
+				require.Equal(t, rowGroup.Columns[0].MetaData.DataPageOffset, *rowGroup.FileOffset)
+				for _, chunk := range rowGroup.Columns {
+					if chunk.OffsetIndexOffset == nil {
+						continue
+					}
+					buf := targetData[*chunk.OffsetIndexOffset : *chunk.OffsetIndexOffset+int64(*chunk.OffsetIndexLength)]
+					mem := thrift.NewTMemoryBufferLen(len(buf))
+					_, _ = mem.Write(buf)
+					offsetIndex := parquet.NewOffsetIndex()
+					require.NoError(t, offsetIndex.Read(context.Background(), thrift.NewTCompactProtocolConf(mem, &thrift.TConfiguration{})))
+					require.Equal(t, chunk.MetaData.DataPageOffset, offsetIndex.PageLocations[0].Offset)
+				}
+			}
+		})
+	}
+}
+
 func TestWriterContextCancellation(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
 	writerChan := make(chan any)
 
-	err := pio.PipelineWriter(ctx, nil, writerChan, "test-target")
+	err := pio.PipelineWriter(ctx, nil, writerChan, "test-target", 0)
 	require.ErrorIs(t, err, context.Canceled)
 }
 
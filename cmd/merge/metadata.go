@@ -0,0 +1,51 @@
+package merge
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/reader"
+)
+
+// keyValueMetadata merges key-value metadata of all source files into metadata
+// of target file, keys are in order of their first appearance, and a key with
+// different values in source files is resolved by --metadata-conflict before
+// --remove-metadata and --set-metadata are applied.
+func (c Cmd) keyValueMetadata(fileReaders []*reader.ParquetReader) ([]*parquet.KeyValue, error) {
+	var merged []*parquet.KeyValue
+	positions := map[string]int{}
+	owners := map[string]int{}
+	conflicts := map[string]struct{}{}
+	for index, fileReader := range fileReaders {
+		for _, kv := range fileReader.Footer.KeyValueMetadata {
+			if kv == nil {
+				continue
+			}
+			position, found := positions[kv.Key]
+			if !found {
+				positions[kv.Key] = len(merged)
+				owners[kv.Key] = index
+				merged = append(merged, kv)
+				continue
+			}
+			if merged[position].Equals(kv) {
+				continue
+			}
+			switch c.MetadataConflict {
+			case "fail":
+				return nil, fmt.Errorf("key-value metadata [%s] of [%s] is different from [%s]", kv.Key, c.Source[index], c.Source[owners[kv.Key]])
+			case "last":
+				merged[position] = kv
+				owners[kv.Key] = index
+			case "drop":
+				conflicts[kv.Key] = struct{}{}
+			}
+		}
+	}
+	merged = slices.DeleteFunc(merged, func(kv *parquet.KeyValue) bool {
+		_, found := conflicts[kv.Key]
+		return found
+	})
+	return c.MetadataOption.KeyValueMetadata(merged)
+}
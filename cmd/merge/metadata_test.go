@@ -0,0 +1,82 @@
+package merge
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/reader"
+	"github.com/stretchr/testify/require"
+
+	pio "github.com/hangxie/parquet-tools/io"
+)
+
+func keyValues(pairs ...string) []*parquet.KeyValue {
+	var result []*parquet.KeyValue
+	for index := 0; index < len(pairs); index += 2 {
+		value := pairs[index+1]
+		result = append(result, &parquet.KeyValue{Key: pairs[index], Value: &value})
+	}
+	return result
+}
+
+func TestKeyValueMetadata(t *testing.T) {
+	fileReaders := []*reader.ParquetReader{
+		{Footer: &parquet.FileMetaData{KeyValueMetadata: keyValues("a", "1", "b", "2")}},
+		{Footer: &parquet.FileMetaData{}},
+		{Footer: &parquet.FileMetaData{KeyValueMetadata: keyValues("c", "3", "b", "x", "a", "1")}},
+	}
+	testCases := map[string]struct {
+		cmd      Cmd
+		expected []*parquet.KeyValue
+		errMsg   string
+	}{
+		"default": {Cmd{}, keyValues("a", "1", "b", "2", "c", "3"), ""},
+		"first":   {Cmd{MetadataConflict: "first"}, keyValues("a", "1", "b", "2", "c", "3"), ""},
+		"last":    {Cmd{MetadataConflict: "last"}, keyValues("a", "1", "b", "x", "c", "3"), ""},
+		"drop":    {Cmd{MetadataConflict: "drop"}, keyValues("a", "1", "c", "3"), ""},
+		"fail":    {Cmd{MetadataConflict: "fail"}, nil, "key-value metadata [b] of [s3] is different from [s1]"},
+		"set": {
+			Cmd{MetadataConflict: "drop", MetadataOption: pio.MetadataOption{SetMetadata: []string{"b=y", "d=4"}, RemoveMetadata: []string{"a"}}},
+			keyValues("c", "3", "b", "y", "d", "4"),
+			"",
+		},
+		"bad-set": {Cmd{MetadataOption: pio.MetadataOption{SetMetadata: []string{"b"}}}, nil, "invalid metadata [b], expected 'key=value'"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.cmd.Source = []string{"s1", "s2", "s3"}
+			metadata, err := tc.cmd.keyValueMetadata(fileReaders)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, metadata)
+		})
+	}
+}
+
+func TestCmdMetadata(t *testing.T) {
+	source := filepath.Join("..", "..", "testdata", "good.parquet")
+	cmd := Cmd{
+		Fast:           true,
+		ReadPageSize:   10,
+		Source:         []string{source, source},
+		URI:            filepath.Join(t.TempDir(), "metadata.parquet"),
+		MetadataOption: pio.MetadataOption{SetMetadata: []string{"owner=parquet-tools"}},
+	}
+	require.NoError(t, cmd.Run(context.Background()))
+
+	fileReader, err := pio.NewParquetFileReader(context.Background(), source, pio.ReadOption{})
+	require.NoError(t, err)
+	_ = fileReader.PFile.Close()
+	expected := append(fileReader.Footer.KeyValueMetadata, keyValues("owner", "parquet-tools")...)
+
+	target, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+	require.NoError(t, err)
+	_ = target.PFile.Close()
+	require.Equal(t, expected, target.Footer.KeyValueMetadata)
+}
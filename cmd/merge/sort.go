@@ -0,0 +1,170 @@
+package merge
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/reader"
+
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// sortColumn is a column in --sort-by, columnIndex is index of the leaf
+// column in target schema.
+type sortColumn struct {
+	path        []string
+	descending  bool
+	columnIndex int32
+}
+
+// sortColumns parses --sort-by against target schema.
+func (c Cmd) sortColumns(root *pschema.SchemaNode) ([]sortColumn, error) {
+	columns := make([]sortColumn, len(c.SortBy))
+	for index, spec := range c.SortBy {
+		path, descending, err := pschema.ParseSortBy(spec, c.FieldDelimiter)
+		if err != nil {
+			return nil, err
+		}
+		columns[index].path, columns[index].descending = path, descending
+		node, err := root.FindSortField(path, c.FieldDelimiter)
+		if err != nil {
+			return nil, err
+		}
+		columns[index].columnIndex = root.ColumnIndex(node)
+	}
+	return columns, nil
+}
+
+// sortingColumns is SortingColumns of target file.
+func sortingColumns(columns []sortColumn) []*parquet.SortingColumn {
+	result := make([]*parquet.SortingColumn, len(columns))
+	for index, column := range columns {
+		result[index] = pschema.NewSortingColumn(column.columnIndex, column.descending)
+	}
+	return result
+}
+
+// sortedSource reads rows of a source file one by one, with keys of the
+// current row.
+type sortedSource struct {
+	index      int
+	reader     *reader.ParquetReader
+	sourceKeys []pschema.SortKey
+	rows       []any
+	row        any
+	keys       []any
+}
+
+// next moves to the next row, it returns false at end of file.
+func (s *sortedSource) next(ctx context.Context, pageSize int) (bool, error) {
+	if len(s.rows) == 0 {
+		rows, err := s.reader.ReadByNumberWithContext(ctx, pageSize)
+		if err != nil {
+			return false, err
+		}
+		if len(rows) == 0 {
+			return false, nil
+		}
+		s.rows = rows
+	}
+	s.row, s.rows = s.rows[0], s.rows[1:]
+	s.keys = make([]any, len(s.sourceKeys))
+	for index, key := range s.sourceKeys {
+		s.keys[index] = key.Value(s.row)
+	}
+	return true, nil
+}
+
+// sourceHeap keeps the source with the smallest current row on top, sources
+// are in order of command line if they have the same keys.
+type sourceHeap struct {
+	sources []*sortedSource
+	columns []sortColumn
+}
+
+func (h *sourceHeap) compare(a, b []any) int {
+	for index, column := range h.columns {
+		result := pschema.CompareSortKeys(a[index], b[index])
+		if column.descending {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+func (h *sourceHeap) Len() int { return len(h.sources) }
+
+func (h *sourceHeap) Less(i, j int) bool {
+	if result := h.compare(h.sources[i].keys, h.sources[j].keys); result != 0 {
+		return result < 0
+	}
+	return h.sources[i].index < h.sources[j].index
+}
+
+func (h *sourceHeap) Swap(i, j int) { h.sources[i], h.sources[j] = h.sources[j], h.sources[i] }
+
+func (h *sourceHeap) Push(x any) { h.sources = append(h.sources, x.(*sortedSource)) }
+
+func (h *sourceHeap) Pop() any {
+	last := h.sources[len(h.sources)-1]
+	h.sources = h.sources[:len(h.sources)-1]
+	return last
+}
+
+// sortedReader sends rows of all source files to writerChan in order of
+// --sort-by, each source file needs to be sorted already.
+func (c Cmd) sortedReader(ctx context.Context, fileReaders []*reader.ParquetReader, schemas []*pschema.SchemaNode, columns []sortColumn, writerChan chan any) error {
+	sources := &sourceHeap{columns: columns}
+	for index, fileReader := range fileReaders {
+		source := &sortedSource{index: index, reader: fileReader, sourceKeys: make([]pschema.SortKey, len(columns))}
+		for columnIndex, column := range columns {
+			if node, err := schemas[index].FindSortField(column.path, c.FieldDelimiter); err == nil {
+				source.sourceKeys[columnIndex] = pschema.NewSortKey(node)
+			}
+		}
+		more, err := source.next(ctx, c.ReadPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to read from [%s]: %w", c.Source[index], err)
+		}
+		if more {
+			sources.sources = append(sources.sources, source)
+		}
+	}
+	heap.Init(sources)
+
+	for sources.Len() != 0 {
+		source := sources.sources[0]
+		row, keys := source.row, source.keys
+		if c.EvolveSchema {
+			var err error
+			if row, err = jsonRow(source.reader)(row); err != nil {
+				return fmt.Errorf("failed to convert row: %w", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case writerChan <- row:
+		}
+
+		more, err := source.next(ctx, c.ReadPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to read from [%s]: %w", c.Source[source.index], err)
+		}
+		if !more {
+			heap.Pop(sources)
+			continue
+		}
+		if sources.compare(source.keys, keys) < 0 {
+			return fmt.Errorf("[%s] is not sorted by [%s]", c.Source[source.index], strings.Join(c.SortBy, ","))
+		}
+		heap.Fix(sources, 0)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package merge
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/cat"
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func TestSortColumns(t *testing.T) {
+	fileReader, err := pio.NewParquetFileReader(context.Background(), "../../testdata/all-types.parquet", pio.ReadOption{})
+	require.NoError(t, err)
+	defer func() {
+		_ = fileReader.PFile.Close()
+	}()
+	schemaRoot, err := pschema.NewSchemaTree(context.Background(), fileReader, pschema.SchemaOption{})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		sortBy  []string
+		columns []sortColumn
+		errMsg  string
+	}{
+		"asc": {
+			sortBy:  []string{"Int64", "Utf8=asc"},
+			columns: []sortColumn{{path: []string{"Int64"}, columnIndex: 2}, {path: []string{"Utf8"}, columnIndex: 17}},
+		},
+		"desc": {
+			sortBy:  []string{"Uint_32=DESC", "Decimal1", "Uuid"},
+			columns: []sortColumn{{path: []string{"Uint_32"}, descending: true, columnIndex: 25}, {path: []string{"Decimal1"}, columnIndex: 40}, {path: []string{"Uuid"}, columnIndex: 9}},
+		},
+		"invalid-order": {sortBy: []string{"Int64=up"}, errMsg: "invalid sort order [up] of [Int64], needs to be ASC or DESC"},
+		"not-exist":     {sortBy: []string{"foo"}, errMsg: "field [foo] does not exist"},
+		"group":         {sortBy: []string{"Variant"}, errMsg: "field [Variant] is not a primitive field, cannot sort by it"},
+		"in-list":       {sortBy: []string{"List.list.element"}, errMsg: "field [List.list.element] does not exist"},
+		"int96":         {sortBy: []string{"Int96"}, errMsg: "field [Int96] is INT96, cannot sort by it"},
+		"json":          {sortBy: []string{"Json"}, errMsg: "field [Json] is JSON, cannot sort by it"},
+		"decimal":       {sortBy: []string{"Decimal3"}, errMsg: "field [Decimal3] is DECIMAL, cannot sort by it"},
+		"float16":       {sortBy: []string{"Float16Val"}, errMsg: "field [Float16Val] is FLOAT16, cannot sort by it"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			columns, err := Cmd{FieldDelimiter: ".", SortBy: tc.sortBy}.sortColumns(schemaRoot)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Equal(t, tc.errMsg, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.columns, columns)
+		})
+	}
+
+	sortingColumns := sortingColumns([]sortColumn{{columnIndex: 2}, {columnIndex: 1, descending: true}})
+	require.Len(t, sortingColumns, 2)
+	require.Equal(t, int32(2), sortingColumns[0].ColumnIdx)
+	require.False(t, sortingColumns[0].Descending)
+	require.True(t, sortingColumns[0].NullsFirst)
+	require.Equal(t, int32(1), sortingColumns[1].ColumnIdx)
+	require.True(t, sortingColumns[1].Descending)
+	require.False(t, sortingColumns[1].NullsFirst)
+}
+
+func TestCmdSortBy(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		testCases := map[string]struct {
+			cmd    Cmd
+			errMsg string
+		}{
+			"fast":       {Cmd{Fast: true, SortBy: []string{"Id"}}, "--sort-by cannot be used with --fast or --concurrent"},
+			"concurrent": {Cmd{Concurrent: true, SortBy: []string{"Id"}}, "--sort-by cannot be used with --fast or --concurrent"},
+			"not-exist":  {Cmd{SortBy: []string{"foo"}}, "field [foo] does not exist"},
+			"not-sorted": {Cmd{SortBy: []string{"Age"}}, "[../../testdata/sorting-col.parquet] is not sorted by [Age]"},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				tc.cmd.FieldDelimiter = "."
+				tc.cmd.ReadPageSize = 10
+				tc.cmd.Source = []string{"../../testdata/sorting-col.parquet", "../../testdata/sorting-col.parquet"}
+				tc.cmd.URI = filepath.Join(t.TempDir(), "sorted.parquet")
+				err := tc.cmd.Run(context.Background())
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+			})
+		}
+	})
+
+	t.Run("good", func(t *testing.T) {
+		cmd := Cmd{
+			FieldDelimiter: ".",
+			ReadPageSize:   3,
+			SortBy:         []string{"Id", "Name=DESC"},
+			Source:         []string{"../../testdata/sorting-col.parquet", "../../testdata/sorting-col.parquet"},
+			URI:            filepath.Join(t.TempDir(), "sorted.parquet"),
+		}
+		require.NoError(t, cmd.Run(context.Background()))
+		require.True(t, testutils.HasSameSchema(cmd.Source[0], cmd.URI))
+
+		fileReader, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+		require.NoError(t, err)
+		defer func() {
+			_ = fileReader.PFile.Close()
+		}()
+		for _, rowGroup := range fileReader.Footer.RowGroups {
+			require.Len(t, rowGroup.SortingColumns, 2)
+			require.Equal(t, int32(2), rowGroup.SortingColumns[0].ColumnIdx)
+			require.False(t, rowGroup.SortingColumns[0].Descending)
+			require.Equal(t, int32(0), rowGroup.SortingColumns[1].ColumnIdx)
+			require.True(t, rowGroup.SortingColumns[1].Descending)
+		}
+
+		catCmd := cat.Cmd{ReadPageSize: 1000, SampleRatio: 1.0, Format: "jsonl", GeoFormat: "geojson", URI: cmd.URI}
+		stdout, _ := testutils.CaptureStdoutStderr(func() {
+			require.NoError(t, catCmd.Run(context.Background()))
+		})
+		var ids []int64
+		var names []string
+		decoder := json.NewDecoder(strings.NewReader(stdout))
+		for decoder.More() {
+			var row struct {
+				Id   int64
+				Name string
+			}
+			require.NoError(t, decoder.Decode(&row))
+			ids = append(ids, row.Id)
+			names = append(names, row.Name)
+		}
+		require.Len(t, ids, 40)
+		for index := 1; index < len(ids); index++ {
+			require.LessOrEqual(t, ids[index-1], ids[index])
+			if ids[index-1] == ids[index] {
+				require.GreaterOrEqual(t, names[index-1], names[index])
+			}
+		}
+	})
+}
@@ -0,0 +1,535 @@
+package retype
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hangxie/parquet-go/v3/common"
+	"github.com/hangxie/parquet-go/v3/parquet"
+
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// decimalTarget matches DECIMAL(precision,scale) of --cast.
+var decimalTarget = regexp.MustCompile(`^DECIMAL\(\s*(\d+)\s*,\s*(\d+)\s*\)$`)
+
+// timeUnitNanos is number of nanoseconds in a TIMESTAMP unit.
+var timeUnitNanos = map[string]int64{
+	"MILLIS": int64(time.Millisecond),
+	"MICROS": int64(time.Microsecond),
+	"NANOS":  1,
+}
+
+// castTarget is TARGET of --cast field.path=TARGET, layout is only for casts
+// from strings to DATE and TIMESTAMP.
+type castTarget struct {
+	spec      string
+	name      string
+	unit      string
+	precision int32
+	scale     int32
+	layout    string
+	hasLayout bool
+}
+
+// parseCastTarget parses INT32, INT64, FLOAT, DOUBLE, DECIMAL(precision,scale),
+// DATE[:layout], and TIMESTAMP_MILLIS/MICROS/NANOS[:layout], layout is a Go
+// time layout.
+func parseCastTarget(spec string) (castTarget, error) {
+	spec = strings.TrimSpace(spec)
+	name, layout, hasLayout := strings.Cut(spec, ":")
+	target := castTarget{spec: spec, name: strings.ToUpper(strings.TrimSpace(name)), layout: layout, hasLayout: hasLayout}
+	switch target.name {
+	case "INT32", "INT64", "FLOAT", "DOUBLE":
+	case "DATE":
+		if !hasLayout {
+			target.layout = time.DateOnly
+		}
+	case "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS", "TIMESTAMP_NANOS":
+		target.name, target.unit, _ = strings.Cut(target.name, "_")
+		if !hasLayout {
+			target.layout = time.RFC3339Nano
+		}
+	default:
+		matches := decimalTarget.FindStringSubmatch(target.name)
+		if matches == nil {
+			return castTarget{}, fmt.Errorf("unknown target type [%s], expected INT32, INT64, FLOAT, DOUBLE, DECIMAL(precision,scale), DATE, TIMESTAMP_MILLIS, TIMESTAMP_MICROS, or TIMESTAMP_NANOS", name)
+		}
+		precision, err := strconv.ParseInt(matches[1], 10, 32)
+		if err != nil || precision < 1 {
+			return castTarget{}, fmt.Errorf("invalid precision [%s], needs to be at least 1", matches[1])
+		}
+		scale, err := strconv.ParseInt(matches[2], 10, 32)
+		if err != nil || scale > precision {
+			return castTarget{}, fmt.Errorf("invalid scale [%s], needs to be between 0 and precision", matches[2])
+		}
+		target.name, target.precision, target.scale = "DECIMAL", int32(precision), int32(scale)
+	}
+	if hasLayout && target.name != "DATE" && target.name != "TIMESTAMP" {
+		return castTarget{}, fmt.Errorf("layout only applies to DATE and TIMESTAMP")
+	}
+	return target, nil
+}
+
+// castRules returns a rule for each --cast, rules match fields of root by
+// node, so they need to be applied before any other rule changes root.
+func (c Cmd) castRules(root *pschema.SchemaNode) ([]*RetypeRule, error) {
+	names := map[string]int{}
+	for _, node := range root.GetPathMap() {
+		if node != root && len(node.InNamePath) != 0 {
+			names[node.InNamePath[len(node.InNamePath)-1]]++
+		}
+	}
+
+	rules := make([]*RetypeRule, 0, len(c.Cast))
+	cast := map[*pschema.SchemaNode]struct{}{}
+	for _, spec := range c.Cast {
+		rawFieldPath, rawTarget, found := strings.Cut(spec, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid cast format [%s], expected 'field.path=TARGET'", spec)
+		}
+		fieldPath := strings.TrimSpace(rawFieldPath)
+		if fieldPath == "" {
+			return nil, fmt.Errorf("empty field path in [%s]", spec)
+		}
+		target, err := parseCastTarget(rawTarget)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cast [%s]: %w", spec, err)
+		}
+
+		node := findField(root, common.StrToPath(pio.NormalizeFieldPath(fieldPath, c.FieldDelimiter)))
+		if node == nil {
+			return nil, fmt.Errorf("field [%s] does not exist", fieldPath)
+		}
+		if node.Type == nil {
+			return nil, fmt.Errorf("field [%s] is not a primitive field, cannot cast it", fieldPath)
+		}
+		if node.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+			return nil, fmt.Errorf("field [%s] is repeated, cannot cast it", fieldPath)
+		}
+		if _, found := cast[node]; found {
+			return nil, fmt.Errorf("field [%s] is cast more than once", fieldPath)
+		}
+		cast[node] = struct{}{}
+		// Converter finds fields by name, a field cannot be told apart from
+		// other fields with the same name
+		if names[node.InNamePath[len(node.InNamePath)-1]] > 1 {
+			return nil, fmt.Errorf("field [%s] cannot be cast as other fields have the same name", fieldPath)
+		}
+
+		rule, err := newCastRule(node, target)
+		if err != nil {
+			return nil, fmt.Errorf("field [%s] cannot be cast to [%s]: %w", fieldPath, target.spec, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// findField returns node of path of external names, or nil if there is no
+// such field.
+func findField(root *pschema.SchemaNode, path []string) *pschema.SchemaNode {
+	node := root
+	for _, name := range path {
+		var child *pschema.SchemaNode
+		for _, candidate := range node.Children {
+			if candidate.Name == name {
+				child = candidate
+				break
+			}
+		}
+		if child == nil {
+			return nil
+		}
+		node = child
+	}
+	return node
+}
+
+// castSchema is schema of a field after cast.
+type castSchema struct {
+	physical      parquet.Type
+	typeLength    *int32
+	logicalType   *parquet.LogicalType
+	convertedType *parquet.ConvertedType
+	scale         *int32
+	precision     *int32
+}
+
+func (s castSchema) apply(node *pschema.SchemaNode) {
+	if *node.Type != s.physical {
+		// encoding of source type may not apply to target type
+		node.Encoding = ""
+	}
+	node.Type = new(s.physical)
+	node.TypeLength = s.typeLength
+	node.LogicalType = s.logicalType
+	node.ConvertedType = s.convertedType
+	node.Scale = s.scale
+	node.Precision = s.precision
+}
+
+// newCastRule returns a rule that only matches node, and casts it to target.
+func newCastRule(node *pschema.SchemaNode, target castTarget) (*RetypeRule, error) {
+	var schema castSchema
+	var convert func(any) (any, error)
+	var err error
+	switch target.name {
+	case "INT32", "INT64":
+		schema, convert, err = integerCast(node, target)
+	case "FLOAT", "DOUBLE":
+		schema, convert, err = floatCast(node, target)
+	case "DECIMAL":
+		schema, convert, err = decimalCast(node, target)
+	case "DATE":
+		schema, convert, err = dateCast(node, target)
+	case "TIMESTAMP":
+		schema, convert, err = timestampCast(node, target)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &RetypeRule{
+		Name: "cast",
+		MatchSchema: func(current, parent *pschema.SchemaNode) bool {
+			return current == node
+		},
+		TransformSchema: schema.apply,
+		ConvertData:     convert,
+		TargetType:      physicalGoType(schema.physical),
+		InputKind:       physicalGoType(*node.Type).Kind(),
+	}, nil
+}
+
+// physicalGoType is Go type of values of a physical type.
+func physicalGoType(physical parquet.Type) reflect.Type {
+	switch physical {
+	case parquet.Type_INT32:
+		return reflect.TypeFor[int32]()
+	case parquet.Type_INT64:
+		return reflect.TypeFor[int64]()
+	case parquet.Type_FLOAT:
+		return reflect.TypeFor[float32]()
+	case parquet.Type_DOUBLE:
+		return reflect.TypeFor[float64]()
+	case parquet.Type_BOOLEAN:
+		return reflect.TypeFor[bool]()
+	}
+	return reflect.TypeFor[string]()
+}
+
+// typeName describes type of node in errors.
+func typeName(node *pschema.SchemaNode) string {
+	tagMap := node.GetTagMap()
+	if logicalType, found := tagMap["logicaltype"]; found {
+		return tagMap["type"] + "/" + logicalType
+	}
+	if convertedType, found := tagMap["convertedtype"]; found {
+		return tagMap["type"] + "/" + convertedType
+	}
+	return tagMap["type"]
+}
+
+// integerCast casts integers, including unsigned ones, to plain INT32 or
+// INT64, values that do not fit are errors.
+func integerCast(node *pschema.SchemaNode, target castTarget) (castSchema, func(any) (any, error), error) {
+	integer, signed := node.IntegerAnnotation()
+	if !integer || (*node.Type != parquet.Type_INT32 && *node.Type != parquet.Type_INT64) {
+		return castSchema{}, nil, fmt.Errorf("%s is not an integer type", typeName(node))
+	}
+	schema := castSchema{physical: parquet.Type_INT64}
+	if target.name == "INT32" {
+		schema.physical = parquet.Type_INT32
+	}
+	return schema, func(value any) (any, error) {
+		var number int64
+		switch v := value.(type) {
+		case int32:
+			number = int64(v)
+			if !signed {
+				number = int64(uint32(v))
+			}
+		case int64:
+			number = v
+			if !signed && v < 0 {
+				return nil, fmt.Errorf("value %d overflows %s", uint64(v), target.name)
+			}
+		default:
+			return nil, fmt.Errorf("expected integer, got %T", value)
+		}
+		if schema.physical == parquet.Type_INT64 {
+			return number, nil
+		}
+		if number < math.MinInt32 || number > math.MaxInt32 {
+			return nil, fmt.Errorf("value %d overflows %s", number, target.name)
+		}
+		return int32(number), nil
+	}, nil
+}
+
+// floatCast casts FLOAT and DOUBLE to each other, finite DOUBLE values out of
+// range of FLOAT are errors.
+func floatCast(node *pschema.SchemaNode, target castTarget) (castSchema, func(any) (any, error), error) {
+	if (*node.Type != parquet.Type_FLOAT && *node.Type != parquet.Type_DOUBLE) || node.LogicalType != nil {
+		return castSchema{}, nil, fmt.Errorf("%s is not a floating point type", typeName(node))
+	}
+	schema := castSchema{physical: parquet.Type_DOUBLE}
+	if target.name == "FLOAT" {
+		schema.physical = parquet.Type_FLOAT
+	}
+	return schema, func(value any) (any, error) {
+		var number float64
+		switch v := value.(type) {
+		case float32:
+			number = float64(v)
+		case float64:
+			number = v
+		default:
+			return nil, fmt.Errorf("expected floating point number, got %T", value)
+		}
+		if schema.physical == parquet.Type_DOUBLE {
+			return number, nil
+		}
+		if !math.IsInf(number, 0) && math.Abs(number) > math.MaxFloat32 {
+			return nil, fmt.Errorf("value %g overflows %s", number, target.name)
+		}
+		return float32(number), nil
+	}, nil
+}
+
+// decimalOf returns precision and scale of a DECIMAL field.
+func decimalOf(node *pschema.SchemaNode) (int32, int32, bool) {
+	if node.LogicalType != nil && node.LogicalType.IsSetDECIMAL() {
+		return node.LogicalType.DECIMAL.Precision, node.LogicalType.DECIMAL.Scale, true
+	}
+	if node.ConvertedType != nil && *node.ConvertedType == parquet.ConvertedType_DECIMAL {
+		return node.GetPrecision(), node.GetScale(), true
+	}
+	return 0, 0, false
+}
+
+// decimalCast changes precision and scale of DECIMAL, physical type is kept
+// if it can hold the new precision, otherwise it becomes INT64 or BYTE_ARRAY.
+// Values that do not fit the new precision, or lose digits because of a smaller
+// scale, are errors.
+func decimalCast(node *pschema.SchemaNode, target castTarget) (castSchema, func(any) (any, error), error) {
+	_, sourceScale, found := decimalOf(node)
+	if !found {
+		return castSchema{}, nil, fmt.Errorf("%s is not DECIMAL", typeName(node))
+	}
+
+	schema := castSchema{
+		physical:      *node.Type,
+		logicalType:   &parquet.LogicalType{DECIMAL: &parquet.DecimalType{Precision: target.precision, Scale: target.scale}},
+		convertedType: new(parquet.ConvertedType_DECIMAL),
+		scale:         new(target.scale),
+		precision:     new(target.precision),
+	}
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(target.precision)), nil)
+	switch {
+	case schema.physical == parquet.Type_INT32 && target.precision <= 9:
+	case schema.physical == parquet.Type_FIXED_LEN_BYTE_ARRAY && limit.BitLen() < int(node.GetTypeLength())*8:
+		schema.typeLength = new(node.GetTypeLength())
+	case schema.physical == parquet.Type_BYTE_ARRAY:
+	case target.precision <= 18:
+		schema.physical = parquet.Type_INT64
+	default:
+		schema.physical = parquet.Type_BYTE_ARRAY
+	}
+
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(max(target.scale-sourceScale, sourceScale-target.scale))), nil)
+	return schema, func(value any) (any, error) {
+		unscaled, err := decodeDecimal(value)
+		if err != nil {
+			return nil, err
+		}
+		result := new(big.Int).Set(unscaled)
+		if target.scale >= sourceScale {
+			result.Mul(result, factor)
+		} else if _, remainder := result.QuoRem(result, factor, new(big.Int)); remainder.Sign() != 0 {
+			return nil, fmt.Errorf("value %s loses digits with scale %d", formatDecimal(unscaled, sourceScale), target.scale)
+		}
+		if new(big.Int).Abs(result).Cmp(limit) >= 0 {
+			return nil, fmt.Errorf("value %s overflows DECIMAL(%d,%d)", formatDecimal(unscaled, sourceScale), target.precision, target.scale)
+		}
+		return encodeDecimal(result, schema)
+	}, nil
+}
+
+// decodeDecimal returns unscaled value of a DECIMAL, byte arrays are in
+// big-endian two's complement.
+func decodeDecimal(value any) (*big.Int, error) {
+	switch v := value.(type) {
+	case int32:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case string:
+		result := new(big.Int).SetBytes([]byte(v))
+		if len(v) != 0 && v[0]&0x80 != 0 {
+			result.Sub(result, new(big.Int).Lsh(big.NewInt(1), uint(len(v))*8))
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("expected DECIMAL value, got %T", value)
+}
+
+// encodeDecimal returns value of unscaled in physical type of schema.
+func encodeDecimal(unscaled *big.Int, schema castSchema) (any, error) {
+	switch schema.physical {
+	case parquet.Type_INT32:
+		return int32(unscaled.Int64()), nil
+	case parquet.Type_INT64:
+		return unscaled.Int64(), nil
+	}
+	length := unscaled.BitLen()/8 + 1
+	if schema.typeLength != nil {
+		length = int(*schema.typeLength)
+	}
+	twosComplement := new(big.Int).Mod(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(length)*8))
+	return string(twosComplement.FillBytes(make([]byte, length))), nil
+}
+
+// formatDecimal formats unscaled value of a DECIMAL with scale.
+func formatDecimal(unscaled *big.Int, scale int32) string {
+	denominator := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	return new(big.Rat).SetFrac(unscaled, denominator).FloatString(int(scale))
+}
+
+// isString returns if node is a plain or STRING annotated BYTE_ARRAY.
+func isString(node *pschema.SchemaNode) bool {
+	return *node.Type == parquet.Type_BYTE_ARRAY &&
+		(node.LogicalType == nil || node.LogicalType.IsSetSTRING()) &&
+		(node.ConvertedType == nil || *node.ConvertedType == parquet.ConvertedType_UTF8)
+}
+
+// parseTime parses value of a string field with layout.
+func parseTime(value any, layout string) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected string, got %T", value)
+	}
+	parsed, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse [%s] with layout [%s]: %w", s, layout, err)
+	}
+	return parsed, nil
+}
+
+// dateCast parses strings to DATE, time of day and time zone in strings are
+// ignored.
+func dateCast(node *pschema.SchemaNode, target castTarget) (castSchema, func(any) (any, error), error) {
+	if !isString(node) {
+		return castSchema{}, nil, fmt.Errorf("%s is not a string type", typeName(node))
+	}
+	schema := castSchema{
+		physical:    parquet.Type_INT32,
+		logicalType: &parquet.LogicalType{DATE: &parquet.DateType{}},
+	}
+	return schema, func(value any) (any, error) {
+		parsed, err := parseTime(value, target.layout)
+		if err != nil {
+			return nil, err
+		}
+		days := time.Date(parsed.Year(), parsed.Month(), parsed.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+		return int32(days), nil
+	}, nil
+}
+
+// timestampOf returns unit of a TIMESTAMP field and whether it is adjusted to
+// UTC.
+func timestampOf(node *pschema.SchemaNode) (string, bool, bool) {
+	if node.LogicalType != nil && node.LogicalType.IsSetTIMESTAMP() {
+		timestamp := node.LogicalType.TIMESTAMP
+		switch {
+		case timestamp.Unit.IsSetMILLIS():
+			return "MILLIS", timestamp.IsAdjustedToUTC, true
+		case timestamp.Unit.IsSetMICROS():
+			return "MICROS", timestamp.IsAdjustedToUTC, true
+		case timestamp.Unit.IsSetNANOS():
+			return "NANOS", timestamp.IsAdjustedToUTC, true
+		}
+	}
+	if node.ConvertedType != nil {
+		switch *node.ConvertedType {
+		case parquet.ConvertedType_TIMESTAMP_MILLIS:
+			return "MILLIS", true, true
+		case parquet.ConvertedType_TIMESTAMP_MICROS:
+			return "MICROS", true, true
+		}
+	}
+	return "", false, false
+}
+
+// timestampCast changes unit of TIMESTAMP, or parses strings to TIMESTAMP
+// adjusted to UTC. A finer unit overflows for times far from 1970, and a
+// coarser unit truncates values toward the past.
+func timestampCast(node *pschema.SchemaNode, target castTarget) (castSchema, func(any) (any, error), error) {
+	unit := &parquet.TimeUnit{}
+	switch target.unit {
+	case "MILLIS":
+		unit.MILLIS = &parquet.MilliSeconds{}
+	case "MICROS":
+		unit.MICROS = &parquet.MicroSeconds{}
+	case "NANOS":
+		unit.NANOS = &parquet.NanoSeconds{}
+	}
+	schema := castSchema{
+		physical:    parquet.Type_INT64,
+		logicalType: &parquet.LogicalType{TIMESTAMP: &parquet.TimestampType{IsAdjustedToUTC: true, Unit: unit}},
+	}
+	targetNanos := timeUnitNanos[target.unit]
+
+	if isString(node) {
+		return schema, func(value any) (any, error) {
+			parsed, err := parseTime(value, target.layout)
+			if err != nil {
+				return nil, err
+			}
+			seconds := big.NewInt(parsed.Unix())
+			result := seconds.Mul(seconds, big.NewInt(int64(time.Second)/targetNanos))
+			result.Add(result, big.NewInt(int64(parsed.Nanosecond())/targetNanos))
+			if !result.IsInt64() {
+				return nil, fmt.Errorf("value [%s] overflows TIMESTAMP_%s", value, target.unit)
+			}
+			return result.Int64(), nil
+		}, nil
+	}
+
+	sourceUnit, adjusted, found := timestampOf(node)
+	if !found || *node.Type != parquet.Type_INT64 {
+		return castSchema{}, nil, fmt.Errorf("%s is not a string or TIMESTAMP type", typeName(node))
+	}
+	if target.hasLayout {
+		return castSchema{}, nil, fmt.Errorf("layout only applies to string fields")
+	}
+	schema.logicalType.TIMESTAMP.IsAdjustedToUTC = adjusted
+	sourceNanos := timeUnitNanos[sourceUnit]
+	return schema, func(value any) (any, error) {
+		v, ok := value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("expected int64, got %T", value)
+		}
+		if sourceNanos <= targetNanos {
+			ratio := targetNanos / sourceNanos
+			result := v / ratio
+			if v%ratio < 0 {
+				result--
+			}
+			return result, nil
+		}
+		ratio := sourceNanos / targetNanos
+		if v > math.MaxInt64/ratio || v < math.MinInt64/ratio {
+			return nil, fmt.Errorf("value %d overflows TIMESTAMP_%s", v, target.unit)
+		}
+		return v * ratio, nil
+	}, nil
+}
@@ -0,0 +1,257 @@
+package retype
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/stretchr/testify/require"
+
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func TestParseCastTarget(t *testing.T) {
+	testCases := map[string]struct {
+		spec     string
+		expected castTarget
+		errMsg   string
+	}{
+		"int32":            {"INT32", castTarget{spec: "INT32", name: "INT32"}, ""},
+		"lower-case":       {" double ", castTarget{spec: "double", name: "DOUBLE"}, ""},
+		"decimal":          {"DECIMAL(20, 4)", castTarget{spec: "DECIMAL(20, 4)", name: "DECIMAL", precision: 20, scale: 4}, ""},
+		"date":             {"DATE", castTarget{spec: "DATE", name: "DATE", layout: time.DateOnly}, ""},
+		"date-layout":      {"date:01/02/2006", castTarget{spec: "date:01/02/2006", name: "DATE", layout: "01/02/2006", hasLayout: true}, ""},
+		"timestamp":        {"TIMESTAMP_MICROS", castTarget{spec: "TIMESTAMP_MICROS", name: "TIMESTAMP", unit: "MICROS", layout: time.RFC3339Nano}, ""},
+		"timestamp-layout": {"TIMESTAMP_MILLIS:2006-01-02 15:04:05", castTarget{spec: "TIMESTAMP_MILLIS:2006-01-02 15:04:05", name: "TIMESTAMP", unit: "MILLIS", layout: "2006-01-02 15:04:05", hasLayout: true}, ""},
+		"unknown":          {"TIME_MILLIS", castTarget{}, "unknown target type [TIME_MILLIS]"},
+		"decimal-format":   {"DECIMAL(10)", castTarget{}, "unknown target type [DECIMAL(10)]"},
+		"zero-precision":   {"DECIMAL(0,0)", castTarget{}, "invalid precision [0], needs to be at least 1"},
+		"large-scale":      {"DECIMAL(5,6)", castTarget{}, "invalid scale [6], needs to be between 0 and precision"},
+		"layout-int":       {"INT64:2006", castTarget{}, "layout only applies to DATE and TIMESTAMP"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			target, err := parseCastTarget(tc.spec)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, target)
+		})
+	}
+}
+
+func allTypesSchema(t *testing.T) *pschema.SchemaNode {
+	fileReader, err := pio.NewParquetFileReader(context.Background(), "../../testdata/all-types.parquet", pio.ReadOption{})
+	require.NoError(t, err)
+	defer func() {
+		_ = fileReader.PFile.Close()
+	}()
+	schemaRoot, err := pschema.NewSchemaTree(context.Background(), fileReader, pschema.SchemaOption{})
+	require.NoError(t, err)
+	return schemaRoot
+}
+
+func TestCastRules(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		testCases := map[string]struct {
+			cast   []string
+			errMsg string
+		}{
+			"format":       {[]string{"Int32"}, "invalid cast format [Int32], expected 'field.path=TARGET'"},
+			"empty-path":   {[]string{"=INT64"}, "empty field path in [=INT64]"},
+			"target":       {[]string{"Int32=INT16"}, "invalid cast [Int32=INT16]: unknown target type [INT16]"},
+			"not-exist":    {[]string{"NoSuchField=INT64"}, "field [NoSuchField] does not exist"},
+			"group":        {[]string{"Map=INT64"}, "field [Map] is not a primitive field, cannot cast it"},
+			"repeated":     {[]string{"Repeated=INT64"}, "field [Repeated] is repeated, cannot cast it"},
+			"twice":        {[]string{"Int32=INT64", "Int32=INT32"}, "field [Int32] is cast more than once"},
+			"same-name":    {[]string{"List.list.element=DATE"}, "field [List.list.element] cannot be cast as other fields have the same name"},
+			"not-integer":  {[]string{"Utf8=INT64"}, "field [Utf8] cannot be cast to [INT64]: BYTE_ARRAY/STRING is not an integer type"},
+			"not-float":    {[]string{"Float16Val=DOUBLE"}, "field [Float16Val] cannot be cast to [DOUBLE]: FIXED_LEN_BYTE_ARRAY/FLOAT16 is not a floating point type"},
+			"not-decimal":  {[]string{"Int64=DECIMAL(10,2)"}, "field [Int64] cannot be cast to [DECIMAL(10,2)]: INT64 is not DECIMAL"},
+			"not-string":   {[]string{"Json=DATE"}, "field [Json] cannot be cast to [DATE]: BYTE_ARRAY/JSON is not a string type"},
+			"not-time":     {[]string{"TimeMicros=TIMESTAMP_MILLIS"}, "field [TimeMicros] cannot be cast to [TIMESTAMP_MILLIS]: INT64/TIME is not a string or TIMESTAMP type"},
+			"time-layout":  {[]string{"TimestampMillis=TIMESTAMP_MICROS:2006"}, "field [TimestampMillis] cannot be cast to [TIMESTAMP_MICROS:2006]: layout only applies to string fields"},
+			"uint-decimal": {[]string{"Uint_8=DECIMAL(3,0)"}, "field [Uint_8] cannot be cast to [DECIMAL(3,0)]: INT32/INTEGER is not DECIMAL"},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				_, err := Cmd{Cast: tc.cast}.castRules(allTypesSchema(t))
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+			})
+		}
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		testCases := map[string]struct {
+			cast     string
+			field    string
+			expected map[string]string
+		}{
+			"int32-to-int64": {"Int32=INT64", "Int32", map[string]string{"type": "INT64"}},
+			"uint-to-int32":  {"Uint_16=INT32", "Uint_16", map[string]string{"type": "INT32"}},
+			"float":          {"Double=FLOAT", "Double", map[string]string{"type": "FLOAT"}},
+			"decimal-int32": {"Decimal1=DECIMAL(9,4)", "Decimal1", map[string]string{
+				"type": "INT32", "logicaltype": "DECIMAL", "logicaltype.precision": "9", "logicaltype.scale": "4",
+				"convertedtype": "DECIMAL", "precision": "9", "scale": "4",
+			}},
+			"decimal-widen": {"Decimal1=DECIMAL(12,2)", "Decimal1", map[string]string{"type": "INT64", "logicaltype": "DECIMAL", "logicaltype.precision": "12", "convertedtype": "DECIMAL"}},
+			"decimal-fixed": {"Decimal3=DECIMAL(12,2)", "Decimal3", map[string]string{"type": "FIXED_LEN_BYTE_ARRAY", "length": "12", "logicaltype": "DECIMAL", "convertedtype": "DECIMAL"}},
+			"decimal-bytes": {"Decimal2=DECIMAL(30,2)", "Decimal2", map[string]string{"type": "BYTE_ARRAY", "logicaltype": "DECIMAL", "logicaltype.precision": "30", "convertedtype": "DECIMAL"}},
+			"date":          {"Utf8=DATE", "Utf8", map[string]string{"type": "INT32", "logicaltype": "DATE"}},
+			"string-timestamp": {"ByteArray=TIMESTAMP_MILLIS", "ByteArray", map[string]string{
+				"type": "INT64", "logicaltype": "TIMESTAMP", "logicaltype.unit": "MILLIS", "logicaltype.isadjustedtoutc": "true",
+			}},
+			"timestamp-unit": {"TimestampNanos2=TIMESTAMP_MICROS", "TimestampNanos2", map[string]string{
+				"type": "INT64", "logicaltype": "TIMESTAMP", "logicaltype.unit": "MICROS", "logicaltype.isadjustedtoutc": "false",
+			}},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				root := allTypesSchema(t)
+				rules, err := Cmd{Cast: []string{tc.cast}}.castRules(root)
+				require.NoError(t, err)
+				require.Len(t, rules, 1)
+				matched := applyRule(root, nil, rules[0].MatchSchema, rules[0].TransformSchema)
+				require.Equal(t, map[string]struct{}{tc.field: {}}, matched)
+
+				tagMap := findField(root, []string{tc.field}).GetTagMap()
+				for key, value := range tc.expected {
+					require.Equal(t, value, tagMap[key], key)
+				}
+				if tc.expected["logicaltype"] == "" {
+					require.NotContains(t, tagMap, "logicaltype")
+				}
+				if tc.expected["convertedtype"] == "" {
+					require.NotContains(t, tagMap, "convertedtype")
+				}
+			})
+		}
+	})
+}
+
+func TestCastConvertData(t *testing.T) {
+	node := func(physical parquet.Type, logicalType *parquet.LogicalType, convertedType *parquet.ConvertedType) *pschema.SchemaNode {
+		return &pschema.SchemaNode{SchemaElement: parquet.SchemaElement{
+			Type:          new(physical),
+			LogicalType:   logicalType,
+			ConvertedType: convertedType,
+			TypeLength:    new(int32(5)),
+		}}
+	}
+	decimal := func(physical parquet.Type, precision, scale int32) *pschema.SchemaNode {
+		return node(physical, &parquet.LogicalType{DECIMAL: &parquet.DecimalType{Precision: precision, Scale: scale}}, new(parquet.ConvertedType_DECIMAL))
+	}
+	timestamp := func(unit *parquet.TimeUnit) *pschema.SchemaNode {
+		return node(parquet.Type_INT64, &parquet.LogicalType{TIMESTAMP: &parquet.TimestampType{IsAdjustedToUTC: true, Unit: unit}}, nil)
+	}
+	plainInt32 := node(parquet.Type_INT32, nil, nil)
+	plainInt64 := node(parquet.Type_INT64, nil, nil)
+	uint32Node := node(parquet.Type_INT32, &parquet.LogicalType{INTEGER: &parquet.IntType{BitWidth: 32, IsSigned: false}}, nil)
+	uint64Node := node(parquet.Type_INT64, nil, new(parquet.ConvertedType_UINT_64))
+	stringNode := node(parquet.Type_BYTE_ARRAY, &parquet.LogicalType{STRING: &parquet.StringType{}}, nil)
+	millis := timestamp(&parquet.TimeUnit{MILLIS: &parquet.MilliSeconds{}})
+	nanos := timestamp(&parquet.TimeUnit{NANOS: &parquet.NanoSeconds{}})
+
+	testCases := map[string]struct {
+		node     *pschema.SchemaNode
+		target   string
+		value    any
+		expected any
+		errMsg   string
+	}{
+		"int32-to-int64":      {plainInt32, "INT64", int32(-7), int64(-7), ""},
+		"int64-to-int32":      {plainInt64, "INT32", int64(math.MinInt32), int32(math.MinInt32), ""},
+		"int64-overflow":      {plainInt64, "INT32", int64(math.MaxInt32 + 1), nil, "value 2147483648 overflows INT32"},
+		"uint32-to-int64":     {uint32Node, "INT64", int32(-1), int64(math.MaxUint32), ""},
+		"uint32-overflow":     {uint32Node, "INT32", int32(-1), nil, "value 4294967295 overflows INT32"},
+		"uint64-overflow":     {uint64Node, "INT64", int64(-1), nil, "value 18446744073709551615 overflows INT64"},
+		"int-type":            {plainInt32, "INT64", "1", nil, "expected integer, got string"},
+		"float-to-double":     {node(parquet.Type_FLOAT, nil, nil), "DOUBLE", float32(1.5), float64(1.5), ""},
+		"double-to-float":     {node(parquet.Type_DOUBLE, nil, nil), "FLOAT", 2.5, float32(2.5), ""},
+		"double-infinity":     {node(parquet.Type_DOUBLE, nil, nil), "FLOAT", math.Inf(-1), float32(math.Inf(-1)), ""},
+		"double-overflow":     {node(parquet.Type_DOUBLE, nil, nil), "FLOAT", 1e39, nil, "value 1e+39 overflows FLOAT"},
+		"float-type":          {node(parquet.Type_FLOAT, nil, nil), "DOUBLE", 1, nil, "expected floating point number, got int"},
+		"decimal-scale-up":    {decimal(parquet.Type_INT32, 5, 2), "DECIMAL(9,4)", int32(-12345), int32(-1234500), ""},
+		"decimal-scale-down":  {decimal(parquet.Type_INT64, 10, 2), "DECIMAL(10,0)", int64(-12300), int64(-123), ""},
+		"decimal-lose-digits": {decimal(parquet.Type_INT64, 10, 2), "DECIMAL(10,1)", int64(12345), nil, "value 123.45 loses digits with scale 1"},
+		"decimal-overflow":    {decimal(parquet.Type_INT32, 9, 2), "DECIMAL(4,2)", int32(-12345), nil, "value -123.45 overflows DECIMAL(4,2)"},
+		"decimal-to-int64":    {decimal(parquet.Type_INT32, 9, 0), "DECIMAL(12,3)", int32(7), int64(7000), ""},
+		"decimal-from-bytes":  {decimal(parquet.Type_BYTE_ARRAY, 20, 2), "DECIMAL(18,2)", "\xff\x85", "\x85", ""},
+		"decimal-to-bytes":    {decimal(parquet.Type_INT64, 18, 0), "DECIMAL(20,0)", int64(-129), "\xff\x7f", ""},
+		"decimal-to-fixed":    {decimal(parquet.Type_FIXED_LEN_BYTE_ARRAY, 10, 0), "DECIMAL(11,1)", "\x00\x00\x00\x00\x80", "\x00\x00\x00\x05\x00", ""},
+		"decimal-type":        {decimal(parquet.Type_INT32, 9, 0), "DECIMAL(9,0)", 1.5, nil, "expected DECIMAL value, got float64"},
+		"date":                {stringNode, "DATE", "1970-01-02", int32(1), ""},
+		"date-before-epoch":   {stringNode, "DATE", "1969-12-31", int32(-1), ""},
+		"date-layout":         {stringNode, "DATE:01/02/2006 15:04", "01/03/1970 23:59", int32(2), ""},
+		"date-bad-value":      {stringNode, "DATE", "1970/01/02", nil, "failed to parse [1970/01/02] with layout [2006-01-02]"},
+		"date-type":           {stringNode, "DATE", int32(1), nil, "expected string, got int32"},
+		"string-to-millis":    {stringNode, "TIMESTAMP_MILLIS", "1970-01-01T00:00:01.5+01:00", int64(-3598500), ""},
+		"string-to-nanos":     {stringNode, "TIMESTAMP_NANOS:2006-01-02 15:04:05.999999999", "1969-12-31 23:59:59.999999999", int64(-1), ""},
+		"nanos-overflow":      {stringNode, "TIMESTAMP_NANOS", "2300-01-01T00:00:00Z", nil, "value [2300-01-01T00:00:00Z] overflows TIMESTAMP_NANOS"},
+		"millis-to-nanos":     {millis, "TIMESTAMP_NANOS", int64(-2), int64(-2000000), ""},
+		"nanos-to-millis":     {nanos, "TIMESTAMP_MILLIS", int64(-1), int64(-1), ""},
+		"nanos-truncated":     {nanos, "TIMESTAMP_MICROS", int64(1999), int64(1), ""},
+		"millis-overflow":     {millis, "TIMESTAMP_NANOS", int64(math.MaxInt64 / 100), nil, "overflows TIMESTAMP_NANOS"},
+		"timestamp-type":      {millis, "TIMESTAMP_NANOS", int32(1), nil, "expected int64, got int32"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			target, err := parseCastTarget(tc.target)
+			require.NoError(t, err)
+			rule, err := newCastRule(tc.node, target)
+			require.NoError(t, err)
+			value, err := rule.ConvertData(tc.value)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, value)
+		})
+	}
+}
+
+func TestCastConverter(t *testing.T) {
+	type row struct {
+		Id    int32
+		Price *int64
+		Name  string
+	}
+	type convertedRow = struct {
+		Id    int64
+		Price *int32
+		Name  string
+	}
+	target, err := parseCastTarget("INT64")
+	require.NoError(t, err)
+	idRule, err := newCastRule(&pschema.SchemaNode{SchemaElement: parquet.SchemaElement{Type: new(parquet.Type_INT32)}}, target)
+	require.NoError(t, err)
+	target, err = parseCastTarget("INT32")
+	require.NoError(t, err)
+	priceRule, err := newCastRule(&pschema.SchemaNode{SchemaElement: parquet.SchemaElement{Type: new(parquet.Type_INT64)}}, target)
+	require.NoError(t, err)
+
+	converter := NewConverter([]*RetypeRule{idRule, priceRule}, []map[string]struct{}{{"Id": {}}, {"Price": {}}})
+	converted, err := converter.Convert(row{Id: 1, Price: new(int64(5)), Name: "a"})
+	require.NoError(t, err)
+	value := converted.(*convertedRow)
+	require.Equal(t, int64(1), value.Id)
+	require.Equal(t, int32(5), *value.Price)
+	require.Equal(t, "a", value.Name)
+
+	converted, err = converter.Convert(row{Id: 2})
+	require.NoError(t, err)
+	require.Nil(t, converted.(*convertedRow).Price)
+
+	_, err = converter.Convert(row{Price: new(int64(math.MaxInt64))})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to convert field [Price]: value 9223372036854775807 overflows INT32")
+}
@@ -10,18 +10,20 @@ import (
 
 // Cmd is a kong command for retype.
 type Cmd struct {
-	BsonToString     bool   `help:"Convert BSON columns to plain strings (JSON encoded)." default:"false"`
-	FieldDelimiter   string `name:"field-delimiter" help:"Delimiter separating nested field path components in field and column parameters" default:"."`
-	Float16ToFloat32 bool   `help:"Convert FLOAT16 columns to FLOAT32." name:"float16-to-float32" default:"false"`
-	GeoToBinary      bool   `help:"Remove GEOGRAPHY and GEOMETRY logical types (keep as plain BYTE_ARRAY)." default:"false"`
-	Int96ToTimestamp bool   `help:"Convert INT96 columns to TIMESTAMP_NANOS." name:"int96-to-timestamp" default:"false"`
-	JsonToString     bool   `help:"Remove JSON logical type from columns." default:"false"`
-	ReadPageSize     int    `help:"Page size to read from Parquet." default:"1000"`
-	RepeatedToList   bool   `help:"Convert legacy repeated primitive columns to LIST format." default:"false"`
-	Source           string `short:"s" help:"Source Parquet file to retype." required:"true"`
-	URI              string `arg:"" predictor:"file" help:"URI of output Parquet file."`
-	UuidToString     bool   `help:"Convert UUID columns to plain strings." default:"false"`
-	VariantToString  bool   `help:"Convert VARIANT columns to plain strings (JSON encoded)." default:"false"`
+	BsonToString     bool     `help:"Convert BSON columns to plain strings (JSON encoded)." default:"false"`
+	Cast             []string `help:"Cast field to INT32, INT64, FLOAT, DOUBLE, DECIMAL(precision,scale), DATE[:layout], or TIMESTAMP_MILLIS/MICROS/NANOS[:layout], repeat it for more fields." placeholder:"field.path=TARGET" sep:"none"`
+	FieldDelimiter   string   `name:"field-delimiter" help:"Delimiter separating nested field path components in field and column parameters" default:"."`
+	Float16ToFloat32 bool     `help:"Convert FLOAT16 columns to FLOAT32." name:"float16-to-float32" default:"false"`
+	GeoToBinary      bool     `help:"Remove GEOGRAPHY and GEOMETRY logical types (keep as plain BYTE_ARRAY)." default:"false"`
+	Int96ToTimestamp bool     `help:"Convert INT96 columns to TIMESTAMP_NANOS." name:"int96-to-timestamp" default:"false"`
+	JsonToString     bool     `help:"Remove JSON logical type from columns." default:"false"`
+	ReadPageSize     int      `help:"Page size to read from Parquet." default:"1000"`
+	RepeatedToList   bool     `help:"Convert legacy repeated primitive columns to LIST format." default:"false"`
+	Source           string   `short:"s" help:"Source Parquet file to retype." required:"true"`
+	URI              string   `arg:"" predictor:"file" help:"URI of output Parquet file."`
+	UuidToString     bool     `help:"Convert UUID columns to plain strings." default:"false"`
+	VariantToString  bool     `help:"Convert VARIANT columns to plain strings (JSON encoded)." default:"false"`
+	pio.MetadataOption
 	pio.ReadOption
 	pio.WriteOption
 }
@@ -50,8 +52,19 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 		return err
 	}
 
-	// Get active rules and apply them to schema
-	activeRules := c.getActiveRules()
+	// Key-value metadata of source file is kept unless it is changed
+	metadata, err := c.KeyValueMetadata(fileReader.Footer.KeyValueMetadata)
+	if err != nil {
+		return err
+	}
+
+	// Get active rules and apply them to schema, casts match fields of source
+	// schema so they go first
+	activeRules, err := c.castRules(schemaTree)
+	if err != nil {
+		return err
+	}
+	activeRules = append(activeRules, c.getActiveRules()...)
 	matchedFields := make([]map[string]struct{}, len(activeRules))
 	for i, rule := range activeRules {
 		matchedFields[i] = applyRule(schemaTree, nil, rule.MatchSchema, rule.TransformSchema)
@@ -70,6 +83,7 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 	if err != nil {
 		return fmt.Errorf("failed to write to [%s]: %w", c.URI, err)
 	}
+	fileWriter.Footer.KeyValueMetadata = metadata
 	defer func() {
 		if err := fileWriter.WriteStopWithContext(ctx); err != nil && retErr == nil {
 			retErr = fmt.Errorf("failed to end write [%s]: %w", c.URI, err)
@@ -79,5 +93,5 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 		}
 	}()
 
-	return pio.RunPipeline(ctx, fileReader, fileWriter, c.Source, c.URI, c.ReadPageSize, converter.Convert)
+	return pio.RunPipeline(ctx, fileReader, fileWriter, c.Source, c.URI, c.ReadPageSize, c.RowGroupRows, converter.Convert)
 }
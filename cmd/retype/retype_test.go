@@ -50,6 +50,14 @@ func TestCmd(t *testing.T) {
 				Cmd{ReadOption: rOpt, ReadPageSize: 10, FieldDelimiter: "::", Source: "../../testdata/good.parquet", URI: "dummy"},
 				"field delimiter must be a single character",
 			},
+			"cast": {
+				Cmd{ReadOption: rOpt, ReadPageSize: 10, Cast: []string{"NoSuchField=INT64"}, Source: "../../testdata/good.parquet", URI: "dummy"},
+				"field [NoSuchField] does not exist",
+			},
+			"set-metadata": {
+				Cmd{ReadOption: rOpt, ReadPageSize: 10, MetadataOption: pio.MetadataOption{RemoveMetadata: []string{""}}, Source: "../../testdata/good.parquet", URI: "dummy"},
+				"empty key in --remove-metadata",
+			},
 		}
 
 		for name, tc := range testCases {
@@ -953,7 +961,7 @@ func TestWriterContextCancellation(t *testing.T) {
 
 	writerChan := make(chan any)
 
-	err := pio.PipelineWriter(ctx, nil, writerChan, "test-target")
+	err := pio.PipelineWriter(ctx, nil, writerChan, "test-target", 0)
 	require.ErrorIs(t, err, context.Canceled)
 }
 
@@ -9,20 +9,27 @@ import (
 
 // Cmd is a kong command for rowcount
 type Cmd struct {
-	URI string `arg:"" predictor:"file" help:"URI of Parquet file."`
+	URI string `arg:"" predictor:"file" help:"URI of Parquet file, wildcards add up all matching files."`
 	pio.ReadOption
 }
 
 // Run does actual rowcount job
 func (c Cmd) Run(ctx context.Context) error {
-	reader, err := pio.NewParquetFileReader(ctx, c.URI, c.ReadOption)
+	uris, err := pio.ExpandURIs(ctx, []string{c.URI}, c.ReadOption)
 	if err != nil {
 		return err
 	}
-	defer func() {
+
+	total := int64(0)
+	for _, uri := range uris {
+		reader, err := pio.NewParquetFileReader(ctx, uri, c.ReadOption)
+		if err != nil {
+			return err
+		}
+		total += reader.GetNumRows()
 		_ = reader.PFile.Close()
-	}()
+	}
 
-	fmt.Println(reader.GetNumRows())
+	fmt.Println(total)
 	return nil
 }
@@ -40,6 +40,26 @@ func TestCmd(t *testing.T) {
 		require.Equal(t, "3\n", stdout)
 		require.Equal(t, "", stderr)
 	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		cmd := &Cmd{}
+		cmd.URI = "../../testdata/good*.parquet"
+
+		stdout, stderr := testutils.CaptureStdoutStderr(func() {
+			require.Nil(t, cmd.Run(context.Background()))
+		})
+		require.Equal(t, "6\n", stdout)
+		require.Equal(t, "", stderr)
+	})
+
+	t.Run("wildcard-no-match", func(t *testing.T) {
+		cmd := &Cmd{}
+		cmd.URI = "../../testdata/*.foo"
+
+		err := cmd.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no file matches [../../testdata/*.foo]")
+	})
 }
 
 func TestCmdEncrypted(t *testing.T) {
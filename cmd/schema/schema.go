@@ -23,23 +23,31 @@ type Cmd struct {
 	Format               string `short:"f" help:"output format (go/json/raw/csv)" enum:"go,json,raw,csv" default:"json"`
 	SkipPageEncoding     bool   `help:"skip reading page encoding information" default:"false"`
 	ShowCompressionCodec bool   `help:"(deprecated, no effect, will be removed) compression codec is always shown" default:"false"`
-	URI                  string `arg:"" predictor:"file" help:"URI of Parquet file."`
+	URI                  string `arg:"" predictor:"file" help:"URI of Parquet file, wildcards need all matching files to have the same schema."`
 	pio.ReadOption
 }
 
 // Run does actual schema job
 func (c Cmd) Run(ctx context.Context) error {
-	reader, err := pio.NewParquetFileReader(ctx, c.URI, c.ReadOption)
+	uris, err := pio.ExpandURIs(ctx, []string{c.URI}, c.ReadOption)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		_ = reader.PFile.Close()
-	}()
 
-	schemaRoot, err := pschema.NewSchemaTree(ctx, reader, pschema.SchemaOption{FailOnInt96: false, SkipPageEncoding: c.SkipPageEncoding})
-	if err != nil {
-		return err
+	// all matching files need to have the same schema
+	var schemaRoot *pschema.SchemaNode
+	for _, uri := range uris {
+		currSchema, err := c.schemaTree(ctx, uri)
+		if err != nil {
+			return err
+		}
+		if schemaRoot == nil {
+			schemaRoot = currSchema
+			continue
+		}
+		if !schemaRoot.IsCompatible(currSchema, pschema.CompareOption{}) {
+			return fmt.Errorf("[%s] does not have same schema as [%s]", uri, uris[0])
+		}
 	}
 
 	switch c.Format {
@@ -70,3 +78,15 @@ func (c Cmd) Run(ctx context.Context) error {
 
 	return nil
 }
+
+func (c Cmd) schemaTree(ctx context.Context, uri string) (*pschema.SchemaNode, error) {
+	reader, err := pio.NewParquetFileReader(ctx, uri, c.ReadOption)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.PFile.Close()
+	}()
+
+	return pschema.NewSchemaTree(ctx, reader, pschema.SchemaOption{FailOnInt96: false, SkipPageEncoding: c.SkipPageEncoding})
+}
@@ -3,6 +3,7 @@ package schema_test
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -52,14 +53,18 @@ func TestCmd(t *testing.T) {
 			cmd:    schema.Cmd{ReadOption: rOpt, Format: "csv", URI: "../../testdata/csv-nested.parquet"},
 			errMsg: "CSV supports flat schema only",
 		},
-		"csv-optional": {
-			cmd:    schema.Cmd{ReadOption: rOpt, Format: "csv", URI: "../../testdata/csv-optional.parquet"},
-			errMsg: "CSV does not support optional column",
-		},
 		"csv-repeated": {
 			cmd:    schema.Cmd{ReadOption: rOpt, Format: "csv", URI: "../../testdata/csv-repeated.parquet"},
 			errMsg: "CSV does not support column in LIST type",
 		},
+		"wildcard-no-match": {
+			cmd:    schema.Cmd{ReadOption: rOpt, Format: "json", URI: "../../testdata/*.foo"},
+			errMsg: "no file matches [../../testdata/*.foo]",
+		},
+		"wildcard-different-schema": {
+			cmd:    schema.Cmd{ReadOption: rOpt, Format: "json", URI: "../../testdata/csv-*.parquet"},
+			errMsg: "[../../testdata/csv-nested.parquet] does not have same schema as [../../testdata/csv-good.parquet]",
+		},
 		// encrypted error cases
 		"encrypted-footer-no-key": {
 			cmd:    schema.Cmd{ReadOption: rOpt, Format: "json", URI: "../../testdata/encrypted-footer.parquet"},
@@ -92,6 +97,10 @@ func TestCmd(t *testing.T) {
 			cmd:    schema.Cmd{ReadOption: rOpt, Format: "csv", URI: "csv-good.parquet"},
 			golden: "schema-csv-good.txt",
 		},
+		"csv-optional": {
+			cmd:    schema.Cmd{ReadOption: rOpt, Format: "csv", URI: "csv-optional.parquet"},
+			golden: "schema-csv-optional.txt",
+		},
 		"raw-map-value-list": {
 			cmd:    schema.Cmd{ReadOption: rOpt, Format: "raw", URI: "map-composite-value.parquet"},
 			golden: "schema-map-composite-value-raw.json",
@@ -264,3 +273,18 @@ func BenchmarkSchemaCmd(b *testing.B) {
 		}
 	})
 }
+
+func TestCmdWildcard(t *testing.T) {
+	dir := t.TempDir()
+	buf, err := os.ReadFile("../../testdata/good.parquet")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "part-0.parquet"), buf, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "part-1.parquet"), buf, 0o644))
+
+	cmd := schema.Cmd{Format: "go", SkipPageEncoding: true, URI: filepath.Join(dir, "part-*.parquet")}
+	stdout, stderr := testutils.CaptureStdoutStderr(func() {
+		require.NoError(t, cmd.Run(context.Background()))
+	})
+	require.Equal(t, testutils.LoadExpected(t, "../../testdata/golden/schema-good-skip-page-encoding-go.txt"), stdout)
+	require.Equal(t, "", stderr)
+}
@@ -19,41 +19,35 @@ const (
 type Cmd struct {
 	Query string `short:"q" help:"Size to query (raw/uncompressed/footer/all)." enum:"raw,uncompressed,footer,all" default:"raw"`
 	JSON  bool   `short:"j" help:"Output in JSON format." default:"false"`
-	URI   string `arg:"" predictor:"file" help:"URI of Parquet file."`
+	URI   string `arg:"" predictor:"file" help:"URI of Parquet file, wildcards add up all matching files."`
 	pio.ReadOption
 }
 
 // Run does actual size job
 func (c Cmd) Run(ctx context.Context) error {
-	reader, err := pio.NewParquetFileReader(ctx, c.URI, c.ReadOption)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = reader.PFile.Close()
-	}()
-
-	footerSize, err := reader.GetFooterSizeWithContext(ctx)
+	uris, err := pio.ExpandURIs(ctx, []string{c.URI}, c.ReadOption)
 	if err != nil {
 		return err
 	}
 
+	// sizes of all matching files are added up
+	footerSize := int64(0)
 	rawSize := int64(0)
 	uncompressedSize := int64(0)
-	if c.Query != queryFooter {
-		// do not scan all row groups whenever we are asked for footer size only
-		for _, rg := range reader.Footer.RowGroups {
-			for _, col := range rg.Columns {
-				rawSize += col.MetaData.TotalCompressedSize
-				uncompressedSize += col.MetaData.TotalUncompressedSize
-			}
+	for _, uri := range uris {
+		raw, uncompressed, footer, err := c.fileSize(ctx, uri)
+		if err != nil {
+			return err
 		}
+		rawSize += raw
+		uncompressedSize += uncompressed
+		footerSize += int64(footer)
 	}
 
 	var size struct {
-		Raw          *int64  `json:",omitempty"`
-		Uncompressed *int64  `json:",omitempty"`
-		Footer       *uint32 `json:",omitempty"`
+		Raw          *int64 `json:",omitempty"`
+		Uncompressed *int64 `json:",omitempty"`
+		Footer       *int64 `json:",omitempty"`
 	}
 
 	switch c.Query {
@@ -92,3 +86,31 @@ func (c Cmd) Run(ctx context.Context) error {
 
 	return nil
 }
+
+func (c Cmd) fileSize(ctx context.Context, uri string) (int64, int64, uint32, error) {
+	reader, err := pio.NewParquetFileReader(ctx, uri, c.ReadOption)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer func() {
+		_ = reader.PFile.Close()
+	}()
+
+	footerSize, err := reader.GetFooterSizeWithContext(ctx)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	rawSize := int64(0)
+	uncompressedSize := int64(0)
+	if c.Query != queryFooter {
+		// do not scan all row groups whenever we are asked for footer size only
+		for _, rg := range reader.Footer.RowGroups {
+			for _, col := range rg.Columns {
+				rawSize += col.MetaData.TotalCompressedSize
+				uncompressedSize += col.MetaData.TotalUncompressedSize
+			}
+		}
+	}
+	return rawSize, uncompressedSize, footerSize, nil
+}
@@ -83,6 +83,14 @@ func TestCmd(t *testing.T) {
 			cmd:    Cmd{ReadOption: rOpt, Query: "all", JSON: false, URI: "../../testdata/good.parquet"},
 			stdout: "588 438 335\n",
 		},
+		"wildcard": {
+			cmd:    Cmd{ReadOption: rOpt, Query: "all", JSON: false, URI: "../../testdata/good*.parquet"},
+			stdout: "1038 876 670\n",
+		},
+		"wildcard-no-match": {
+			cmd:    Cmd{ReadOption: rOpt, Query: "raw", URI: "../../testdata/*.foo"},
+			errMsg: "no file matches [../../testdata/*.foo]",
+		},
 		"all-json": {
 			cmd:    Cmd{ReadOption: rOpt, Query: "all", JSON: true, URI: "../../testdata/good.parquet"},
 			stdout: `{"Raw":588,"Uncompressed":438,"Footer":335}` + "\n",
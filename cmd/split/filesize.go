@@ -0,0 +1,76 @@
+package split
+
+import (
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/source"
+)
+
+// countingWriter counts bytes the parquet writer has flushed to a file.
+type countingWriter struct {
+	source.ParquetFileWriter
+	written int64
+}
+
+func (w *countingWriter) Write(buf []byte) (int, error) {
+	n, err := w.ParquetFileWriter.Write(buf)
+	w.written += int64(n)
+	return n, err
+}
+
+// fileSizer estimates size of result files, rows are held in memory by the
+// writer until a row group is flushed, so their size is estimated from
+// in-memory size and compression ratio, which starts with ratio of source
+// file and is corrected by row groups flushed so far.
+type fileSizer struct {
+	initialRatio float64
+	flushedRaw   int64
+	flushedBytes int64
+}
+
+func newFileSizer(footer *parquet.FileMetaData) fileSizer {
+	compressed, uncompressed := int64(0), int64(0)
+	for _, rowGroup := range footer.RowGroups {
+		for _, column := range rowGroup.Columns {
+			if column.MetaData != nil {
+				compressed += column.MetaData.TotalCompressedSize
+				uncompressed += column.MetaData.TotalUncompressedSize
+			}
+		}
+	}
+	sizer := fileSizer{initialRatio: 1}
+	if compressed != 0 && uncompressed != 0 {
+		sizer.initialRatio = float64(compressed) / float64(uncompressed)
+	}
+	return sizer
+}
+
+func (s *fileSizer) ratio() float64 {
+	if s.flushedRaw == 0 {
+		return s.initialRatio
+	}
+	return float64(s.flushedBytes) / float64(s.flushedRaw)
+}
+
+// exceeds tells if current file will be larger than maxSize with a row of
+// rowSize bytes, a file has at least one row.
+func (s *fileSizer) exceeds(current *trunkWriter, rowSize, maxSize int64) bool {
+	if maxSize == 0 || current.output == nil || current.recordCount <= 0 {
+		return false
+	}
+	pending := float64(current.pendingSize+rowSize) * s.ratio()
+	return float64(current.output.written)+pending > float64(maxSize)
+}
+
+// written updates state after a row of rowSize bytes was written, writer
+// flushes all rows held in memory when a row group is full.
+func (s *fileSizer) written(current *trunkWriter, rowSize, writtenBefore int64) {
+	if current.output == nil {
+		return
+	}
+	current.pendingSize += rowSize
+	if flushed := current.output.written - writtenBefore; flushed > 0 {
+		s.flushedRaw += current.pendingSize
+		s.flushedBytes += flushed
+		current.pendingSize = 0
+	}
+}
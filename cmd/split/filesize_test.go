@@ -0,0 +1,82 @@
+package split
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	pio "github.com/hangxie/parquet-tools/io"
+)
+
+func TestCmdMaxFileSize(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		testCases := map[string]struct {
+			cmd    Cmd
+			errMsg string
+		}{
+			"file-count": {Cmd{FileCount: 2, MaxFileSize: "1MB"}, "--max-file-size cannot be used with --file-count"},
+			"bad-size":   {Cmd{MaxFileSize: "1PB"}, "invalid max file size [1PB]: unknown unit [PB]"},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				tc.cmd.ReadPageSize = 10
+				tc.cmd.NameFormat = filepath.Join(t.TempDir(), "ut-%d.parquet")
+				tc.cmd.URI = "../../testdata/all-types.parquet"
+				err := tc.cmd.Run(context.Background())
+				require.Error(t, err)
+				require.Equal(t, tc.errMsg, err.Error())
+			})
+		}
+	})
+
+	testCases := map[string]struct {
+		cmd    Cmd
+		result map[string]int64
+	}{
+		"one-row-per-file": {
+			cmd:    Cmd{MaxFileSize: "1B"},
+			result: map[string]int64{"ut-0.parquet": 1, "ut-1.parquet": 1, "ut-2.parquet": 1, "ut-3.parquet": 1, "ut-4.parquet": 1},
+		},
+		"large-enough": {
+			cmd:    Cmd{MaxFileSize: "1GB"},
+			result: map[string]int64{"ut-0.parquet": 5},
+		},
+		"with-record-count": {
+			cmd:    Cmd{MaxFileSize: "1GB", RecordCount: 3},
+			result: map[string]int64{"ut-0.parquet": 3, "ut-1.parquet": 2},
+		},
+		"with-partition-by": {
+			cmd:    Cmd{MaxFileSize: "1B", MaxOpenFiles: 10, PartitionBy: []string{"Bool"}},
+			result: map[string]int64{"Bool=true/ut-0.parquet": 1, "Bool=true/ut-1.parquet": 1, "Bool=true/ut-2.parquet": 1, "Bool=false/ut-0.parquet": 1, "Bool=false/ut-1.parquet": 1},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			cmd := tc.cmd
+			cmd.ReadPageSize = 2
+			cmd.NameFormat = filepath.Join(tempDir, "ut-%d.parquet")
+			cmd.URI = "../../testdata/all-types.parquet"
+			require.NoError(t, cmd.Run(context.Background()))
+
+			result := map[string]int64{}
+			require.NoError(t, filepath.WalkDir(tempDir, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil || entry.IsDir() {
+					return err
+				}
+				reader, err := pio.NewParquetFileReader(context.Background(), path, pio.ReadOption{})
+				require.NoError(t, err)
+				defer func() {
+					_ = reader.PFile.Close()
+				}()
+				rel, _ := filepath.Rel(tempDir, path)
+				result[filepath.ToSlash(rel)] = reader.GetNumRows()
+				return nil
+			}))
+			require.Equal(t, tc.result, result)
+		})
+	}
+}
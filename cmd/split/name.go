@@ -0,0 +1,176 @@
+package split
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultTimestampLayout is layout of {timestamp} without a format.
+const defaultTimestampLayout = "20060102T150405Z"
+
+// namePart is either a literal or a placeholder of a name template.
+type namePart struct {
+	literal     string
+	placeholder string
+	format      string
+}
+
+// placeholderPattern tells a template from a printf format, which can have
+// literal braces like "data{x}-%06d.parquet".
+var placeholderPattern = regexp.MustCompile(`\{(base|index|partition|timestamp|uuid)\b`)
+
+// nameTemplate generates names of result files from --name-format, which is
+// either a format with one integer verb like "result-%06d.parquet", or a
+// template like "{base}-{index:06d}-{uuid}.parquet".
+type nameTemplate struct {
+	parts        []namePart
+	hasPartition bool
+	base         string
+	timestamp    time.Time
+}
+
+func newNameTemplate(nameFormat, sourceURI string) (nameTemplate, error) {
+	var parts []namePart
+	var err error
+	if placeholderPattern.MatchString(nameFormat) {
+		parts, err = parseNameTemplate(nameFormat)
+	} else {
+		parts, err = parsePrintfFormat(nameFormat)
+	}
+	if err != nil {
+		return nameTemplate{}, err
+	}
+
+	template := nameTemplate{parts: parts, base: sourceBase(sourceURI), timestamp: time.Now().UTC()}
+	for _, part := range parts {
+		if part.placeholder == "partition" {
+			template.hasPartition = true
+		}
+	}
+	return template, nil
+}
+
+// parsePrintfFormat turns a format with one integer verb to template parts.
+func parsePrintfFormat(nameFormat string) ([]namePart, error) {
+	if err := checkNameFormat(nameFormat); err != nil {
+		return nil, err
+	}
+	allVerbs := regexp.MustCompile(`(%%|%[0-9\-\.]*[a-zA-Z])`)
+	var parts []namePart
+	literal := ""
+	last := 0
+	for _, loc := range allVerbs.FindAllStringIndex(nameFormat, -1) {
+		literal += nameFormat[last:loc[0]]
+		last = loc[1]
+		if verb := nameFormat[loc[0]:loc[1]]; verb == "%%" {
+			literal += "%"
+			continue
+		}
+		parts = append(parts, namePart{literal: literal}, namePart{placeholder: "index", format: nameFormat[loc[0]+1 : loc[1]]})
+		literal = ""
+	}
+	return append(parts, namePart{literal: literal + nameFormat[last:]}), nil
+}
+
+// parseNameTemplate parses placeholders in braces, "{{" and "}}" are literal
+// braces.
+func parseNameTemplate(nameFormat string) ([]namePart, error) {
+	indexFormat := regexp.MustCompile(`^[0-9]*[bdoxX]$`)
+	var parts []namePart
+	var literal strings.Builder
+	unique := false
+	for rest := nameFormat; rest != ""; {
+		switch {
+		case strings.HasPrefix(rest, "{{"), strings.HasPrefix(rest, "}}"):
+			literal.WriteByte(rest[0])
+			rest = rest[2:]
+			continue
+		case rest[0] == '}':
+			return nil, fmt.Errorf("unmatched [}]")
+		case rest[0] != '{':
+			literal.WriteByte(rest[0])
+			rest = rest[1:]
+			continue
+		}
+
+		end := strings.IndexByte(rest, '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unmatched [{]")
+		}
+		placeholder, format, hasFormat := strings.Cut(rest[1:end], ":")
+		switch placeholder {
+		case "index":
+			if !hasFormat {
+				format = "d"
+			} else if !indexFormat.MatchString(format) {
+				return nil, fmt.Errorf("[%s] is not an allowed format of {index}", format)
+			}
+			unique = true
+		case "timestamp":
+			if !hasFormat {
+				format = defaultTimestampLayout
+			}
+		case "uuid":
+			unique = true
+			fallthrough
+		case "base", "partition":
+			if hasFormat {
+				return nil, fmt.Errorf("{%s} does not take a format", placeholder)
+			}
+		default:
+			return nil, fmt.Errorf("unknown placeholder [%s]", rest[:end+1])
+		}
+		parts = append(parts, namePart{literal: literal.String()}, namePart{placeholder: placeholder, format: format})
+		literal.Reset()
+		rest = rest[end+1:]
+	}
+	if !unique {
+		return nil, fmt.Errorf("needs {index} or {uuid} to tell result files apart")
+	}
+	return append(parts, namePart{literal: literal.String()}), nil
+}
+
+// name returns name of the index-th result file, directory of partition is put
+// between directory and file name if template does not have {partition}.
+func (t nameTemplate) name(index int64, partition string) string {
+	var builder strings.Builder
+	for _, part := range t.parts {
+		switch part.placeholder {
+		case "":
+			builder.WriteString(part.literal)
+		case "base":
+			builder.WriteString(t.base)
+		case "index":
+			fmt.Fprintf(&builder, "%"+part.format, index)
+		case "partition":
+			builder.WriteString(partition)
+		case "timestamp":
+			builder.WriteString(t.timestamp.Format(part.format))
+		case "uuid":
+			builder.WriteString(uuid.NewString())
+		}
+	}
+	name := builder.String()
+	if partition != "" && !t.hasPartition {
+		baseIndex := strings.LastIndex(name, "/") + 1
+		name = name[:baseIndex] + partition + "/" + name[baseIndex:]
+	}
+	return name
+}
+
+// sourceBase is file name of source without extension.
+func sourceBase(uri string) string {
+	name := filepath.ToSlash(uri)
+	if u, err := url.Parse(uri); err == nil && len(u.Scheme) > 1 {
+		name = u.Path
+	}
+	name = path.Base(name)
+	return strings.TrimSuffix(name, path.Ext(name))
+}
@@ -0,0 +1,109 @@
+package split
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewNameTemplate(t *testing.T) {
+	testCases := map[string]string{
+		"{index.parquet":     "unmatched [{]",
+		"{index}-}.parquet":  "unmatched [}]",
+		"{foo}-{index}":      "unknown placeholder [{foo}]",
+		"{index:s}":          "[s] is not an allowed format of {index}",
+		"{index:0.2f}":       "[0.2f] is not an allowed format of {index}",
+		"{uuid:x}":           "{uuid} does not take a format",
+		"{base:x}-{index}":   "{base} does not take a format",
+		"{base}.parquet":     "needs {index} or {uuid} to tell result files apart",
+		"{{index}}.parquet":  "needs {index} or {uuid} to tell result files apart",
+		"{partition}/%d.csv": "needs {index} or {uuid} to tell result files apart",
+		"%d-%x":              "has more than one useable verb: [%d] and [%x]",
+	}
+	for nameFormat, errMsg := range testCases {
+		t.Run(nameFormat, func(t *testing.T) {
+			_, err := newNameTemplate(nameFormat, "source.parquet")
+			require.Error(t, err)
+			require.Equal(t, errMsg, err.Error())
+		})
+	}
+}
+
+func TestNameTemplate(t *testing.T) {
+	testCases := map[string]struct {
+		nameFormat string
+		sourceURI  string
+		partition  string
+		expected   string
+	}{
+		"printf":            {"result-%06d.parquet", "a.parquet", "", "result-000012.parquet"},
+		"printf-percent":    {"50%%-%x.parquet", "a.parquet", "", "50%-c.parquet"},
+		"printf-partition":  {"part-%05d.parquet", "a.parquet", "a=1", "a=1/part-00012.parquet"},
+		"printf-dir":        {"out/part-%d.parquet", "a.parquet", "a=1/b=2", "out/a=1/b=2/part-12.parquet"},
+		"printf-s3":         {"s3://bucket/out/%d.parquet", "a.parquet", "a=1", "s3://bucket/out/a=1/12.parquet"},
+		"printf-escaped":    {"out/%d.parquet", "a.parquet", "a=50%25", "out/a=50%25/12.parquet"},
+		"printf-braces":     {"data{x}-%06d.parquet", "a.parquet", "", "data{x}-000012.parquet"},
+		"printf-brace-dir":  {"{date}/{indexes}-%d.parquet", "a.parquet", "a=1", "{date}/a=1/{indexes}-12.parquet"},
+		"index":             {"{base}-{index}.parquet", "dir/data.parquet", "", "data-12.parquet"},
+		"index-format":      {"{base}-{index:06d}.parquet", "s3://bucket/dir/data.parquet", "", "data-000012.parquet"},
+		"index-hex":         {"{index:04x}.parquet", "data.parquet", "", "000c.parquet"},
+		"base-http":         {"{base}-{index}.parquet", "https://example.com/data.parquet?version=1", "", "data-12.parquet"},
+		"base-dir":          {"gs://bucket/{base}/{index}.parquet", "/tmp/data.snappy.parquet", "", "gs://bucket/data.snappy/12.parquet"},
+		"braces":            {"{{{index}}}%d.parquet", "data.parquet", "", "{12}%d.parquet"},
+		"partition-auto":    {"out/{base}-{index}.parquet", "data.parquet", "a=1", "out/a=1/data-12.parquet"},
+		"partition-in-name": {"out/{partition}/{base}-{index}.parquet", "data.parquet", "a=1", "out/a=1/data-12.parquet"},
+		"partition-escaped": {"{index}.parquet", "data.parquet", "a=50%25", "a=50%25/12.parquet"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			names, err := newNameTemplate(tc.nameFormat, tc.sourceURI)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, names.name(12, tc.partition))
+		})
+	}
+
+	t.Run("uuid", func(t *testing.T) {
+		names, err := newNameTemplate("{base}-{uuid}.parquet", "data.parquet")
+		require.NoError(t, err)
+		name1, name2 := names.name(0, ""), names.name(0, "")
+		require.Regexp(t, regexp.MustCompile(`^data-[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\.parquet$`), name1)
+		require.NotEqual(t, name1, name2)
+	})
+
+	t.Run("timestamp", func(t *testing.T) {
+		names, err := newNameTemplate("{timestamp}/{timestamp:2006-01-02}-{index}.parquet", "data.parquet")
+		require.NoError(t, err)
+		names.timestamp = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		require.Equal(t, "20240102T030405Z/2024-01-02-1.parquet", names.name(1, ""))
+	})
+}
+
+func TestCmdNameTemplate(t *testing.T) {
+	t.Run("partition-without-partition-by", func(t *testing.T) {
+		cmd := Cmd{NameFormat: "{partition}/{index}.parquet", ReadPageSize: 10, RecordCount: 1, URI: "../../testdata/good.parquet"}
+		err := cmd.Run(context.Background())
+		require.Error(t, err)
+		require.Equal(t, "invalid name format [{partition}/{index}.parquet]: {partition} can only be used with --partition-by", err.Error())
+	})
+
+	tempDir := t.TempDir()
+	cmd := Cmd{
+		NameFormat:    filepath.Join(tempDir, "{base}", "{base}-{index:02d}.parquet"),
+		ReadPageSize:  10,
+		RowGroupCount: 1,
+		URI:           "../../testdata/row-group.parquet",
+	}
+	require.NoError(t, cmd.Run(context.Background()))
+	entries, err := os.ReadDir(filepath.Join(tempDir, "row-group"))
+	require.NoError(t, err)
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	require.Equal(t, []string{"row-group-00.parquet", "row-group-01.parquet"}, names)
+}
@@ -0,0 +1,232 @@
+package split
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/reader"
+	"github.com/hangxie/parquet-go/v3/types"
+
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// hiveDefaultPartition is directory name of null and empty values, same as Hive.
+const hiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// partitionColumn is a top level field in --partition-by, inName is name of
+// the field in rows.
+type partitionColumn struct {
+	name    string
+	inName  string
+	element *parquet.SchemaElement
+}
+
+// partitionColumns parses --partition-by against schema of source file.
+func (c Cmd) partitionColumns(root *pschema.SchemaNode) ([]partitionColumn, error) {
+	columns := make([]partitionColumn, len(c.PartitionBy))
+	for index, name := range c.PartitionBy {
+		var node *pschema.SchemaNode
+		for _, child := range root.Children {
+			if child.Name == name {
+				node = child
+				break
+			}
+		}
+		if node == nil {
+			return nil, fmt.Errorf("field [%s] does not exist", name)
+		}
+		if node.Type == nil || node.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+			return nil, fmt.Errorf("field [%s] is not a top level primitive field, cannot partition by it", name)
+		}
+		columns[index] = partitionColumn{name: name, inName: node.InNamePath[1], element: &node.SchemaElement}
+	}
+	return columns, nil
+}
+
+// targetSchema is schema of result files, partition columns are removed with
+// --drop-partition-columns.
+func (c Cmd) targetSchema(root *pschema.SchemaNode) (string, error) {
+	if !c.DropPartitionColumns {
+		return root.JSONSchema(), nil
+	}
+	target := c.selectFields(root, false)
+	if len(target.Children) == 0 {
+		return "", fmt.Errorf("cannot drop all fields of [%s]", c.URI)
+	}
+	return target.JSONSchema(), nil
+}
+
+// selectFields returns root with top level fields that are partition columns
+// if partition is true, or with the other fields if partition is false.
+func (c Cmd) selectFields(root *pschema.SchemaNode, partition bool) *pschema.SchemaNode {
+	target := *root
+	target.Children = nil
+	for _, child := range root.Children {
+		if slices.Contains(c.PartitionBy, child.Name) == partition {
+			target.Children = append(target.Children, child)
+		}
+	}
+	return &target
+}
+
+// partitionDir returns Hive-style directory of row, eg "country=US/dt=2024-01-01".
+func partitionDir(row any, columns []partitionColumn) string {
+	value := reflect.ValueOf(row)
+	for value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	names := make([]string, len(columns))
+	for index, column := range columns {
+		names[index] = escapePartitionName(column.name) + "=" + partitionValue(value.FieldByName(column.inName), column.element)
+	}
+	return strings.Join(names, "/")
+}
+
+func partitionValue(field reflect.Value, element *parquet.SchemaElement) string {
+	for field.IsValid() && field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return hiveDefaultPartition
+		}
+		field = field.Elem()
+	}
+	if !field.IsValid() {
+		return hiveDefaultPartition
+	}
+	value := fmt.Sprint(types.ConvertToJSONType(field.Interface(), element))
+	if value == "" {
+		return hiveDefaultPartition
+	}
+	return escapePartitionName(value)
+}
+
+// escapePartitionName escapes characters that cannot be in directory names the
+// same way as Hive does.
+func escapePartitionName(name string) string {
+	var builder strings.Builder
+	for _, b := range []byte(name) {
+		if b < 0x20 || b == 0x7f || strings.IndexByte("\"#%'*/:=?\\{[]^", b) >= 0 {
+			fmt.Fprintf(&builder, "%%%02X", b)
+			continue
+		}
+		builder.WriteByte(b)
+	}
+	return builder.String()
+}
+
+// splitByPartition writes each row to file of its partition, at most
+// --max-open-files files are open, the least recently used one is closed to
+// open another one.
+func (c *Cmd) splitByPartition(ctx context.Context, parquetReader *reader.ParquetReader, schemaRoot *pschema.SchemaNode) error {
+	columns, err := c.partitionColumns(schemaRoot)
+	if err != nil {
+		return err
+	}
+	schemaJSON, err := c.targetSchema(schemaRoot)
+	if err != nil {
+		return err
+	}
+
+	// with --drop-partition-columns rows are read without partition columns to
+	// match target schema, partition values are read along with them by another
+	// reader of partition columns only
+	rowReader, keyReader := parquetReader, parquetReader
+	if c.DropPartitionColumns {
+		if rowReader, err = pio.NewProjectedParquetReader(ctx, parquetReader, schemaJSON, c.ReadOption); err != nil {
+			return err
+		}
+		keySchema := c.selectFields(schemaRoot, true).JSONSchema()
+		if keyReader, err = pio.NewProjectedParquetReader(ctx, parquetReader, keySchema, c.ReadOption); err != nil {
+			return err
+		}
+	}
+
+	writers := map[string]*trunkWriter{}
+	// Best-effort close of all open target writers on any early return.
+	defer func() {
+		for _, current := range writers {
+			if current.writer != nil {
+				_ = current.writer.PFile.Close()
+			}
+		}
+	}()
+
+	openCount := 0
+	clock := int64(0)
+	for {
+		rows, err := rowReader.ReadByNumberWithContext(ctx, c.ReadPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to read from [%s]: %w", c.URI, err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		keys := rows
+		if keyReader != rowReader {
+			if keys, err = keyReader.ReadByNumberWithContext(ctx, c.ReadPageSize); err != nil {
+				return fmt.Errorf("failed to read from [%s]: %w", c.URI, err)
+			}
+			if len(keys) != len(rows) {
+				return fmt.Errorf("failed to read from [%s]: %d rows of partition columns, expect %d", c.URI, len(keys), len(rows))
+			}
+		}
+		for index, row := range rows {
+			dir := partitionDir(keys[index], columns)
+			current, found := writers[dir]
+			if !found {
+				current = &trunkWriter{partition: dir, schemaJSON: schemaJSON}
+				writers[dir] = current
+			}
+			clock++
+			current.lastUsed = clock
+			size := c.rowSize(row)
+
+			if current.writer == nil {
+				if openCount == c.MaxOpenFiles {
+					if err := c.closeWriter(ctx, leastRecentlyUsed(writers)); err != nil {
+						return err
+					}
+					openCount--
+				}
+				if err := c.switchWriter(ctx, current); err != nil {
+					return err
+				}
+				openCount++
+			} else if (c.RecordCount != 0 && current.recordCount == c.RecordCount) || c.sizer.exceeds(current, size, c.maxFileSize) {
+				if err := c.switchWriter(ctx, current); err != nil {
+					return err
+				}
+			}
+
+			if err := c.writeRow(ctx, current, row, size); err != nil {
+				return err
+			}
+		}
+	}
+
+	dirs := make([]string, 0, len(writers))
+	for dir := range writers {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+	for _, dir := range dirs {
+		if err := c.closeWriter(ctx, writers[dir]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func leastRecentlyUsed(writers map[string]*trunkWriter) *trunkWriter {
+	var result *trunkWriter
+	for _, current := range writers {
+		if current.writer != nil && (result == nil || current.lastUsed < result.lastUsed) {
+			result = current
+		}
+	}
+	return result
+}
@@ -0,0 +1,160 @@
+package split
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/cat"
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func TestCmdPartitionBy(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		testCases := map[string]struct {
+			cmd    Cmd
+			errMsg string
+		}{
+			"file-count":     {Cmd{FileCount: 2, MaxOpenFiles: 10, URI: "../../testdata/all-types.parquet"}, "--partition-by cannot be used with --file-count"},
+			"max-open-files": {Cmd{URI: "../../testdata/all-types.parquet"}, "invalid max open files 0, needs to be at least 1"},
+			"not-exist":      {Cmd{MaxOpenFiles: 10, PartitionBy: []string{"foo"}, URI: "../../testdata/all-types.parquet"}, "field [foo] does not exist"},
+			"group":          {Cmd{MaxOpenFiles: 10, PartitionBy: []string{"Map"}, URI: "../../testdata/all-types.parquet"}, "field [Map] is not a top level primitive field, cannot partition by it"},
+			"repeated":       {Cmd{MaxOpenFiles: 10, PartitionBy: []string{"Repeated"}, URI: "../../testdata/all-types.parquet"}, "field [Repeated] is not a top level primitive field, cannot partition by it"},
+			"drop-all": {
+				Cmd{DropPartitionColumns: true, MaxOpenFiles: 10, PartitionBy: []string{"shoe_name", "shoe_brand"}, URI: "../../testdata/good.parquet"},
+				"cannot drop all fields of [../../testdata/good.parquet]",
+			},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				if tc.cmd.PartitionBy == nil {
+					tc.cmd.PartitionBy = []string{"Bool"}
+				}
+				tc.cmd.ReadPageSize = 10
+				tc.cmd.NameFormat = filepath.Join(t.TempDir(), "part-%d.parquet")
+				err := tc.cmd.Run(context.Background())
+				require.Error(t, err)
+				require.Equal(t, tc.errMsg, err.Error())
+			})
+		}
+	})
+
+	testCases := map[string]struct {
+		cmd     Cmd
+		result  map[string]int64
+		dropped []string
+	}{
+		"partition": {
+			cmd: Cmd{MaxOpenFiles: 10, PartitionBy: []string{"Bool", "DecimalPointer"}, RecordCount: 2},
+			result: map[string]int64{
+				"Bool=true/DecimalPointer=__HIVE_DEFAULT_PARTITION__/part-0.parquet": 2,
+				"Bool=true/DecimalPointer=__HIVE_DEFAULT_PARTITION__/part-1.parquet": 1,
+				"Bool=false/DecimalPointer=0/part-0.parquet":                         1,
+				"Bool=false/DecimalPointer=0.03/part-0.parquet":                      1,
+			},
+		},
+		"max-open-files": {
+			cmd: Cmd{MaxOpenFiles: 1, PartitionBy: []string{"Bool"}},
+			result: map[string]int64{
+				"Bool=true/part-0.parquet":  1,
+				"Bool=true/part-1.parquet":  1,
+				"Bool=true/part-2.parquet":  1,
+				"Bool=false/part-0.parquet": 1,
+				"Bool=false/part-1.parquet": 1,
+			},
+		},
+		"drop-partition-columns": {
+			cmd: Cmd{DropPartitionColumns: true, MaxOpenFiles: 10, PartitionBy: []string{"Date"}},
+			result: map[string]int64{
+				"Date=1970-01-01/part-0.parquet": 1,
+				"Date=1967-04-07/part-0.parquet": 1,
+				"Date=1964-07-11/part-0.parquet": 1,
+				"Date=1961-10-15/part-0.parquet": 1,
+				"Date=1959-01-19/part-0.parquet": 1,
+			},
+			dropped: []string{"Date"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			cmd := tc.cmd
+			cmd.ReadPageSize = 2
+			cmd.NameFormat = filepath.Join(tempDir, "part-%d.parquet")
+			cmd.URI = "../../testdata/all-types.parquet"
+			require.NoError(t, cmd.Run(context.Background()))
+
+			result := map[string]int64{}
+			require.NoError(t, filepath.WalkDir(tempDir, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil || entry.IsDir() {
+					return err
+				}
+				reader, err := pio.NewParquetFileReader(context.Background(), path, pio.ReadOption{})
+				require.NoError(t, err)
+				defer func() {
+					_ = reader.PFile.Close()
+				}()
+				rel, _ := filepath.Rel(tempDir, path)
+				result[filepath.ToSlash(rel)] = reader.GetNumRows()
+
+				schemaRoot, err := pschema.NewSchemaTree(context.Background(), reader, pschema.SchemaOption{})
+				require.NoError(t, err)
+				for _, child := range schemaRoot.Children {
+					require.NotContains(t, tc.dropped, child.Name)
+				}
+				require.Len(t, schemaRoot.Children, 50-len(tc.dropped))
+				return nil
+			}))
+			require.Equal(t, tc.result, result)
+		})
+	}
+}
+
+func TestCmdDropPartitionColumns(t *testing.T) {
+	tempDir := t.TempDir()
+	cmd := Cmd{
+		DropPartitionColumns: true,
+		MaxOpenFiles:         10,
+		NameFormat:           filepath.Join(tempDir, "part-%d.parquet"),
+		PartitionBy:          []string{"shoe_brand"},
+		ReadPageSize:         2,
+		URI:                  "../../testdata/good.parquet",
+	}
+	require.NoError(t, cmd.Run(context.Background()))
+
+	expected := map[string]string{
+		"shoe_brand=nike/part-0.parquet":        `[{"shoe_name":"air_griffey"}]`,
+		"shoe_brand=fila/part-0.parquet":        `[{"shoe_name":"grant_hill_2"}]`,
+		"shoe_brand=steph_curry/part-0.parquet": `[{"shoe_name":"curry7"}]`,
+	}
+	for name, rows := range expected {
+		catCmd := cat.Cmd{
+			ReadPageSize: 1000,
+			SampleRatio:  1.0,
+			Format:       "json",
+			URI:          filepath.Join(tempDir, name),
+		}
+		require.Equal(t, rows+"\n", testutils.CommandStdout(t, catCmd), name)
+	}
+}
+
+func TestEscapePartitionName(t *testing.T) {
+	testCases := map[string]string{
+		"US":                       "US",
+		"a b-c_d.e":                "a b-c_d.e",
+		"2022-01-01T00:00:00.000Z": "2022-01-01T00%3A00%3A00.000Z",
+		"a/b=c%d":                  "a%2Fb%3Dc%25d",
+		"\x01#'*?\\{[]^\"\x7f":     "%01%23%27%2A%3F%5C%7B%5B%5D%5E%22%7F",
+		"中文":                       "中文",
+	}
+	for value, expected := range testCases {
+		t.Run(value, func(t *testing.T) {
+			require.Equal(t, expected, escapePartitionName(value))
+		})
+	}
+}
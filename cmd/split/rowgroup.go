@@ -0,0 +1,38 @@
+package split
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hangxie/parquet-go/v3/reader"
+
+	pio "github.com/hangxie/parquet-tools/io"
+)
+
+// splitByRowGroup copies every --row-group-count row groups into a result file
+// as is, so encodings, statistics, and bloom filters are kept.
+func (c Cmd) splitByRowGroup(ctx context.Context, parquetReader *reader.ParquetReader) error {
+	if err := pio.CheckCopyable(parquetReader.Footer); err != nil {
+		return fmt.Errorf("cannot copy row groups from [%s]: %w", c.URI, err)
+	}
+
+	footer := *parquetReader.Footer
+	footer.KeyValueMetadata = c.metadata
+	rowGroups := parquetReader.Footer.RowGroups
+	for fileIndex := int64(0); len(rowGroups) != 0; fileIndex++ {
+		count := min(int64(len(rowGroups)), c.RowGroupCount)
+		sources := make([]pio.RowGroupSource, count)
+		for index := range sources {
+			sources[index] = pio.RowGroupSource{URI: c.URI, PFile: parquetReader.PFile, RowGroup: rowGroups[index]}
+		}
+		targetFile := c.names.name(fileIndex, "")
+		if err := pio.CreateParentDir(targetFile); err != nil {
+			return fmt.Errorf("failed to create directory for [%s]: %w", targetFile, err)
+		}
+		if err := pio.CopyRowGroups(ctx, targetFile, &footer, sources); err != nil {
+			return err
+		}
+		rowGroups = rowGroups[count:]
+	}
+	return nil
+}
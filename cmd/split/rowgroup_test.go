@@ -0,0 +1,123 @@
+package split
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pio "github.com/hangxie/parquet-tools/io"
+)
+
+func TestCmdRowGroupCount(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		testCases := map[string]struct {
+			cmd    Cmd
+			errMsg string
+		}{
+			"negative":     {Cmd{RowGroupCount: -1}, "invalid row group count -1, needs to be at least 1"},
+			"record-count": {Cmd{RowGroupCount: 1, RecordCount: 10}, "--row-group-count cannot be used with --file-count, --record-count, --max-file-size, or --partition-by"},
+			"partition-by": {Cmd{RowGroupCount: 1, PartitionBy: []string{"Bool"}}, "--row-group-count cannot be used with --file-count, --record-count, --max-file-size, or --partition-by"},
+			"encrypted-output": {
+				Cmd{RowGroupCount: 1, WriteOption: pio.WriteOption{EncryptAllColumns: true}},
+				"--row-group-count cannot write encrypted file",
+			},
+			"target-file": {
+				Cmd{RowGroupCount: 1, NameFormat: "dummy://%d.parquet"},
+				"unknown location scheme",
+			},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				tc.cmd.ReadPageSize = 10
+				if tc.cmd.NameFormat == "" {
+					tc.cmd.NameFormat = filepath.Join(t.TempDir(), "ut-%d.parquet")
+				}
+				if tc.cmd.URI == "" {
+					tc.cmd.URI = "../../testdata/row-group.parquet"
+				}
+				err := tc.cmd.Run(context.Background())
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+			})
+		}
+	})
+
+	testCases := map[string]struct {
+		source        string
+		rowGroupCount int64
+		result        [][]int64
+	}{
+		"one-per-file":  {"row-group.parquet", 1, [][]int64{{17}, {3}}},
+		"all-in-one":    {"row-group.parquet", 2, [][]int64{{17, 3}}},
+		"more-than-all": {"row-group.parquet", 10, [][]int64{{17, 3}}},
+		"bloom-filter":  {"bloom-filter.parquet", 1, [][]int64{{10}}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			cmd := Cmd{ReadPageSize: 10, RowGroupCount: tc.rowGroupCount, URI: filepath.Join("..", "..", "testdata", tc.source)}
+			cmd.NameFormat = filepath.Join(tempDir, "ut-%d.parquet")
+			require.NoError(t, cmd.Run(context.Background()))
+
+			source, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+			require.NoError(t, err)
+			_ = source.PFile.Close()
+			sourceData, err := os.ReadFile(cmd.URI)
+			require.NoError(t, err)
+
+			entries, err := os.ReadDir(tempDir)
+			require.NoError(t, err)
+			require.Len(t, entries, len(tc.result))
+			rowGroups := source.Footer.RowGroups
+			for fileIndex, expected := range tc.result {
+				targetFile := filepath.Join(tempDir, fmt.Sprintf("ut-%d.parquet", fileIndex))
+				require.True(t, testutils.HasSameSchema(cmd.URI, targetFile))
+				target, err := pio.NewParquetFileReader(context.Background(), targetFile, pio.ReadOption{})
+				require.NoError(t, err)
+				_ = target.PFile.Close()
+				targetData, err := os.ReadFile(targetFile)
+				require.NoError(t, err)
+
+				numRows := []int64{}
+				for _, rowGroup := range target.Footer.RowGroups {
+					numRows = append(numRows, rowGroup.NumRows)
+					for index, chunk := range rowGroups[0].Columns {
+						start, length := pio.ChunkRange(chunk.MetaData)
+						newStart, newLength := pio.ChunkRange(rowGroup.Columns[index].MetaData)
+						require.Equal(t, sourceData[start:start+length], targetData[newStart:newStart+newLength])
+						require.Equal(t, chunk.MetaData.Statistics, rowGroup.Columns[index].MetaData.Statistics)
+						require.Equal(t, chunk.MetaData.BloomFilterOffset == nil, rowGroup.Columns[index].MetaData.BloomFilterOffset == nil)
+					}
+					rowGroups = rowGroups[1:]
+				}
+				require.Equal(t, expected, numRows)
+			}
+			require.Empty(t, rowGroups)
+		})
+	}
+	t.Run("metadata", func(t *testing.T) {
+		tempDir := t.TempDir()
+		cmd := Cmd{
+			ReadPageSize:   10,
+			RowGroupCount:  1,
+			URI:            filepath.Join("..", "..", "testdata", "row-group.parquet"),
+			NameFormat:     filepath.Join(tempDir, "ut-%d.parquet"),
+			MetadataOption: pio.MetadataOption{SetMetadata: []string{"owner=parquet-tools"}},
+		}
+		require.NoError(t, cmd.Run(context.Background()))
+
+		for fileIndex := range 2 {
+			target, err := pio.NewParquetFileReader(context.Background(), filepath.Join(tempDir, fmt.Sprintf("ut-%d.parquet", fileIndex)), pio.ReadOption{})
+			require.NoError(t, err)
+			_ = target.PFile.Close()
+			require.Len(t, target.Footer.KeyValueMetadata, 1)
+			require.Equal(t, "owner", target.Footer.KeyValueMetadata[0].Key)
+			require.Equal(t, "parquet-tools", target.Footer.KeyValueMetadata[0].GetValue())
+		}
+	})
+}
@@ -3,8 +3,10 @@ package split
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"regexp"
 
+	"github.com/hangxie/parquet-go/v3/parquet"
 	"github.com/hangxie/parquet-go/v3/reader"
 	"github.com/hangxie/parquet-go/v3/writer"
 
@@ -15,39 +17,59 @@ import (
 // current writer state
 type trunkWriter struct {
 	fileIndex    int64
+	partition    string
 	targetFile   string
 	writer       *writer.ParquetWriter
 	recordCount  int64
 	paddingCount int64
 	schemaJSON   string
+	lastUsed     int64
+	output       *countingWriter
+	pendingSize  int64
+	rowGroups    *pio.RowGroupLimiter
 }
 
 // Cmd is a kong command for split
 type Cmd struct {
-	FailOnInt96    bool   `help:"Fail command if INT96 data type is present." name:"fail-on-int96" default:"false"`
-	FieldDelimiter string `name:"field-delimiter" help:"Delimiter separating nested field path components in field and column parameters" default:"."`
-	FileCount      int64  `xor:"RecordCount" help:"Generate exactly this number of result files, including empty files when needed."`
-	NameFormat     string `help:"Format to populate target file names" default:"result-%06d.parquet"`
-	ReadPageSize   int    `help:"Page size to read from Parquet." default:"1000"`
-	RecordCount    int64  `xor:"FileCount" help:"Result files will have at most this number of records"`
-	URI            string `arg:"" predictor:"file" help:"URI of Parquet file."`
+	DropPartitionColumns bool     `help:"(with --partition-by) remove partition columns from data, their values are in directory names only." default:"false"`
+	FailOnInt96          bool     `help:"Fail command if INT96 data type is present." name:"fail-on-int96" default:"false"`
+	FieldDelimiter       string   `name:"field-delimiter" help:"Delimiter separating nested field path components in field and column parameters" default:"."`
+	FileCount            int64    `xor:"RecordCount" help:"Generate exactly this number of result files, including empty files when needed."`
+	MaxFileSize          string   `help:"Roll over to next result file when current one is about to exceed this size, eg 512MB, units are B, KB, MB, GB, and TB in powers of 1024." placeholder:"size"`
+	MaxOpenFiles         int      `help:"(with --partition-by) maximum number of result files to keep open, rows of a partition whose file was closed go to a new file." default:"100"`
+	NameFormat           string   `help:"Format to populate target file names, either with one integer verb like result-%06d.parquet, or a template like {base}-{index:06d}-{uuid}.parquet." default:"result-%06d.parquet"`
+	PartitionBy          []string `name:"partition-by" help:"Split by values of these top level fields into Hive-style directories like field1=value1/field2=value2." placeholder:"field"`
+	ReadPageSize         int      `help:"Page size to read from Parquet." default:"1000"`
+	RecordCount          int64    `xor:"FileCount" help:"Result files will have at most this number of records"`
+	RowGroupCount        int64    `help:"Copy this number of row groups into each result file as is without decoding, write options do not apply."`
+	URI                  string   `arg:"" predictor:"file" help:"URI of Parquet file."`
+	pio.MetadataOption
 	pio.ReadOption
 	pio.WriteOption
 
-	current trunkWriter
+	current     trunkWriter
+	maxFileSize int64
+	metadata    []*parquet.KeyValue
+	names       nameTemplate
+	sizer       fileSizer
 }
 
-func (c *Cmd) openReader(ctx context.Context) (*reader.ParquetReader, error) {
+func (c *Cmd) openReader(ctx context.Context) (*reader.ParquetReader, *pschema.SchemaNode, error) {
 	parquetReader, err := pio.NewParquetFileReader(ctx, c.URI, c.ReadOption)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open [%s]: %w", c.URI, err)
+		return nil, nil, fmt.Errorf("failed to open [%s]: %w", c.URI, err)
 	}
 	schemaRoot, err := pschema.NewSchemaTree(ctx, parquetReader, pschema.SchemaOption{FailOnInt96: c.FailOnInt96})
 	if err != nil {
 		_ = parquetReader.PFile.Close()
-		return nil, fmt.Errorf("failed to load schema for [%s]: %w", c.URI, err)
+		return nil, nil, fmt.Errorf("failed to load schema for [%s]: %w", c.URI, err)
 	}
 	c.current.schemaJSON = schemaRoot.JSONSchema()
+	if c.metadata, err = c.KeyValueMetadata(parquetReader.Footer.KeyValueMetadata); err != nil {
+		_ = parquetReader.PFile.Close()
+		return nil, nil, err
+	}
+	c.sizer = newFileSizer(parquetReader.Footer)
 
 	if c.FileCount != 0 {
 		// Distribute rows across exactly FileCount files: each file holds
@@ -59,34 +81,36 @@ func (c *Cmd) openReader(ctx context.Context) (*reader.ParquetReader, error) {
 		c.current.paddingCount = parquetReader.GetNumRows() % c.FileCount
 	}
 
-	return parquetReader, nil
+	return parquetReader, schemaRoot, nil
 }
 
-func (c *Cmd) switchWriter(ctx context.Context) error {
-	if c.current.writer != nil {
-		if err := c.current.writer.WriteStopWithContext(ctx); err != nil {
-			return fmt.Errorf("failed to end write [%s]: %w", c.current.targetFile, err)
-		}
-		if err := c.current.writer.PFile.Close(); err != nil {
-			return fmt.Errorf("failed to close [%s]: %w", c.current.targetFile, err)
-		}
-		c.current.writer = nil
+func (c *Cmd) switchWriter(ctx context.Context, current *trunkWriter) error {
+	if err := c.closeWriter(ctx, current); err != nil {
+		return err
 	}
 
 	var err error
 	c.ReadOption.FieldDelimiter = c.FieldDelimiter
 	c.WriteOption.FieldDelimiter = c.FieldDelimiter
-	c.current.targetFile = fmt.Sprintf(c.NameFormat, c.current.fileIndex)
-	c.current.writer, err = pio.NewGenericWriter(ctx, c.current.targetFile, c.WriteOption, c.current.schemaJSON)
+	current.targetFile = c.names.name(current.fileIndex, current.partition)
+	if err := pio.CreateParentDir(current.targetFile); err != nil {
+		return fmt.Errorf("failed to create directory for [%s]: %w", current.targetFile, err)
+	}
+	current.writer, err = pio.NewGenericWriter(ctx, current.targetFile, c.WriteOption, current.schemaJSON)
 	if err != nil {
-		return fmt.Errorf("failed to write to [%s]: %w", c.current.targetFile, err)
+		return fmt.Errorf("failed to write to [%s]: %w", current.targetFile, err)
 	}
-	c.current.fileIndex++
-	if c.current.paddingCount != 0 {
-		c.current.recordCount = -1
-		c.current.paddingCount--
+	current.writer.Footer.KeyValueMetadata = c.metadata
+	current.output = &countingWriter{ParquetFileWriter: current.writer.PFile}
+	current.writer.PFile = current.output
+	current.pendingSize = 0
+	current.rowGroups = pio.NewRowGroupLimiter(c.RowGroupRows)
+	current.fileIndex++
+	if current.paddingCount != 0 {
+		current.recordCount = -1
+		current.paddingCount--
 	} else {
-		c.current.recordCount = 0
+		current.recordCount = 0
 	}
 
 	return nil
@@ -100,20 +124,60 @@ func (c Cmd) Run(ctx context.Context) error {
 	if c.ReadPageSize < 1 {
 		return fmt.Errorf("invalid read page size %d, needs to be at least 1", c.ReadPageSize)
 	}
-	if c.FileCount == 0 && c.RecordCount == 0 {
-		return fmt.Errorf("needs either --file-count or --record-count")
+	if c.RowGroupCount < 0 {
+		return fmt.Errorf("invalid row group count %d, needs to be at least 1", c.RowGroupCount)
 	}
-	if err := checkNameFormat(c.NameFormat); err != nil {
+	if c.RowGroupCount != 0 {
+		if c.FileCount != 0 || c.RecordCount != 0 || c.MaxFileSize != "" || len(c.PartitionBy) != 0 {
+			return fmt.Errorf("--row-group-count cannot be used with --file-count, --record-count, --max-file-size, or --partition-by")
+		}
+		if c.WriterFooterKey != nil || len(c.WriterColumnKeys) != 0 || c.WriterKeyFile != nil || c.EncryptAllColumns || c.PlaintextFooter {
+			return fmt.Errorf("--row-group-count cannot write encrypted file")
+		}
+	}
+	if c.MaxFileSize != "" {
+		if c.FileCount != 0 {
+			return fmt.Errorf("--max-file-size cannot be used with --file-count")
+		}
+		maxFileSize, err := pio.ParseByteSize(c.MaxFileSize)
+		if err != nil {
+			return fmt.Errorf("invalid max file size [%s]: %w", c.MaxFileSize, err)
+		}
+		c.maxFileSize = maxFileSize
+	}
+	if len(c.PartitionBy) != 0 {
+		if c.FileCount != 0 {
+			return fmt.Errorf("--partition-by cannot be used with --file-count")
+		}
+		if c.MaxOpenFiles < 1 {
+			return fmt.Errorf("invalid max open files %d, needs to be at least 1", c.MaxOpenFiles)
+		}
+	} else if c.FileCount == 0 && c.RecordCount == 0 && c.maxFileSize == 0 && c.RowGroupCount == 0 {
+		return fmt.Errorf("needs one of --file-count, --record-count, --max-file-size, or --row-group-count")
+	}
+	names, err := newNameTemplate(c.NameFormat, c.URI)
+	if err != nil {
 		return fmt.Errorf("invalid name format [%s]: %w", c.NameFormat, err)
 	}
+	if names.hasPartition && len(c.PartitionBy) == 0 {
+		return fmt.Errorf("invalid name format [%s]: {partition} can only be used with --partition-by", c.NameFormat)
+	}
+	c.names = names
 
-	parquetReader, err := c.openReader(ctx)
+	parquetReader, schemaRoot, err := c.openReader(ctx)
 	if err != nil {
 		return err
 	}
 	defer func() {
 		_ = parquetReader.PFile.Close()
 	}()
+
+	if len(c.PartitionBy) != 0 {
+		return c.splitByPartition(ctx, parquetReader, schemaRoot)
+	}
+	if c.RowGroupCount != 0 {
+		return c.splitByRowGroup(ctx, parquetReader)
+	}
 	// Best-effort close of the in-flight target writer on any early return
 	// (read/write/switch error). closeWriter clears current.writer on the
 	// success path so this does not double-close.
@@ -137,40 +201,61 @@ func (c Cmd) Run(ctx context.Context) error {
 			break
 		}
 		for _, row := range rows {
-			if c.current.recordCount == c.RecordCount {
-				if err := c.switchWriter(ctx); err != nil {
+			size := c.rowSize(row)
+			if c.current.recordCount == c.RecordCount || c.sizer.exceeds(&c.current, size, c.maxFileSize) {
+				if err := c.switchWriter(ctx, &c.current); err != nil {
 					return err
 				}
 			}
-			if err := c.current.writer.WriteWithContext(ctx, row); err != nil {
-				return fmt.Errorf("failed to write data from [%s]: %w", c.current.targetFile, err)
+			if err := c.writeRow(ctx, &c.current, row, size); err != nil {
+				return err
 			}
-			c.current.recordCount++
 		}
 	}
 	for c.current.fileIndex < c.FileCount {
-		if err := c.switchWriter(ctx); err != nil {
+		if err := c.switchWriter(ctx, &c.current); err != nil {
 			return err
 		}
 	}
-	if err := c.closeWriter(ctx); err != nil {
+	if err := c.closeWriter(ctx, &c.current); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (c *Cmd) closeWriter(ctx context.Context) error {
-	if c.current.writer == nil {
+// rowSize is only needed by --max-file-size.
+func (c *Cmd) rowSize(row any) int64 {
+	if c.maxFileSize == 0 {
+		return 0
+	}
+	return pio.RowSize(reflect.ValueOf(row))
+}
+
+func (c *Cmd) writeRow(ctx context.Context, current *trunkWriter, row any, size int64) error {
+	writtenBefore := current.output.written
+	if err := current.writer.WriteWithContext(ctx, row); err != nil {
+		return fmt.Errorf("failed to write data from [%s]: %w", current.targetFile, err)
+	}
+	if err := current.rowGroups.Written(ctx, current.writer); err != nil {
+		return fmt.Errorf("failed to write data from [%s]: %w", current.targetFile, err)
+	}
+	c.sizer.written(current, size, writtenBefore)
+	current.recordCount++
+	return nil
+}
+
+func (c *Cmd) closeWriter(ctx context.Context, current *trunkWriter) error {
+	if current.writer == nil {
 		return nil
 	}
-	if err := c.current.writer.WriteStopWithContext(ctx); err != nil {
-		return fmt.Errorf("failed to end write [%s]: %w", c.current.targetFile, err)
+	if err := current.writer.WriteStopWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to end write [%s]: %w", current.targetFile, err)
 	}
-	if err := c.current.writer.PFile.Close(); err != nil {
-		return fmt.Errorf("failed to close [%s]: %w", c.current.targetFile, err)
+	if err := current.writer.PFile.Close(); err != nil {
+		return fmt.Errorf("failed to close [%s]: %w", current.targetFile, err)
 	}
-	c.current.writer = nil
+	current.writer = nil
 	return nil
 }
 
@@ -34,7 +34,7 @@ func TestCmd(t *testing.T) {
 		},
 		"no-count": {
 			cmd:    Cmd{ReadOption: rOpt, FailOnInt96: false, FileCount: 0, NameFormat: "", ReadPageSize: 1000, RecordCount: 0, URI: "dummy", current: tw},
-			errMsg: "needs either --file-count or --record-count",
+			errMsg: "needs one of --file-count, --record-count, --max-file-size, or --row-group-count",
 		},
 		"name-format": {
 			cmd:    Cmd{ReadOption: rOpt, FailOnInt96: false, FileCount: 0, NameFormat: "ut-%%parquet", ReadPageSize: 1000, RecordCount: 10, URI: "", current: tw},
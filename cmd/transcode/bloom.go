@@ -0,0 +1,105 @@
+package transcode
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/reader"
+
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+const (
+	// autoBloomFilterSampleRows is number of rows --auto-bloom-filter samples
+	// from beginning of source file.
+	autoBloomFilterSampleRows = 10000
+	// autoBloomFilterRatio is minimum ratio of distinct values to non-null
+	// values of a high-cardinality field, fields below it are better served
+	// by dictionary encoding and statistics.
+	autoBloomFilterRatio = 0.5
+)
+
+// autoBloomFilterCandidates returns BYTE_ARRAY, INT32, and INT64 fields that
+// do not have bloom filter yet, fields in repeated fields and annotated groups
+// like VARIANT are not candidates.
+func autoBloomFilterCandidates(node *pschema.SchemaNode) []*pschema.SchemaNode {
+	var candidates []*pschema.SchemaNode
+	for _, child := range node.Children {
+		if child.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+			continue
+		}
+		if child.Type == nil {
+			if child.LogicalType == nil && child.ConvertedType == nil {
+				candidates = append(candidates, autoBloomFilterCandidates(child)...)
+			}
+			continue
+		}
+		if child.BloomFilter == "true" {
+			continue
+		}
+		switch *child.Type {
+		case parquet.Type_BYTE_ARRAY, parquet.Type_INT32, parquet.Type_INT64:
+			candidates = append(candidates, child)
+		}
+	}
+	return candidates
+}
+
+// autoBloomFilter enables bloom filters of high-cardinality fields in root,
+// cardinality is from rows sampled from beginning of source file.
+func (c Cmd) autoBloomFilter(ctx context.Context, fileReader *reader.ParquetReader, root *pschema.SchemaNode) error {
+	candidates := autoBloomFilterCandidates(root)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	paths := make([][]string, len(candidates))
+	keys := make([]pschema.SortKey, len(candidates))
+	for index, node := range candidates {
+		paths[index] = node.ExNamePath[1:]
+		keys[index] = pschema.SortKey{InPath: node.InNamePath[1:]}
+	}
+	sampleRoot := cloneSchema(root)
+	keepColumns(sampleRoot, paths)
+	sampler, err := pio.NewProjectedParquetReader(ctx, fileReader, sampleRoot.JSONSchema(), c.ReadOption)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sampler.ReadStopWithContext(context.WithoutCancel(ctx))
+	}()
+
+	distinct := make([]map[any]struct{}, len(candidates))
+	nonNull := make([]int, len(candidates))
+	for index := range candidates {
+		distinct[index] = map[any]struct{}{}
+	}
+	for sampled := 0; sampled < autoBloomFilterSampleRows; {
+		rows, err := sampler.ReadByNumberWithContext(ctx, min(c.ReadPageSize, autoBloomFilterSampleRows-sampled))
+		if err != nil {
+			return fmt.Errorf("failed to read from [%s]: %w", c.Source, err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		for _, row := range rows {
+			for index, key := range keys {
+				if value := key.Value(row); value != nil {
+					nonNull[index]++
+					distinct[index][value] = struct{}{}
+				}
+			}
+		}
+		sampled += len(rows)
+	}
+
+	for index, node := range candidates {
+		if nonNull[index] != 0 && float64(len(distinct[index])) >= float64(nonNull[index])*autoBloomFilterRatio {
+			node.BloomFilter = "true"
+			node.BloomFilterSize = ""
+		}
+	}
+	return nil
+}
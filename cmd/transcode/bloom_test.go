@@ -0,0 +1,151 @@
+package transcode
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/stretchr/testify/require"
+
+	pio "github.com/hangxie/parquet-tools/io"
+)
+
+func TestAutoBloomFilterCandidates(t *testing.T) {
+	schemaTree := loadSchemaTree(t, "../../testdata/all-types.parquet")
+	candidates := autoBloomFilterCandidates(schemaTree)
+	require.Len(t, candidates, 34)
+	require.Equal(t, "Int32", candidates[0].Name)
+	require.Equal(t, "decimal5", candidates[len(candidates)-1].Name)
+	for _, node := range candidates {
+		require.NotContains(t, []string{"Bool", "Int96", "Uuid", "Repeated", "Variant"}, node.Name)
+	}
+
+	schemaTree.Children[1].BloomFilter = "true"
+	candidates = autoBloomFilterCandidates(schemaTree)
+	require.Len(t, candidates, 33)
+	require.Equal(t, "Int64", candidates[0].Name)
+}
+
+func TestCmdPageIndexAndBloomFilter(t *testing.T) {
+	testCases := map[string]struct {
+		cmd         Cmd
+		bloomFilter []bool
+		rowGroups   []int64
+	}{
+		"auto-bloom-filter": {
+			cmd:         Cmd{AutoBloomFilter: true},
+			bloomFilter: []bool{true, true},
+			rowGroups:   []int64{3},
+		},
+		"field-bloom-filter-wins": {
+			cmd:         Cmd{AutoBloomFilter: true, FieldBloomFilter: []string{"name=false"}, RenameColumn: []string{"shoe_name=name"}},
+			bloomFilter: []bool{true, false},
+			rowGroups:   []int64{3},
+		},
+		"row-group-rows": {
+			cmd:         Cmd{WriteOption: pio.WriteOption{RowGroupRows: 2}},
+			bloomFilter: []bool{false, false},
+			rowGroups:   []int64{2, 1},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cmd := tc.cmd
+			cmd.FieldDelimiter = "."
+			cmd.ReadPageSize = 10
+			cmd.Source = "../../testdata/good.parquet"
+			cmd.URI = filepath.Join(t.TempDir(), "target.parquet")
+			cmd.CompressionCodec = "SNAPPY"
+			cmd.PageSize = 1024 * 1024
+			cmd.RowGroupSize = 128 * 1024 * 1024
+			require.NoError(t, cmd.Run(context.Background()))
+
+			fileReader, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+			require.NoError(t, err)
+			defer func() {
+				_ = fileReader.PFile.Close()
+			}()
+			var rowGroups []int64
+			for _, rowGroup := range fileReader.Footer.RowGroups {
+				rowGroups = append(rowGroups, rowGroup.NumRows)
+				for index, column := range rowGroup.Columns {
+					require.Equal(t, tc.bloomFilter[index], column.MetaData.BloomFilterOffset != nil)
+					require.NotNil(t, column.ColumnIndexOffset)
+					require.NotNil(t, column.OffsetIndexOffset)
+				}
+			}
+			require.Equal(t, tc.rowGroups, rowGroups)
+		})
+	}
+}
+
+func TestCmdPageIndex(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+
+	// source file without page indexes, chunks of good.parquet are copied as is
+	// with their page indexes dropped
+	sourceReader, err := pio.NewParquetFileReader(ctx, "../../testdata/good.parquet", pio.ReadOption{})
+	require.NoError(t, err)
+	defer func() {
+		_ = sourceReader.PFile.Close()
+	}()
+	var rowGroups []pio.RowGroupSource
+	for _, rowGroup := range sourceReader.Footer.RowGroups {
+		noIndex := *rowGroup
+		noIndex.Columns = make([]*parquet.ColumnChunk, len(rowGroup.Columns))
+		for index, column := range rowGroup.Columns {
+			chunk := *column
+			chunk.ColumnIndexOffset, chunk.ColumnIndexLength = nil, nil
+			chunk.OffsetIndexOffset, chunk.OffsetIndexLength = nil, nil
+			noIndex.Columns[index] = &chunk
+		}
+		rowGroups = append(rowGroups, pio.RowGroupSource{URI: "good.parquet", PFile: sourceReader.PFile, RowGroup: &noIndex})
+	}
+	source := filepath.Join(tempDir, "no-index.parquet")
+	require.NoError(t, pio.CopyRowGroups(ctx, source, sourceReader.Footer, rowGroups))
+
+	testCases := map[string]struct {
+		uri         string
+		omitStats   string
+		columnIndex bool
+		offsetIndex bool
+	}{
+		"source":     {source, "", false, false},
+		"transcoded": {filepath.Join(tempDir, "transcoded.parquet"), "", true, true},
+		"omit-stats": {filepath.Join(tempDir, "omit-stats.parquet"), "true", false, true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if tc.uri != source {
+				cmd := Cmd{FieldDelimiter: ".", OmitStats: tc.omitStats, ReadPageSize: 10, Source: source, URI: tc.uri}
+				cmd.CompressionCodec = "SNAPPY"
+				cmd.PageSize = 1024 * 1024
+				cmd.RowGroupSize = 128 * 1024 * 1024
+				require.NoError(t, cmd.Run(ctx))
+			}
+
+			fileReader, err := pio.NewParquetFileReader(ctx, tc.uri, pio.ReadOption{})
+			require.NoError(t, err)
+			defer func() {
+				_ = fileReader.PFile.Close()
+			}()
+			require.NotEmpty(t, fileReader.Footer.RowGroups)
+			for rowGroupIndex, rowGroup := range fileReader.Footer.RowGroups {
+				for index := range rowGroup.Columns {
+					columnIndex, err := fileReader.ReadColumnIndexWithContext(ctx, rowGroupIndex, index)
+					require.NoError(t, err)
+					require.Equal(t, tc.columnIndex, columnIndex != nil)
+
+					offsetIndex, err := fileReader.ReadOffsetIndexWithContext(ctx, rowGroupIndex, index)
+					require.NoError(t, err)
+					require.Equal(t, tc.offsetIndex, offsetIndex != nil)
+					if offsetIndex != nil {
+						require.NotEmpty(t, offsetIndex.PageLocations)
+					}
+				}
+			}
+		})
+	}
+}
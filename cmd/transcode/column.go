@@ -0,0 +1,250 @@
+package transcode
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hangxie/parquet-go/v3/common"
+	"github.com/hangxie/parquet-go/v3/parquet"
+
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// columnRename is a parsed --rename-column.
+type columnRename struct {
+	path    []string
+	newName string
+}
+
+func (c Cmd) columnPath(spec string) ([]string, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty field path")
+	}
+	return common.StrToPath(pio.NormalizeFieldPath(spec, c.FieldDelimiter)), nil
+}
+
+// parseColumnPaths parses field paths of --keep-column or --drop-column.
+func (c Cmd) parseColumnPaths(specs []string, flag string) ([][]string, error) {
+	paths := make([][]string, len(specs))
+	for index, spec := range specs {
+		path, err := c.columnPath(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s [%s]: %w", flag, spec, err)
+		}
+		paths[index] = path
+	}
+	return paths, nil
+}
+
+// parseColumnRenames parses --rename-column in "field.path=new_name" format.
+func (c Cmd) parseColumnRenames() ([]columnRename, error) {
+	renames := make([]columnRename, len(c.RenameColumn))
+	for index, spec := range c.RenameColumn {
+		rawFieldPath, rawName, found := strings.Cut(spec, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid rename column format [%s], expected 'field.path=new_name'", spec)
+		}
+		path, err := c.columnPath(rawFieldPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rename column [%s]: %w", spec, err)
+		}
+		newName := strings.TrimSpace(rawName)
+		if newName == "" {
+			return nil, fmt.Errorf("empty new name in [%s]", spec)
+		}
+		if strings.Contains(newName, c.columnPathDelimiter()) {
+			return nil, fmt.Errorf("new name [%s] in [%s] is a name instead of a path, it cannot have field delimiter", newName, spec)
+		}
+		renames[index] = columnRename{path: path, newName: newName}
+	}
+	return renames, nil
+}
+
+func (c Cmd) columnPathDelimiter() string {
+	if c.FieldDelimiter == "" {
+		return "."
+	}
+	return c.FieldDelimiter
+}
+
+// selectColumns returns a copy of schema tree with --keep-column and
+// --drop-column applied, a path to a group node selects everything underneath
+// it, fields are in the same order as source.
+func (c Cmd) selectColumns(root *pschema.SchemaNode, keepPaths, dropPaths [][]string) (*pschema.SchemaNode, error) {
+	for _, path := range slices.Concat(keepPaths, dropPaths) {
+		if _, err := c.findColumn(root, path); err != nil {
+			return nil, err
+		}
+	}
+
+	selected := cloneSchema(root)
+	if len(keepPaths) != 0 {
+		keepColumns(selected, keepPaths)
+	}
+	if err := c.dropColumns(selected, dropPaths); err != nil {
+		return nil, err
+	}
+	return selected, nil
+}
+
+// findColumn returns node on path, nodes that LIST, MAP, and other annotated
+// groups are made of cannot be selected on their own.
+func (c Cmd) findColumn(root *pschema.SchemaNode, path []string) (*pschema.SchemaNode, error) {
+	var parent, grandparent *pschema.SchemaNode
+	node := root
+	for _, name := range path {
+		grandparent, parent = parent, node
+		if node = childByName(parent, name); node == nil {
+			return nil, fmt.Errorf("field [%s] does not exist", strings.Join(path, c.columnPathDelimiter()))
+		}
+	}
+	annotated := parent.LogicalType != nil || parent.ConvertedType != nil
+	repeated := parent.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED
+	if annotated || (repeated && grandparent != nil && isListOrMap(grandparent)) {
+		return nil, fmt.Errorf("field [%s] is part of LIST, MAP, or other annotated group, it cannot be dropped, kept, or renamed on its own", strings.Join(path, c.columnPathDelimiter()))
+	}
+	return node, nil
+}
+
+func isListOrMap(node *pschema.SchemaNode) bool {
+	if node.LogicalType != nil && (node.LogicalType.IsSetLIST() || node.LogicalType.IsSetMAP()) {
+		return true
+	}
+	if node.ConvertedType == nil {
+		return false
+	}
+	switch *node.ConvertedType {
+	case parquet.ConvertedType_LIST, parquet.ConvertedType_MAP, parquet.ConvertedType_MAP_KEY_VALUE:
+		return true
+	}
+	return false
+}
+
+// keepColumns removes fields that are not on any of paths.
+func keepColumns(node *pschema.SchemaNode, paths [][]string) {
+	children := node.Children[:0]
+	for _, child := range node.Children {
+		var childPaths [][]string
+		keepAll := false
+		for _, path := range paths {
+			if path[0] != child.Name {
+				continue
+			}
+			if len(path) == 1 {
+				keepAll = true
+				break
+			}
+			childPaths = append(childPaths, path[1:])
+		}
+		if !keepAll && len(childPaths) == 0 {
+			continue
+		}
+		if !keepAll {
+			keepColumns(child, childPaths)
+		}
+		children = append(children, child)
+	}
+	node.Children = children
+	node.NumChildren = new(int32(len(children)))
+}
+
+// dropColumns removes fields on paths, a group cannot lose all of its fields.
+func (c Cmd) dropColumns(node *pschema.SchemaNode, paths [][]string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	children := node.Children[:0]
+	for _, child := range node.Children {
+		var childPaths [][]string
+		dropped := false
+		for _, path := range paths {
+			if path[0] != child.Name {
+				continue
+			}
+			if len(path) == 1 {
+				dropped = true
+				break
+			}
+			childPaths = append(childPaths, path[1:])
+		}
+		if dropped {
+			continue
+		}
+		if err := c.dropColumns(child, childPaths); err != nil {
+			return err
+		}
+		children = append(children, child)
+	}
+	if len(children) == 0 {
+		if len(node.ExNamePath) <= 1 {
+			return fmt.Errorf("cannot drop all fields")
+		}
+		return fmt.Errorf("cannot drop all fields of [%s]", strings.Join(node.ExNamePath[1:], c.columnPathDelimiter()))
+	}
+	node.Children = children
+	node.NumChildren = new(int32(len(children)))
+	return nil
+}
+
+// renameColumns applies --rename-column to selected schema tree, only
+// external names change so rows read with source names can be written as is.
+func (c Cmd) renameColumns(root *pschema.SchemaNode, renames []columnRename) error {
+	nodes := make([]*pschema.SchemaNode, len(renames))
+	parents := make([]*pschema.SchemaNode, len(renames))
+	for index, rename := range renames {
+		node, err := c.findColumn(root, rename.path)
+		if err != nil {
+			return err
+		}
+		nodes[index] = node
+		parents[index] = root
+		for _, name := range rename.path[:len(rename.path)-1] {
+			parents[index] = childByName(parents[index], name)
+		}
+	}
+	for index, rename := range renames {
+		if sibling := childByName(parents[index], rename.newName); sibling != nil && sibling != nodes[index] {
+			return fmt.Errorf("cannot rename [%s] to [%s], field [%s] already exists", strings.Join(rename.path, c.columnPathDelimiter()), rename.newName, rename.newName)
+		}
+		renameNode(nodes[index], len(rename.path), rename.newName)
+	}
+	return nil
+}
+
+// renameNode changes name of node, depth is where its name is in ExNamePath
+// of node and its descendants.
+func renameNode(node *pschema.SchemaNode, depth int, newName string) {
+	if len(node.ExNamePath) > depth {
+		node.ExNamePath = slices.Clone(node.ExNamePath)
+		node.ExNamePath[depth] = newName
+		if len(node.ExNamePath) == depth+1 {
+			node.Name = newName
+		}
+	}
+	for _, child := range node.Children {
+		renameNode(child, depth, newName)
+	}
+}
+
+func childByName(node *pschema.SchemaNode, name string) *pschema.SchemaNode {
+	for _, child := range node.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// cloneSchema copies the tree so that it can be changed without touching
+// schema of source file.
+func cloneSchema(node *pschema.SchemaNode) *pschema.SchemaNode {
+	clone := *node
+	clone.Children = make([]*pschema.SchemaNode, len(node.Children))
+	for index, child := range node.Children {
+		clone.Children[index] = cloneSchema(child)
+	}
+	return &clone
+}
@@ -0,0 +1,175 @@
+package transcode
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func loadSchemaTree(t *testing.T, uri string) *pschema.SchemaNode {
+	fileReader, err := pio.NewParquetFileReader(context.Background(), uri, pio.ReadOption{})
+	require.NoError(t, err)
+	defer func() {
+		_ = fileReader.PFile.Close()
+	}()
+	schemaTree, err := pschema.NewSchemaTree(context.Background(), fileReader, pschema.SchemaOption{})
+	require.NoError(t, err)
+	return schemaTree
+}
+
+func childNames(node *pschema.SchemaNode) []string {
+	names := make([]string, len(node.Children))
+	for index, child := range node.Children {
+		names[index] = child.Name
+	}
+	return names
+}
+
+func TestCmdColumns(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		testCases := map[string]struct {
+			cmd    Cmd
+			errMsg string
+		}{
+			"empty-keep":      {Cmd{KeepColumn: []string{" "}}, "invalid keep column [ ]: empty field path"},
+			"empty-drop":      {Cmd{DropColumn: []string{""}}, "invalid drop column []: empty field path"},
+			"rename-format":   {Cmd{RenameColumn: []string{"shoe_name"}}, "invalid rename column format [shoe_name], expected 'field.path=new_name'"},
+			"rename-no-path":  {Cmd{RenameColumn: []string{"=name"}}, "invalid rename column [=name]: empty field path"},
+			"rename-no-name":  {Cmd{RenameColumn: []string{"shoe_name= "}}, "empty new name in [shoe_name= ]"},
+			"rename-path":     {Cmd{RenameColumn: []string{"shoe_name=a.b"}}, "new name [a.b] in [shoe_name=a.b] is a name instead of a path, it cannot have field delimiter"},
+			"keep-not-exist":  {Cmd{KeepColumn: []string{"foo"}}, "field [foo] does not exist"},
+			"drop-all":        {Cmd{DropColumn: []string{"shoe_name", "shoe_brand"}}, "cannot drop all fields"},
+			"keep-drop-all":   {Cmd{DropColumn: []string{"shoe_name"}, KeepColumn: []string{"shoe_name"}}, "cannot drop all fields"},
+			"rename-conflict": {Cmd{RenameColumn: []string{"shoe_name=shoe_brand"}}, "cannot rename [shoe_name] to [shoe_brand], field [shoe_brand] already exists"},
+			"rename-dropped":  {Cmd{DropColumn: []string{"shoe_name"}, RenameColumn: []string{"shoe_name=name"}}, "field [shoe_name] does not exist"},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				tc.cmd.FieldDelimiter = "."
+				tc.cmd.ReadPageSize = 10
+				tc.cmd.Source = "../../testdata/good.parquet"
+				tc.cmd.URI = filepath.Join(t.TempDir(), "target.parquet")
+				err := tc.cmd.Run(context.Background())
+				require.Error(t, err)
+				require.Equal(t, tc.errMsg, err.Error())
+			})
+		}
+	})
+
+	testCases := map[string]struct {
+		cmd    Cmd
+		fields []string
+		output string
+	}{
+		"keep": {
+			cmd:    Cmd{KeepColumn: []string{"shoe_name"}},
+			fields: []string{"shoe_name"},
+			output: `{"shoe_name":"air_griffey"}` + "\n" + `{"shoe_name":"grant_hill_2"}` + "\n" + `{"shoe_name":"curry7"}` + "\n",
+		},
+		"drop": {
+			cmd:    Cmd{DropColumn: []string{"shoe_name"}},
+			fields: []string{"shoe_brand"},
+			output: `{"shoe_brand":"nike"}` + "\n" + `{"shoe_brand":"fila"}` + "\n" + `{"shoe_brand":"steph_curry"}` + "\n",
+		},
+		"rename": {
+			cmd:    Cmd{RenameColumn: []string{"shoe_brand=brand", "shoe_name=name"}},
+			fields: []string{"brand", "name"},
+			output: `{"brand":"nike","name":"air_griffey"}` + "\n" + `{"brand":"fila","name":"grant_hill_2"}` + "\n" + `{"brand":"steph_curry","name":"curry7"}` + "\n",
+		},
+		"keep-and-rename": {
+			cmd:    Cmd{FieldCompression: []string{"brand=GZIP"}, KeepColumn: []string{"shoe_brand"}, RenameColumn: []string{"shoe_brand=brand"}},
+			fields: []string{"brand"},
+			output: `{"brand":"nike"}` + "\n" + `{"brand":"fila"}` + "\n" + `{"brand":"steph_curry"}` + "\n",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cmd := tc.cmd
+			cmd.FieldDelimiter = "."
+			cmd.ReadPageSize = 10
+			cmd.Source = "../../testdata/good.parquet"
+			cmd.URI = filepath.Join(t.TempDir(), "target.parquet")
+			cmd.WriteOption = pio.WriteOption{CompressionCodec: "SNAPPY", PageSize: 1024 * 1024, RowGroupSize: 128 * 1024 * 1024}
+			require.NoError(t, cmd.Run(context.Background()))
+
+			require.Equal(t, tc.fields, childNames(loadSchemaTree(t, cmd.URI)))
+			catCmd := transcodeTestCatCmd(cmd.URI, pio.ReadOption{})
+			catCmd.Format = "jsonl"
+			require.Equal(t, tc.output, testutils.CommandStdout(t, catCmd))
+		})
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	schemaTree := loadSchemaTree(t, "../../testdata/all-types.parquet")
+	cmd := Cmd{FieldDelimiter: "."}
+
+	t.Run("keep", func(t *testing.T) {
+		selected, err := cmd.selectColumns(schemaTree, [][]string{{"NestedList", "list", "element", "Map"}, {"Bool"}}, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"Bool", "NestedList"}, childNames(selected))
+		element := selected.Children[1].Children[0].Children[0]
+		require.Equal(t, []string{"Map"}, childNames(element))
+		require.Equal(t, []string{"key_value"}, childNames(element.Children[0]))
+		require.Equal(t, int32(1), *element.NumChildren)
+		require.Len(t, schemaTree.Children, 50)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		selected, err := cmd.selectColumns(schemaTree, nil, [][]string{{"Int96"}, {"NestedMap", "key_value", "value", "List"}})
+		require.NoError(t, err)
+		require.Len(t, selected.Children, 49)
+		require.NotContains(t, childNames(selected), "Int96")
+		value := selected.Children[len(selected.Children)-2].Children[0].Children[1]
+		require.Equal(t, []string{"Map"}, childNames(value))
+		require.Len(t, schemaTree.Children, 50)
+	})
+
+	errorCases := map[string]struct {
+		keep   [][]string
+		drop   [][]string
+		errMsg string
+	}{
+		"not-exist":        {keep: [][]string{{"List", "foo"}}, errMsg: "field [List.foo] does not exist"},
+		"list-element":     {drop: [][]string{{"List", "list", "element"}}, errMsg: "field [List.list.element] is part of LIST, MAP, or other annotated group, it cannot be dropped, kept, or renamed on its own"},
+		"list-repeated":    {keep: [][]string{{"List", "list"}}, errMsg: "field [List.list] is part of LIST, MAP, or other annotated group, it cannot be dropped, kept, or renamed on its own"},
+		"map-key":          {drop: [][]string{{"Map", "key_value", "key"}}, errMsg: "field [Map.key_value.key] is part of LIST, MAP, or other annotated group, it cannot be dropped, kept, or renamed on its own"},
+		"variant":          {drop: [][]string{{"Variant", "value"}}, errMsg: "field [Variant.value] is part of LIST, MAP, or other annotated group, it cannot be dropped, kept, or renamed on its own"},
+		"drop-whole-group": {drop: [][]string{{"NestedList", "list", "element", "Map"}, {"NestedList", "list", "element", "List"}}, errMsg: "cannot drop all fields of [NestedList.list.element]"},
+	}
+	for name, tc := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := cmd.selectColumns(schemaTree, tc.keep, tc.drop)
+			require.Error(t, err)
+			require.Equal(t, tc.errMsg, err.Error())
+		})
+	}
+}
+
+func TestRenameColumns(t *testing.T) {
+	schemaTree := cloneSchema(loadSchemaTree(t, "../../testdata/all-types.parquet"))
+	cmd := Cmd{FieldDelimiter: "."}
+	renames := []columnRename{
+		{path: []string{"NestedList"}, newName: "nested_list"},
+		{path: []string{"NestedList", "list", "element", "Map"}, newName: "m"},
+		{path: []string{"Bool"}, newName: "Bool"},
+	}
+	require.NoError(t, cmd.renameColumns(schemaTree, renames))
+
+	nestedList := schemaTree.Children[len(schemaTree.Children)-1]
+	require.Equal(t, "nested_list", nestedList.Name)
+	require.Equal(t, "NestedList", nestedList.InNamePath[1])
+	renamed := nestedList.Children[0].Children[0].Children[0]
+	require.Equal(t, "m", renamed.Name)
+	require.Equal(t, []string{"nested_list", "list", "element", "m"}, renamed.ExNamePath[1:])
+	require.Equal(t, []string{"nested_list", "list", "element", "m", "key_value", "key"}, renamed.Children[0].Children[0].ExNamePath[1:])
+
+	source := loadSchemaTree(t, "../../testdata/all-types.parquet")
+	require.Equal(t, "NestedList", source.Children[len(source.Children)-1].Name)
+}
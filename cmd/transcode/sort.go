@@ -0,0 +1,264 @@
+package transcode
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/reader"
+	"github.com/hangxie/parquet-go/v3/writer"
+	"golang.org/x/sync/errgroup"
+
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+// sortField is a field in --sort-by, columnIndex is index of the leaf column
+// in target schema.
+type sortField struct {
+	descending  bool
+	columnIndex int32
+	key         pschema.SortKey
+}
+
+// sortFields parses --sort-by against target schema.
+func (c Cmd) sortFields(root *pschema.SchemaNode) ([]sortField, error) {
+	fields := make([]sortField, len(c.SortBy))
+	for index, spec := range c.SortBy {
+		path, descending, err := pschema.ParseSortBy(spec, c.columnPathDelimiter())
+		if err != nil {
+			return nil, err
+		}
+		node, err := root.FindSortField(path, c.columnPathDelimiter())
+		if err != nil {
+			return nil, err
+		}
+		fields[index] = sortField{descending: descending, columnIndex: root.ColumnIndex(node), key: pschema.NewSortKey(node)}
+	}
+	return fields, nil
+}
+
+// sortingColumns is SortingColumns of row groups of target file.
+func sortingColumns(fields []sortField) []*parquet.SortingColumn {
+	result := make([]*parquet.SortingColumn, len(fields))
+	for index, field := range fields {
+		result[index] = pschema.NewSortingColumn(field.columnIndex, field.descending)
+	}
+	return result
+}
+
+// compareRows compares keys of two rows in order of --sort-by.
+func compareRows(fields []sortField, a, b []any) int {
+	for index, field := range fields {
+		result := pschema.CompareSortKeys(a[index], b[index])
+		if field.descending {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// sortedRow is a row with its keys.
+type sortedRow struct {
+	row  any
+	keys []any
+}
+
+func rowKeys(fields []sortField, row any) []any {
+	keys := make([]any, len(fields))
+	for index, field := range fields {
+		keys[index] = field.key.Value(row)
+	}
+	return keys
+}
+
+// sortRun is sorted rows, either held in memory or spilled to a file.
+type sortRun struct {
+	index  int
+	reader *reader.ParquetReader
+	rows   []sortedRow
+	row    sortedRow
+}
+
+// next moves to the next row, it returns false at end of run.
+func (r *sortRun) next(ctx context.Context, fields []sortField, pageSize int) (bool, error) {
+	if len(r.rows) == 0 && r.reader != nil {
+		rows, err := r.reader.ReadByNumberWithContext(ctx, pageSize)
+		if err != nil {
+			return false, err
+		}
+		for _, row := range rows {
+			r.rows = append(r.rows, sortedRow{row: row, keys: rowKeys(fields, row)})
+		}
+	}
+	if len(r.rows) == 0 {
+		return false, nil
+	}
+	r.row, r.rows = r.rows[0], r.rows[1:]
+	return true, nil
+}
+
+// runHeap keeps the run with the smallest current row on top, runs are in
+// order of source rows if they have the same keys so that sort is stable.
+type runHeap struct {
+	runs   []*sortRun
+	fields []sortField
+}
+
+func (h *runHeap) Len() int { return len(h.runs) }
+
+func (h *runHeap) Less(i, j int) bool {
+	if result := compareRows(h.fields, h.runs[i].row.keys, h.runs[j].row.keys); result != 0 {
+		return result < 0
+	}
+	return h.runs[i].index < h.runs[j].index
+}
+
+func (h *runHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+
+func (h *runHeap) Push(x any) { h.runs = append(h.runs, x.(*sortRun)) }
+
+func (h *runHeap) Pop() any {
+	last := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return last
+}
+
+// runSortedPipeline writes rows of fileReader to fileWriter in order of
+// --sort-by, rows beyond memory budget are sorted and spilled to files with
+// spillSchema, which is schema of rows from fileReader, then all runs are
+// merged.
+func (c Cmd) runSortedPipeline(ctx context.Context, fileReader *reader.ParquetReader, fileWriter *writer.ParquetWriter, fields []sortField, memory int64, spillSchema string) error {
+	tempDir, err := os.MkdirTemp(c.SortTempDir, "parquet-tools-sort-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	var runs []*sortRun
+	defer func() {
+		for _, run := range runs {
+			if run.reader != nil {
+				_ = run.reader.PFile.Close()
+			}
+		}
+	}()
+
+	var rows []sortedRow
+	size := int64(0)
+	for {
+		page, err := fileReader.ReadByNumberWithContext(ctx, c.ReadPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to read from [%s]: %w", c.Source, err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, row := range page {
+			rows = append(rows, sortedRow{row: row, keys: rowKeys(fields, row)})
+			size += pio.RowSize(reflect.ValueOf(row))
+		}
+		if size < memory {
+			continue
+		}
+		run, err := c.spill(ctx, rows, fields, filepath.Join(tempDir, fmt.Sprintf("run-%d.parquet", len(runs))), spillSchema)
+		if err != nil {
+			return err
+		}
+		run.index = len(runs)
+		runs = append(runs, run)
+		rows, size = nil, 0
+	}
+	slices.SortStableFunc(rows, func(a, b sortedRow) int {
+		return compareRows(fields, a.keys, b.keys)
+	})
+	runs = append(runs, &sortRun{index: len(runs), rows: rows})
+
+	g, gctx := errgroup.WithContext(ctx)
+	writerChan := make(chan any)
+	g.Go(func() error {
+		return pio.PipelineWriter(gctx, fileWriter, writerChan, c.URI, c.RowGroupRows)
+	})
+	g.Go(func() error {
+		defer close(writerChan)
+		return c.mergeRuns(gctx, runs, fields, writerChan)
+	})
+	return g.Wait()
+}
+
+// spill sorts rows and writes them to a file, it returns a run that reads
+// rows back from the file.
+func (c Cmd) spill(ctx context.Context, rows []sortedRow, fields []sortField, uri, spillSchema string) (*sortRun, error) {
+	slices.SortStableFunc(rows, func(a, b sortedRow) int {
+		return compareRows(fields, a.keys, b.keys)
+	})
+
+	spillWriter, err := pio.NewGenericWriter(ctx, uri, pio.WriteOption{}, spillSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write to [%s]: %w", uri, err)
+	}
+	for _, row := range rows {
+		if err := spillWriter.WriteWithContext(ctx, row.row); err != nil {
+			_ = spillWriter.PFile.Close()
+			return nil, fmt.Errorf("failed to write data to [%s]: %w", uri, err)
+		}
+	}
+	if err := spillWriter.WriteStopWithContext(ctx); err != nil {
+		_ = spillWriter.PFile.Close()
+		return nil, fmt.Errorf("failed to end write [%s]: %w", uri, err)
+	}
+	if err := spillWriter.PFile.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close [%s]: %w", uri, err)
+	}
+
+	spillReader, err := pio.NewParquetFileReader(ctx, uri, pio.ReadOption{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from [%s]: %w", uri, err)
+	}
+	return &sortRun{reader: spillReader}, nil
+}
+
+// mergeRuns sends rows of all runs to writerChan in order of --sort-by.
+func (c Cmd) mergeRuns(ctx context.Context, runs []*sortRun, fields []sortField, writerChan chan any) error {
+	pending := &runHeap{fields: fields}
+	for _, run := range runs {
+		more, err := run.next(ctx, fields, c.ReadPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to read sorted rows: %w", err)
+		}
+		if more {
+			pending.runs = append(pending.runs, run)
+		}
+	}
+	heap.Init(pending)
+
+	for pending.Len() != 0 {
+		run := pending.runs[0]
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case writerChan <- run.row.row:
+		}
+
+		more, err := run.next(ctx, fields, c.ReadPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to read sorted rows: %w", err)
+		}
+		if !more {
+			heap.Pop(pending)
+			continue
+		}
+		heap.Fix(pending, 0)
+	}
+	return nil
+}
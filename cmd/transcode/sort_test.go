@@ -0,0 +1,166 @@
+package transcode
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hangxie/parquet-tools/cmd/internal/testutils"
+	pio "github.com/hangxie/parquet-tools/io"
+	pschema "github.com/hangxie/parquet-tools/schema"
+)
+
+func TestSortFields(t *testing.T) {
+	schemaTree := loadSchemaTree(t, "../../testdata/all-types.parquet")
+
+	testCases := map[string]struct {
+		sortBy  []string
+		columns []int32
+		desc    []bool
+		errMsg  string
+	}{
+		"asc":           {sortBy: []string{"Int64", " Utf8=asc"}, columns: []int32{2, 17}, desc: []bool{false, false}},
+		"desc":          {sortBy: []string{"Uint_32=DESC", "Decimal1"}, columns: []int32{25, 40}, desc: []bool{true, false}},
+		"minus":         {sortBy: []string{"Int64", "-Utf8"}, columns: []int32{2, 17}, desc: []bool{false, true}},
+		"empty":         {sortBy: []string{"=DESC"}, errMsg: "empty field path in [=DESC]"},
+		"invalid-order": {sortBy: []string{"Int64=up"}, errMsg: "invalid sort order [up] of [Int64], needs to be ASC or DESC"},
+		"not-exist":     {sortBy: []string{"foo=DESC"}, errMsg: "field [foo] does not exist"},
+		"group":         {sortBy: []string{"Variant"}, errMsg: "field [Variant] is not a primitive field, cannot sort by it"},
+		"in-list":       {sortBy: []string{"List.list.element"}, errMsg: "field [List.list.element] does not exist"},
+		"int96":         {sortBy: []string{"Int96"}, errMsg: "field [Int96] is INT96, cannot sort by it"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			fields, err := Cmd{FieldDelimiter: ".", SortBy: tc.sortBy}.sortFields(schemaTree)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Equal(t, tc.errMsg, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, fields, len(tc.columns))
+			for index, field := range fields {
+				require.Equal(t, tc.columns[index], field.columnIndex)
+				require.Equal(t, tc.desc[index], field.descending)
+			}
+		})
+	}
+}
+
+func TestMergeRuns(t *testing.T) {
+	type row struct {
+		Brand string
+		Size  *int32
+	}
+	fields := []sortField{
+		{key: pschema.SortKey{InPath: []string{"Brand"}}},
+		{descending: true, key: pschema.SortKey{InPath: []string{"Size"}}},
+	}
+	newRun := func(index int, rows ...row) *sortRun {
+		run := &sortRun{index: index}
+		for _, r := range rows {
+			run.rows = append(run.rows, sortedRow{row: r, keys: rowKeys(fields, r)})
+		}
+		return run
+	}
+	runs := []*sortRun{
+		newRun(0, row{"a", new(int32(9))}, row{"b", nil}, row{"c", new(int32(1))}),
+		newRun(1, row{"a", nil}, row{"b", new(int32(2))}),
+		newRun(2),
+		newRun(3, row{"a", new(int32(9))}, row{"d", nil}),
+	}
+
+	writerChan := make(chan any, 10)
+	require.NoError(t, Cmd{ReadPageSize: 1}.mergeRuns(context.Background(), runs, fields, writerChan))
+	close(writerChan)
+	var result []any
+	for value := range writerChan {
+		result = append(result, value)
+	}
+	require.Equal(t, []any{
+		row{"a", new(int32(9))}, row{"a", new(int32(9))}, row{"a", nil}, row{"b", new(int32(2))}, row{"b", nil}, row{"c", new(int32(1))}, row{"d", nil},
+	}, result)
+}
+
+func TestCmdSortBy(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		testCases := map[string]struct {
+			cmd    Cmd
+			errMsg string
+		}{
+			"bad-memory":   {Cmd{SortBy: []string{"shoe_name"}, SortMemory: "1PB"}, "invalid sort memory [1PB]: unknown unit [PB]"},
+			"not-exist":    {Cmd{SortBy: []string{"foo=DESC"}, SortMemory: "1MB"}, "field [foo] does not exist"},
+			"dropped":      {Cmd{DropColumn: []string{"shoe_name"}, SortBy: []string{"shoe_name"}, SortMemory: "1MB"}, "field [shoe_name] does not exist"},
+			"renamed":      {Cmd{RenameColumn: []string{"shoe_name=name"}, SortBy: []string{"shoe_name"}, SortMemory: "1MB"}, "field [shoe_name] does not exist"},
+			"no-temp-dir":  {Cmd{SortBy: []string{"shoe_name"}, SortMemory: "1MB", SortTempDir: "does/not/exist"}, "failed to create temporary directory: "},
+			"memory-empty": {Cmd{SortBy: []string{"shoe_name"}}, "invalid sort memory []: needs a positive integer"},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				tc.cmd.FieldDelimiter = "."
+				tc.cmd.ReadPageSize = 10
+				tc.cmd.Source = "../../testdata/good.parquet"
+				tc.cmd.URI = filepath.Join(t.TempDir(), "target.parquet")
+				err := tc.cmd.Run(context.Background())
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+			})
+		}
+	})
+
+	testCases := map[string]struct {
+		cmd     Cmd
+		sorting []int32
+		output  string
+	}{
+		"asc": {
+			cmd:     Cmd{SortBy: []string{"shoe_brand"}, SortMemory: "1MB"},
+			sorting: []int32{0},
+			output:  `{"shoe_brand":"fila","shoe_name":"grant_hill_2"}` + "\n" + `{"shoe_brand":"nike","shoe_name":"air_griffey"}` + "\n" + `{"shoe_brand":"steph_curry","shoe_name":"curry7"}` + "\n",
+		},
+		"desc-with-spill": {
+			cmd:     Cmd{SortBy: []string{"shoe_name=DESC"}, SortMemory: "1B"},
+			sorting: []int32{1},
+			output:  `{"shoe_brand":"steph_curry","shoe_name":"curry7"}` + "\n" + `{"shoe_brand":"fila","shoe_name":"grant_hill_2"}` + "\n" + `{"shoe_brand":"nike","shoe_name":"air_griffey"}` + "\n",
+		},
+		"renamed-and-kept": {
+			cmd:     Cmd{KeepColumn: []string{"shoe_name"}, RenameColumn: []string{"shoe_name=name"}, SortBy: []string{"name"}, SortMemory: "1B"},
+			sorting: []int32{0},
+			output:  `{"name":"air_griffey"}` + "\n" + `{"name":"curry7"}` + "\n" + `{"name":"grant_hill_2"}` + "\n",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cmd := tc.cmd
+			cmd.FieldDelimiter = "."
+			cmd.ReadPageSize = 1
+			cmd.Source = "../../testdata/good.parquet"
+			cmd.SortTempDir = t.TempDir()
+			cmd.URI = filepath.Join(t.TempDir(), "target.parquet")
+			cmd.WriteOption = pio.WriteOption{CompressionCodec: "SNAPPY", PageSize: 1024 * 1024, RowGroupSize: 128 * 1024 * 1024}
+			require.NoError(t, cmd.Run(context.Background()))
+
+			fileReader, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+			require.NoError(t, err)
+			defer func() {
+				_ = fileReader.PFile.Close()
+			}()
+			for _, rowGroup := range fileReader.Footer.RowGroups {
+				require.Len(t, rowGroup.SortingColumns, len(tc.sorting))
+				for index, column := range rowGroup.SortingColumns {
+					require.Equal(t, tc.sorting[index], column.ColumnIdx)
+				}
+			}
+
+			catCmd := transcodeTestCatCmd(cmd.URI, pio.ReadOption{})
+			catCmd.Format = "jsonl"
+			require.Equal(t, tc.output, testutils.CommandStdout(t, catCmd))
+
+			entries, err := filepath.Glob(filepath.Join(cmd.SortTempDir, "*"))
+			require.NoError(t, err)
+			require.Empty(t, entries)
+		})
+	}
+}
@@ -18,15 +18,23 @@ const minBloomFilterSize = 32
 
 // Cmd is a kong command for transcode
 type Cmd struct {
+	AutoBloomFilter  bool     `help:"Enable bloom filters of high-cardinality BYTE_ARRAY, INT32, and INT64 fields, cardinality is from first rows of source file." default:"false"`
+	DropColumn       []string `help:"Remove these fields from output, a group removes everything underneath it." placeholder:"field.path"`
 	FailOnInt96      bool     `help:"Fail if INT96 fields are detected in the source file." name:"fail-on-int96" default:"false"`
 	FieldBloomFilter []string `help:"Field-specific bloom filter." placeholder:"field.path=true/false/<size>"`
 	FieldCompression []string `help:"Field-specific compression." placeholder:"field.path=CODEC"`
 	FieldDelimiter   string   `name:"field-delimiter" help:"Delimiter separating nested field path components in field and column parameters" default:"."`
 	FieldEncoding    []string `help:"Field-specific encoding." placeholder:"field.path=ENCODING"`
+	KeepColumn       []string `help:"Only keep these fields in output, a group keeps everything underneath it." placeholder:"field.path"`
 	OmitStats        string   `help:"Control statistics (true/false). Leave empty to keep original." default:""`
 	ReadPageSize     int      `help:"Page size to read from Parquet." default:"1000"`
+	RenameColumn     []string `help:"Rename field, field-specific options take new names." placeholder:"field.path=new_name"`
+	SortBy           []string `help:"Sort rows by these fields, order is ASC by default, leading - means DESC." placeholder:"[-]field.path[=ASC|DESC]"`
+	SortMemory       string   `help:"Memory budget of --sort-by, sorted rows are spilled to temporary files beyond it." placeholder:"size" default:"256MB"`
+	SortTempDir      string   `help:"Directory of temporary files of --sort-by, system temporary directory is used if empty." placeholder:"dir" default:""`
 	Source           string   `short:"s" help:"Source Parquet file to transcode." required:"true"`
 	URI              string   `arg:"" predictor:"file" help:"URI of output Parquet file."`
+	pio.MetadataOption
 	pio.ReadOption
 	pio.WriteOption
 }
@@ -228,6 +236,28 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 		return err
 	}
 
+	// Parse column selection and renames
+	keepPaths, err := c.parseColumnPaths(c.KeepColumn, "keep column")
+	if err != nil {
+		return err
+	}
+	dropPaths, err := c.parseColumnPaths(c.DropColumn, "drop column")
+	if err != nil {
+		return err
+	}
+	renames, err := c.parseColumnRenames()
+	if err != nil {
+		return err
+	}
+
+	// Parse memory budget of sorting
+	sortMemory := int64(0)
+	if len(c.SortBy) != 0 {
+		if sortMemory, err = pio.ParseByteSize(c.SortMemory); err != nil {
+			return fmt.Errorf("invalid sort memory [%s]: %w", c.SortMemory, err)
+		}
+	}
+
 	// Open source file
 	fileReader, err := pio.NewParquetFileReader(ctx, c.Source, c.ReadOption)
 	if err != nil {
@@ -243,6 +273,50 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 		return err
 	}
 
+	// Key-value metadata of source file is kept unless it is changed
+	metadata, err := c.KeyValueMetadata(fileReader.Footer.KeyValueMetadata)
+	if err != nil {
+		return err
+	}
+
+	// Select and rename columns, rows are read with selected columns only and
+	// written with new names
+	rowReader := fileReader
+	if len(keepPaths) != 0 || len(dropPaths) != 0 {
+		if schemaTree, err = c.selectColumns(schemaTree, keepPaths, dropPaths); err != nil {
+			return err
+		}
+		if rowReader, err = pio.NewProjectedParquetReader(ctx, fileReader, schemaTree.JSONSchema(), c.ReadOption); err != nil {
+			return err
+		}
+	}
+	spillSchema := ""
+	if len(c.SortBy) != 0 {
+		spillSchema = schemaTree.JSONSchema()
+	}
+	// Field-specific bloom filters are applied later so they win over
+	// --auto-bloom-filter
+	if c.AutoBloomFilter {
+		if err := c.autoBloomFilter(ctx, fileReader, schemaTree); err != nil {
+			return err
+		}
+	}
+	if len(renames) != 0 {
+		schemaTree = cloneSchema(schemaTree)
+		if err := c.renameColumns(schemaTree, renames); err != nil {
+			return err
+		}
+	}
+
+	// Sort fields take new names, each row group is sorted by them
+	var fields []sortField
+	if len(c.SortBy) != 0 {
+		if fields, err = c.sortFields(schemaTree); err != nil {
+			return err
+		}
+		c.SortingColumns = sortingColumns(fields)
+	}
+
 	// Modify schema tree: custom writer directives (encoding, compression, omitstats)
 	// Preserve source encodings by default, but allow user-specified values to override
 	if err := c.modifySchemaTree(schemaTree, fieldEncodings, fieldCompressions, fieldBloomFilters, c.CompressionCodec); err != nil {
@@ -259,6 +333,7 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 	if err != nil {
 		return fmt.Errorf("failed to write to [%s]: %w", c.URI, err)
 	}
+	fileWriter.Footer.KeyValueMetadata = metadata
 	defer func() {
 		if err := fileWriter.WriteStopWithContext(ctx); err != nil && retErr == nil {
 			retErr = fmt.Errorf("failed to end write [%s]: %w", c.URI, err)
@@ -268,5 +343,8 @@ func (c Cmd) Run(ctx context.Context) (retErr error) {
 		}
 	}()
 
-	return pio.RunPipeline(ctx, fileReader, fileWriter, c.Source, c.URI, c.ReadPageSize, nil)
+	if len(fields) != 0 {
+		return c.runSortedPipeline(ctx, rowReader, fileWriter, fields, sortMemory, spillSchema)
+	}
+	return pio.RunPipeline(ctx, rowReader, fileWriter, c.Source, c.URI, c.ReadPageSize, c.RowGroupRows, nil)
 }
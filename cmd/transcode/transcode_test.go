@@ -52,6 +52,7 @@ func TestCmd(t *testing.T) {
 	t.Run("decrypt-encrypted", testCmdDecryptEncrypted)
 	t.Run("encrypt-writer", testCmdEncryptWriter)
 	t.Run("encrypt-writer-errors", testCmdEncryptWriterErrors)
+	t.Run("metadata", testCmdMetadata)
 }
 
 func testCmdError(t *testing.T) {
@@ -97,6 +98,10 @@ func testCmdError(t *testing.T) {
 			RowGroupSize:     128 * 1024 * 1024,
 		}, ReadPageSize: 10, Source: "../../testdata/good.parquet", URI: filepath.Join(tempDir, "dummy")}, "not a valid CompressionCode"},
 		"field-delimiter": {Cmd{ReadOption: rOpt, WriteOption: wOpt, ReadPageSize: 10, FieldDelimiter: "::", Source: "../../testdata/good.parquet", URI: "dummy"}, "field delimiter must be a single character"},
+		"set-metadata": {
+			Cmd{ReadOption: rOpt, WriteOption: wOpt, ReadPageSize: 10, MetadataOption: pio.MetadataOption{SetMetadata: []string{"key"}}, Source: "../../testdata/good.parquet", URI: filepath.Join(tempDir, "dummy")},
+			"invalid metadata [key], expected 'key=value'",
+		},
 	}
 
 	for name, tc := range testCases {
@@ -1533,7 +1538,7 @@ func TestWriterContextCancellation(t *testing.T) {
 
 	writerChan := make(chan any)
 
-	err := pio.PipelineWriter(ctx, nil, writerChan, "test-target")
+	err := pio.PipelineWriter(ctx, nil, writerChan, "test-target", 0)
 	require.ErrorIs(t, err, context.Canceled)
 }
 
@@ -1853,3 +1858,26 @@ func BenchmarkTranscodeCmd(b *testing.B) {
 		}
 	})
 }
+
+func testCmdMetadata(t *testing.T) {
+	cmd := Cmd{
+		ReadPageSize:   10,
+		Source:         "../../testdata/good.parquet",
+		URI:            filepath.Join(t.TempDir(), "metadata.parquet"),
+		MetadataOption: pio.MetadataOption{SetMetadata: []string{"owner=parquet-tools"}},
+		WriteOption: pio.WriteOption{
+			CompressionCodec: "SNAPPY",
+			DataPageVersion:  2,
+			PageSize:         1024 * 1024,
+			RowGroupSize:     128 * 1024 * 1024,
+		},
+	}
+	require.NoError(t, cmd.Run(context.Background()))
+
+	target, err := pio.NewParquetFileReader(context.Background(), cmd.URI, pio.ReadOption{})
+	require.NoError(t, err)
+	_ = target.PFile.Close()
+	require.Len(t, target.Footer.KeyValueMetadata, 1)
+	require.Equal(t, "owner", target.Footer.KeyValueMetadata[0].Key)
+	require.Equal(t, "parquet-tools", target.Footer.KeyValueMetadata[0].GetValue())
+}
@@ -0,0 +1,84 @@
+package io
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdio "io"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/source"
+)
+
+// bloomFilterHeaderSize is large enough for any thrift encoded BloomFilterHeader,
+// it is only used when the writer did not record bloom_filter_length.
+const bloomFilterHeaderSize = 64
+
+// ReadBloomFilter loads bitset of a split block bloom filter of a column chunk.
+func ReadBloomFilter(ctx context.Context, pFile source.ParquetFileReader, meta *parquet.ColumnMetaData) ([]byte, error) {
+	header, headerSize, buf, err := readBloomFilterHeader(ctx, pFile, meta)
+	if err != nil {
+		return nil, err
+	}
+	if header.Algorithm == nil || !header.Algorithm.IsSetBLOCK() ||
+		header.Hash == nil || !header.Hash.IsSetXXHASH() ||
+		header.Compression == nil || !header.Compression.IsSetUNCOMPRESSED() {
+		return nil, fmt.Errorf("unsupported bloom filter")
+	}
+	if header.NumBytes <= 0 || header.NumBytes%32 != 0 {
+		return nil, fmt.Errorf("invalid bloom filter size %d", header.NumBytes)
+	}
+
+	if headerSize+int64(header.NumBytes) <= int64(len(buf)) {
+		return buf[headerSize : headerSize+int64(header.NumBytes)], nil
+	}
+	return readAt(pFile, meta.GetBloomFilterOffset()+headerSize, int64(header.NumBytes))
+}
+
+// bloomFilterLength returns size of bloom filter including its header.
+func bloomFilterLength(ctx context.Context, pFile source.ParquetFileReader, meta *parquet.ColumnMetaData) (int32, error) {
+	if meta.BloomFilterLength != nil && *meta.BloomFilterLength > 0 {
+		return *meta.BloomFilterLength, nil
+	}
+	header, headerSize, _, err := readBloomFilterHeader(ctx, pFile, meta)
+	if err != nil {
+		return 0, err
+	}
+	return int32(headerSize) + header.NumBytes, nil
+}
+
+// readBloomFilterHeader parses header of bloom filter, it also returns size of
+// the header and what has been read, which covers the whole bloom filter if
+// the writer recorded bloom_filter_length.
+func readBloomFilterHeader(ctx context.Context, pFile source.ParquetFileReader, meta *parquet.ColumnMetaData) (*parquet.BloomFilterHeader, int64, []byte, error) {
+	length := int64(meta.GetBloomFilterLength())
+	if length <= 0 {
+		length = bloomFilterHeaderSize
+	}
+	buf, err := readAt(pFile, meta.GetBloomFilterOffset(), length)
+	if err != nil && (!errors.Is(err, stdio.ErrUnexpectedEOF) || len(buf) == 0) {
+		return nil, 0, nil, err
+	}
+
+	mem := thrift.NewTMemoryBufferLen(len(buf))
+	if _, err := mem.Write(buf); err != nil {
+		return nil, 0, nil, err
+	}
+	header := parquet.NewBloomFilterHeader()
+	if err := header.Read(ctx, thrift.NewTCompactProtocolConf(mem, &thrift.TConfiguration{})); err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to read bloom filter header: %w", err)
+	}
+	return header, int64(len(buf) - mem.Len()), buf, nil
+}
+
+// readAt reads length bytes from offset of pFile, what has been read is
+// returned with io.ErrUnexpectedEOF if the file ends early.
+func readAt(pFile source.ParquetFileReader, offset, length int64) ([]byte, error) {
+	if _, err := pFile.Seek(offset, stdio.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, length)
+	n, err := stdio.ReadFull(pFile, buf)
+	return buf[:n], err
+}
@@ -0,0 +1,77 @@
+package io
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBloomFilter(t *testing.T) {
+	fileReader, err := NewParquetFileReader(context.Background(), "../testdata/bloom-filter.parquet", ReadOption{})
+	require.NoError(t, err)
+	defer func() {
+		_ = fileReader.PFile.Close()
+	}()
+
+	// column 0 is ID (INT64), column 1 is Name (STRING)
+	idMeta := fileReader.Footer.RowGroups[0].Columns[0].MetaData
+	nameMeta := *fileReader.Footer.RowGroups[0].Columns[1].MetaData
+	nameMeta.BloomFilterLength = nil
+	badMeta := *idMeta
+	badMeta.BloomFilterOffset = new(int64(4))
+	badMeta.BloomFilterLength = nil
+	testCases := map[string]struct {
+		meta   *parquet.ColumnMetaData
+		length int
+		errMsg string
+	}{
+		"with-length":    {idMeta, 0, ""},
+		"without-length": {&nameMeta, 4096, ""},
+		"bad-offset":     {&badMeta, 0, "bloom filter"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			bitset, err := ReadBloomFilter(context.Background(), fileReader.PFile, tc.meta)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.NotEmpty(t, bitset)
+			require.Zero(t, len(bitset)%32)
+			if tc.length != 0 {
+				require.Len(t, bitset, tc.length)
+			}
+		})
+	}
+}
+
+func TestBloomFilterLength(t *testing.T) {
+	fileReader, err := NewParquetFileReader(context.Background(), "../testdata/bloom-filter.parquet", ReadOption{})
+	require.NoError(t, err)
+	defer func() {
+		_ = fileReader.PFile.Close()
+	}()
+
+	checked := 0
+	for _, column := range fileReader.Footer.RowGroups[0].Columns {
+		meta := *column.MetaData
+		if meta.BloomFilterOffset == nil || meta.BloomFilterLength == nil {
+			continue
+		}
+		checked++
+		recorded, err := bloomFilterLength(context.Background(), fileReader.PFile, &meta)
+		require.NoError(t, err)
+		require.Equal(t, *meta.BloomFilterLength, recorded)
+
+		// without bloom_filter_length the size is worked out from the header
+		meta.BloomFilterLength = nil
+		parsed, err := bloomFilterLength(context.Background(), fileReader.PFile, &meta)
+		require.NoError(t, err)
+		require.Equal(t, recorded, parsed)
+	}
+	require.NotZero(t, checked)
+}
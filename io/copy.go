@@ -0,0 +1,247 @@
+package io
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	stdio "io"
+	"math"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/hangxie/parquet-go/v3/source"
+)
+
+const parquetMagic = "PAR1"
+
+// offsetWriter keeps track of where the next byte goes in output file.
+type offsetWriter struct {
+	writer stdio.Writer
+	offset int64
+}
+
+func (w *offsetWriter) Write(buf []byte) (int, error) {
+	n, err := w.writer.Write(buf)
+	w.offset += int64(n)
+	return n, err
+}
+
+// RowGroupSource is a row group to be copied to another file as is, URI is
+// where PFile is from.
+type RowGroupSource struct {
+	URI      string
+	PFile    source.ParquetFileReader
+	RowGroup *parquet.RowGroup
+}
+
+// copiedChunk is a column chunk in output file, its bloom filter and page
+// indexes are still in source file, delta is how far the chunk moved.
+type copiedChunk struct {
+	source string
+	pFile  source.ParquetFileReader
+	chunk  *parquet.ColumnChunk
+	delta  int64
+}
+
+// CopyRowGroups writes row groups to uri by copying column chunks as is, only
+// footer and offset indexes are rewritten as offsets of pages change. Schema
+// and other file level metadata are from footer, source files need to pass
+// CheckCopyable.
+func CopyRowGroups(ctx context.Context, uri string, footer *parquet.FileMetaData, rowGroups []RowGroupSource) (retErr error) {
+	fileWriter, err := NewParquetFileWriter(ctx, uri)
+	if err != nil {
+		return fmt.Errorf("failed to write to [%s]: %w", uri, err)
+	}
+	defer func() {
+		if err := fileWriter.Close(); err != nil && retErr == nil {
+			retErr = fmt.Errorf("failed to close [%s]: %w", uri, err)
+		}
+	}()
+
+	output := &offsetWriter{writer: fileWriter}
+	if _, err := stdio.WriteString(output, parquetMagic); err != nil {
+		return fmt.Errorf("failed to write to [%s]: %w", uri, err)
+	}
+
+	newFooter := &parquet.FileMetaData{
+		Version:          footer.Version,
+		Schema:           footer.Schema,
+		CreatedBy:        footer.CreatedBy,
+		ColumnOrders:     footer.ColumnOrders,
+		KeyValueMetadata: footer.KeyValueMetadata,
+	}
+	var chunks []copiedChunk
+	for _, rowGroupSource := range rowGroups {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rowGroup := rowGroupSource.RowGroup
+		newRowGroup := *rowGroup
+		newRowGroup.FileOffset = new(output.offset)
+		newRowGroup.Ordinal = nil
+		if len(newFooter.RowGroups) <= math.MaxInt16 {
+			newRowGroup.Ordinal = new(int16(len(newFooter.RowGroups)))
+		}
+		newRowGroup.Columns = make([]*parquet.ColumnChunk, len(rowGroup.Columns))
+		for index, chunk := range rowGroup.Columns {
+			start, length := ChunkRange(chunk.MetaData)
+			delta := output.offset - start
+			if err := copyBytes(output, rowGroupSource.PFile, start, length); err != nil {
+				return fmt.Errorf("failed to copy column chunk from [%s]: %w", rowGroupSource.URI, err)
+			}
+			newRowGroup.Columns[index] = moveChunk(chunk, delta)
+			chunks = append(chunks, copiedChunk{source: rowGroupSource.URI, pFile: rowGroupSource.PFile, chunk: newRowGroup.Columns[index], delta: delta})
+		}
+		newFooter.RowGroups = append(newFooter.RowGroups, &newRowGroup)
+		newFooter.NumRows += rowGroup.NumRows
+	}
+
+	if err := copyIndexes(ctx, output, chunks); err != nil {
+		return err
+	}
+
+	footerStart := output.offset
+	if err := writeThrift(ctx, output, newFooter); err != nil {
+		return fmt.Errorf("failed to write footer to [%s]: %w", uri, err)
+	}
+	tail := binary.LittleEndian.AppendUint32(nil, uint32(output.offset-footerStart))
+	if _, err := output.Write(append(tail, parquetMagic...)); err != nil {
+		return fmt.Errorf("failed to write footer to [%s]: %w", uri, err)
+	}
+	return nil
+}
+
+// CheckCopyable rejects files whose column chunks cannot be used in another
+// file as is.
+func CheckCopyable(footer *parquet.FileMetaData) error {
+	if footer.EncryptionAlgorithm != nil {
+		return fmt.Errorf("file is encrypted")
+	}
+	for _, rowGroup := range footer.RowGroups {
+		for _, chunk := range rowGroup.Columns {
+			if chunk.CryptoMetadata != nil || chunk.MetaData == nil {
+				return fmt.Errorf("file is encrypted")
+			}
+			if chunk.FilePath != nil {
+				return fmt.Errorf("column chunk is in external file [%s]", *chunk.FilePath)
+			}
+		}
+	}
+	return nil
+}
+
+// ChunkRange returns where pages of a column chunk are, dictionary page and
+// legacy index page come before data pages.
+func ChunkRange(meta *parquet.ColumnMetaData) (int64, int64) {
+	start := meta.DataPageOffset
+	for _, offset := range []*int64{meta.DictionaryPageOffset, meta.IndexPageOffset} {
+		if offset != nil && *offset > 0 && *offset < start {
+			start = *offset
+		}
+	}
+	return start, meta.TotalCompressedSize
+}
+
+func moveChunk(chunk *parquet.ColumnChunk, delta int64) *parquet.ColumnChunk {
+	newChunk := *chunk
+	newMeta := *chunk.MetaData
+	newChunk.MetaData = &newMeta
+	if newChunk.FileOffset != 0 {
+		newChunk.FileOffset += delta
+	}
+	newMeta.DataPageOffset += delta
+	if newMeta.DictionaryPageOffset != nil && *newMeta.DictionaryPageOffset > 0 {
+		newMeta.DictionaryPageOffset = new(*newMeta.DictionaryPageOffset + delta)
+	}
+	if newMeta.IndexPageOffset != nil && *newMeta.IndexPageOffset > 0 {
+		newMeta.IndexPageOffset = new(*newMeta.IndexPageOffset + delta)
+	}
+	return &newChunk
+}
+
+// copyIndexes writes bloom filters, column indexes and offset indexes after
+// all row groups, in the order of the spec.
+func copyIndexes(ctx context.Context, output *offsetWriter, chunks []copiedChunk) error {
+	for _, copied := range chunks {
+		meta := copied.chunk.MetaData
+		if meta.BloomFilterOffset == nil {
+			continue
+		}
+		length, err := bloomFilterLength(ctx, copied.pFile, meta)
+		if err != nil {
+			return fmt.Errorf("failed to read bloom filter from [%s]: %w", copied.source, err)
+		}
+		offset := output.offset
+		if err := copyBytes(output, copied.pFile, *meta.BloomFilterOffset, int64(length)); err != nil {
+			return fmt.Errorf("failed to copy bloom filter from [%s]: %w", copied.source, err)
+		}
+		meta.BloomFilterOffset, meta.BloomFilterLength = new(offset), new(length)
+	}
+
+	for _, copied := range chunks {
+		chunk := copied.chunk
+		if chunk.ColumnIndexOffset == nil || chunk.ColumnIndexLength == nil {
+			chunk.ColumnIndexOffset, chunk.ColumnIndexLength = nil, nil
+			continue
+		}
+		offset := output.offset
+		if err := copyBytes(output, copied.pFile, *chunk.ColumnIndexOffset, int64(*chunk.ColumnIndexLength)); err != nil {
+			return fmt.Errorf("failed to copy column index from [%s]: %w", copied.source, err)
+		}
+		chunk.ColumnIndexOffset = new(offset)
+	}
+
+	for _, copied := range chunks {
+		chunk := copied.chunk
+		if chunk.OffsetIndexOffset == nil || chunk.OffsetIndexLength == nil {
+			chunk.OffsetIndexOffset, chunk.OffsetIndexLength = nil, nil
+			continue
+		}
+		buf, err := readAt(copied.pFile, *chunk.OffsetIndexOffset, int64(*chunk.OffsetIndexLength))
+		if err != nil {
+			return fmt.Errorf("failed to read offset index from [%s]: %w", copied.source, err)
+		}
+		offsetIndex := parquet.NewOffsetIndex()
+		if err := readThrift(ctx, buf, offsetIndex); err != nil {
+			return fmt.Errorf("failed to read offset index from [%s]: %w", copied.source, err)
+		}
+		for _, location := range offsetIndex.PageLocations {
+			location.Offset += copied.delta
+		}
+		offset := output.offset
+		if err := writeThrift(ctx, output, offsetIndex); err != nil {
+			return fmt.Errorf("failed to write offset index: %w", err)
+		}
+		chunk.OffsetIndexOffset, chunk.OffsetIndexLength = new(offset), new(int32(output.offset-offset))
+	}
+	return nil
+}
+
+func copyBytes(output stdio.Writer, pFile source.ParquetFileReader, offset, length int64) error {
+	if _, err := pFile.Seek(offset, stdio.SeekStart); err != nil {
+		return err
+	}
+	_, err := stdio.CopyN(output, pFile, length)
+	return err
+}
+
+func readThrift(ctx context.Context, buf []byte, obj thrift.TStruct) error {
+	mem := thrift.NewTMemoryBufferLen(len(buf))
+	if _, err := mem.Write(buf); err != nil {
+		return err
+	}
+	return obj.Read(ctx, thrift.NewTCompactProtocolConf(mem, &thrift.TConfiguration{}))
+}
+
+func writeThrift(ctx context.Context, output stdio.Writer, obj thrift.TStruct) error {
+	mem := thrift.NewTMemoryBuffer()
+	protocol := thrift.NewTCompactProtocolConf(mem, &thrift.TConfiguration{})
+	if err := obj.Write(ctx, protocol); err != nil {
+		return err
+	}
+	if err := protocol.Flush(ctx); err != nil {
+		return err
+	}
+	_, err := output.Write(mem.Bytes())
+	return err
+}
@@ -0,0 +1,37 @@
+package io
+
+import (
+	"testing"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCopyable(t *testing.T) {
+	chunk := func(modify func(*parquet.ColumnChunk)) *parquet.FileMetaData {
+		result := &parquet.ColumnChunk{MetaData: &parquet.ColumnMetaData{}}
+		modify(result)
+		return &parquet.FileMetaData{RowGroups: []*parquet.RowGroup{{Columns: []*parquet.ColumnChunk{result}}}}
+	}
+	testCases := map[string]struct {
+		footer *parquet.FileMetaData
+		errMsg string
+	}{
+		"good":            {chunk(func(*parquet.ColumnChunk) {}), ""},
+		"encrypted":       {&parquet.FileMetaData{EncryptionAlgorithm: &parquet.EncryptionAlgorithm{}}, "file is encrypted"},
+		"encrypted-chunk": {chunk(func(c *parquet.ColumnChunk) { c.CryptoMetadata = &parquet.ColumnCryptoMetaData{} }), "file is encrypted"},
+		"no-metadata":     {chunk(func(c *parquet.ColumnChunk) { c.MetaData = nil }), "file is encrypted"},
+		"external-file":   {chunk(func(c *parquet.ColumnChunk) { c.FilePath = new("other.parquet") }), "column chunk is in external file [other.parquet]"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := CheckCopyable(tc.footer)
+			if tc.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
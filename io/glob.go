@@ -0,0 +1,302 @@
+package io
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	gohdfs "github.com/colinmarc/hdfs/v2"
+	"google.golang.org/api/iterator"
+	googleoption "google.golang.org/api/option"
+)
+
+// globMeta are characters that make a URI a wildcard pattern, "**" matches
+// any number of directories.
+const globMeta = "*?["
+
+// ExpandURIs replaces each URI that has wildcards in its path with all files
+// matching it, in lexical order, other URIs are kept as is.
+func ExpandURIs(ctx context.Context, uris []string, option ReadOption) ([]string, error) {
+	var result []string
+	for _, uri := range uris {
+		matches, err := expandURI(ctx, uri, option)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, matches...)
+	}
+	return result, nil
+}
+
+func expandURI(ctx context.Context, uri string, option ReadOption) ([]string, error) {
+	listFuncTable := map[string]func(context.Context, *url.URL, string, int, ReadOption) ([]string, error){
+		schemeLocal:              listLocal,
+		schemeAWSS3:              listAWSS3,
+		schemeGoogleCloudStorage: listGoogleCloudStorage,
+		schemeAzureStorageBlob:   listAzureStorageBlob,
+		schemeHDFS:               listHDFS,
+	}
+
+	metaIndex := strings.IndexAny(uri, globMeta)
+	if metaIndex < 0 {
+		return []string{uri}, nil
+	}
+	if _, found := existingLocalURI(uri); found {
+		// local file whose name has wildcard characters
+		return []string{uri}, nil
+	}
+	u, err := parseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != schemeLocal && !strings.ContainsAny(u.Host+u.Path, globMeta) {
+		// wildcard characters are in query string
+		return []string{uri}, nil
+	}
+	listFunc, found := listFuncTable[u.Scheme]
+	if !found {
+		// scheme like HTTP cannot be listed, wildcard characters are part of its name
+		return []string{uri}, nil
+	}
+
+	// base is the directory part before the first wildcard, names listed
+	// under it are matched against pattern
+	baseEnd := strings.LastIndex(uri[:metaIndex], "/") + 1
+	base, pattern := uri[:baseEnd], uri[baseEnd:]
+	baseURI, err := parseURI(base)
+	if err != nil {
+		return nil, err
+	}
+	if baseURI.Scheme != u.Scheme || (u.Scheme != schemeLocal && baseURI.Host == "") {
+		return nil, fmt.Errorf("wildcard can only be used in path of [%s]", uri)
+	}
+
+	segments := strings.Split(pattern, "/")
+	depth := len(segments)
+	for _, segment := range segments {
+		if segment == "**" {
+			depth = -1
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid wildcard [%s]: %w", uri, err)
+		}
+	}
+
+	names, err := listFunc(ctx, baseURI, pattern[:metaIndex-baseEnd], depth, option)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list [%s]: %w", base, err)
+	}
+	var matches []string
+	for _, name := range names {
+		if matchSegments(segments, strings.Split(name, "/")) {
+			matches = append(matches, base+name)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no file matches [%s]", uri)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// matchSegments matches a name against a pattern segment by segment, "**"
+// matches zero or more segments.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for index := range len(name) + 1 {
+			if matchSegments(pattern[1:], name[index:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	// pattern has been validated
+	matched, _ := path.Match(pattern[0], name[0])
+	return matched && matchSegments(pattern[1:], name[1:])
+}
+
+// treeLister collects files under dir whose path relative to dir starts with
+// prefix, it does not go deeper than depth levels unless depth is negative.
+type treeLister struct {
+	dir    string
+	prefix string
+	depth  int
+	names  []string
+}
+
+func (l *treeLister) visit(name string, isDir bool, err error) error {
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(l.dir, name)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+	if isDir {
+		// prefix does not have "/", so it only filters top level directories
+		if !strings.HasPrefix(rel, l.prefix) || (l.depth >= 0 && strings.Count(rel, "/")+1 >= l.depth) {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+	if strings.HasPrefix(rel, l.prefix) {
+		l.names = append(l.names, rel)
+	}
+	return nil
+}
+
+func listLocal(_ context.Context, u *url.URL, prefix string, depth int, _ ReadOption) ([]string, error) {
+	lister := &treeLister{dir: u.Path, prefix: prefix, depth: depth}
+	if lister.dir == "" {
+		lister.dir = "."
+	}
+	err := filepath.WalkDir(lister.dir, func(name string, entry fs.DirEntry, err error) error {
+		return lister.visit(name, entry != nil && entry.IsDir(), err)
+	})
+	return lister.names, err
+}
+
+func listHDFS(_ context.Context, u *url.URL, prefix string, depth int, _ ReadOption) ([]string, error) {
+	client, err := gohdfs.NewClient(gohdfs.ClientOptions{Addresses: []string{u.Host}, User: hdfsUserName(u)})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	lister := &treeLister{dir: u.Path, prefix: prefix, depth: depth}
+	if lister.dir == "" {
+		lister.dir = "/"
+	}
+	err = client.Walk(lister.dir, func(name string, info os.FileInfo, err error) error {
+		return lister.visit(name, info != nil && info.IsDir(), err)
+	})
+	return lister.names, err
+}
+
+// objectNames returns keys under dir with dir trimmed, directory placeholders
+// are skipped.
+func objectNames(dir string, keys []string) []string {
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "/") {
+			names = append(names, strings.TrimPrefix(key, dir))
+		}
+	}
+	return names
+}
+
+func listAWSS3(ctx context.Context, u *url.URL, prefix string, _ int, option ReadOption) ([]string, error) {
+	s3Client, err := getS3Client(ctx, u.Host, option.Anonymous, option.HTTPIgnoreTLSError)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := strings.TrimLeft(u.Path, "/")
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(u.Host),
+		Prefix: aws.String(dir + prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+	return objectNames(dir, keys), nil
+}
+
+func listGoogleCloudStorage(ctx context.Context, u *url.URL, prefix string, _ int, option ReadOption) ([]string, error) {
+	var options []googleoption.ClientOption
+	if option.Anonymous {
+		options = append(options, googleoption.WithoutAuthentication())
+	}
+	client, err := storage.NewClient(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	dir := strings.TrimLeft(u.Path, "/")
+	var keys []string
+	objects := client.Bucket(u.Host).Objects(ctx, &storage.Query{Prefix: dir + prefix})
+	for {
+		object, err := objects.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, object.Name)
+	}
+	return objectNames(dir, keys), nil
+}
+
+func listAzureStorageBlob(ctx context.Context, u *url.URL, prefix string, _ int, option ReadOption) ([]string, error) {
+	containerName := u.User.Username()
+	if containerName == "" {
+		return nil, fmt.Errorf("azure blob URI format: wasbs://container@storageaccount.blob.core.windows.net/path/to/blob")
+	}
+	credential, err := azureCredential(u.Host, option.Anonymous)
+	if err != nil {
+		return nil, err
+	}
+	containerURL := fmt.Sprintf("https://%s/%s", u.Host, containerName)
+	var client *container.Client
+	if credential == nil {
+		client, err = container.NewClientWithNoCredential(containerURL, nil)
+	} else {
+		client, err = container.NewClientWithSharedKeyCredential(containerURL, credential, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	dir := strings.TrimLeft(u.Path, "/")
+	var keys []string
+	pager := client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: new(dir + prefix)})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if page.Segment == nil {
+			continue
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name != nil {
+				keys = append(keys, *item.Name)
+			}
+		}
+	}
+	return objectNames(dir, keys), nil
+}
@@ -0,0 +1,109 @@
+package io
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandURIs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"a.parquet",
+		"b.parquet",
+		"c.csv",
+		"d[1].csv",
+		"dt=2023-12/part-0.parquet",
+		"dt=2024-01/part-0.parquet",
+		"dt=2024-01/part-1.parquet",
+		"dt=2024-01/nested/part-2.parquet",
+		"dt=2024-02/part-0.parquet",
+	} {
+		name = filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+		require.NoError(t, os.WriteFile(name, nil, 0o644))
+	}
+
+	testCases := map[string]struct {
+		uris     []string
+		expected []string
+		errMsg   string
+	}{
+		"no-wildcard": {
+			uris:     []string{dir + "/b.parquet", "does/not/exist", dir + "/a.parquet"},
+			expected: []string{dir + "/b.parquet", "does/not/exist", dir + "/a.parquet"},
+		},
+		"literal":  {uris: []string{dir + "/d[1].csv"}, expected: []string{dir + "/d[1].csv"}},
+		"star":     {uris: []string{dir + "/*.parquet"}, expected: []string{dir + "/a.parquet", dir + "/b.parquet"}},
+		"question": {uris: []string{dir + "/?.csv"}, expected: []string{dir + "/c.csv"}},
+		"class":    {uris: []string{dir + "/[bcd].*"}, expected: []string{dir + "/b.parquet", dir + "/c.csv"}},
+		"prefix": {
+			uris:     []string{dir + "/dt=2024-*/part-*.parquet"},
+			expected: []string{dir + "/dt=2024-01/part-0.parquet", dir + "/dt=2024-01/part-1.parquet", dir + "/dt=2024-02/part-0.parquet"},
+		},
+		"double-star": {
+			uris: []string{dir + "/**/part-0.parquet"},
+			expected: []string{
+				dir + "/dt=2023-12/part-0.parquet",
+				dir + "/dt=2024-01/part-0.parquet",
+				dir + "/dt=2024-02/part-0.parquet",
+			},
+		},
+		"double-star-middle": {
+			uris:     []string{dir + "/dt=2024-01/**/*.parquet"},
+			expected: []string{dir + "/dt=2024-01/nested/part-2.parquet", dir + "/dt=2024-01/part-0.parquet", dir + "/dt=2024-01/part-1.parquet"},
+		},
+		"file-scheme": {uris: []string{"file://" + dir + "/*.parquet"}, expected: []string{"file://" + dir + "/a.parquet", "file://" + dir + "/b.parquet"}},
+		"in-order": {
+			uris:     []string{dir + "/dt=2024-02/*", dir + "/*.parquet"},
+			expected: []string{dir + "/dt=2024-02/part-0.parquet", dir + "/a.parquet", dir + "/b.parquet"},
+		},
+		"http-query":  {uris: []string{"https://example.com/a.parquet?version=1"}, expected: []string{"https://example.com/a.parquet?version=1"}},
+		"no-match":    {uris: []string{dir + "/*.json"}, errMsg: "no file matches [" + dir + "/*.json]"},
+		"bad-pattern": {uris: []string{dir + "/[a.parquet"}, errMsg: "invalid wildcard [" + dir + "/[a.parquet]: syntax error in pattern"},
+		"no-dir":      {uris: []string{dir + "/foo/*.parquet"}, errMsg: "failed to list [" + dir + "/foo/]"},
+		"http": {
+			uris:     []string{"https://example.com/*.parquet", "http://example.com/data[1]/a?.parquet"},
+			expected: []string{"https://example.com/*.parquet", "http://example.com/data[1]/a?.parquet"},
+		},
+		"bucket": {uris: []string{"s3://bucket-*/a.parquet"}, errMsg: "wildcard can only be used in path of [s3://bucket-*/a.parquet]"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			uris, err := ExpandURIs(context.Background(), tc.uris, ReadOption{})
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, uris)
+		})
+	}
+}
+
+func TestMatchSegments(t *testing.T) {
+	testCases := map[string]struct {
+		pattern  []string
+		name     []string
+		expected bool
+	}{
+		"exact":            {[]string{"a", "b"}, []string{"a", "b"}, true},
+		"star":             {[]string{"*", "b*"}, []string{"a", "bc"}, true},
+		"star-one-segment": {[]string{"*"}, []string{"a", "b"}, false},
+		"double-star-zero": {[]string{"**", "b"}, []string{"b"}, true},
+		"double-star-many": {[]string{"a", "**", "d"}, []string{"a", "b", "c", "d"}, true},
+		"double-star-last": {[]string{"a", "**"}, []string{"a", "b", "c"}, true},
+		"shorter-name":     {[]string{"a", "b"}, []string{"a"}, false},
+		"longer-name":      {[]string{"a"}, []string{"a", "b"}, false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, matchSegments(tc.pattern, tc.name))
+		})
+	}
+}
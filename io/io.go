@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/user"
 	"path/filepath"
 	"strings"
 
@@ -187,18 +188,39 @@ func azureAccessDetail(azURL url.URL, anonymous bool, versionId string) (string,
 		httpURL = fmt.Sprintf("%s?versionid=%s", httpURL, versionId)
 	}
 
+	credential, err := azureCredential(azURL.Host, anonymous)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return httpURL, credential, nil
+}
+
+// azureCredential returns nil for anonymous access.
+func azureCredential(host string, anonymous bool) (*azblob.SharedKeyCredential, error) {
 	accessKey := os.Getenv("AZURE_STORAGE_ACCESS_KEY")
 	if anonymous || accessKey == "" {
 		// anonymous access
-		return httpURL, nil, nil
+		return nil, nil
 	}
 
-	credential, err := azblob.NewSharedKeyCredential(strings.Split(azURL.Host, ".")[0], accessKey)
+	credential, err := azblob.NewSharedKeyCredential(strings.Split(host, ".")[0], accessKey)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to create Azure credential: %w", err)
+		return nil, fmt.Errorf("failed to create Azure credential: %w", err)
 	}
+	return credential, nil
+}
 
-	return httpURL, credential, nil
+// hdfsUserName is user in URI, or current OS user.
+func hdfsUserName(u *url.URL) string {
+	userName := u.User.Username()
+	if userName == "" {
+		osUser, err := user.Current()
+		if err == nil && osUser != nil {
+			userName = osUser.Username
+		}
+	}
+	return userName
 }
 
 // ValidCompressionCodecs lists the compression codecs supported for writing.
@@ -0,0 +1,70 @@
+package io
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+)
+
+// MetadataOption includes options to change key-value metadata in footer of
+// target file, key-value metadata of source file is kept otherwise.
+type MetadataOption struct {
+	RemoveMetadata []string `help:"Remove this key from key-value metadata of target file." placeholder:"key"`
+	SetMetadata    []string `help:"Set key-value metadata of target file, value of an existing key is replaced, repeat it for more keys as value can have commas." placeholder:"key=value" sep:"none"`
+}
+
+// KeyValueMetadata returns metadata with keys in --remove-metadata removed and
+// keys in --set-metadata set, keys already in metadata stay where they are and
+// new keys are appended. metadata itself is not changed.
+func (o MetadataOption) KeyValueMetadata(metadata []*parquet.KeyValue) ([]*parquet.KeyValue, error) {
+	removed := map[string]struct{}{}
+	for _, key := range o.RemoveMetadata {
+		if key == "" {
+			return nil, fmt.Errorf("empty key in --remove-metadata")
+		}
+		removed[key] = struct{}{}
+	}
+
+	values := map[string]string{}
+	var newKeys []string
+	for _, spec := range o.SetMetadata {
+		key, value, found := strings.Cut(spec, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid metadata [%s], expected 'key=value'", spec)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("empty key in metadata [%s]", spec)
+		}
+		if _, found := removed[key]; found {
+			return nil, fmt.Errorf("metadata key [%s] cannot be both set and removed", key)
+		}
+		if _, found := values[key]; !found {
+			newKeys = append(newKeys, key)
+		}
+		values[key] = value
+	}
+
+	var result []*parquet.KeyValue
+	for _, kv := range metadata {
+		if kv == nil {
+			continue
+		}
+		if _, found := removed[kv.Key]; found {
+			continue
+		}
+		value, found := values[kv.Key]
+		if !found {
+			result = append(result, kv)
+			continue
+		}
+		result = append(result, &parquet.KeyValue{Key: kv.Key, Value: &value})
+		newKeys = slices.DeleteFunc(newKeys, func(key string) bool { return key == kv.Key })
+	}
+	for _, key := range newKeys {
+		value := values[key]
+		result = append(result, &parquet.KeyValue{Key: key, Value: &value})
+	}
+	return result, nil
+}
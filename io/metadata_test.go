@@ -0,0 +1,58 @@
+package io
+
+import (
+	"testing"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyValueMetadata(t *testing.T) {
+	kv := func(pairs ...string) []*parquet.KeyValue {
+		var result []*parquet.KeyValue
+		for index := 0; index < len(pairs); index += 2 {
+			value := pairs[index+1]
+			result = append(result, &parquet.KeyValue{Key: pairs[index], Value: &value})
+		}
+		return result
+	}
+	testCases := map[string]struct {
+		option   MetadataOption
+		metadata []*parquet.KeyValue
+		expected []*parquet.KeyValue
+		errMsg   string
+	}{
+		"nil":             {MetadataOption{}, nil, nil, ""},
+		"keep":            {MetadataOption{}, kv("a", "1", "b", "2"), kv("a", "1", "b", "2"), ""},
+		"remove":          {MetadataOption{RemoveMetadata: []string{"a", "c"}}, kv("a", "1", "b", "2"), kv("b", "2"), ""},
+		"remove-all":      {MetadataOption{RemoveMetadata: []string{"a"}}, kv("a", "1"), nil, ""},
+		"replace":         {MetadataOption{SetMetadata: []string{"a=x"}}, kv("a", "1", "b", "2"), kv("a", "x", "b", "2"), ""},
+		"append":          {MetadataOption{SetMetadata: []string{"c=3", "d="}}, kv("a", "1"), kv("a", "1", "c", "3", "d", ""), ""},
+		"last-set-wins":   {MetadataOption{SetMetadata: []string{"c=3", "c=4"}}, nil, kv("c", "4"), ""},
+		"value-has-equal": {MetadataOption{SetMetadata: []string{"a=b=c"}}, nil, kv("a", "b=c"), ""},
+		"remove-and-set":  {MetadataOption{RemoveMetadata: []string{"a"}, SetMetadata: []string{"b=x", "c=3"}}, kv("a", "1", "b", "2"), kv("b", "x", "c", "3"), ""},
+		"no-equal":        {MetadataOption{SetMetadata: []string{"a"}}, nil, nil, "invalid metadata [a], expected 'key=value'"},
+		"empty-set-key":   {MetadataOption{SetMetadata: []string{"=a"}}, nil, nil, "empty key in metadata [=a]"},
+		"empty-remove":    {MetadataOption{RemoveMetadata: []string{""}}, nil, nil, "empty key in --remove-metadata"},
+		"set-and-remove":  {MetadataOption{RemoveMetadata: []string{"a"}, SetMetadata: []string{"a=1"}}, nil, nil, "metadata key [a] cannot be both set and removed"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			metadata, err := tc.option.KeyValueMetadata(tc.metadata)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, metadata)
+		})
+	}
+
+	t.Run("source-not-changed", func(t *testing.T) {
+		metadata := kv("a", "1")
+		_, err := MetadataOption{SetMetadata: []string{"a=2"}}.KeyValueMetadata(metadata)
+		require.NoError(t, err)
+		require.Equal(t, kv("a", "1"), metadata)
+	})
+}
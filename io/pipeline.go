@@ -9,8 +9,10 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-// PipelineWriter reads rows from writerChan and writes them to fileWriter.
-func PipelineWriter(ctx context.Context, fileWriter *writer.ParquetWriter, writerChan chan any, target string) error {
+// PipelineWriter reads rows from writerChan and writes them to fileWriter, a
+// row group has at most rowGroupRows rows if it is positive.
+func PipelineWriter(ctx context.Context, fileWriter *writer.ParquetWriter, writerChan chan any, target string, rowGroupRows int64) error {
+	limiter := NewRowGroupLimiter(rowGroupRows)
 	for {
 		select {
 		case <-ctx.Done():
@@ -22,6 +24,9 @@ func PipelineWriter(ctx context.Context, fileWriter *writer.ParquetWriter, write
 			if err := fileWriter.WriteWithContext(ctx, row); err != nil {
 				return fmt.Errorf("failed to write data to [%s]: %w", target, err)
 			}
+			if err := limiter.Written(ctx, fileWriter); err != nil {
+				return fmt.Errorf("failed to write data to [%s]: %w", target, err)
+			}
 		}
 	}
 }
@@ -29,12 +34,12 @@ func PipelineWriter(ctx context.Context, fileWriter *writer.ParquetWriter, write
 // RunPipeline runs a reader and writer in parallel using errgroup. The reader sends rows
 // through an internal channel to the writer. If either side fails, the shared context is
 // cancelled so the other side exits promptly.
-func RunPipeline(ctx context.Context, fileReader *reader.ParquetReader, fileWriter *writer.ParquetWriter, source, target string, pageSize int, transform func(any) (any, error)) error {
+func RunPipeline(ctx context.Context, fileReader *reader.ParquetReader, fileWriter *writer.ParquetWriter, source, target string, pageSize int, rowGroupRows int64, transform func(any) (any, error)) error {
 	g, gctx := errgroup.WithContext(ctx)
 	writerChan := make(chan any)
 
 	g.Go(func() error {
-		return PipelineWriter(gctx, fileWriter, writerChan, target)
+		return PipelineWriter(gctx, fileWriter, writerChan, target, rowGroupRows)
 	})
 
 	g.Go(func() error {
@@ -51,7 +51,7 @@ func TestPipelineWriter(t *testing.T) {
 		writerChan <- &row{Id: 2}
 		close(writerChan)
 
-		err := PipelineWriter(context.Background(), pw, writerChan, "test-target")
+		err := PipelineWriter(context.Background(), pw, writerChan, "test-target", 0)
 		require.NoError(t, err)
 	})
 
@@ -60,7 +60,7 @@ func TestPipelineWriter(t *testing.T) {
 		cancel()
 
 		writerChan := make(chan any)
-		err := PipelineWriter(ctx, nil, writerChan, "test-target")
+		err := PipelineWriter(ctx, nil, writerChan, "test-target", 0)
 		require.ErrorIs(t, err, context.Canceled)
 	})
 
@@ -78,7 +78,7 @@ func TestPipelineWriter(t *testing.T) {
 		writerChan <- &row{Id: 1}
 		close(writerChan)
 
-		err := PipelineWriter(context.Background(), pw, writerChan, "test-target")
+		err := PipelineWriter(context.Background(), pw, writerChan, "test-target", 0)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to write data to [test-target]")
 	})
@@ -161,7 +161,7 @@ func TestRunPipeline(t *testing.T) {
 		// Stop the writer so writes fail immediately.
 		_ = pw.WriteStopWithContext(context.Background())
 
-		err := RunPipeline(context.Background(), pr, pw, "good.parquet", "test-target", 1, nil)
+		err := RunPipeline(context.Background(), pr, pw, "good.parquet", "test-target", 1, 0, nil)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to write data to [test-target]")
 	})
@@ -171,7 +171,7 @@ func TestRunPipeline(t *testing.T) {
 		tempDir := t.TempDir()
 		pw := newTestWriter(t, filepath.Join(tempDir, "out.parquet"), schema)
 
-		err := RunPipeline(context.Background(), pr, pw, "good.parquet", "test-target", 1, func(any) (any, error) {
+		err := RunPipeline(context.Background(), pr, pw, "good.parquet", "test-target", 1, 0, func(any) (any, error) {
 			return nil, fmt.Errorf("injected reader failure")
 		})
 		require.Error(t, err)
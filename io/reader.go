@@ -1,11 +1,13 @@
 package io
 
 import (
+	"bufio"
 	"context"
 	"encoding/base64"
 	"fmt"
+	stdio "io"
 	"net/url"
-	"os/user"
+	"os"
 	"runtime"
 	"sort"
 	"strconv"
@@ -24,6 +26,13 @@ import (
 	googleoption "google.golang.org/api/option"
 )
 
+// StdinURI is the URI of standard input for NewSourceFileReader
+const StdinURI = "-"
+
+// sourceBufferSize is buffer size of NewSourceFileReader, remote readers issue a
+// request for each read so small reads from CSV and JSON parsers are expensive.
+const sourceBufferSize = 1024 * 1024
+
 // ReadOption includes options for read operation
 type ReadOption struct {
 	AADPrefix              *string           `name:"aad-prefix" group:"Encryption" help:"(encrypted files only) base64-encoded AAD prefix (if not stored in file)."`
@@ -38,6 +47,16 @@ type ReadOption struct {
 	KeyFile                *string           `name:"key-file" group:"Encryption" help:"path to a JSON file containing decryption keys ({footer_key, aad_prefix, column_keys}); CLI flags override file values."`
 }
 
+// SourceOption includes options to read non-Parquet source from remote
+// locations, they are the part of ReadOption that is not about Parquet.
+type SourceOption struct {
+	Anonymous              bool              `help:"(S3, GCS, and Azure only) object is publicly accessible." default:"false"`
+	HTTPExtraHeaders       map[string]string `mapsep:"," help:"(HTTP URI only) extra HTTP headers." default:""`
+	HTTPIgnoreTLSError     bool              `help:"(HTTP and S3 URI) ignore TLS error." default:"false"`
+	HTTPMultipleConnection bool              `help:"(HTTP URI only) use multiple HTTP connection." default:"false"`
+	ObjectVersion          *string           `help:"(S3, GCS, and Azure only) object version."`
+}
+
 // decodeBase64 accepts only standard base64 with padding (RFC 4648 §4).
 // URL-safe and unpadded variants are rejected so that sentinels like
 // "@footer-key" remain the only path to special-cased values and so that
@@ -162,15 +181,7 @@ func newHTTPReader(ctx context.Context, u *url.URL, option ReadOption) (source.P
 }
 
 func newHDFSReader(_ context.Context, u *url.URL, _ ReadOption) (source.ParquetFileReader, error) {
-	userName := u.User.Username()
-	if userName == "" {
-		osUser, err := user.Current()
-		if err == nil && osUser != nil {
-			userName = osUser.Username
-		}
-	}
-
-	return hdfs.NewHdfsFileReader([]string{u.Host}, userName, u.Path)
+	return hdfs.NewHdfsFileReader([]string{u.Host}, hdfsUserName(u), u.Path)
 }
 
 func newSourceReader(ctx context.Context, URI string, option ReadOption) (source.ParquetFileReader, error) {
@@ -244,3 +255,54 @@ func NewParquetFileReader(ctx context.Context, URI string, option ReadOption) (*
 
 	return pr, nil
 }
+
+// NewProjectedParquetReader creates another reader on the file opened by pr
+// that only reads columns in jsonSchema, which has to be a subset of the file
+// schema, column chunks of other columns are not fetched at all.
+func NewProjectedParquetReader(ctx context.Context, pr *reader.ParquetReader, jsonSchema string, option ReadOption) (*reader.ParquetReader, error) {
+	if option.KeyFile != nil {
+		kf, err := parseKeyFile(*option.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		applyKeyFile(kf, &option)
+	}
+
+	encOpts, err := buildReaderOptions(option)
+	if err != nil {
+		return nil, err
+	}
+
+	readerOpts := append(encOpts, reader.WithNP(int64(runtime.NumCPU())))
+	projected, err := reader.NewParquetReaderWithContext(ctx, pr.PFile, jsonSchema, readerOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create projected reader: %w", err)
+	}
+	return projected, nil
+}
+
+type bufferedReadCloser struct {
+	*bufio.Reader
+	stdio.Closer
+}
+
+// NewSourceFileReader opens URI for sequential reading of non-Parquet data like
+// CSV and JSON, URI can be any location that NewParquetFileReader supports, or
+// StdinURI for standard input.
+func NewSourceFileReader(ctx context.Context, URI string, option SourceOption) (stdio.ReadCloser, error) {
+	if URI == StdinURI {
+		return stdio.NopCloser(bufio.NewReaderSize(os.Stdin, sourceBufferSize)), nil
+	}
+
+	fileReader, err := newSourceReader(ctx, URI, ReadOption{
+		Anonymous:              option.Anonymous,
+		HTTPExtraHeaders:       option.HTTPExtraHeaders,
+		HTTPIgnoreTLSError:     option.HTTPIgnoreTLSError,
+		HTTPMultipleConnection: option.HTTPMultipleConnection,
+		ObjectVersion:          option.ObjectVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bufferedReadCloser{bufio.NewReaderSize(fileReader, sourceBufferSize), fileReader}, nil
+}
@@ -3,6 +3,7 @@ package io
 import (
 	"context"
 	"encoding/base64"
+	stdio "io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -253,6 +254,91 @@ func TestNewParquetFileReader(t *testing.T) {
 	}
 }
 
+func TestNewProjectedParquetReader(t *testing.T) {
+	pr, err := NewParquetFileReader(context.Background(), "../testdata/good.parquet", ReadOption{})
+	require.NoError(t, err)
+	defer func() {
+		_ = pr.PFile.Close()
+	}()
+
+	jsonSchema := `{"Tag":"name=parquet_go_root","Fields":[{"Tag":"name=shoe_name, type=BYTE_ARRAY, convertedtype=UTF8"}]}`
+	t.Run("bad-key-file", func(t *testing.T) {
+		_, err := NewProjectedParquetReader(context.Background(), pr, jsonSchema, ReadOption{KeyFile: new("does-not-exist.json")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does-not-exist.json")
+	})
+
+	t.Run("bad-column-key", func(t *testing.T) {
+		_, err := NewProjectedParquetReader(context.Background(), pr, jsonSchema, ReadOption{ColumnKeys: []string{"shoe_name"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid column key format")
+	})
+
+	t.Run("good", func(t *testing.T) {
+		projected, err := NewProjectedParquetReader(context.Background(), pr, jsonSchema, ReadOption{})
+		require.NoError(t, err)
+		require.Len(t, projected.SchemaHandler.ValueColumns, 1)
+		rows, err := projected.ReadByNumberWithContext(context.Background(), 10)
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+	})
+}
+
+func TestNewSourceFileReader(t *testing.T) {
+	expected, err := os.ReadFile("../testdata/csv.source")
+	require.NoError(t, err)
+
+	t.Run("local", func(t *testing.T) {
+		source, err := NewSourceFileReader(context.Background(), "../testdata/csv.source", SourceOption{})
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, source.Close())
+		}()
+		content, err := stdio.ReadAll(source)
+		require.NoError(t, err)
+		require.Equal(t, expected, content)
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		stdin, err := os.Open("../testdata/csv.source")
+		require.NoError(t, err)
+		defer func() {
+			_ = stdin.Close()
+		}()
+		savedStdin := os.Stdin
+		os.Stdin = stdin
+		defer func() {
+			os.Stdin = savedStdin
+		}()
+
+		source, err := NewSourceFileReader(context.Background(), StdinURI, SourceOption{})
+		require.NoError(t, err)
+		content, err := stdio.ReadAll(source)
+		require.NoError(t, err)
+		require.Equal(t, expected, content)
+		// closing does not close standard input
+		require.NoError(t, source.Close())
+		_, err = stdin.Stat()
+		require.NoError(t, err)
+	})
+
+	testCases := map[string]struct {
+		uri    string
+		errMsg string
+	}{
+		"not-exist":      {uri: "../testdata/does-not-exist", errMsg: "unable to open file"},
+		"bad-uri":        {uri: "://uri", errMsg: "unable to parse file location"},
+		"unknown-scheme": {uri: "ftp://host/file", errMsg: "unknown location scheme [ftp]"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewSourceFileReader(context.Background(), tc.uri, SourceOption{})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
+
 func TestNewParquetFileReaderEncryption(t *testing.T) {
 	testCases := map[string]struct {
 		uri      string
@@ -0,0 +1,39 @@
+package io
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hangxie/parquet-go/v3/writer"
+)
+
+// RowGroupLimiter ends a row group after every --row-group-rows rows, writer
+// itself only ends a row group when it reaches RowGroupSize bytes, so row
+// groups can still be smaller.
+type RowGroupLimiter struct {
+	rowGroupRows int64
+	rows         int64
+}
+
+// NewRowGroupLimiter returns a limiter of rowGroupRows, it does nothing if
+// rowGroupRows is 0.
+func NewRowGroupLimiter(rowGroupRows int64) *RowGroupLimiter {
+	return &RowGroupLimiter{rowGroupRows: rowGroupRows}
+}
+
+// Written counts a row written by fileWriter, and flushes the row group if it
+// has rowGroupRows rows.
+func (l *RowGroupLimiter) Written(ctx context.Context, fileWriter *writer.ParquetWriter) error {
+	if l == nil || l.rowGroupRows <= 0 {
+		return nil
+	}
+	l.rows++
+	if l.rows < l.rowGroupRows {
+		return nil
+	}
+	l.rows = 0
+	if err := fileWriter.FlushWithContext(ctx, true); err != nil {
+		return fmt.Errorf("failed to flush row group: %w", err)
+	}
+	return nil
+}
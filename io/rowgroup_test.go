@@ -0,0 +1,54 @@
+package io
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowGroupLimiter(t *testing.T) {
+	testCases := map[string]struct {
+		rowGroupRows int64
+		expected     []int64
+	}{
+		"no-limit":  {0, []int64{5}},
+		"one":       {1, []int64{1, 1, 1, 1, 1}},
+		"two":       {2, []int64{2, 2, 1}},
+		"all":       {5, []int64{5}},
+		"too-large": {10, []int64{5}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), "row-group.parquet")
+			schema := `{"Tag":"name=root","Fields":[{"Tag":"name=id, type=INT64"}]}`
+			pw, err := NewJSONWriter(ctx, path, WriteOption{RowGroupRows: tc.rowGroupRows}, schema)
+			require.NoError(t, err)
+			limiter := NewRowGroupLimiter(tc.rowGroupRows)
+			for id := range 5 {
+				require.NoError(t, pw.WriteWithContext(ctx, fmt.Sprintf(`{"id":%d}`, id)))
+				require.NoError(t, limiter.Written(ctx, &pw.ParquetWriter))
+			}
+			require.NoError(t, pw.WriteStopWithContext(ctx))
+			require.NoError(t, pw.PFile.Close())
+
+			pr, err := NewParquetFileReader(ctx, path, ReadOption{})
+			require.NoError(t, err)
+			defer func() { require.NoError(t, pr.PFile.Close()) }()
+			var rows []int64
+			for _, rowGroup := range pr.Footer.RowGroups {
+				rows = append(rows, rowGroup.NumRows)
+			}
+			require.Equal(t, tc.expected, rows)
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		var limiter *RowGroupLimiter
+		require.NoError(t, limiter.Written(context.Background(), nil))
+		require.NoError(t, NewRowGroupLimiter(0).Written(context.Background(), nil))
+	})
+}
@@ -0,0 +1,84 @@
+package io
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// byteSizeUnits are units of ParseByteSize, in powers of 1024.
+var byteSizeUnits = map[string]int64{
+	"":   1,
+	"B":  1,
+	"K":  1 << 10,
+	"KB": 1 << 10,
+	"M":  1 << 20,
+	"MB": 1 << 20,
+	"G":  1 << 30,
+	"GB": 1 << 30,
+	"T":  1 << 40,
+	"TB": 1 << 40,
+}
+
+// ParseByteSize parses sizes like "512MB" or "1048576".
+func ParseByteSize(size string) (int64, error) {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	numberEnd := strings.IndexFunc(size, func(r rune) bool { return r < '0' || r > '9' })
+	if numberEnd < 0 {
+		numberEnd = len(size)
+	}
+	unit, found := byteSizeUnits[strings.TrimSpace(size[numberEnd:])]
+	if !found {
+		return 0, fmt.Errorf("unknown unit [%s]", strings.TrimSpace(size[numberEnd:]))
+	}
+	number, err := strconv.ParseInt(size[:numberEnd], 10, 64)
+	if err != nil || number < 1 {
+		return 0, fmt.Errorf("needs a positive integer with optional unit B, KB, MB, GB, or TB")
+	}
+	if number > (1<<63-1)/unit {
+		return 0, fmt.Errorf("size is too large")
+	}
+	return number * unit, nil
+}
+
+// RowSize is in-memory size of a row, close to its size in PLAIN encoding.
+func RowSize(value reflect.Value) int64 {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return 0
+		}
+		return RowSize(value.Elem())
+	case reflect.Struct:
+		size := int64(0)
+		for index := range value.NumField() {
+			size += RowSize(value.Field(index))
+		}
+		return size
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return int64(value.Len()) + 4
+		}
+		size := int64(0)
+		for index := range value.Len() {
+			size += RowSize(value.Index(index))
+		}
+		return size
+	case reflect.Map:
+		size := int64(0)
+		iter := value.MapRange()
+		for iter.Next() {
+			size += RowSize(iter.Key()) + RowSize(iter.Value())
+		}
+		return size
+	case reflect.String:
+		// BYTE_ARRAY has 4 bytes of length
+		return int64(value.Len()) + 4
+	case reflect.Bool:
+		return 1
+	}
+	return int64(value.Type().Size())
+}
@@ -0,0 +1,74 @@
+package io
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseByteSize(t *testing.T) {
+	testCases := map[string]struct {
+		size   int64
+		errMsg string
+	}{
+		"1024":      {size: 1024},
+		"100B":      {size: 100},
+		"512MB":     {size: 512 << 20},
+		"512mb":     {size: 512 << 20},
+		"2 GB":      {size: 2 << 30},
+		"1K":        {size: 1 << 10},
+		"1TB":       {size: 1 << 40},
+		"":          {errMsg: "needs a positive integer"},
+		"0MB":       {errMsg: "needs a positive integer"},
+		"-1MB":      {errMsg: "unknown unit [-1MB]"},
+		"1.5GB":     {errMsg: "unknown unit [.5GB]"},
+		"1PB":       {errMsg: "unknown unit [PB]"},
+		"MB":        {errMsg: "needs a positive integer"},
+		"9000000TB": {errMsg: "size is too large"},
+	}
+	for input, tc := range testCases {
+		t.Run(input, func(t *testing.T) {
+			size, err := ParseByteSize(input)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.size, size)
+		})
+	}
+}
+
+func TestRowSize(t *testing.T) {
+	str := "abc"
+	testCases := map[string]struct {
+		row      any
+		expected int64
+	}{
+		"nil":     {nil, 0},
+		"int32":   {int32(1), 4},
+		"int64":   {int64(1), 8},
+		"bool":    {true, 1},
+		"string":  {"abcd", 8},
+		"bytes":   {[]byte("ab"), 6},
+		"pointer": {&str, 7},
+		"nil-ptr": {(*string)(nil), 0},
+		"list":    {[]int32{1, 2, 3}, 12},
+		"map":     {map[string]int64{"a": 1}, 13},
+		"struct": {
+			struct {
+				A int32
+				B *string
+				C []float64
+			}{1, &str, []float64{1}},
+			19,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, RowSize(reflect.ValueOf(tc.row)))
+		})
+	}
+}
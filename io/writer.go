@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"net/url"
-	"os/user"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/hangxie/parquet-go/v3/parquet"
 	parquetschema "github.com/hangxie/parquet-go/v3/schema"
 	"github.com/hangxie/parquet-go/v3/source"
 	"github.com/hangxie/parquet-go/v3/source/azblob"
@@ -36,20 +38,22 @@ var WriterEncryptionAlgorithms = []string{
 
 // WriteOption includes options for write operation
 type WriteOption struct {
-	BinaryMinMaxTruncateLength int      `name:"binary-min-max-truncate-length" help:"Target byte limit for binary and string min/max statistics and column-index bounds; 0 disables truncation." default:"0"`
-	CompressionCodec           string   `short:"z" name:"compression" help:"compression codec (UNCOMPRESSED/SNAPPY/GZIP/LZ4/LZ4_RAW/ZSTD/BROTLI)" default:"SNAPPY"`
-	CompressionLevel           []string `help:"Compression level setting." placeholder:"CODEC=LEVEL"`
-	DataPageVersion            int32    `help:"Data page version (1 or 2). Use 1 for legacy DATA_PAGE format." enum:"1,2" default:"2"`
-	EncryptAllColumns          bool     `name:"encrypt-all-columns" group:"Encryption" help:"encrypt every leaf column. Columns not listed in --writer-column-key use --writer-footer-key. Default: false (only columns listed in --writer-column-key are encrypted)." default:"false"`
-	EncryptionAlgorithm        string   `name:"encryption-algorithm" group:"Encryption" help:"encryption algorithm used when --writer-footer-key is set. AES-GCM-V1 authenticates every module; AES-GCM-CTR-V1 uses AES-CTR for page bodies (lower overhead, page body tampering not detected). Has no effect without --writer-footer-key." enum:"${writer_encryption_algorithms}" default:"AES-GCM-V1"`
-	FieldDelimiter             string   `kong:"-"`
-	MaxDictionarySize          int64    `name:"max-dictionary-size" help:"Maximum encoded dictionary value bytes per column and row group; 0 uses the writer default." default:"0"`
-	PageSize                   int64    `help:"Page size in bytes." default:"1048576"`
-	PlaintextFooter            bool     `name:"plaintext-footer" group:"Encryption" help:"write a PAR1 file with a plaintext footer signed by --writer-footer-key instead of an encrypted PARE footer. Without --encrypt-all-columns or --writer-column-key the footer is signed for integrity only (columns remain plaintext)." default:"false"`
-	RowGroupSize               int64    `help:"Row group size in bytes." default:"134217728"`
-	WriterFooterKey            *string  `name:"writer-footer-key" group:"Encryption" help:"base64-encoded AES-128/192/256 key. Encrypts the footer; also used for columns marked '=@footer-key' and for unlisted columns when --encrypt-all-columns is set. With --plaintext-footer the key signs the footer instead of encrypting it."`
-	WriterColumnKeys           []string `name:"writer-column-key" group:"Encryption" help:"per-column encryption directive 'column.path=VALUE'; repeatable. column.path is the file-schema path of a leaf column without the schema root (e.g. Parent.Child, not parquet_go_root.Parent.Child), separated by --field-delimiter. VALUE is a base64-encoded AES key, or the literal '@footer-key' to encrypt the column with --writer-footer-key. Columns not listed are plaintext unless --encrypt-all-columns is set." placeholder:"column.path=base64key"`
-	WriterKeyFile              *string  `name:"writer-key-file" group:"Encryption" help:"path to a JSON file containing encryption keys ({footer_key, column_keys}); CLI flags override file values; --writer-column-key flags merge with file column_keys, CLI wins per path. Recommend chmod 600 on the file."`
+	BinaryMinMaxTruncateLength int                      `name:"binary-min-max-truncate-length" help:"Target byte limit for binary and string min/max statistics and column-index bounds; 0 disables truncation." default:"0"`
+	CompressionCodec           string                   `short:"z" name:"compression" help:"compression codec (UNCOMPRESSED/SNAPPY/GZIP/LZ4/LZ4_RAW/ZSTD/BROTLI)" default:"SNAPPY"`
+	CompressionLevel           []string                 `help:"Compression level setting." placeholder:"CODEC=LEVEL"`
+	DataPageVersion            int32                    `help:"Data page version (1 or 2). Use 1 for legacy DATA_PAGE format." enum:"1,2" default:"2"`
+	EncryptAllColumns          bool                     `name:"encrypt-all-columns" group:"Encryption" help:"encrypt every leaf column. Columns not listed in --writer-column-key use --writer-footer-key. Default: false (only columns listed in --writer-column-key are encrypted)." default:"false"`
+	EncryptionAlgorithm        string                   `name:"encryption-algorithm" group:"Encryption" help:"encryption algorithm used when --writer-footer-key is set. AES-GCM-V1 authenticates every module; AES-GCM-CTR-V1 uses AES-CTR for page bodies (lower overhead, page body tampering not detected). Has no effect without --writer-footer-key." enum:"${writer_encryption_algorithms}" default:"AES-GCM-V1"`
+	FieldDelimiter             string                   `kong:"-"`
+	MaxDictionarySize          int64                    `name:"max-dictionary-size" help:"Maximum encoded dictionary value bytes per column and row group; 0 uses the writer default." default:"0"`
+	PageSize                   int64                    `help:"Page size in bytes." default:"1048576"`
+	PlaintextFooter            bool                     `name:"plaintext-footer" group:"Encryption" help:"write a PAR1 file with a plaintext footer signed by --writer-footer-key instead of an encrypted PARE footer. Without --encrypt-all-columns or --writer-column-key the footer is signed for integrity only (columns remain plaintext)." default:"false"`
+	RowGroupRows               int64                    `help:"Maximum number of rows in a row group; 0 means no limit other than --row-group-size." default:"0"`
+	RowGroupSize               int64                    `help:"Row group size in bytes." default:"134217728"`
+	SortingColumns             []*parquet.SortingColumn `kong:"-"`
+	WriterFooterKey            *string                  `name:"writer-footer-key" group:"Encryption" help:"base64-encoded AES-128/192/256 key. Encrypts the footer; also used for columns marked '=@footer-key' and for unlisted columns when --encrypt-all-columns is set. With --plaintext-footer the key signs the footer instead of encrypting it."`
+	WriterColumnKeys           []string                 `name:"writer-column-key" group:"Encryption" help:"per-column encryption directive 'column.path=VALUE'; repeatable. column.path is the file-schema path of a leaf column without the schema root (e.g. Parent.Child, not parquet_go_root.Parent.Child), separated by --field-delimiter. VALUE is a base64-encoded AES key, or the literal '@footer-key' to encrypt the column with --writer-footer-key. Columns not listed are plaintext unless --encrypt-all-columns is set." placeholder:"column.path=base64key"`
+	WriterKeyFile              *string                  `name:"writer-key-file" group:"Encryption" help:"path to a JSON file containing encryption keys ({footer_key, column_keys}); CLI flags override file values; --writer-column-key flags merge with file column_keys, CLI wins per path. Recommend chmod 600 on the file."`
 }
 
 func newLocalWriter(_ context.Context, u *url.URL) (source.ParquetFileWriter, error) {
@@ -100,20 +104,26 @@ func newHTTPWriter(_ context.Context, u *url.URL) (source.ParquetFileWriter, err
 }
 
 func newHDFSWriter(_ context.Context, u *url.URL) (source.ParquetFileWriter, error) {
-	userName := u.User.Username()
-	if userName == "" {
-		osUser, err := user.Current()
-		if err == nil && osUser != nil {
-			userName = osUser.Username
-		}
-	}
-	fileWriter, err := hdfs.NewHdfsFileWriter([]string{u.Host}, userName, u.Path)
+	fileWriter, err := hdfs.NewHdfsFileWriter([]string{u.Host}, hdfsUserName(u), u.Path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open HDFS source [%s]: %w", u.String(), err)
 	}
 	return fileWriter, nil
 }
 
+// CreateParentDir creates parent directories of a local file, other locations
+// are not changed.
+func CreateParentDir(uri string) error {
+	u, err := parseURI(uri)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != schemeLocal {
+		return nil
+	}
+	return os.MkdirAll(filepath.Dir(u.Path), 0o755)
+}
+
 func NewParquetFileWriter(ctx context.Context, uri string) (source.ParquetFileWriter, error) {
 	writerFuncTable := map[string]func(context.Context, *url.URL) (source.ParquetFileWriter, error){
 		schemeLocal:              newLocalWriter,
@@ -143,6 +153,9 @@ func writerOpts(option WriteOption, columnKeys []writerColumnKey) ([]writer.Writ
 	if option.BinaryMinMaxTruncateLength > 0 {
 		opts = append(opts, writer.WithBinaryMinMaxTruncateLength(option.BinaryMinMaxTruncateLength))
 	}
+	if option.RowGroupRows < 0 {
+		return nil, fmt.Errorf("row group rows must not be negative: %d", option.RowGroupRows)
+	}
 	if option.MaxDictionarySize < 0 {
 		return nil, fmt.Errorf("maximum dictionary size must not be negative: %d", option.MaxDictionarySize)
 	}
@@ -172,6 +185,9 @@ func writerOpts(option WriteOption, columnKeys []writerColumnKey) ([]writer.Writ
 	if option.RowGroupSize > 0 {
 		opts = append(opts, writer.WithRowGroupSize(option.RowGroupSize))
 	}
+	if len(option.SortingColumns) != 0 {
+		opts = append(opts, writer.WithSortingColumns(option.SortingColumns...))
+	}
 	encryptionOpts, err := writerEncryptionOpts(option, columnKeys)
 	if err != nil {
 		return nil, err
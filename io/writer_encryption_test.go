@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/hangxie/parquet-go/v3/common"
+	"github.com/hangxie/parquet-go/v3/parquet"
 	parquetschema "github.com/hangxie/parquet-go/v3/schema"
 	"github.com/stretchr/testify/require"
 )
@@ -435,6 +436,10 @@ func TestWriterOpts(t *testing.T) {
 			option:      WriteOption{},
 			expectedLen: 2, // DataPageVersion + NP
 		},
+		"sorting-columns": {
+			option:      WriteOption{SortingColumns: []*parquet.SortingColumn{{ColumnIdx: 1, Descending: true}}},
+			expectedLen: 3,
+		},
 		"invalid-compression": {
 			option: WriteOption{CompressionCodec: "INVALID"},
 			errMsg: "not a valid CompressionCodec",
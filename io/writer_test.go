@@ -543,6 +543,15 @@ func TestMaxDictionarySizeRejectsNegativeValue(t *testing.T) {
 	require.Contains(t, err.Error(), "maximum dictionary size must not be negative")
 }
 
+func TestRowGroupRowsRejectsNegativeValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "row-group.parquet")
+	schema := `{"Tag":"name=root","Fields":[{"Tag":"name=value, type=BYTE_ARRAY"}]}`
+	pw, err := NewJSONWriter(context.Background(), path, WriteOption{RowGroupRows: -1}, schema)
+	require.Error(t, err)
+	require.Nil(t, pw)
+	require.Contains(t, err.Error(), "row group rows must not be negative")
+}
+
 func TestNewGenericWriter(t *testing.T) {
 	tempDir := t.TempDir()
 	tempFile := filepath.Join(tempDir, "unit-test.parquet")
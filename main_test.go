@@ -37,3 +37,28 @@ func TestWriterFinalizationPreservesCancellation(t *testing.T) {
 	})
 	require.NoError(t, err)
 }
+
+func TestImportFlags(t *testing.T) {
+	testCases := map[string]struct {
+		args   []string
+		errMsg string
+	}{
+		"remote-option":  {[]string{"--anonymous", "--object-version", "v1"}, ""},
+		"http-option":    {[]string{"--http-extra-headers", "key=value"}, ""},
+		"decryption-key": {[]string{"--footer-key", "MDEyMzQ1Njc4OTAxMjM0NQ=="}, "unknown flag --footer-key"},
+		"key-file":       {[]string{"--key-file", "keys.json"}, "unknown flag --key-file"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			parser := newParser(new(cli))
+			args := append([]string{"import", "-s", "testdata/csv.source", "-m", "testdata/csv.schema"}, tc.args...)
+			_, err := parser.Parse(append(args, "dummy.parquet"))
+			if tc.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
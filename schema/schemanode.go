@@ -120,9 +120,6 @@ func (s SchemaNode) CSVSchema() (string, error) {
 		if strings.Contains(f.Tag, "repetitiontype=REPEATED") {
 			return "", fmt.Errorf("CSV does not support column in LIST type")
 		}
-		if strings.Contains(f.Tag, "repetitiontype=OPTIONAL") {
-			return "", fmt.Errorf("CSV does not support optional column")
-		}
 		tag := strings.Replace(f.Tag, ", repetitiontype=REQUIRED", "", 1)
 		// Remove inname tag from CSV schema as it's Go-specific
 		tag = removeTagFromString(tag, "inname")
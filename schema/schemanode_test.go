@@ -867,9 +867,9 @@ func TestCSVSchema(t *testing.T) {
 			errMsg: "CSV supports flat schema only",
 		},
 		{
-			name:   "optional column not supported",
-			uri:    "../testdata/csv-optional.parquet",
-			errMsg: "CSV does not support optional column",
+			name:       "optional column",
+			uri:        "../testdata/csv-optional.parquet",
+			goldenFile: "../testdata/golden/schema-csv-optional.txt",
 		},
 		{
 			name:   "repeated column not supported",
@@ -0,0 +1,235 @@
+package schema
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hangxie/parquet-go/v3/parquet"
+)
+
+// SortKey tells where a sort field is in rows read with a schema, InPath is
+// nil if rows do not have the field.
+type SortKey struct {
+	InPath   []string
+	Unsigned bool
+}
+
+// ParseSortBy parses a sort field in "[-]field.path[=ASC|DESC]" format, order
+// is ASC by default and a leading "-" means DESC, it returns path of the field
+// and whether it is descending.
+func ParseSortBy(spec, delimiter string) ([]string, bool, error) {
+	rawFieldPath, order, hasOrder := strings.Cut(spec, "=")
+	rawFieldPath, order = strings.TrimSpace(rawFieldPath), strings.TrimSpace(order)
+	rawFieldPath, minus := strings.CutPrefix(rawFieldPath, "-")
+	if rawFieldPath == "" {
+		return nil, false, fmt.Errorf("empty field path in [%s]", spec)
+	}
+	if minus && hasOrder {
+		return nil, false, fmt.Errorf("sort order of [%s] is given by both leading - and =%s", rawFieldPath, order)
+	}
+	descending := minus
+	switch strings.ToUpper(order) {
+	case "", "ASC":
+	case "DESC":
+		descending = true
+	default:
+		return nil, false, fmt.Errorf("invalid sort order [%s] of [%s], needs to be ASC or DESC", order, rawFieldPath)
+	}
+	if delimiter == "" {
+		delimiter = "."
+	}
+	return strings.Split(rawFieldPath, delimiter), descending, nil
+}
+
+// FindSortField returns leaf node of path that rows can be sorted by, fields
+// in repeated fields cannot be sorted by.
+func (s *SchemaNode) FindSortField(path []string, delimiter string) (*SchemaNode, error) {
+	fieldPath := strings.Join(path, delimiter)
+	node := s
+	for _, name := range path {
+		var child *SchemaNode
+		for _, candidate := range node.Children {
+			if candidate.Name == name {
+				child = candidate
+				break
+			}
+		}
+		if child == nil || child.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+			return nil, fmt.Errorf("field [%s] does not exist", fieldPath)
+		}
+		node = child
+	}
+	if err := node.sortable(fieldPath); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// sortable rejects groups and types whose values do not sort in order of their
+// physical values.
+func (s *SchemaNode) sortable(path string) error {
+	if s.Type == nil {
+		return fmt.Errorf("field [%s] is not a primitive field, cannot sort by it", path)
+	}
+	switch *s.Type {
+	case parquet.Type_INT96:
+		return fmt.Errorf("field [%s] is INT96, cannot sort by it", path)
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		// only binary and strings sort byte by byte
+		if s.ConvertedType != nil && *s.ConvertedType != parquet.ConvertedType_UTF8 && *s.ConvertedType != parquet.ConvertedType_ENUM {
+			return fmt.Errorf("field [%s] is %s, cannot sort by it", path, *s.ConvertedType)
+		}
+		if lt := s.LogicalType; lt != nil && !lt.IsSetSTRING() && !lt.IsSetENUM() && !lt.IsSetUUID() {
+			return fmt.Errorf("field [%s] is %s, cannot sort by it", path, s.GetTagMap()["logicaltype"])
+		}
+	}
+	return nil
+}
+
+// IntegerAnnotation returns if field is a plain integer and whether it is
+// signed.
+func (s *SchemaNode) IntegerAnnotation() (bool, bool) {
+	if s.LogicalType != nil {
+		if !s.LogicalType.IsSetINTEGER() {
+			return false, false
+		}
+		return true, s.LogicalType.INTEGER.IsSigned
+	}
+	if s.ConvertedType == nil {
+		return true, true
+	}
+	switch *s.ConvertedType {
+	case parquet.ConvertedType_INT_8, parquet.ConvertedType_INT_16, parquet.ConvertedType_INT_32, parquet.ConvertedType_INT_64:
+		return true, true
+	case parquet.ConvertedType_UINT_8, parquet.ConvertedType_UINT_16, parquet.ConvertedType_UINT_32, parquet.ConvertedType_UINT_64:
+		return true, false
+	}
+	return false, false
+}
+
+// ColumnIndex returns index of leaf node among leaf columns of s, or -1 if
+// node is not a leaf of s.
+func (s *SchemaNode) ColumnIndex(node *SchemaNode) int32 {
+	index := int32(0)
+	var walk func(current *SchemaNode) bool
+	walk = func(current *SchemaNode) bool {
+		if len(current.Children) == 0 {
+			if current == node {
+				return true
+			}
+			index++
+			return false
+		}
+		for _, child := range current.Children {
+			if walk(child) {
+				return true
+			}
+		}
+		return false
+	}
+	if !walk(s) {
+		return -1
+	}
+	return index
+}
+
+// NewSortKey returns SortKey of a field found by FindSortField.
+func NewSortKey(node *SchemaNode) SortKey {
+	integer, signed := node.IntegerAnnotation()
+	return SortKey{InPath: node.InNamePath[1:], Unsigned: integer && !signed}
+}
+
+// Value returns value of field in row, signed integers are int64, unsigned
+// integers are uint64, and floats are float64.
+func (k SortKey) Value(row any) any {
+	if k.InPath == nil {
+		return nil
+	}
+	value := reflect.ValueOf(row)
+	for _, name := range k.InPath {
+		for value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return nil
+			}
+			value = value.Elem()
+		}
+		if value = value.FieldByName(name); !value.IsValid() {
+			return nil
+		}
+	}
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Int32:
+		if k.Unsigned {
+			return uint64(uint32(value.Int()))
+		}
+		return value.Int()
+	case reflect.Int64:
+		if k.Unsigned {
+			return uint64(value.Int())
+		}
+		return value.Int()
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	}
+	return value.Interface()
+}
+
+// CompareSortKeys compares values returned by SortKey.Value, nil is smaller
+// than any value.
+func CompareSortKeys(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch x := a.(type) {
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		}
+		if x {
+			return 1
+		}
+		return -1
+	case int64:
+		if y, ok := b.(uint64); ok {
+			return -CompareSortKeys(y, x)
+		}
+		return cmp.Compare(x, b.(int64))
+	case uint64:
+		if y, ok := b.(int64); ok {
+			if y < 0 {
+				return 1
+			}
+			return cmp.Compare(x, uint64(y))
+		}
+		return cmp.Compare(x, b.(uint64))
+	case float64:
+		return cmp.Compare(x, b.(float64))
+	case string:
+		return strings.Compare(x, b.(string))
+	}
+	return 0
+}
+
+// NewSortingColumn returns SortingColumn of a row group, nulls are smaller
+// than any value.
+func NewSortingColumn(columnIndex int32, descending bool) *parquet.SortingColumn {
+	return &parquet.SortingColumn{
+		ColumnIdx:  columnIndex,
+		Descending: descending,
+		NullsFirst: !descending,
+	}
+}
@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSortBy(t *testing.T) {
+	testCases := map[string]struct {
+		delimiter  string
+		path       []string
+		descending bool
+		errMsg     string
+	}{
+		"a.b":         {".", []string{"a", "b"}, false, ""},
+		" a=ASC":      {".", []string{"a"}, false, ""},
+		"a.b = desc":  {".", []string{"a", "b"}, true, ""},
+		"a.b=DESC":    {"", []string{"a", "b"}, true, ""},
+		"a/b.c=DESC":  {"/", []string{"a", "b.c"}, true, ""},
+		"-a.b":        {".", []string{"a", "b"}, true, ""},
+		" -a ":        {".", []string{"a"}, true, ""},
+		"-":           {".", nil, false, "empty field path in [-]"},
+		"-a=DESC":     {".", nil, false, "sort order of [a] is given by both leading - and =DESC"},
+		"=DESC":       {".", nil, false, "empty field path in [=DESC]"},
+		" ":           {".", nil, false, "empty field path in [ ]"},
+		"a=up":        {".", nil, false, "invalid sort order [up] of [a], needs to be ASC or DESC"},
+		"a=DESC=DESC": {".", nil, false, "invalid sort order [DESC=DESC] of [a], needs to be ASC or DESC"},
+	}
+	for spec, tc := range testCases {
+		t.Run(spec, func(t *testing.T) {
+			path, descending, err := ParseSortBy(spec, tc.delimiter)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Equal(t, tc.errMsg, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.path, path)
+			require.Equal(t, tc.descending, descending)
+		})
+	}
+}
+
+func TestSortKeyValue(t *testing.T) {
+	type nested struct {
+		Value *int32
+	}
+	type row struct {
+		Int32   int32
+		Int64   int64
+		Float   float32
+		String  *string
+		Nested  *nested
+		Missing *nested
+	}
+	value := row{Int32: -1, Int64: -2, Float: 1.5, String: new("foo"), Nested: &nested{Value: new(int32(-3))}}
+
+	testCases := map[string]struct {
+		key      SortKey
+		expected any
+	}{
+		"int32":         {SortKey{InPath: []string{"Int32"}}, int64(-1)},
+		"uint32":        {SortKey{InPath: []string{"Int32"}, Unsigned: true}, uint64(0xffffffff)},
+		"int64":         {SortKey{InPath: []string{"Int64"}}, int64(-2)},
+		"uint64":        {SortKey{InPath: []string{"Int64"}, Unsigned: true}, uint64(0xfffffffffffffffe)},
+		"float":         {SortKey{InPath: []string{"Float"}}, float64(1.5)},
+		"string":        {SortKey{InPath: []string{"String"}}, "foo"},
+		"nested":        {SortKey{InPath: []string{"Nested", "Value"}}, int64(-3)},
+		"nil-parent":    {SortKey{InPath: []string{"Missing", "Value"}}, nil},
+		"no-such-field": {SortKey{InPath: []string{"Foo"}}, nil},
+		"not-in-source": {SortKey{}, nil},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.key.Value(value))
+			require.Equal(t, tc.expected, tc.key.Value(&value))
+		})
+	}
+}
+
+func TestCompareSortKeys(t *testing.T) {
+	testCases := map[string]struct {
+		a, b     any
+		expected int
+	}{
+		"nil-nil":         {nil, nil, 0},
+		"nil-value":       {nil, int64(-1), -1},
+		"value-nil":       {"", nil, 1},
+		"bool":            {false, true, -1},
+		"bool-equal":      {true, true, 0},
+		"bool-greater":    {true, false, 1},
+		"int64":           {int64(-1), int64(1), -1},
+		"uint64":          {uint64(2), uint64(1), 1},
+		"int64-uint64":    {int64(-1), uint64(0), -1},
+		"uint64-int64":    {uint64(1<<63 + 1), int64(1<<63 - 1), 1},
+		"uint64-negative": {uint64(0), int64(-1), 1},
+		"uint64-int64-eq": {uint64(5), int64(5), 0},
+		"int64-uint64-eq": {int64(2), uint64(2), 0},
+		"float64":         {1.5, 2.5, -1},
+		"float64-equal":   {1.5, 1.5, 0},
+		"string":          {"b", "a", 1},
+		"string-not-utf8": {"\xff", "a", 1},
+		"string-prefix":   {"a", "ab", -1},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, CompareSortKeys(tc.a, tc.b))
+		})
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	a, b, c := &SchemaNode{}, &SchemaNode{}, &SchemaNode{}
+	group := &SchemaNode{Children: []*SchemaNode{b, c}}
+	root := &SchemaNode{Children: []*SchemaNode{a, group}}
+
+	require.Equal(t, int32(0), root.ColumnIndex(a))
+	require.Equal(t, int32(1), root.ColumnIndex(b))
+	require.Equal(t, int32(2), root.ColumnIndex(c))
+	require.Equal(t, int32(-1), root.ColumnIndex(group))
+	require.Equal(t, int32(-1), root.ColumnIndex(&SchemaNode{}))
+}